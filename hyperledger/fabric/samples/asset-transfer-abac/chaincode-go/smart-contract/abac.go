@@ -25,25 +25,8 @@ type Asset struct {
 // CreateAsset issues a new asset to the world state with given details.
 func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface, id string, color string, size int, appraisedValue int) error {
 
-	// Demonstrate the use of Attribute-Based Access Control (ABAC) by checking
-	// to see if the caller has the "abac.creator" attribute with a value of true;
-	// if not, return an error.
-	//
-	err := ctx.GetClientIdentity().AssertAttributeValue("abac.creator", "true")
-	if err != nil {
-		return fmt.Errorf("submitting client not authorized to create asset, does not have abac.creator role")
-	}
-
-	exists, err := s.AssetExists(ctx, id)
-	if err != nil {
-		return err
-	}
-	if exists {
-		return fmt.Errorf("the asset %s already exists", id)
-	}
-
 	// Get ID of submitting client identity
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	clientID, err := getSubmittingClientID(ctx)
 	if err != nil {
 		return err
 	}
@@ -55,6 +38,24 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 		Owner:          clientID,
 		AppraisedValue: appraisedValue,
 	}
+
+	// Demonstrate the use of Attribute-Based Access Control (ABAC) by checking
+	// the rules for creating the asset. By default, the caller must have the
+	// "abac.creator" attribute with a value of true; if not, return an error.
+	//
+	err = Authorize(ctx, "CreateAsset", &asset)
+	if err != nil {
+		return err
+	}
+
+	exists, err := assetExists(ctx, id)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("the asset %s already exists", id)
+	}
+
 	assetJSON, err := json.Marshal(asset)
 	if err != nil {
 		return err
@@ -66,20 +67,16 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface,
 // UpdateAsset updates an existing asset in the world state with provided parameters.
 func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface, id string, newColor string, newSize int, newValue int) error {
 
-	asset, err := s.ReadAsset(ctx, id)
+	asset, err := readAsset(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	err = Authorize(ctx, "UpdateAsset", asset)
 	if err != nil {
 		return err
 	}
 
-	if clientID != asset.Owner {
-		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
-	}
-
 	asset.Color = newColor
 	asset.Size = newSize
 	asset.AppraisedValue = newValue
@@ -95,18 +92,19 @@ func (s *SmartContract) UpdateAsset(ctx contractapi.TransactionContextInterface,
 // DeleteAsset deletes a given asset from the world state.
 func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface, id string) error {
 
-	asset, err := s.ReadAsset(ctx, id)
+	asset, err := readAsset(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	err = Authorize(ctx, "DeleteAsset", asset)
 	if err != nil {
 		return err
 	}
 
-	if clientID != asset.Owner {
-		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
+	err = deleteDelegations(ctx, id)
+	if err != nil {
+		return err
 	}
 
 	return ctx.GetStub().DelState(id)
@@ -115,18 +113,20 @@ func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface,
 // TransferAsset updates the owner field of asset with given id in world state.
 func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterface, id string, newOwner string) error {
 
-	asset, err := s.ReadAsset(ctx, id)
+	asset, err := readAsset(ctx, id)
 	if err != nil {
 		return err
 	}
 
-	clientID, err := s.GetSubmittingClientIdentity(ctx)
+	err = Authorize(ctx, "TransferAsset", asset)
 	if err != nil {
 		return err
 	}
 
-	if clientID != asset.Owner {
-		return fmt.Errorf("submitting client not authorized to update asset, does not own asset")
+	// The delegations of the previous owner do not carry over to the new owner
+	err = deleteDelegations(ctx, id)
+	if err != nil {
+		return err
 	}
 
 	asset.Owner = newOwner
@@ -141,25 +141,34 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 // ReadAsset returns the asset stored in the world state with given id.
 func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
 
-	assetJSON, err := ctx.GetStub().GetState(id)
+	asset, err := readAsset(ctx, id)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read from world state: %v", err)
-	}
-	if assetJSON == nil {
-		return nil, fmt.Errorf("the asset %s does not exist", id)
+		return nil, err
 	}
 
-	var asset Asset
-	err = json.Unmarshal(assetJSON, &asset)
+	err = Authorize(ctx, "ReadAsset", asset)
 	if err != nil {
 		return nil, err
 	}
 
-	return &asset, nil
+	return asset, nil
 }
 
-// GetAllAssets returns all assets found in world state
+// GetAllAssets returns all assets found in world state that the rules allow the submitting client to read
 func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface) ([]*Asset, error) {
+
+	err := Authorize(ctx, "GetAllAssets", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each asset is checked like for ReadAsset, so that the list does not include assets that the client
+	// cannot read on their own
+	rules, err := readRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	// range query with empty string for startKey and endKey does an
 	// open-ended query of all assets in the chaincode namespace.
 	resultsIterator, err := ctx.GetStub().GetStateByRange("", "")
@@ -180,7 +189,14 @@ func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface
 		if err != nil {
 			return nil, err
 		}
-		assets = append(assets, &asset)
+
+		decision, err := evaluateRules(ctx, rules, "ReadAsset", asset.ID, &asset)
+		if err != nil {
+			return nil, err
+		}
+		if decision.Allowed {
+			assets = append(assets, &asset)
+		}
 	}
 
 	return assets, nil
@@ -189,12 +205,12 @@ func (s *SmartContract) GetAllAssets(ctx contractapi.TransactionContextInterface
 // AssetExists returns true when asset with given ID exists in world state
 func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
 
-	assetJSON, err := ctx.GetStub().GetState(id)
+	err := Authorize(ctx, "AssetExists", nil)
 	if err != nil {
-		return false, fmt.Errorf("failed to read from world state: %v", err)
+		return false, err
 	}
 
-	return assetJSON != nil, nil
+	return assetExists(ctx, id)
 }
 
 // GetSubmittingClientIdentity returns the name and issuer of the identity that
@@ -202,6 +218,49 @@ func (s *SmartContract) AssetExists(ctx contractapi.TransactionContextInterface,
 // before returning the value to the client or smart contract.
 func (s *SmartContract) GetSubmittingClientIdentity(ctx contractapi.TransactionContextInterface) (string, error) {
 
+	err := Authorize(ctx, "GetSubmittingClientIdentity", nil)
+	if err != nil {
+		return "", err
+	}
+
+	return getSubmittingClientID(ctx)
+}
+
+// readAsset returns the asset stored in the world state with given id, without checking access.
+func readAsset(ctx contractapi.TransactionContextInterface, id string) (*Asset, error) {
+
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assetJSON == nil {
+		return nil, fmt.Errorf("the asset %s does not exist", id)
+	}
+
+	var asset Asset
+	err = json.Unmarshal(assetJSON, &asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+// assetExists returns true when asset with given ID exists in world state
+func assetExists(ctx contractapi.TransactionContextInterface, id string) (bool, error) {
+
+	assetJSON, err := ctx.GetStub().GetState(id)
+	if err != nil {
+		return false, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	return assetJSON != nil, nil
+}
+
+// getSubmittingClientID returns the base64 decoded name and issuer of the identity that
+// invokes the smart contract.
+func getSubmittingClientID(ctx contractapi.TransactionContextInterface) (string, error) {
+
 	b64ID, err := ctx.GetClientIdentity().GetID()
 	if err != nil {
 		return "", fmt.Errorf("Failed to read clientID: %v", err)
@@ -0,0 +1,303 @@
+package abac
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	assetDelegateIndex = "asset~delegate"
+	delegateAssetIndex = "delegate~asset"
+)
+
+// delegableActions are the actions that the owner of an asset can delegate
+var delegableActions = []string{"UpdateAsset", "TransferAsset", "DeleteAsset"}
+
+// Delegation allows a client to perform actions on an asset on behalf of its owner until it expires.
+// A delegation is void once the asset has a new owner.
+type Delegation struct {
+	AssetID    string   `json:"assetID"`
+	DelegateID string   `json:"delegateID"`
+	Delegator  string   `json:"delegator"`
+	Actions    []string `json:"actions"`
+	Expiry     string   `json:"expiry"`
+}
+
+// Delegate allows a client to update, transfer or delete an asset on behalf of its owner until the expiry,
+// which is an RFC3339 time. A delegation replaces any previous delegation of the asset to the same client.
+// By default only the owner can delegate, and the rules still apply to the delegate.
+func (s *SmartContract) Delegate(ctx contractapi.TransactionContextInterface, assetID string, delegateID string, actions []string, expiry string) error {
+
+	asset, err := readAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	err = Authorize(ctx, "Delegate", asset)
+	if err != nil {
+		return err
+	}
+
+	if delegateID == "" {
+		return fmt.Errorf("the delegate ID must not be empty")
+	}
+	if delegateID == asset.Owner {
+		return fmt.Errorf("the owner of asset %s cannot be its delegate", assetID)
+	}
+	if len(actions) == 0 {
+		return fmt.Errorf("at least one action must be delegated")
+	}
+	for _, action := range actions {
+		if !isDelegable(action) {
+			return fmt.Errorf("the action %s cannot be delegated, must be one of %v", action, delegableActions)
+		}
+	}
+
+	expiryTime, err := time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		return fmt.Errorf("invalid expiry %q: %v", expiry, err)
+	}
+	txTime, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !txTime.Before(expiryTime) {
+		return fmt.Errorf("the expiry %s is in the past", expiry)
+	}
+
+	delegation := Delegation{
+		AssetID:    assetID,
+		DelegateID: delegateID,
+		Delegator:  asset.Owner,
+		Actions:    actions,
+		Expiry:     expiry,
+	}
+	delegationJSON, err := json.Marshal(delegation)
+	if err != nil {
+		return err
+	}
+
+	assetDelegateKey, err := ctx.GetStub().CreateCompositeKey(assetDelegateIndex, []string{assetID, delegateID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(assetDelegateKey, delegationJSON)
+	if err != nil {
+		return err
+	}
+
+	// The delegate index only needs the key, the delegation is read from the asset index
+	delegateAssetKey, err := ctx.GetStub().CreateCompositeKey(delegateAssetIndex, []string{delegateID, assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().PutState(delegateAssetKey, []byte{0x00})
+}
+
+// RevokeDelegation revokes the delegation of an asset to a client. By default only the owner can revoke delegations.
+func (s *SmartContract) RevokeDelegation(ctx contractapi.TransactionContextInterface, assetID string, delegateID string) error {
+
+	asset, err := readAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	err = Authorize(ctx, "RevokeDelegation", asset)
+	if err != nil {
+		return err
+	}
+
+	delegation, err := readDelegation(ctx, assetID, delegateID)
+	if err != nil {
+		return err
+	}
+	if delegation == nil {
+		return fmt.Errorf("asset %s is not delegated to %s", assetID, delegateID)
+	}
+
+	return deleteDelegation(ctx, assetID, delegateID)
+}
+
+// GetDelegationsByAsset returns the delegations of an asset
+func (s *SmartContract) GetDelegationsByAsset(ctx contractapi.TransactionContextInterface, assetID string) ([]*Delegation, error) {
+
+	err := Authorize(ctx, "GetDelegationsByAsset", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return readDelegationsByAsset(ctx, assetID)
+}
+
+// GetDelegationsByDelegate returns the delegations to a client
+func (s *SmartContract) GetDelegationsByDelegate(ctx contractapi.TransactionContextInterface, delegateID string) ([]*Delegation, error) {
+
+	err := Authorize(ctx, "GetDelegationsByDelegate", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(delegateAssetIndex, []string{delegateID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	delegations := []*Delegation{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+
+		delegation, err := readDelegation(ctx, compositeKeyParts[1], delegateID)
+		if err != nil {
+			return nil, err
+		}
+		if delegation != nil {
+			delegations = append(delegations, delegation)
+		}
+	}
+
+	return delegations, nil
+}
+
+// delegatedBy returns the delegation that allows the client to perform an action on an asset at the time of
+// the transaction, or nil if there is none
+func delegatedBy(ctx contractapi.TransactionContextInterface, asset *Asset, clientID string, action string, txTime time.Time) (*Delegation, error) {
+
+	if !isDelegable(action) {
+		return nil, nil
+	}
+
+	delegation, err := readDelegation(ctx, asset.ID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if delegation == nil || delegation.Delegator != asset.Owner {
+		return nil, nil
+	}
+
+	expiry, _ := time.Parse(time.RFC3339, delegation.Expiry) // Error handling not needed since the expiry was validated by Delegate.
+	if !txTime.Before(expiry) {
+		return nil, nil
+	}
+
+	for _, a := range delegation.Actions {
+		if a == action {
+			return delegation, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// readDelegation returns the delegation of an asset to a client, or nil if there is none
+func readDelegation(ctx contractapi.TransactionContextInterface, assetID string, delegateID string) (*Delegation, error) {
+
+	assetDelegateKey, err := ctx.GetStub().CreateCompositeKey(assetDelegateIndex, []string{assetID, delegateID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	delegationJSON, err := ctx.GetStub().GetState(assetDelegateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if delegationJSON == nil {
+		return nil, nil
+	}
+
+	var delegation Delegation
+	err = json.Unmarshal(delegationJSON, &delegation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &delegation, nil
+}
+
+// readDelegationsByAsset returns the delegations of an asset
+func readDelegationsByAsset(ctx contractapi.TransactionContextInterface, assetID string) ([]*Delegation, error) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(assetDelegateIndex, []string{assetID})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	delegations := []*Delegation{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var delegation Delegation
+		err = json.Unmarshal(queryResponse.Value, &delegation)
+		if err != nil {
+			return nil, err
+		}
+		delegations = append(delegations, &delegation)
+	}
+
+	return delegations, nil
+}
+
+// deleteDelegations deletes all delegations of an asset, when it is transferred or deleted
+func deleteDelegations(ctx contractapi.TransactionContextInterface, assetID string) error {
+
+	delegations, err := readDelegationsByAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	for _, delegation := range delegations {
+		err = deleteDelegation(ctx, assetID, delegation.DelegateID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteDelegation deletes the delegation of an asset to a client from both indexes
+func deleteDelegation(ctx contractapi.TransactionContextInterface, assetID string, delegateID string) error {
+
+	assetDelegateKey, err := ctx.GetStub().CreateCompositeKey(assetDelegateIndex, []string{assetID, delegateID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().DelState(assetDelegateKey)
+	if err != nil {
+		return err
+	}
+
+	delegateAssetKey, err := ctx.GetStub().CreateCompositeKey(delegateAssetIndex, []string{delegateID, assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(delegateAssetKey)
+}
+
+// isDelegable returns true if the owner of an asset can delegate an action
+func isDelegable(action string) bool {
+	for _, a := range delegableActions {
+		if a == action {
+			return true
+		}
+	}
+
+	return false
+}
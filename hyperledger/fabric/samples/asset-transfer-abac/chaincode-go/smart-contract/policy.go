@@ -0,0 +1,661 @@
+package abac
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const ruleObjectType = "rule"
+
+// Rule effects
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
+// Condition types
+const (
+	ConditionAttribute = "attribute"
+	ConditionMSP       = "msp"
+	ConditionOU        = "ou"
+	ConditionField     = "field"
+	ConditionTime      = "time"
+)
+
+// Condition operators
+const (
+	OperatorEq      = "eq"
+	OperatorNe      = "ne"
+	OperatorIn      = "in"
+	OperatorLt      = "lt"
+	OperatorGt      = "gt"
+	OperatorPresent = "present"
+)
+
+// clientIDValue can be used as the value of a field condition to compare the field to the ID of the submitting client,
+// for example to require that the client owns the asset
+const clientIDValue = "$client.id"
+
+// Rule is an access control rule stored on the ledger. A rule applies to a request if the action of the request is
+// one of its actions, or if its actions include "*", and all of its conditions are met. If any rule that applies denies
+// the request it is denied, otherwise it is allowed if any rule that applies allows it. Requests that no rule allows
+// are denied.
+type Rule struct {
+	ID          string      `json:"ID"`
+	Description string      `json:"description,omitempty" metadata:",optional"`
+	Effect      string      `json:"effect"`
+	Actions     []string    `json:"actions"`
+	Conditions  []Condition `json:"conditions,omitempty" metadata:",optional"`
+}
+
+// Condition is a condition of a rule on the submitting client, the asset or the time of the transaction:
+//   - attribute compares the value of the client certificate attribute Name
+//   - msp compares the MSP ID of the client
+//   - ou compares the organizational units of the client certificate, and is met if any of them matches, or for ne
+//     if none of them is equal to the value
+//   - field compares the value of the asset field Name, which is the JSON name of the field
+//   - time is met if the transaction time is between Values[0] and Values[1], which are either RFC3339 times or
+//     daily UTC times such as 09:00
+//
+// The operators eq, ne and in compare strings, lt and gt compare numbers, and present is met if the value is set.
+type Condition struct {
+	Type     string   `json:"type"`
+	Name     string   `json:"name,omitempty" metadata:",optional"`
+	Operator string   `json:"operator,omitempty" metadata:",optional"`
+	Values   []string `json:"values,omitempty" metadata:",optional"`
+}
+
+// Decision is the result of evaluating the rules for a request, with the trace of the evaluation.
+// If no rule applies, the request may be allowed by a delegation from the owner of the asset.
+type Decision struct {
+	Action     string      `json:"action"`
+	Resource   string      `json:"resource"`
+	Allowed    bool        `json:"allowed"`
+	Reason     string      `json:"reason"`
+	Trace      []RuleTrace `json:"trace"`
+	Delegation *Delegation `json:"delegation,omitempty" metadata:",optional"`
+}
+
+// RuleTrace records whether a rule applied to a request
+type RuleTrace struct {
+	RuleID     string           `json:"ruleID"`
+	Effect     string           `json:"effect"`
+	Applied    bool             `json:"applied"`
+	Conditions []ConditionTrace `json:"conditions"`
+}
+
+// ConditionTrace records the value that a condition was evaluated against, and whether it was met
+type ConditionTrace struct {
+	Condition Condition `json:"condition"`
+	Actual    string    `json:"actual"`
+	Met       bool      `json:"met"`
+}
+
+// defaultRules are used until an admin stores rules on the ledger, and are stored along with the first rule, so that
+// they stay in effect until they are explicitly replaced or deleted. They only allow clients with the
+// abac.creator=true attribute to create assets, and only the owner of an asset to update, transfer, delete or delegate it.
+// All clients can read assets, the rules and their own identity.
+var defaultRules = []*Rule{
+	{
+		ID:          "default-create",
+		Description: "clients with the abac.creator role can create assets",
+		Effect:      EffectAllow,
+		Actions:     []string{"CreateAsset"},
+		Conditions:  []Condition{{Type: ConditionAttribute, Name: "abac.creator", Operator: OperatorEq, Values: []string{"true"}}},
+	},
+	{
+		ID:          "default-owner",
+		Description: "the owner of an asset can update, transfer, delete and delegate it",
+		Effect:      EffectAllow,
+		Actions:     []string{"UpdateAsset", "TransferAsset", "DeleteAsset", "Delegate", "RevokeDelegation"},
+		Conditions:  []Condition{{Type: ConditionField, Name: "owner", Operator: OperatorEq, Values: []string{clientIDValue}}},
+	},
+	{
+		ID:          "default-read",
+		Description: "all clients can read assets, their delegations and the rules, and their own identity",
+		Effect:      EffectAllow,
+		Actions: []string{"ReadAsset", "GetAllAssets", "AssetExists", "GetDelegationsByAsset", "GetDelegationsByDelegate",
+			"GetRules", "ExplainDecision", "GetSubmittingClientIdentity"},
+	},
+}
+
+// PutRule creates or replaces an access control rule. Only clients with the abac.admin=true attribute can manage rules.
+// The first rule that is stored is added to the default rules, which can be replaced by storing a rule with their ID.
+func (s *SmartContract) PutRule(ctx contractapi.TransactionContextInterface, rule Rule) error {
+	err := assertAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = validateRule(&rule)
+	if err != nil {
+		return err
+	}
+
+	err = storeDefaultRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	return putRule(ctx, &rule)
+}
+
+// DeleteRule deletes an access control rule, which may be one of the default rules. Only clients with the
+// abac.admin=true attribute can manage rules. If all rules are deleted, the default rules apply again.
+func (s *SmartContract) DeleteRule(ctx contractapi.TransactionContextInterface, ruleID string) error {
+	err := assertAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules, err := readRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, rule := range rules {
+		if rule.ID == ruleID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("the rule %s does not exist", ruleID)
+	}
+
+	err = storeDefaultRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	ruleKey, err := ctx.GetStub().CreateCompositeKey(ruleObjectType, []string{ruleID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	return ctx.GetStub().DelState(ruleKey)
+}
+
+// GetRules returns the access control rules in effect, which are the default rules if no rules are stored.
+// Like other queries, it is only allowed by the rules, which by default allow all clients to read the rules.
+func (s *SmartContract) GetRules(ctx contractapi.TransactionContextInterface) ([]*Rule, error) {
+	err := Authorize(ctx, "GetRules", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return readRules(ctx)
+}
+
+// ExplainDecision evaluates the rules for the submitting client performing an action on an asset, and returns
+// the decision with the trace of the evaluation. The asset ID can be empty for actions that are not on an asset.
+// The rules must allow the client to ExplainDecision on the asset, since the trace reveals its fields.
+func (s *SmartContract) ExplainDecision(ctx contractapi.TransactionContextInterface, action string, assetID string) (*Decision, error) {
+	var asset *Asset
+	if assetID != "" {
+		assetJSON, err := ctx.GetStub().GetState(assetID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read from world state: %v", err)
+		}
+		if assetJSON != nil {
+			err = json.Unmarshal(assetJSON, &asset)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err := Authorize(ctx, "ExplainDecision", asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return evaluate(ctx, action, assetID, asset)
+}
+
+// Authorize checks that the rules, or a delegation from the owner of the asset, allow the submitting client to
+// perform an action on an asset. The asset is nil for actions that are not on an asset.
+func Authorize(ctx contractapi.TransactionContextInterface, action string, resource *Asset) error {
+	resourceID := ""
+	if resource != nil {
+		resourceID = resource.ID
+	}
+
+	decision, err := evaluate(ctx, action, resourceID, resource)
+	if err != nil {
+		return err
+	}
+	if !decision.Allowed {
+		return fmt.Errorf("submitting client not authorized to %s, %s", action, decision.Reason)
+	}
+
+	return nil
+}
+
+// evaluate evaluates the rules for the submitting client performing an action on a resource
+func evaluate(ctx contractapi.TransactionContextInterface, action string, resourceID string, resource *Asset) (*Decision, error) {
+	rules, err := readRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return evaluateRules(ctx, rules, action, resourceID, resource)
+}
+
+// evaluateRules evaluates the given rules for the submitting client performing an action on a resource, so that
+// the rules only need to be read once to evaluate the same action on several resources
+func evaluateRules(ctx contractapi.TransactionContextInterface, rules []*Rule, action string, resourceID string, resource *Asset) (*Decision, error) {
+	request, err := newRequest(ctx, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &Decision{
+		Action:   action,
+		Resource: resourceID,
+		Reason:   "no rule allows the action",
+		Trace:    []RuleTrace{},
+	}
+
+	allowedBy := ""
+	deniedBy := ""
+	for _, rule := range rules {
+		if !ruleHasAction(rule, action) {
+			continue
+		}
+
+		trace := RuleTrace{RuleID: rule.ID, Effect: rule.Effect, Applied: true, Conditions: []ConditionTrace{}}
+		for _, condition := range rule.Conditions {
+			conditionTrace, err := request.evaluateCondition(condition)
+			if err != nil {
+				return nil, fmt.Errorf("failed to evaluate rule %s: %v", rule.ID, err)
+			}
+			trace.Conditions = append(trace.Conditions, conditionTrace)
+			trace.Applied = trace.Applied && conditionTrace.Met
+		}
+		decision.Trace = append(decision.Trace, trace)
+
+		if !trace.Applied {
+			continue
+		}
+		if rule.Effect == EffectDeny && deniedBy == "" {
+			deniedBy = rule.ID
+		}
+		if rule.Effect == EffectAllow && allowedBy == "" {
+			allowedBy = rule.ID
+		}
+	}
+
+	// Deny rules take precedence over allow rules and delegations
+	switch {
+	case deniedBy != "":
+		decision.Reason = fmt.Sprintf("denied by rule %s", deniedBy)
+	case allowedBy != "":
+		decision.Allowed = true
+		decision.Reason = fmt.Sprintf("allowed by rule %s", allowedBy)
+	case resource != nil:
+		delegation, err := delegatedBy(ctx, resource, request.clientID, action, request.txTime)
+		if err != nil {
+			return nil, err
+		}
+		if delegation != nil {
+			decision.Allowed = true
+			decision.Reason = fmt.Sprintf("allowed by delegation from the owner until %s", delegation.Expiry)
+			decision.Delegation = delegation
+		}
+	}
+
+	return decision, nil
+}
+
+// request holds the properties of the submitting client, the resource and the transaction that conditions are evaluated against
+type request struct {
+	ctx      contractapi.TransactionContextInterface
+	clientID string
+	fields   map[string]interface{}
+	txTime   time.Time
+}
+
+func newRequest(ctx contractapi.TransactionContextInterface, resource *Asset) (*request, error) {
+	clientID, err := getSubmittingClientID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if resource != nil {
+		resourceJSON, err := json.Marshal(resource)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(resourceJSON, &fields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	txTime, err := getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &request{
+		ctx:      ctx,
+		clientID: clientID,
+		fields:   fields,
+		txTime:   txTime,
+	}, nil
+}
+
+// evaluateCondition evaluates a condition of a rule against the request
+func (r *request) evaluateCondition(condition Condition) (ConditionTrace, error) {
+	trace := ConditionTrace{Condition: condition}
+
+	var actual []string
+	switch condition.Type {
+	case ConditionAttribute:
+		value, found, err := r.ctx.GetClientIdentity().GetAttributeValue(condition.Name)
+		if err != nil {
+			return trace, fmt.Errorf("failed to read attribute %s: %v", condition.Name, err)
+		}
+		if found {
+			actual = []string{value}
+		}
+	case ConditionMSP:
+		mspID, err := r.ctx.GetClientIdentity().GetMSPID()
+		if err != nil {
+			return trace, fmt.Errorf("failed to read MSPID: %v", err)
+		}
+		actual = []string{mspID}
+	case ConditionOU:
+		cert, err := r.ctx.GetClientIdentity().GetX509Certificate()
+		if err != nil {
+			return trace, fmt.Errorf("failed to read client certificate: %v", err)
+		}
+		actual = cert.Subject.OrganizationalUnit
+	case ConditionField:
+		if value, ok := r.fields[condition.Name]; ok {
+			actual = []string{formatField(value)}
+		}
+	case ConditionTime:
+		actual = []string{r.txTime.Format(time.RFC3339)}
+		trace.Actual = actual[0]
+		trace.Met = inTimeWindow(r.txTime, condition.Values[0], condition.Values[1])
+		return trace, nil
+	}
+
+	trace.Actual = strings.Join(actual, ",")
+
+	// A condition on several values, such as the OUs, is met if any value matches, but ne is only met if none
+	// of the values is equal, so that it cannot be met by another value of the same client
+	if condition.Operator == OperatorNe {
+		trace.Met = true
+		for _, value := range actual {
+			if !r.compare(condition, value) {
+				trace.Met = false
+				break
+			}
+		}
+		return trace, nil
+	}
+
+	for _, value := range actual {
+		if r.compare(condition, value) {
+			trace.Met = true
+			break
+		}
+	}
+
+	return trace, nil
+}
+
+// compare returns true if a value meets the operator and values of a condition
+func (r *request) compare(condition Condition, value string) bool {
+	values := make([]string, len(condition.Values))
+	for i, v := range condition.Values {
+		if v == clientIDValue {
+			v = r.clientID
+		}
+		values[i] = v
+	}
+
+	switch condition.Operator {
+	case OperatorEq:
+		return value == values[0]
+	case OperatorNe:
+		return value != values[0]
+	case OperatorIn:
+		for _, v := range values {
+			if value == v {
+				return true
+			}
+		}
+		return false
+	case OperatorLt, OperatorGt:
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return false
+		}
+		bound, _ := strconv.ParseFloat(values[0], 64) // Error handling not needed since the bound was validated by PutRule.
+		if condition.Operator == OperatorLt {
+			return number < bound
+		}
+		return number > bound
+	case OperatorPresent:
+		return true
+	}
+
+	return false
+}
+
+// readRules returns the rules stored on the ledger, or the default rules if there are none
+func readRules(ctx contractapi.TransactionContextInterface) ([]*Rule, error) {
+	rules, err := readStoredRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rules) == 0 {
+		return defaultRules, nil
+	}
+
+	return rules, nil
+}
+
+// readStoredRules returns the rules stored on the ledger
+func readStoredRules(ctx contractapi.TransactionContextInterface) ([]*Rule, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(ruleObjectType, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	rules := []*Rule{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var rule Rule
+		err = json.Unmarshal(queryResponse.Value, &rule)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, nil
+}
+
+// storeDefaultRules stores the default rules on the ledger before the first change to the rules, so that a new
+// rule is added to them rather than replacing them all. A rule that is put or deleted afterwards in the same
+// transaction overrides the default rule with its ID.
+func storeDefaultRules(ctx contractapi.TransactionContextInterface) error {
+	rules, err := readStoredRules(ctx)
+	if err != nil {
+		return err
+	}
+	if len(rules) > 0 {
+		return nil
+	}
+
+	for _, rule := range defaultRules {
+		err = putRule(ctx, rule)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// putRule stores a rule on the ledger
+func putRule(ctx contractapi.TransactionContextInterface, rule *Rule) error {
+	ruleKey, err := ctx.GetStub().CreateCompositeKey(ruleObjectType, []string{rule.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	ruleJSON, err := json.Marshal(rule)
+	if err != nil {
+		return err
+	}
+
+	return ctx.GetStub().PutState(ruleKey, ruleJSON)
+}
+
+// validateRule checks that a rule can be evaluated
+func validateRule(rule *Rule) error {
+	if rule.ID == "" {
+		return fmt.Errorf("the rule ID must not be empty")
+	}
+	if rule.Effect != EffectAllow && rule.Effect != EffectDeny {
+		return fmt.Errorf("the effect of rule %s must be %s or %s", rule.ID, EffectAllow, EffectDeny)
+	}
+	if len(rule.Actions) == 0 {
+		return fmt.Errorf("rule %s must have at least one action", rule.ID)
+	}
+
+	for i, condition := range rule.Conditions {
+		switch condition.Type {
+		case ConditionAttribute, ConditionField:
+			if condition.Name == "" {
+				return fmt.Errorf("condition %d of rule %s must have a name", i, rule.ID)
+			}
+		case ConditionMSP, ConditionOU:
+		case ConditionTime:
+			if len(condition.Values) != 2 {
+				return fmt.Errorf("time condition %d of rule %s must have a start and an end", i, rule.ID)
+			}
+			for _, value := range condition.Values {
+				if _, err := parseTimeOfWindow(value); err != nil {
+					return fmt.Errorf("time condition %d of rule %s: %v", i, rule.ID, err)
+				}
+			}
+			continue
+		default:
+			return fmt.Errorf("condition %d of rule %s has unknown type %q", i, rule.ID, condition.Type)
+		}
+
+		switch condition.Operator {
+		case OperatorEq, OperatorNe, OperatorLt, OperatorGt:
+			if len(condition.Values) != 1 {
+				return fmt.Errorf("condition %d of rule %s must have exactly one value", i, rule.ID)
+			}
+			if condition.Operator == OperatorLt || condition.Operator == OperatorGt {
+				if _, err := strconv.ParseFloat(condition.Values[0], 64); err != nil {
+					return fmt.Errorf("condition %d of rule %s must have a number value", i, rule.ID)
+				}
+			}
+		case OperatorIn:
+			if len(condition.Values) == 0 {
+				return fmt.Errorf("condition %d of rule %s must have at least one value", i, rule.ID)
+			}
+		case OperatorPresent:
+		default:
+			return fmt.Errorf("condition %d of rule %s has unknown operator %q", i, rule.ID, condition.Operator)
+		}
+	}
+
+	return nil
+}
+
+// ruleHasAction returns true if a rule applies to an action
+func ruleHasAction(rule *Rule, action string) bool {
+	for _, a := range rule.Actions {
+		if a == action || a == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// inTimeWindow returns true if a time is between the start and end of a window. A window of daily times,
+// such as 22:00 to 06:00, may span midnight.
+func inTimeWindow(t time.Time, start string, end string) bool {
+	startTime, _ := parseTimeOfWindow(start) // Error handling not needed since the window was validated by PutRule.
+	endTime, _ := parseTimeOfWindow(end)
+
+	if startTime.Year() != 0 || endTime.Year() != 0 {
+		return !t.Before(startTime) && t.Before(endTime)
+	}
+
+	timeOfDay := time.Date(0, time.January, 1, t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+	if startTime.After(endTime) {
+		return !timeOfDay.Before(startTime) || timeOfDay.Before(endTime)
+	}
+
+	return !timeOfDay.Before(startTime) && timeOfDay.Before(endTime)
+}
+
+// parseTimeOfWindow parses the start or end of a time window, which is either an RFC3339 time or a daily UTC time
+func parseTimeOfWindow(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("15:04", value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %q, must be an RFC3339 time or a daily time such as 09:00", value)
+}
+
+// formatField formats the value of an asset field for comparison
+func formatField(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// getTxTime returns the timestamp of the transaction, which is the same on all endorsing peers
+func getTxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return time.Unix(txTimestamp.GetSeconds(), int64(txTimestamp.GetNanos())).UTC(), nil
+}
+
+// assertAdmin checks that the submitting client has the abac.admin=true attribute
+func assertAdmin(ctx contractapi.TransactionContextInterface) error {
+	err := ctx.GetClientIdentity().AssertAttributeValue("abac.admin", "true")
+	if err != nil {
+		return fmt.Errorf("submitting client not authorized to manage rules, does not have abac.admin role")
+	}
+
+	return nil
+}
@@ -0,0 +1,273 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// ledgerState exports the world state of the asset-transfer-ledger-queries chaincode
+// to a JSON Lines file and imports such a file into another channel or chaincode.
+//
+// Usage:
+//
+//	go run ledgerState.go export <file>
+//	go run ledgerState.go import <file> <importID>
+//
+// The export pages through the ExportState query. The import replays the file in
+// batches through ImportState and can be re-run with the same importID after a
+// failure: batches already recorded in the import checkpoint are skipped.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hyperledger/fabric-sdk-go/pkg/core/config"
+	"github.com/hyperledger/fabric-sdk-go/pkg/gateway"
+)
+
+const (
+	exportPageSize = 100
+	// the batch limits stay below the maxImportBatchRecords and
+	// maxImportBatchBytes limits enforced by the chaincode
+	importBatchRecords = 500
+	importBatchBytes   = 1<<20 - 1024
+)
+
+// importCheckpoint mirrors the ImportCheckpoint returned by the chaincode
+type importCheckpoint struct {
+	ImportID        string `json:"importID"`
+	LastBatch       int    `json:"lastBatch"`
+	LastKey         string `json:"lastKey"`
+	RecordsImported int    `json:"recordsImported"`
+}
+
+func main() {
+	if len(os.Args) < 3 || (os.Args[1] == "import" && len(os.Args) < 4) {
+		log.Println("Usage: ledgerState export <file>")
+		log.Fatalf("       ledgerState import <file> <importID>")
+	}
+
+	gw, contract, err := connect()
+	if err != nil {
+		log.Fatalf("Failed to connect: %v", err)
+	}
+	defer gw.Close()
+
+	switch os.Args[1] {
+	case "export":
+		count, err := exportState(contract, os.Args[2])
+		if err != nil {
+			log.Fatalf("Export failed: %v", err)
+		}
+		log.Printf("Exported %d keys to %s", count, os.Args[2])
+	case "import":
+		checkpoint, err := importState(contract, os.Args[2], os.Args[3])
+		if err != nil {
+			log.Fatalf("Import failed: %v", err)
+		}
+		log.Printf("Imported %d keys in %d batches", checkpoint.RecordsImported, checkpoint.LastBatch)
+	default:
+		log.Fatalf("Unknown command %s", os.Args[1])
+	}
+}
+
+// exportState writes every exported record to the file as one JSON object per line
+func exportState(contract *gateway.Contract, path string) (int, error) {
+	file, err := os.Create(filepath.Clean(path))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	writer := bufio.NewWriter(file)
+
+	count := 0
+	bookmark := ""
+	for {
+		result, err := contract.EvaluateTransaction("ExportState", strconv.Itoa(exportPageSize), bookmark)
+		if err != nil {
+			return count, fmt.Errorf("failed to evaluate ExportState: %v", err)
+		}
+
+		var page struct {
+			Records  []json.RawMessage `json:"records"`
+			Bookmark string            `json:"bookmark"`
+		}
+		err = json.Unmarshal(result, &page)
+		if err != nil {
+			return count, err
+		}
+
+		for _, record := range page.Records {
+			_, err = writer.Write(append(record, '\n'))
+			if err != nil {
+				return count, err
+			}
+			count++
+		}
+
+		if page.Bookmark == "" {
+			return count, writer.Flush()
+		}
+		bookmark = page.Bookmark
+	}
+}
+
+// importState submits the records of the file in size-bounded batches, skipping
+// the batches already covered by the checkpoint of the import
+func importState(contract *gateway.Contract, path string, importID string) (*importCheckpoint, error) {
+	result, err := contract.EvaluateTransaction("GetImportCheckpoint", importID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate GetImportCheckpoint: %v", err)
+	}
+	checkpoint := &importCheckpoint{}
+	err = json.Unmarshal(result, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if checkpoint.LastBatch > 0 {
+		log.Printf("Resuming import %s after batch %d (last key %q)", importID, checkpoint.LastBatch, checkpoint.LastKey)
+	}
+
+	file, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), importBatchBytes)
+
+	batch := 0
+	var records []json.RawMessage
+	size := 2
+	submit := func() error {
+		batch++
+		if batch <= checkpoint.LastBatch {
+			return nil
+		}
+		batchJSON, err := json.Marshal(records)
+		if err != nil {
+			return err
+		}
+		log.Printf("--> Submit Transaction: ImportState, batch %d with %d keys", batch, len(records))
+		result, err := contract.SubmitTransaction("ImportState", importID, strconv.Itoa(batch), string(batchJSON))
+		if err != nil {
+			return fmt.Errorf("failed to submit batch %d: %v", batch, err)
+		}
+		return json.Unmarshal(result, checkpoint)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		if len(records) == importBatchRecords || size+len(line)+1 > importBatchBytes {
+			if err := submit(); err != nil {
+				return nil, err
+			}
+			records, size = nil, 2
+		}
+		records = append(records, json.RawMessage(append([]byte{}, line...)))
+		size += len(line) + 1
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(records) > 0 {
+		if err := submit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return checkpoint, nil
+}
+
+func connect() (*gateway.Gateway, *gateway.Contract, error) {
+	err := os.Setenv("DISCOVERY_AS_LOCALHOST", "true")
+	if err != nil {
+		return nil, nil, fmt.Errorf("error setting DISCOVERY_AS_LOCALHOST environemnt variable: %v", err)
+	}
+
+	wallet, err := gateway.NewFileSystemWallet("wallet")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create wallet: %v", err)
+	}
+
+	if !wallet.Exists("appUser") {
+		err = populateWallet(wallet)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to populate wallet contents: %v", err)
+		}
+	}
+
+	ccpPath := filepath.Join(
+		"..",
+		"..",
+		"test-network",
+		"organizations",
+		"peerOrganizations",
+		"org1.example.com",
+		"connection-org1.yaml",
+	)
+
+	gw, err := gateway.Connect(
+		gateway.WithConfig(config.FromFile(filepath.Clean(ccpPath))),
+		gateway.WithIdentity(wallet, "appUser"),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to gateway: %v", err)
+	}
+
+	network, err := gw.GetNetwork("mychannel")
+	if err != nil {
+		gw.Close()
+		return nil, nil, fmt.Errorf("failed to get network: %v", err)
+	}
+
+	return gw, network.GetContract("ledger"), nil
+}
+
+func populateWallet(wallet *gateway.Wallet) error {
+	credPath := filepath.Join(
+		"..",
+		"..",
+		"test-network",
+		"organizations",
+		"peerOrganizations",
+		"org1.example.com",
+		"users",
+		"User1@org1.example.com",
+		"msp",
+	)
+
+	certPath := filepath.Join(credPath, "signcerts", "cert.pem")
+	// read the certificate pem
+	cert, err := ioutil.ReadFile(filepath.Clean(certPath))
+	if err != nil {
+		return err
+	}
+
+	keyDir := filepath.Join(credPath, "keystore")
+	// there's a single file in this dir containing the private key
+	files, err := ioutil.ReadDir(keyDir)
+	if err != nil {
+		return err
+	}
+	if len(files) != 1 {
+		return fmt.Errorf("keystore folder should have contain one file")
+	}
+	keyPath := filepath.Join(keyDir, files[0].Name())
+	key, err := ioutil.ReadFile(filepath.Clean(keyPath))
+	if err != nil {
+		return err
+	}
+
+	identity := gateway.NewX509Identity("Org1MSP", string(cert), string(key))
+
+	return wallet.Put("appUser", identity)
+}
@@ -0,0 +1,210 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+/*
+====ASSET CHANGE LOG (CLI) ==================
+
+Every write to an asset also records which client submitted it, keyed by the asset
+and the transaction ID. GetAssetChangeLog joins those records with the key history
+and returns the difference between consecutive versions as RFC 6902 JSON Patch
+operations.
+
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["GetAssetChangeLog","asset1"]}'
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// attributionIndex keys the attribution of each asset write by asset ID and transaction ID
+const attributionIndex = "attribution~assetID~txID"
+
+// Attribution identifies the client that submitted a write
+type Attribution struct {
+	MSPID    string `json:"mspId"`
+	ClientID string `json:"clientId"`
+}
+
+// PatchOperation is a single RFC 6902 JSON Patch operation
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// ChangeLogEntry structure used for returning the changes made to an asset by one transaction
+type ChangeLogEntry struct {
+	TxId      string           `json:"txId"`
+	Timestamp time.Time        `json:"timestamp"`
+	MSPID     string           `json:"mspId"`
+	ClientID  string           `json:"clientId"`
+	IsDelete  bool             `json:"isDelete"`
+	Patch     []PatchOperation `json:"patch"`
+}
+
+// recordAttribution saves the identity of the submitting client for a write to assetID
+// made by the current transaction
+func recordAttribution(ctx contractapi.TransactionContextInterface, assetID string) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client ID: %v", err)
+	}
+
+	attributionBytes, err := json.Marshal(Attribution{MSPID: mspID, ClientID: clientID})
+	if err != nil {
+		return err
+	}
+
+	return putCompositeState(ctx, attributionIndex, []string{assetID, ctx.GetStub().GetTxID()}, attributionBytes)
+}
+
+// GetAssetChangeLog returns the changes made to an asset, oldest first, as JSON Patch
+// operations between consecutive versions together with the submitting client.
+// Writes made before attribution was recorded have an empty MSP and client ID.
+func (t *SimpleChaincode) GetAssetChangeLog(ctx contractapi.TransactionContextInterface, assetID string) ([]ChangeLogEntry, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var entries []ChangeLogEntry
+	var previous interface{}
+	var versions []interface{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp, err := ptypes.Timestamp(response.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+
+		var current interface{}
+		if !response.IsDelete {
+			err = json.Unmarshal(response.Value, &current)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		entries = append(entries, ChangeLogEntry{
+			TxId:      response.TxId,
+			Timestamp: timestamp,
+			IsDelete:  response.IsDelete,
+		})
+		versions = append(versions, current)
+	}
+
+	// the history is returned newest first
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+
+	for i := range entries {
+		if entries[i].IsDelete {
+			entries[i].Patch = []PatchOperation{{Op: "remove", Path: ""}}
+		} else if previous == nil {
+			entries[i].Patch = []PatchOperation{{Op: "add", Path: "", Value: versions[i]}}
+		} else {
+			entries[i].Patch = diffJSON("", previous, versions[i])
+		}
+		previous = versions[i]
+
+		attribution, err := readAttribution(ctx, assetID, entries[i].TxId)
+		if err != nil {
+			return nil, err
+		}
+		entries[i].MSPID = attribution.MSPID
+		entries[i].ClientID = attribution.ClientID
+	}
+
+	return entries, nil
+}
+
+func readAttribution(ctx contractapi.TransactionContextInterface, assetID string, txID string) (*Attribution, error) {
+	attributionKey, err := ctx.GetStub().CreateCompositeKey(attributionIndex, []string{assetID, txID})
+	if err != nil {
+		return nil, err
+	}
+	attributionBytes, err := ctx.GetStub().GetState(attributionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attribution of %s in %s: %v", assetID, txID, err)
+	}
+
+	var attribution Attribution
+	if attributionBytes == nil {
+		return &attribution, nil
+	}
+	err = json.Unmarshal(attributionBytes, &attribution)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attribution, nil
+}
+
+// diffJSON returns the JSON Patch operations that turn the decoded JSON document from
+// into to. Objects are compared member by member in key order; any other change,
+// including to arrays, replaces the value as a whole.
+func diffJSON(path string, from, to interface{}) []PatchOperation {
+	fromObject, fromIsObject := from.(map[string]interface{})
+	toObject, toIsObject := to.(map[string]interface{})
+	if !fromIsObject || !toIsObject {
+		if reflect.DeepEqual(from, to) {
+			return nil
+		}
+		return []PatchOperation{{Op: "replace", Path: path, Value: to}}
+	}
+
+	var keys []string
+	for key := range fromObject {
+		keys = append(keys, key)
+	}
+	for key := range toObject {
+		if _, ok := fromObject[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	var operations []PatchOperation
+	for _, key := range keys {
+		memberPath := path + "/" + escapeJSONPointer(key)
+		fromValue, inFrom := fromObject[key]
+		toValue, inTo := toObject[key]
+		switch {
+		case !inTo:
+			operations = append(operations, PatchOperation{Op: "remove", Path: memberPath})
+		case !inFrom:
+			operations = append(operations, PatchOperation{Op: "add", Path: memberPath, Value: toValue})
+		default:
+			operations = append(operations, diffJSON(memberPath, fromValue, toValue)...)
+		}
+	}
+
+	return operations
+}
+
+// escapeJSONPointer escapes a member name for use in a JSON Pointer (RFC 6901)
+func escapeJSONPointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
@@ -0,0 +1,40 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffJSON(t *testing.T) {
+	from := map[string]interface{}{
+		"color": "blue",
+		"a/b":   1.0,
+		"tags":  []interface{}{"x"},
+		"nested": map[string]interface{}{
+			"keep": true,
+			"drop": "me",
+		},
+	}
+	to := map[string]interface{}{
+		"color": "blue",
+		"tags":  []interface{}{"x", "y"},
+		"size~": 10.0,
+		"nested": map[string]interface{}{
+			"keep": false,
+		},
+	}
+
+	require.Equal(t, []PatchOperation{
+		{Op: "remove", Path: "/a~1b"},
+		{Op: "remove", Path: "/nested/drop"},
+		{Op: "replace", Path: "/nested/keep", Value: false},
+		{Op: "add", Path: "/size~0", Value: 10.0},
+		{Op: "replace", Path: "/tags", Value: []interface{}{"x", "y"}},
+	}, diffJSON("", from, to))
+	require.Empty(t, diffJSON("", from, from))
+}
@@ -0,0 +1,282 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+/*
+====ASSET EXPIRY (CLI) ==================
+
+An asset can be given an expiry time with SetAssetExpiry. From that time on the asset
+is treated as absent by every read, and PurgeExpired removes it from the world state,
+optionally keeping a copy in the archive. Expiry is evaluated against the transaction
+timestamp, so that every endorser reaches the same decision. DeleteAsset still removes
+an expired asset that has not been purged yet.
+
+Expiry only applies to the assets of this chaincode. The employee and agreement
+objects of the other samples have no expiry.
+
+peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["SetAssetExpiry","asset1","2021-06-01T00:00:00Z","true"]}'
+peer chaincode invoke -C myc1 -n asset_transfer -c '{"Args":["PurgeExpired","50"]}'
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["GetArchivedAsset","asset1"]}'
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	// expiryIndex orders assets by expiry time, so that PurgeExpired only visits
+	// the entries that are due
+	expiryIndex = "expiry~timestamp~key"
+	// archiveIndex keeps the last version of purged assets that were set to be archived
+	archiveIndex = "archive~key"
+	// expiryTimestampLayout is a fixed width UTC variant of RFC 3339, which makes
+	// the lexical order of the index keys the chronological order
+	expiryTimestampLayout = "2006-01-02T15:04:05.000000000Z"
+)
+
+// expiry index entry values, telling PurgeExpired what to do with the asset
+var (
+	expiryActionDelete  = []byte{0x00}
+	expiryActionArchive = []byte{0x01}
+)
+
+// PurgeResult structure used for returning the assets removed by PurgeExpired
+type PurgeResult struct {
+	Purged   []string `json:"purged"`
+	Archived []string `json:"archived"`
+	More     bool     `json:"more"` // true if the limit was reached before all expired assets were purged
+}
+
+// SetAssetExpiry sets the time, in RFC 3339 format, at which an asset expires. An empty
+// expiresAt removes the expiry. If archive is true PurgeExpired keeps a copy of the
+// asset, which can be read with GetArchivedAsset.
+func (t *SimpleChaincode) SetAssetExpiry(ctx contractapi.TransactionContextInterface, assetID string, expiresAt string, archive bool) error {
+	asset, err := t.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	err = deleteExpiryIndex(ctx, asset)
+	if err != nil {
+		return err
+	}
+
+	asset.ExpiresAt = ""
+	if expiresAt != "" {
+		expiry, err := time.Parse(time.RFC3339Nano, expiresAt)
+		if err != nil {
+			return fmt.Errorf("invalid expiry time %q: %v", expiresAt, err)
+		}
+		asset.ExpiresAt = expiry.UTC().Format(expiryTimestampLayout)
+
+		action := expiryActionDelete
+		if archive {
+			action = expiryActionArchive
+		}
+		err = putCompositeState(ctx, expiryIndex, []string{asset.ExpiresAt, asset.ID}, action)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = putAsset(ctx, asset)
+	if err != nil {
+		return err
+	}
+
+	return recordAttribution(ctx, assetID)
+}
+
+// PurgeExpired removes up to limit assets whose expiry time is not after the transaction
+// timestamp, oldest expiry first. Assets whose expiry was set with archive are copied to
+// the archive before they are removed. Submit it again while More is true to purge the rest.
+// The range query over the expiry index protects the transaction against concurrent
+// changes to the expired entries.
+func (t *SimpleChaincode) PurgeExpired(ctx contractapi.TransactionContextInterface, limit int) (*PurgeResult, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("limit must be greater than zero")
+	}
+
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(expiryIndex, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	result := &PurgeResult{Purged: []string{}, Archived: []string{}}
+	for resultsIterator.HasNext() {
+		responseRange, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(responseRange.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(compositeKeyParts) != 2 {
+			return nil, fmt.Errorf("invalid expiry index key %q", responseRange.Key)
+		}
+		expiry, err := time.Parse(expiryTimestampLayout, compositeKeyParts[0])
+		if err != nil {
+			return nil, err
+		}
+		if expiry.After(now) {
+			break
+		}
+		if len(result.Purged) == limit {
+			result.More = true
+			break
+		}
+
+		asset, err := getAsset(ctx, compositeKeyParts[1])
+		if err != nil {
+			return nil, err
+		}
+		if asset == nil {
+			// the asset was removed without its expiry index entry
+			err = ctx.GetStub().DelState(responseRange.Key)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if string(responseRange.Value) == string(expiryActionArchive) {
+			err = archiveAsset(ctx, asset)
+			if err != nil {
+				return nil, err
+			}
+			result.Archived = append(result.Archived, asset.ID)
+		}
+		err = removeAsset(ctx, asset)
+		if err != nil {
+			return nil, err
+		}
+		result.Purged = append(result.Purged, asset.ID)
+	}
+
+	return result, nil
+}
+
+// GetArchivedAsset returns the last version of an asset archived by PurgeExpired
+func (t *SimpleChaincode) GetArchivedAsset(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, error) {
+	archiveKey, err := ctx.GetStub().CreateCompositeKey(archiveIndex, []string{assetID})
+	if err != nil {
+		return nil, err
+	}
+	assetBytes, err := ctx.GetStub().GetState(archiveKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get archived asset %s: %v", assetID, err)
+	}
+	if assetBytes == nil {
+		return nil, fmt.Errorf("archived asset %s does not exist", assetID)
+	}
+
+	var asset Asset
+	err = json.Unmarshal(assetBytes, &asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+// getAsset reads an asset from the world state whether or not it has expired.
+// It returns nil if there is no asset with the given ID.
+func getAsset(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, error) {
+	assetBytes, err := ctx.GetStub().GetState(assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset %s: %v", assetID, err)
+	}
+	if assetBytes == nil {
+		return nil, nil
+	}
+
+	var asset Asset
+	err = json.Unmarshal(assetBytes, &asset)
+	if err != nil {
+		return nil, err
+	}
+
+	return &asset, nil
+}
+
+// isExpired returns true if the expiry time of the asset is not after the transaction timestamp
+func isExpired(ctx contractapi.TransactionContextInterface, asset *Asset) (bool, error) {
+	if asset.ExpiresAt == "" {
+		return false, nil
+	}
+	expiry, err := time.Parse(expiryTimestampLayout, asset.ExpiresAt)
+	if err != nil {
+		return false, fmt.Errorf("invalid expiry time of asset %s: %v", asset.ID, err)
+	}
+	now, err := txTimestamp(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !expiry.After(now), nil
+}
+
+func txTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return ptypes.Timestamp(timestamp)
+}
+
+// removeAsset deletes an asset together with its color and expiry index entries
+func removeAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	err := ctx.GetStub().DelState(asset.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete asset %s: %v", asset.ID, err)
+	}
+
+	err = recordAttribution(ctx, asset.ID)
+	if err != nil {
+		return err
+	}
+
+	colorNameIndexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{asset.Color, asset.ID})
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().DelState(colorNameIndexKey)
+	if err != nil {
+		return err
+	}
+
+	return deleteExpiryIndex(ctx, asset)
+}
+
+func deleteExpiryIndex(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	if asset.ExpiresAt == "" {
+		return nil
+	}
+	expiryIndexKey, err := ctx.GetStub().CreateCompositeKey(expiryIndex, []string{asset.ExpiresAt, asset.ID})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().DelState(expiryIndexKey)
+}
+
+func archiveAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	assetBytes, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+	return putCompositeState(ctx, archiveIndex, []string{asset.ID}, assetBytes)
+}
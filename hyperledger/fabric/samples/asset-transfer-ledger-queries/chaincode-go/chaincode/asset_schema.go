@@ -0,0 +1,160 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+/*
+====SCHEMA VALIDATION (CLI) ==================
+
+Every object is validated against the JSON Schema of its docType before it is written
+to the world state. The schemas are part of the chaincode package and can be read with
+GetSchema, so that clients can validate their input before submitting it. A rejected
+write returns a JSON error listing every field that failed validation, e.g.
+
+{"objectType":"asset","errors":[{"field":"size","rule":"number_gte","message":"Must be greater than or equal to 0"}]}
+
+ImportState rejects objects stored under simple keys whose docType is missing or has no
+schema. Only the assets of this chaincode have a schema. The employee chaincode outside
+the samples is a separate package that does not build against this one, so its writes
+are not validated.
+
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["GetSchema","asset"]}'
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// schemaDocuments holds the JSON Schema of each object type stored by the chaincode
+var schemaDocuments = map[string]string{
+	"asset": `{
+  "$schema": "http://json-schema.org/draft-07/schema#",
+  "title": "asset",
+  "type": "object",
+  "properties": {
+    "docType": {"type": "string", "enum": ["asset"]},
+    "ID": {"type": "string", "minLength": 1, "maxLength": 64, "pattern": "^[A-Za-z0-9_.-]+$"},
+    "color": {"type": "string", "minLength": 1, "maxLength": 32},
+    "size": {"type": "integer", "minimum": 0},
+    "owner": {"type": "string", "minLength": 1, "maxLength": 128},
+    "appraisedValue": {"type": "integer", "minimum": 0},
+    "expiresAt": {"type": "string", "format": "date-time"}
+  },
+  "required": ["docType", "ID", "color", "size", "owner", "appraisedValue"],
+  "additionalProperties": false
+}`,
+}
+
+// schemas holds the compiled schemaDocuments
+var schemas = compileSchemas(schemaDocuments)
+
+// FieldError describes a single schema violation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when an object does not match the schema of its type.
+// Its message is the JSON encoding of the error so that clients can decode it.
+type ValidationError struct {
+	ObjectType string       `json:"objectType"`
+	Errors     []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	errorBytes, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Sprintf("%s failed schema validation", e.ObjectType)
+	}
+	return string(errorBytes)
+}
+
+// GetSchema returns the JSON Schema document that objects of the given type are validated against
+func (t *SimpleChaincode) GetSchema(ctx contractapi.TransactionContextInterface, objectType string) (string, error) {
+	document, ok := schemaDocuments[objectType]
+	if !ok {
+		return "", fmt.Errorf("no schema for object type %q, known types are %s", objectType, strings.Join(schemaTypes(), ", "))
+	}
+	return document, nil
+}
+
+// validateObject validates the JSON encoding of an object against the schema of objectType
+func validateObject(objectType string, objectBytes []byte) error {
+	schema, ok := schemas[objectType]
+	if !ok {
+		return fmt.Errorf("no schema for object type %q", objectType)
+	}
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(objectBytes))
+	if err != nil {
+		return fmt.Errorf("failed to validate %s: %v", objectType, err)
+	}
+	if result.Valid() {
+		return nil
+	}
+
+	validationError := &ValidationError{ObjectType: objectType}
+	for _, resultError := range result.Errors() {
+		field := resultError.Field()
+		if property, ok := resultError.Details()["property"].(string); ok && resultError.Type() == "required" {
+			field = property
+		}
+		validationError.Errors = append(validationError.Errors, FieldError{
+			Field:   field,
+			Rule:    resultError.Type(),
+			Message: resultError.Description(),
+		})
+	}
+	// the order of the validator is not guaranteed, endorsers must agree on the error
+	sort.Slice(validationError.Errors, func(i, j int) bool {
+		a, b := validationError.Errors[i], validationError.Errors[j]
+		if a.Field != b.Field {
+			return a.Field < b.Field
+		}
+		return a.Rule < b.Rule
+	})
+
+	return validationError
+}
+
+// putAsset validates an asset against the asset schema and writes it to the world state
+func putAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	assetBytes, err := json.Marshal(asset)
+	if err != nil {
+		return err
+	}
+	err = validateObject("asset", assetBytes)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(asset.ID, assetBytes)
+}
+
+func schemaTypes() []string {
+	var objectTypes []string
+	for objectType := range schemaDocuments {
+		objectTypes = append(objectTypes, objectType)
+	}
+	sort.Strings(objectTypes)
+	return objectTypes
+}
+
+func compileSchemas(documents map[string]string) map[string]*gojsonschema.Schema {
+	compiled := make(map[string]*gojsonschema.Schema)
+	for objectType, document := range documents {
+		schema, err := gojsonschema.NewSchema(gojsonschema.NewStringLoader(document))
+		if err != nil {
+			panic(fmt.Sprintf("invalid schema for %s: %v", objectType, err))
+		}
+		compiled[objectType] = schema
+	}
+	return compiled
+}
@@ -0,0 +1,33 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateObject(t *testing.T) {
+	err := validateObject("asset", []byte(`{"docType":"car","ID":"asset1","size":"big","expiresAt":"tomorrow","extra":1}`))
+	require.Error(t, err)
+
+	var rules []string
+	for _, fieldError := range err.(*ValidationError).Errors {
+		rules = append(rules, fieldError.Field+":"+fieldError.Rule)
+	}
+	require.Equal(t, []string{
+		"(root):additional_property_not_allowed",
+		"appraisedValue:required",
+		"color:required",
+		"docType:enum",
+		"expiresAt:format",
+		"owner:required",
+		"size:invalid_type",
+	}, rules)
+
+	err = validateObject("employee", []byte(`{}`))
+	require.EqualError(t, err, `no schema for object type "employee"`)
+}
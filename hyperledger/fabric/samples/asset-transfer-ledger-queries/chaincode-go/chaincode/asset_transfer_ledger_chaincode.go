@@ -67,7 +67,7 @@ Rich Query with index design doc specified only (Only supported if CouchDB is us
 peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["QueryAssets","{\"selector\":{\"docType\":{\"$eq\":\"asset\"},\"owner\":{\"$eq\":\"tom\"},\"size\":{\"$gt\":0}},\"fields\":[\"docType\",\"owner\",\"size\"],\"sort\":[{\"size\":\"desc\"}],\"use_index\":\"_design/indexSizeSortDoc\"}"]}'
 */
 
-package main
+package chaincode
 
 import (
 	"encoding/json"
@@ -94,6 +94,7 @@ type Asset struct {
 	Size           int    `json:"size"`
 	Owner          string `json:"owner"`
 	AppraisedValue int    `json:"appraisedValue"`
+	ExpiresAt      string `json:"expiresAt,omitempty" metadata:",optional"` //an expired asset is treated as absent, see asset_expiry.go
 }
 
 // HistoryQueryResult structure used for returning result of history query
@@ -121,6 +122,18 @@ func (t *SimpleChaincode) CreateAsset(ctx contractapi.TransactionContextInterfac
 		return fmt.Errorf("asset already exists: %s", assetID)
 	}
 
+	// an expired asset that has not been purged yet is replaced, together with its index entries
+	expired, err := getAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if expired != nil {
+		err = removeAsset(ctx, expired)
+		if err != nil {
+			return err
+		}
+	}
+
 	asset := &Asset{
 		DocType:        "asset",
 		ID:             assetID,
@@ -129,12 +142,12 @@ func (t *SimpleChaincode) CreateAsset(ctx contractapi.TransactionContextInterfac
 		Owner:          owner,
 		AppraisedValue: appraisedValue,
 	}
-	assetBytes, err := json.Marshal(asset)
+	err = putAsset(ctx, asset)
 	if err != nil {
 		return err
 	}
 
-	err = ctx.GetStub().PutState(assetID, assetBytes)
+	err = recordAttribution(ctx, assetID)
 	if err != nil {
 		return err
 	}
@@ -144,54 +157,46 @@ func (t *SimpleChaincode) CreateAsset(ctx contractapi.TransactionContextInterfac
 	//  The key is a composite key, with the elements that you want to range query on listed first.
 	//  In our case, the composite key is based on indexName~color~name.
 	//  This will enable very efficient state range queries based on composite keys matching indexName~color~*
-	colorNameIndexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{asset.Color, asset.ID})
-	if err != nil {
-		return err
-	}
 	//  Save index entry to world state. Only the key name is needed, no need to store a duplicate copy of the asset.
 	//  Note - passing a 'nil' value will effectively delete the key from state, therefore we pass null character as value
 	value := []byte{0x00}
-	return ctx.GetStub().PutState(colorNameIndexKey, value)
+	return putCompositeState(ctx, index, []string{asset.Color, asset.ID}, value)
 }
 
-// ReadAsset retrieves an asset from the ledger
+// ReadAsset retrieves an asset from the ledger. Expired assets do not exist.
 func (t *SimpleChaincode) ReadAsset(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, error) {
-	assetBytes, err := ctx.GetStub().GetState(assetID)
+	asset, err := getAsset(ctx, assetID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get asset %s: %v", assetID, err)
+		return nil, err
 	}
-	if assetBytes == nil {
+	if asset == nil {
 		return nil, fmt.Errorf("asset %s does not exist", assetID)
 	}
 
-	var asset Asset
-	err = json.Unmarshal(assetBytes, &asset)
+	expired, err := isExpired(ctx, asset)
 	if err != nil {
 		return nil, err
 	}
+	if expired {
+		return nil, fmt.Errorf("asset %s does not exist", assetID)
+	}
 
-	return &asset, nil
+	return asset, nil
 }
 
-// DeleteAsset removes an asset key-value pair from the ledger
+// DeleteAsset removes an asset key-value pair from the ledger. An expired asset that
+// has not been purged yet can still be deleted, in which case it is not archived.
 func (t *SimpleChaincode) DeleteAsset(ctx contractapi.TransactionContextInterface, assetID string) error {
-	asset, err := t.ReadAsset(ctx, assetID)
+	asset, err := getAsset(ctx, assetID)
 	if err != nil {
 		return err
 	}
-
-	err = ctx.GetStub().DelState(assetID)
-	if err != nil {
-		return fmt.Errorf("failed to delete asset %s: %v", assetID, err)
-	}
-
-	colorNameIndexKey, err := ctx.GetStub().CreateCompositeKey(index, []string{asset.Color, asset.ID})
-	if err != nil {
-		return err
+	if asset == nil {
+		return fmt.Errorf("asset %s does not exist", assetID)
 	}
 
-	// Delete index entry
-	return ctx.GetStub().DelState(colorNameIndexKey)
+	// Delete the asset and its index entries
+	return removeAsset(ctx, asset)
 }
 
 // TransferAsset transfers an asset by setting a new owner name on the asset
@@ -202,16 +207,17 @@ func (t *SimpleChaincode) TransferAsset(ctx contractapi.TransactionContextInterf
 	}
 
 	asset.Owner = newOwner
-	assetBytes, err := json.Marshal(asset)
+	err = putAsset(ctx, asset)
 	if err != nil {
 		return err
 	}
 
-	return ctx.GetStub().PutState(assetID, assetBytes)
+	return recordAttribution(ctx, assetID)
 }
 
 // constructQueryResponseFromIterator constructs a slice of assets from the resultsIterator
-func constructQueryResponseFromIterator(resultsIterator shim.StateQueryIteratorInterface) ([]*Asset, error) {
+// skipping expired assets
+func constructQueryResponseFromIterator(ctx contractapi.TransactionContextInterface, resultsIterator shim.StateQueryIteratorInterface) ([]*Asset, error) {
 	var assets []*Asset
 	for resultsIterator.HasNext() {
 		queryResult, err := resultsIterator.Next()
@@ -223,6 +229,13 @@ func constructQueryResponseFromIterator(resultsIterator shim.StateQueryIteratorI
 		if err != nil {
 			return nil, err
 		}
+		expired, err := isExpired(ctx, &asset)
+		if err != nil {
+			return nil, err
+		}
+		if expired {
+			continue
+		}
 		assets = append(assets, &asset)
 	}
 
@@ -244,7 +257,7 @@ func (t *SimpleChaincode) GetAssetsByRange(ctx contractapi.TransactionContextInt
 	}
 	defer resultsIterator.Close()
 
-	return constructQueryResponseFromIterator(resultsIterator)
+	return constructQueryResponseFromIterator(ctx, resultsIterator)
 }
 
 // TransferAssetByColor will transfer assets of a given color to a certain new owner.
@@ -275,19 +288,29 @@ func (t *SimpleChaincode) TransferAssetByColor(ctx contractapi.TransactionContex
 
 		if len(compositeKeyParts) > 1 {
 			returnedAssetID := compositeKeyParts[1]
-			asset, err := t.ReadAsset(ctx, returnedAssetID)
+			asset, err := getAsset(ctx, returnedAssetID)
 			if err != nil {
 				return err
 			}
-			asset.Owner = newOwner
-			assetBytes, err := json.Marshal(asset)
+			if asset == nil {
+				return fmt.Errorf("asset %s does not exist", returnedAssetID)
+			}
+			expired, err := isExpired(ctx, asset)
 			if err != nil {
 				return err
 			}
-			err = ctx.GetStub().PutState(returnedAssetID, assetBytes)
+			if expired {
+				continue
+			}
+			asset.Owner = newOwner
+			err = putAsset(ctx, asset)
 			if err != nil {
 				return fmt.Errorf("transfer failed for asset %s: %v", returnedAssetID, err)
 			}
+			err = recordAttribution(ctx, returnedAssetID)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -323,7 +346,7 @@ func getQueryResultForQueryString(ctx contractapi.TransactionContextInterface, q
 	}
 	defer resultsIterator.Close()
 
-	return constructQueryResponseFromIterator(resultsIterator)
+	return constructQueryResponseFromIterator(ctx, resultsIterator)
 }
 
 // GetAssetsByRangeWithPagination performs a range query based on the start and end key,
@@ -339,7 +362,7 @@ func (t *SimpleChaincode) GetAssetsByRangeWithPagination(ctx contractapi.Transac
 	}
 	defer resultsIterator.Close()
 
-	return constructQueryResponseFromIterator(resultsIterator)
+	return constructQueryResponseFromIterator(ctx, resultsIterator)
 }
 
 // QueryAssetsWithPagination uses a query string, page size and a bookmark to perform a query
@@ -365,7 +388,7 @@ func getQueryResultForQueryStringWithPagination(ctx contractapi.TransactionConte
 	}
 	defer resultsIterator.Close()
 
-	assets, err := constructQueryResponseFromIterator(resultsIterator)
+	assets, err := constructQueryResponseFromIterator(ctx, resultsIterator)
 	if err != nil {
 		return nil, err
 	}
@@ -423,14 +446,21 @@ func (t *SimpleChaincode) GetAssetHistory(ctx contractapi.TransactionContextInte
 	return records, nil
 }
 
-// AssetExists returns true when asset with given ID exists in the ledger.
+// AssetExists returns true when asset with given ID exists in the ledger and has not expired.
 func (t *SimpleChaincode) AssetExists(ctx contractapi.TransactionContextInterface, assetID string) (bool, error) {
-	assetBytes, err := ctx.GetStub().GetState(assetID)
+	asset, err := getAsset(ctx, assetID)
 	if err != nil {
 		return false, fmt.Errorf("failed to read asset %s from world state. %v", assetID, err)
 	}
+	if asset == nil {
+		return false, nil
+	}
 
-	return assetBytes != nil, nil
+	expired, err := isExpired(ctx, asset)
+	if err != nil {
+		return false, err
+	}
+	return !expired, nil
 }
 
 // InitLedger creates the initial set of assets in the ledger.
@@ -453,14 +483,3 @@ func (t *SimpleChaincode) InitLedger(ctx contractapi.TransactionContextInterface
 
 	return nil
 }
-
-func main() {
-	chaincode, err := contractapi.NewChaincode(&SimpleChaincode{})
-	if err != nil {
-		log.Panicf("Error creating asset chaincode: %v", err)
-	}
-
-	if err := chaincode.Start(); err != nil {
-		log.Panicf("Error starting asset chaincode: %v", err)
-	}
-}
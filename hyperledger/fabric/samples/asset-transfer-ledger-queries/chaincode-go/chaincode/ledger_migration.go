@@ -0,0 +1,371 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+/*
+====LEDGER MIGRATION (CLI) ==================
+
+The export functions stream every key of the chaincode namespace, page by page, so
+that the state can be replayed into another channel or a new chaincode deployment
+without re-running InitLedger. See ../application-go for a client that writes the
+pages to a JSON Lines file and imports them again in checkpointed batches. Only
+clients of the importer organization, Org1, can import state.
+
+peer chaincode query -C myc1 -n asset_transfer -c '{"Args":["ExportState","100",""]}'
+peer chaincode invoke -C myc2 -n asset_transfer -c '{"Args":["ImportState","migration1","1","[{\"key\":\"asset1\",\"value\":\"...\",\"objectType\":\"asset\"}]"]}'
+peer chaincode query -C myc2 -n asset_transfer -c '{"Args":["GetImportCheckpoint","migration1"]}'
+*/
+
+package chaincode
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	// importCheckpointIndex keeps import checkpoints out of the simple key range.
+	// It is not registered as an object type so that checkpoints are never exported.
+	importCheckpointIndex = "importCheckpoint"
+	// objectTypeIndex registers the object type of every composite key written by the
+	// chaincode. The shim cannot range over all composite keys, so ExportState visits
+	// the composite keys of each registered object type in turn.
+	objectTypeIndex = "objectType"
+	// importerMSPID is the organization allowed to import state. Importing writes
+	// arbitrary keys, including the assets of other owners, so this sample assumes
+	// Org1 is the administrator of ledger migrations.
+	importerMSPID = "Org1MSP"
+	// maxImportBatchRecords and maxImportBatchBytes bound the size of a single
+	// ImportState transaction
+	maxImportBatchRecords = 500
+	maxImportBatchBytes   = 1 << 20
+	// compositeKeyNamespace is the first character of every composite key
+	compositeKeyNamespace = "\x00"
+)
+
+// ExportRecord is a single ledger key as exported by ExportState
+type ExportRecord struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"` // base64 encoded state value
+	ObjectType string `json:"objectType"`
+}
+
+// ExportPage structure used for returning a page of exported records and the
+// bookmark to request the next page with
+type ExportPage struct {
+	Records             []*ExportRecord `json:"records"`
+	FetchedRecordsCount int32           `json:"fetchedRecordsCount"`
+	Bookmark            string          `json:"bookmark"`
+}
+
+// ImportCheckpoint records the progress of an import so that it can be resumed
+type ImportCheckpoint struct {
+	ImportID        string `json:"importID"`
+	LastBatch       int    `json:"lastBatch"`
+	LastKey         string `json:"lastKey"`
+	RecordsImported int    `json:"recordsImported"`
+}
+
+// exportPosition is the key space an export is scanning, either the simple keys or
+// the composite keys of one object type, and the bookmark of the scan within it
+type exportPosition struct {
+	Composite  bool   `json:"composite,omitempty"`
+	ObjectType string `json:"objectType,omitempty"`
+	Bookmark   string `json:"bookmark,omitempty"`
+}
+
+// ExportState returns one page of the chaincode namespace, visiting the simple keys
+// first and then the composite keys of every registered object type. Pass the returned
+// bookmark to get the next page; an empty bookmark means the export is complete.
+// Paginated queries are only valid for read only transactions.
+func (t *SimpleChaincode) ExportState(ctx contractapi.TransactionContextInterface, pageSize int, bookmark string) (*ExportPage, error) {
+	if pageSize <= 0 {
+		return nil, fmt.Errorf("page size must be greater than zero")
+	}
+
+	position, err := parseExportBookmark(bookmark)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ExportPage{}
+	for position != nil && len(page.Records) < pageSize {
+		remaining := int32(pageSize - len(page.Records))
+		records, nextBookmark, err := exportScan(ctx, position, remaining)
+		if err != nil {
+			return nil, err
+		}
+		page.Records = append(page.Records, records...)
+
+		if nextBookmark != "" {
+			position.Bookmark = nextBookmark
+			break
+		}
+		position, err = nextExportPosition(ctx, position)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	page.FetchedRecordsCount = int32(len(page.Records))
+	if position != nil {
+		positionJSON, err := json.Marshal(position)
+		if err != nil {
+			return nil, err
+		}
+		page.Bookmark = base64.StdEncoding.EncodeToString(positionJSON)
+	}
+	return page, nil
+}
+
+// nextExportPosition returns the start of the key space after the one of the position,
+// or nil if the position is in the last key space. The composite key spaces are visited
+// in the order of their object types.
+func nextExportPosition(ctx contractapi.TransactionContextInterface, position *exportPosition) (*exportPosition, error) {
+	objectTypes, err := getObjectTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, objectType := range objectTypes {
+		if !position.Composite || objectType > position.ObjectType {
+			return &exportPosition{Composite: true, ObjectType: objectType}, nil
+		}
+	}
+	return nil, nil
+}
+
+// exportScan reads one page of the key space of the position
+func exportScan(ctx contractapi.TransactionContextInterface, position *exportPosition, pageSize int32) ([]*ExportRecord, string, error) {
+	var resultsIterator shim.StateQueryIteratorInterface
+	var responseMetadata *peer.QueryResponseMetadata
+	var err error
+	if !position.Composite {
+		resultsIterator, responseMetadata, err = ctx.GetStub().GetStateByRangeWithPagination("", "", pageSize, position.Bookmark)
+	} else {
+		resultsIterator, responseMetadata, err = ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(position.ObjectType, []string{}, pageSize, position.Bookmark)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer resultsIterator.Close()
+
+	var records []*ExportRecord
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, "", err
+		}
+
+		record := &ExportRecord{
+			Key:        queryResult.Key,
+			Value:      base64.StdEncoding.EncodeToString(queryResult.Value),
+			ObjectType: position.ObjectType,
+		}
+		if !position.Composite {
+			record.ObjectType = decodeDocType(queryResult.Value)
+		}
+		records = append(records, record)
+	}
+
+	return records, responseMetadata.Bookmark, nil
+}
+
+// decodeDocType returns the docType of a JSON state value, or an empty string for other values
+func decodeDocType(value []byte) string {
+	var object struct {
+		DocType string `json:"docType"`
+	}
+	if err := json.Unmarshal(value, &object); err != nil {
+		return ""
+	}
+	return object.DocType
+}
+
+func parseExportBookmark(bookmark string) (*exportPosition, error) {
+	position := &exportPosition{}
+	if bookmark == "" {
+		return position, nil
+	}
+	positionJSON, err := base64.StdEncoding.DecodeString(bookmark)
+	if err == nil {
+		err = json.Unmarshal(positionJSON, position)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid export bookmark %q", bookmark)
+	}
+	return position, nil
+}
+
+// putCompositeState writes a composite key and registers its object type, so that
+// ExportState visits the composite keys of that type
+func putCompositeState(ctx contractapi.TransactionContextInterface, objectType string, attributes []string, value []byte) error {
+	key, err := ctx.GetStub().CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return err
+	}
+	err = ctx.GetStub().PutState(key, value)
+	if err != nil {
+		return err
+	}
+	return registerObjectType(ctx, objectType)
+}
+
+// registerObjectType records a composite key object type. The registration is a blind
+// write of a constant value, so concurrent transactions registering the same type do
+// not conflict.
+func registerObjectType(ctx contractapi.TransactionContextInterface, objectType string) error {
+	registryKey, err := ctx.GetStub().CreateCompositeKey(objectTypeIndex, []string{objectType})
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(registryKey, []byte{0x00})
+}
+
+// getObjectTypes returns the registered composite key object types in order
+func getObjectTypes(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(objectTypeIndex, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var objectTypes []string
+	for resultsIterator.HasNext() {
+		queryResult, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attributes, err := ctx.GetStub().SplitCompositeKey(queryResult.Key)
+		if err != nil {
+			return nil, err
+		}
+		objectTypes = append(objectTypes, attributes[0])
+	}
+
+	return objectTypes, nil
+}
+
+// ImportState writes a batch of exported records to the ledger and advances the
+// checkpoint of the import. Batches must be submitted in sequence starting at 1.
+// Re-submitting a batch that is already covered by the checkpoint is a no-op, so a
+// failed import can simply be restarted from the beginning of the export.
+// Only clients of the importer organization can import.
+func (t *SimpleChaincode) ImportState(ctx contractapi.TransactionContextInterface, importID string, batch int, recordsJSON string) (*ImportCheckpoint, error) {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client MSP ID: %v", err)
+	}
+	if clientMSPID != importerMSPID {
+		return nil, fmt.Errorf("client of %s is not authorized to import state, only %s can import", clientMSPID, importerMSPID)
+	}
+
+	if importID == "" {
+		return nil, fmt.Errorf("import ID must not be empty")
+	}
+	if len(recordsJSON) > maxImportBatchBytes {
+		return nil, fmt.Errorf("batch %d is %d bytes, the limit is %d", batch, len(recordsJSON), maxImportBatchBytes)
+	}
+
+	checkpoint, err := t.GetImportCheckpoint(ctx, importID)
+	if err != nil {
+		return nil, err
+	}
+	if batch <= checkpoint.LastBatch {
+		return checkpoint, nil
+	}
+	if batch != checkpoint.LastBatch+1 {
+		return nil, fmt.Errorf("import %s expects batch %d, got batch %d", importID, checkpoint.LastBatch+1, batch)
+	}
+
+	var records []*ExportRecord
+	err = json.Unmarshal([]byte(recordsJSON), &records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal batch %d: %v", batch, err)
+	}
+	if len(records) > maxImportBatchRecords {
+		return nil, fmt.Errorf("batch %d has %d records, the limit is %d", batch, len(records), maxImportBatchRecords)
+	}
+
+	for _, record := range records {
+		value, err := base64.StdEncoding.DecodeString(record.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode value of key %q: %v", record.Key, err)
+		}
+		// composite keys register their object type, objects stored under simple keys
+		// are validated against the schema of their docType
+		if strings.HasPrefix(record.Key, compositeKeyNamespace) {
+			objectType, _, err := ctx.GetStub().SplitCompositeKey(record.Key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid composite key %q: %v", record.Key, err)
+			}
+			if objectType == importCheckpointIndex || objectType == objectTypeIndex {
+				return nil, fmt.Errorf("key %q of object type %s cannot be imported", record.Key, objectType)
+			}
+			err = registerObjectType(ctx, objectType)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			docType := decodeDocType(value)
+			if docType == "" {
+				return nil, fmt.Errorf("invalid value of key %q: missing docType", record.Key)
+			}
+			err = validateObject(docType, value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of key %q: %v", record.Key, err)
+			}
+		}
+		err = ctx.GetStub().PutState(record.Key, value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to import key %q: %v", record.Key, err)
+		}
+		checkpoint.LastKey = record.Key
+	}
+	checkpoint.LastBatch = batch
+	checkpoint.RecordsImported += len(records)
+
+	checkpointBytes, err := json.Marshal(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	checkpointKey, err := ctx.GetStub().CreateCompositeKey(importCheckpointIndex, []string{importID})
+	if err != nil {
+		return nil, err
+	}
+	err = ctx.GetStub().PutState(checkpointKey, checkpointBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
+
+// GetImportCheckpoint returns the progress of an import. An import that has not
+// started yet has a LastBatch of 0.
+func (t *SimpleChaincode) GetImportCheckpoint(ctx contractapi.TransactionContextInterface, importID string) (*ImportCheckpoint, error) {
+	checkpointKey, err := ctx.GetStub().CreateCompositeKey(importCheckpointIndex, []string{importID})
+	if err != nil {
+		return nil, err
+	}
+	checkpointBytes, err := ctx.GetStub().GetState(checkpointKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get checkpoint of import %s: %v", importID, err)
+	}
+
+	checkpoint := &ImportCheckpoint{ImportID: importID}
+	if checkpointBytes == nil {
+		return checkpoint, nil
+	}
+	err = json.Unmarshal(checkpointBytes, checkpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	return checkpoint, nil
+}
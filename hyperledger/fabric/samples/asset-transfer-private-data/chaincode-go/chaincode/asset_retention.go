@@ -0,0 +1,394 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const retentionPolicyKey = "retentionPolicy"
+const retentionRecordObjectType = "retention"
+
+// RetentionPolicy is the number of days after their creation that assets can be purged by PurgeExpiredAssets
+type RetentionPolicy struct {
+	RetentionDays int `json:"retentionDays"`
+}
+
+// RetentionRecord records when an asset was created and which collection holds its private details,
+// so that the asset can be purged when its retention period ends
+type RetentionRecord struct {
+	ID              string `json:"assetID"`
+	OwnerCollection string `json:"ownerCollection"`
+	CreatedAt       string `json:"createdAt"`
+}
+
+// RetentionReportEntry describes when an asset will be purged, as returned by RetentionReport
+type RetentionReportEntry struct {
+	ID              string `json:"assetID"`
+	OwnerCollection string `json:"ownerCollection"`
+	CreatedAt       string `json:"createdAt"`
+	PurgeAt         string `json:"purgeAt"`
+	Eligible        bool   `json:"eligible"`
+}
+
+// SetRetentionPolicy sets the number of days after their creation that assets are kept, before
+// PurgeExpiredAssets can purge them. Only a client with the retention.admin=true attribute can set the policy.
+func (s *SmartContract) SetRetentionPolicy(ctx contractapi.TransactionContextInterface, retentionDays int) error {
+
+	if retentionDays <= 0 {
+		return fmt.Errorf("retentionDays must be a positive integer")
+	}
+
+	err := ctx.GetClientIdentity().AssertAttributeValue("retention.admin", "true")
+	if err != nil {
+		return fmt.Errorf("submitting client not authorized to set the retention policy, does not have retention.admin role")
+	}
+
+	// Verify that the client is submitting request to peer in their organization
+	err = verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return fmt.Errorf("SetRetentionPolicy cannot be performed: Error %v", err)
+	}
+
+	policyJSONasBytes, err := json.Marshal(RetentionPolicy{RetentionDays: retentionDays})
+	if err != nil {
+		return fmt.Errorf("failed to marshal retention policy into JSON: %v", err)
+	}
+
+	log.Printf("SetRetentionPolicy Put: collection %v, retention days %v", assetCollection, retentionDays)
+	err = ctx.GetStub().PutPrivateData(assetCollection, retentionPolicyKey, policyJSONasBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put retention policy: %v", err)
+	}
+
+	return nil
+}
+
+// PurgeAsset can be used by the owner of the asset to purge the asset. Unlike DeleteAsset, which only removes
+// the asset from the current state, purging also removes it from the private data stores of the peers, together
+// with its history, as required to honor a request to erase personal data. The transfer agreement and retention
+// record of the asset are purged as well.
+func (s *SmartContract) PurgeAsset(ctx contractapi.TransactionContextInterface) error {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+
+	// Asset properties are private, therefore they get passed in transient field
+	transientPurgeJSON, ok := transientMap["asset_purge"]
+	if !ok {
+		return fmt.Errorf("asset to purge not found in the transient map")
+	}
+
+	type assetPurge struct {
+		ID string `json:"assetID"`
+	}
+
+	var assetPurgeInput assetPurge
+	err = json.Unmarshal(transientPurgeJSON, &assetPurgeInput)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	if len(assetPurgeInput.ID) == 0 {
+		return fmt.Errorf("assetID field must be a non-empty string")
+	}
+
+	// Verify that the client is submitting request to peer in their organization
+	err = verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return fmt.Errorf("PurgeAsset cannot be performed: Error %v", err)
+	}
+
+	valAsbytes, err := ctx.GetStub().GetPrivateData(assetCollection, assetPurgeInput.ID) //get the asset from chaincode state
+	if err != nil {
+		return fmt.Errorf("failed to read asset: %v", err)
+	}
+	if valAsbytes == nil {
+		return fmt.Errorf("asset not found: %v", assetPurgeInput.ID)
+	}
+
+	ownerCollection, err := getCollectionName(ctx) // Get owners collection
+	if err != nil {
+		return fmt.Errorf("failed to infer private collection name for the org: %v", err)
+	}
+
+	//check the asset is in the caller org's private collection
+	valAsbytes, err = ctx.GetStub().GetPrivateData(ownerCollection, assetPurgeInput.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read asset from owner's Collection: %v", err)
+	}
+	if valAsbytes == nil {
+		return fmt.Errorf("asset not found in owner's private Collection %v: %v", ownerCollection, assetPurgeInput.ID)
+	}
+
+	log.Printf("Purging Asset: %v", assetPurgeInput.ID)
+	return purgeAsset(ctx, assetPurgeInput.ID, ownerCollection)
+}
+
+// PurgeExpiredAssets purges the listed assets, whose retention period must have ended at the time of the
+// transaction, and returns their IDs. It is meant to be submitted on a schedule, for example daily, by either
+// organization, with the IDs of the eligible assets in RetentionReport. The assets cannot be looked up in the
+// same transaction, as Fabric does not allow a transaction to write or purge private data after it has run a
+// range query on private data. The private details of an asset are purged from the collection of the org that
+// owns the asset, so the transaction must be endorsed by the peers that the endorsement policy of that
+// collection requires.
+func (s *SmartContract) PurgeExpiredAssets(ctx contractapi.TransactionContextInterface, assetIDs []string) ([]string, error) {
+
+	// Verify that the client is submitting request to peer in their organization
+	err := verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("PurgeExpiredAssets cannot be performed: Error %v", err)
+	}
+
+	policy, err := readRetentionPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	purged := []string{}
+	seen := make(map[string]bool)
+	for _, assetID := range assetIDs {
+		if seen[assetID] {
+			continue
+		}
+		seen[assetID] = true
+
+		record, err := readRetentionRecord(ctx, assetID)
+		if err != nil {
+			return nil, err
+		}
+		if record == nil {
+			return nil, fmt.Errorf("asset %v has no retention record", assetID)
+		}
+
+		purgeAt, err := record.purgeAt(policy)
+		if err != nil {
+			return nil, err
+		}
+		if now.Before(purgeAt) {
+			return nil, fmt.Errorf("the retention period of asset %v ends at %v", assetID, purgeAt.Format(time.RFC3339))
+		}
+
+		log.Printf("Purging expired Asset: %v, created at %v", record.ID, record.CreatedAt)
+		err = purgeAsset(ctx, record.ID, record.OwnerCollection)
+		if err != nil {
+			return nil, err
+		}
+		purged = append(purged, record.ID)
+	}
+
+	return purged, nil
+}
+
+// RetentionReport lists all assets with the time they were created and the time their retention period ends,
+// in the order they will be purged. Assets whose retention period has ended are eligible to be purged by
+// PurgeExpiredAssets. RetentionReport is a query, so it is evaluated rather than submitted.
+func (s *SmartContract) RetentionReport(ctx contractapi.TransactionContextInterface) ([]RetentionReportEntry, error) {
+
+	policy, err := readRetentionPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(assetCollection, retentionRecordObjectType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retention records: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	report := []RetentionReportEntry{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var record RetentionRecord
+		err = json.Unmarshal(response.Value, &record)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		}
+
+		purgeAt, err := record.purgeAt(policy)
+		if err != nil {
+			return nil, err
+		}
+
+		report = append(report, RetentionReportEntry{
+			ID:              record.ID,
+			OwnerCollection: record.OwnerCollection,
+			CreatedAt:       record.CreatedAt,
+			PurgeAt:         purgeAt.Format(time.RFC3339),
+			Eligible:        !now.Before(purgeAt),
+		})
+	}
+
+	sort.SliceStable(report, func(i, j int) bool {
+		return report[i].PurgeAt < report[j].PurgeAt
+	})
+
+	return report, nil
+}
+
+// purgeAt returns the time at which the retention period of an asset ends
+func (record *RetentionRecord) purgeAt(policy *RetentionPolicy) (time.Time, error) {
+
+	createdAt, err := time.Parse(time.RFC3339, record.CreatedAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid creation time of asset %v: %v", record.ID, err)
+	}
+
+	return createdAt.AddDate(0, 0, policy.RetentionDays), nil
+}
+
+// readRetentionPolicy returns the retention policy, which must have been set with SetRetentionPolicy
+func readRetentionPolicy(ctx contractapi.TransactionContextInterface) (*RetentionPolicy, error) {
+
+	policyJSON, err := ctx.GetStub().GetPrivateData(assetCollection, retentionPolicyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retention policy: %v", err)
+	}
+	if policyJSON == nil {
+		return nil, fmt.Errorf("no retention policy has been set")
+	}
+
+	var policy RetentionPolicy
+	err = json.Unmarshal(policyJSON, &policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	return &policy, nil
+}
+
+// putRetentionRecord records the creation time and owner collection of an asset
+// Dependant functions include CreateAsset and TransferAsset
+func putRetentionRecord(ctx contractapi.TransactionContextInterface, record RetentionRecord) error {
+
+	retentionKey, err := ctx.GetStub().CreateCompositeKey(retentionRecordObjectType, []string{record.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	recordJSONasBytes, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal retention record into JSON: %v", err)
+	}
+
+	log.Printf("Put: collection %v, ID %v, Key %v", assetCollection, record.ID, retentionKey)
+	err = ctx.GetStub().PutPrivateData(assetCollection, retentionKey, recordJSONasBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put retention record: %v", err)
+	}
+
+	return nil
+}
+
+// readRetentionRecord returns the retention record of an asset, or nil if the asset has none
+func readRetentionRecord(ctx contractapi.TransactionContextInterface, assetID string) (*RetentionRecord, error) {
+
+	retentionKey, err := ctx.GetStub().CreateCompositeKey(retentionRecordObjectType, []string{assetID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	recordJSON, err := ctx.GetStub().GetPrivateData(assetCollection, retentionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retention record: %v", err)
+	}
+	if recordJSON == nil {
+		return nil, nil
+	}
+
+	var record RetentionRecord
+	err = json.Unmarshal(recordJSON, &record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	return &record, nil
+}
+
+// purgeAsset purges an asset, its private details in the owner collection, its transfer agreement, its retention
+// record and the copies of its private details shared with other orgs. The salt of its consent records is purged
+// as well, so that the records that remain can no longer be linked to the asset.
+func purgeAsset(ctx contractapi.TransactionContextInterface, assetID string, ownerCollection string) error {
+
+	err := revokeAllGrants(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PurgePrivateData(assetCollection, assetID)
+	if err != nil {
+		return fmt.Errorf("failed to purge asset: %v", err)
+	}
+
+	err = ctx.GetStub().PurgePrivateData(ownerCollection, assetID)
+	if err != nil {
+		return fmt.Errorf("failed to purge asset private details: %v", err)
+	}
+
+	transferAgreeKey, err := ctx.GetStub().CreateCompositeKey(transferAgreementObjectType, []string{assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().PurgePrivateData(assetCollection, transferAgreeKey)
+	if err != nil {
+		return fmt.Errorf("failed to purge transfer agreement: %v", err)
+	}
+
+	retentionKey, err := ctx.GetStub().CreateCompositeKey(retentionRecordObjectType, []string{assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().PurgePrivateData(assetCollection, retentionKey)
+	if err != nil {
+		return fmt.Errorf("failed to purge retention record: %v", err)
+	}
+
+	saltKey, err := ctx.GetStub().CreateCompositeKey(accessGrantSaltObjectType, []string{assetID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().PurgePrivateData(assetCollection, saltKey)
+	if err != nil {
+		return fmt.Errorf("failed to purge access grant salt: %v", err)
+	}
+
+	return nil
+}
+
+// getTxTime returns the timestamp of the transaction, which is the same on all endorsing peers
+func getTxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return txTimestamp.AsTime(), nil
+}
@@ -0,0 +1,511 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	accessGrantObjectType     = "accessGrant"
+	accessGrantSaltObjectType = "accessGrantSalt"
+)
+
+const (
+	accessGranted = "granted"
+	accessRevoked = "revoked"
+)
+
+// SharedAssetDetails is the encrypted copy of the private details of an asset that the owner shares with another org,
+// in the pairwise collection of the two orgs
+type SharedAssetDetails struct {
+	ID         string `json:"assetID"`
+	OwnerMSP   string `json:"ownerMSP"`
+	Ciphertext string `json:"ciphertext"`
+}
+
+// AccessGrant is the public consent record of the owner of an asset to share its private details with another org.
+// The asset ID and the shared details are only recorded as hashes, so that access can be audited by all orgs
+// without revealing them. The asset ID is hashed with a secret salt of the asset, kept in assetCollection, so that
+// it cannot be guessed from its hash by those who cannot read the collection.
+type AccessGrant struct {
+	AssetHash   string `json:"assetHash"`
+	OwnerMSP    string `json:"ownerMSP"`
+	GranteeMSP  string `json:"granteeMSP"`
+	Collection  string `json:"collection"`
+	DetailsHash string `json:"detailsHash"`
+	Status      string `json:"status"`
+	TxID        string `json:"txID"`
+	Timestamp   string `json:"timestamp"`
+}
+
+// GrantAccess is used by the owner of an asset to share the private details of the asset with another org.
+// The details are encrypted with the key passed in the transient field, which the owner shares with the grantee
+// off-chain, and the encrypted copy is put in the pairwise collection of the owner org and the grantee org.
+// The consent of the owner is recorded in the public state.
+func (s *SmartContract) GrantAccess(ctx contractapi.TransactionContextInterface) error {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+
+	// The asset ID and the encryption key are private, therefore they get passed in transient field
+	transientGrantJSON, ok := transientMap["access_grant"]
+	if !ok {
+		return fmt.Errorf("access_grant key not found in the transient map")
+	}
+
+	type accessGrantTransientInput struct {
+		ID            string `json:"assetID"`
+		GranteeMSP    string `json:"granteeMSP"`
+		EncryptionKey string `json:"encryptionKey"`
+	}
+
+	var grantInput accessGrantTransientInput
+	err = json.Unmarshal(transientGrantJSON, &grantInput)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	if len(grantInput.ID) == 0 {
+		return fmt.Errorf("assetID field must be a non-empty string")
+	}
+	if len(grantInput.GranteeMSP) == 0 {
+		return fmt.Errorf("granteeMSP field must be a non-empty string")
+	}
+
+	encryptionKey, err := base64.StdEncoding.DecodeString(grantInput.EncryptionKey)
+	if err != nil {
+		return fmt.Errorf("encryptionKey field must be base64 encoded: %v", err)
+	}
+	if len(encryptionKey) != 32 {
+		return fmt.Errorf("encryptionKey field must be a 256 bit key")
+	}
+
+	ownerMSP, ownerCollection, err := s.verifyClientOwnsAsset(ctx, grantInput.ID)
+	if err != nil {
+		return fmt.Errorf("GrantAccess cannot be performed: Error %v", err)
+	}
+	if grantInput.GranteeMSP == ownerMSP {
+		return fmt.Errorf("access cannot be granted to the owner org %v", ownerMSP)
+	}
+
+	assetDetailsJSON, err := ctx.GetStub().GetPrivateData(ownerCollection, grantInput.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read asset details: %v", err)
+	}
+	if assetDetailsJSON == nil {
+		return fmt.Errorf("asset not found in owner's private Collection %v: %v", ownerCollection, grantInput.ID)
+	}
+
+	ciphertext, err := encryptAssetDetails(encryptionKey, ctx.GetStub().GetTxID(), assetDetailsJSON)
+	if err != nil {
+		return err
+	}
+
+	sharedDetailsJSON, err := json.Marshal(SharedAssetDetails{
+		ID:         grantInput.ID,
+		OwnerMSP:   ownerMSP,
+		Ciphertext: base64.StdEncoding.EncodeToString(ciphertext),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal into JSON: %v", err)
+	}
+
+	// The first grant of an asset creates its salt, which is kept for the later grants
+	salt, err := readGrantSalt(ctx, grantInput.ID)
+	if err != nil {
+		return err
+	}
+	if salt == nil {
+		salt, err = putGrantSalt(ctx, grantInput.ID, encryptionKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	sharedCollection := getPairwiseCollectionName(ownerMSP, grantInput.GranteeMSP)
+	log.Printf("GrantAccess Put: collection %v, ID %v", sharedCollection, grantInput.ID)
+	err = ctx.GetStub().PutPrivateData(sharedCollection, grantInput.ID, sharedDetailsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put shared asset details: %v", err)
+	}
+
+	detailsHash := sha256.Sum256(sharedDetailsJSON)
+	return putAccessGrant(ctx, hashAssetID(salt, grantInput.ID), AccessGrant{
+		OwnerMSP:    ownerMSP,
+		GranteeMSP:  grantInput.GranteeMSP,
+		Collection:  sharedCollection,
+		DetailsHash: hex.EncodeToString(detailsHash[:]),
+		Status:      accessGranted,
+	})
+}
+
+// RevokeAccess is used by the owner of an asset to stop sharing its private details with another org.
+// The encrypted copy is purged from the pairwise collection, and the consent record is marked as revoked.
+func (s *SmartContract) RevokeAccess(ctx contractapi.TransactionContextInterface) error {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient: %v", err)
+	}
+
+	// The asset ID is private, therefore it gets passed in transient field
+	transientRevokeJSON, ok := transientMap["access_revoke"]
+	if !ok {
+		return fmt.Errorf("access_revoke key not found in the transient map")
+	}
+
+	type accessRevokeTransientInput struct {
+		ID         string `json:"assetID"`
+		GranteeMSP string `json:"granteeMSP"`
+	}
+
+	var revokeInput accessRevokeTransientInput
+	err = json.Unmarshal(transientRevokeJSON, &revokeInput)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	if len(revokeInput.ID) == 0 {
+		return fmt.Errorf("assetID field must be a non-empty string")
+	}
+	if len(revokeInput.GranteeMSP) == 0 {
+		return fmt.Errorf("granteeMSP field must be a non-empty string")
+	}
+
+	ownerMSP, _, err := s.verifyClientOwnsAsset(ctx, revokeInput.ID)
+	if err != nil {
+		return fmt.Errorf("RevokeAccess cannot be performed: Error %v", err)
+	}
+
+	salt, err := readGrantSalt(ctx, revokeInput.ID)
+	if err != nil {
+		return err
+	}
+	if salt == nil {
+		return fmt.Errorf("access to %v has not been granted to %v", revokeInput.ID, revokeInput.GranteeMSP)
+	}
+
+	assetHash := hashAssetID(salt, revokeInput.ID)
+	grant, err := readAccessGrant(ctx, assetHash, revokeInput.GranteeMSP)
+	if err != nil {
+		return err
+	}
+	if grant == nil || grant.Status != accessGranted || grant.OwnerMSP != ownerMSP {
+		return fmt.Errorf("access to %v has not been granted to %v", revokeInput.ID, revokeInput.GranteeMSP)
+	}
+
+	return revokeAccessGrant(ctx, revokeInput.ID, grant)
+}
+
+// ListGrants returns the consent records of an asset, with the orgs its private details are or were shared with
+func (s *SmartContract) ListGrants(ctx contractapi.TransactionContextInterface, assetID string) ([]*AccessGrant, error) {
+
+	salt, err := readGrantSalt(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := []*AccessGrant{}
+	if salt == nil {
+		return grants, nil
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(accessGrantObjectType, []string{hashAssetID(salt, assetID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access grants: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var grant *AccessGrant
+		err = json.Unmarshal(response.Value, &grant)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+		}
+		grants = append(grants, grant)
+	}
+
+	return grants, nil
+}
+
+// ReadSharedAssetDetails is used by a grantee to read the encrypted copy of the private details of an asset
+// that the owner org shared with the grantee org
+func (s *SmartContract) ReadSharedAssetDetails(ctx contractapi.TransactionContextInterface, assetID string, ownerMSP string) (*SharedAssetDetails, error) {
+
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verified MSPID: %v", err)
+	}
+
+	sharedCollection := getPairwiseCollectionName(ownerMSP, clientMSPID)
+	log.Printf("ReadSharedAssetDetails: collection %v, ID %v", sharedCollection, assetID)
+	sharedDetailsJSON, err := ctx.GetStub().GetPrivateData(sharedCollection, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared asset details: %v", err)
+	}
+	if sharedDetailsJSON == nil {
+		log.Printf("SharedAssetDetails for %v does not exist in collection %v", assetID, sharedCollection)
+		return nil, nil
+	}
+
+	var sharedDetails *SharedAssetDetails
+	err = json.Unmarshal(sharedDetailsJSON, &sharedDetails)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	return sharedDetails, nil
+}
+
+// verifyClientOwnsAsset is an internal function used to verify that the submitting client owns an asset,
+// and returns the MSP ID and collection of the owner org
+func (s *SmartContract) verifyClientOwnsAsset(ctx contractapi.TransactionContextInterface, assetID string) (string, string, error) {
+
+	// Verify that the client is submitting request to peer in their organization
+	err := verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return "", "", err
+	}
+	if asset == nil {
+		return "", "", fmt.Errorf("%v does not exist", assetID)
+	}
+
+	clientID, err := submittingClientIdentity(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	if clientID != asset.Owner {
+		return "", "", fmt.Errorf("submitting client identity does not own asset")
+	}
+
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get verified MSPID: %v", err)
+	}
+
+	ownerCollection, err := getCollectionName(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to infer private collection name for the org: %v", err)
+	}
+
+	return clientMSPID, ownerCollection, nil
+}
+
+// revokeAllGrants revokes all the grants of an asset that are still in force, when its owner no longer has
+// the asset to share
+func revokeAllGrants(ctx contractapi.TransactionContextInterface, assetID string) error {
+
+	salt, err := readGrantSalt(ctx, assetID)
+	if err != nil {
+		return err
+	}
+	if salt == nil {
+		return nil
+	}
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(accessGrantObjectType, []string{hashAssetID(salt, assetID)})
+	if err != nil {
+		return fmt.Errorf("failed to read access grants: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return err
+		}
+
+		var grant *AccessGrant
+		err = json.Unmarshal(response.Value, &grant)
+		if err != nil {
+			return fmt.Errorf("failed to unmarshal JSON: %v", err)
+		}
+		if grant.Status != accessGranted {
+			continue
+		}
+
+		err = revokeAccessGrant(ctx, assetID, grant)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// revokeAccessGrant purges the encrypted copy of the private details of an asset from the pairwise collection
+// of a grant, and marks the consent record as revoked
+func revokeAccessGrant(ctx contractapi.TransactionContextInterface, assetID string, grant *AccessGrant) error {
+
+	log.Printf("Revoke access Purge: collection %v, ID %v", grant.Collection, assetID)
+	err := ctx.GetStub().PurgePrivateData(grant.Collection, assetID)
+	if err != nil {
+		return fmt.Errorf("failed to purge shared asset details: %v", err)
+	}
+
+	grant.Status = accessRevoked
+	grant.DetailsHash = ""
+	return putAccessGrant(ctx, grant.AssetHash, *grant)
+}
+
+// putAccessGrant records a consent record in the public state, under the salted hash of the asset ID
+func putAccessGrant(ctx contractapi.TransactionContextInterface, assetHash string, grant AccessGrant) error {
+
+	txTime, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	grant.AssetHash = assetHash
+	grant.TxID = ctx.GetStub().GetTxID()
+	grant.Timestamp = txTime.Format(time.RFC3339)
+
+	grantKey, err := ctx.GetStub().CreateCompositeKey(accessGrantObjectType, []string{grant.AssetHash, grant.GranteeMSP})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	grantJSON, err := json.Marshal(grant)
+	if err != nil {
+		return fmt.Errorf("failed to marshal into JSON: %v", err)
+	}
+
+	log.Printf("Put: access grant %v, status %v", grantKey, grant.Status)
+	err = ctx.GetStub().PutState(grantKey, grantJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put access grant: %v", err)
+	}
+
+	return nil
+}
+
+// readAccessGrant returns the consent record of an asset for a grantee org, or nil if there is none
+func readAccessGrant(ctx contractapi.TransactionContextInterface, assetHash string, granteeMSP string) (*AccessGrant, error) {
+
+	grantKey, err := ctx.GetStub().CreateCompositeKey(accessGrantObjectType, []string{assetHash, granteeMSP})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	grantJSON, err := ctx.GetStub().GetState(grantKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access grant: %v", err)
+	}
+	if grantJSON == nil {
+		return nil, nil
+	}
+
+	var grant *AccessGrant
+	err = json.Unmarshal(grantJSON, &grant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	return grant, nil
+}
+
+// encryptAssetDetails encrypts the private details of an asset with AES-GCM. The nonce is derived from the
+// transaction ID, so that all endorsing peers compute the same ciphertext. It is prepended to the ciphertext.
+func encryptAssetDetails(key []byte, txID string, plaintext []byte) ([]byte, error) {
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+
+	txHash := sha256.Sum256([]byte(txID))
+	nonce := txHash[:gcm.NonceSize()]
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// getPairwiseCollectionName returns the name of the collection shared by two orgs, which is the same
+// whichever org is passed first
+func getPairwiseCollectionName(mspID string, otherMSPID string) string {
+	orgs := []string{mspID, otherMSPID}
+	sort.Strings(orgs)
+
+	return orgs[0] + "_" + orgs[1] + "SharedCollection"
+}
+
+// readGrantSalt returns the salt of the asset ID in the consent records of an asset, or nil if access to the asset
+// has never been granted
+func readGrantSalt(ctx contractapi.TransactionContextInterface, assetID string) ([]byte, error) {
+
+	saltKey, err := ctx.GetStub().CreateCompositeKey(accessGrantSaltObjectType, []string{assetID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	salt, err := ctx.GetStub().GetPrivateData(assetCollection, saltKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read access grant salt: %v", err)
+	}
+
+	return salt, nil
+}
+
+// putGrantSalt creates the salt of an asset ID and puts it in assetCollection. The salt is derived from the
+// encryption key of the first grant and the transaction ID, so that all endorsing peers compute the same salt,
+// and it cannot be computed by orgs that do not know the key.
+func putGrantSalt(ctx contractapi.TransactionContextInterface, assetID string, encryptionKey []byte) ([]byte, error) {
+
+	saltKey, err := ctx.GetStub().CreateCompositeKey(accessGrantSaltObjectType, []string{assetID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, encryptionKey)
+	mac.Write([]byte(ctx.GetStub().GetTxID()))
+	salt := mac.Sum(nil)
+
+	err = ctx.GetStub().PutPrivateData(assetCollection, saltKey, salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put access grant salt: %v", err)
+	}
+
+	return salt, nil
+}
+
+// hashAssetID returns the hex encoded HMAC-SHA256 of an asset ID keyed with its salt, which identifies the asset
+// in public consent records
+func hashAssetID(salt []byte, assetID string) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(assetID))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
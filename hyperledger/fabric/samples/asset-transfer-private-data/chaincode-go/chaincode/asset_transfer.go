@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
@@ -162,7 +163,18 @@ func (s *SmartContract) CreateAsset(ctx contractapi.TransactionContextInterface)
 	if err != nil {
 		return fmt.Errorf("failed to put asset private details: %v", err)
 	}
-	return nil
+
+	// Record the creation time of the asset, from which its retention period is counted
+	createdAt, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	return putRetentionRecord(ctx, RetentionRecord{
+		ID:              assetInput.ID,
+		OwnerCollection: orgCollection,
+		CreatedAt:       createdAt.Format(time.RFC3339),
+	})
 }
 
 // AgreeToTransfer is used by the potential buyer of the asset to agree to the
@@ -345,6 +357,25 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 		return err
 	}
 
+	// The seller can no longer share the private details of the asset
+	err = revokeAllGrants(ctx, assetTransferInput.ID)
+	if err != nil {
+		return err
+	}
+
+	// The private details of the asset are now in the buyer's collection
+	retentionRecord, err := readRetentionRecord(ctx, assetTransferInput.ID)
+	if err != nil {
+		return err
+	}
+	if retentionRecord != nil {
+		retentionRecord.OwnerCollection = assetTransferInput.BuyerMSP + "PrivateCollection"
+		err = putRetentionRecord(ctx, *retentionRecord)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 
 }
@@ -471,6 +502,23 @@ func (s *SmartContract) DeleteAsset(ctx contractapi.TransactionContextInterface)
 		return err
 	}
 
+	// The private details of a deleted asset are no longer shared
+	err = revokeAllGrants(ctx, assetDeleteInput.ID)
+	if err != nil {
+		return err
+	}
+
+	// A deleted asset is no longer subject to the retention policy
+	retentionKey, err := ctx.GetStub().CreateCompositeKey(retentionRecordObjectType, []string{assetDeleteInput.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().DelPrivateData(assetCollection, retentionKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete retention record: %v", err)
+	}
+
 	return nil
 
 }
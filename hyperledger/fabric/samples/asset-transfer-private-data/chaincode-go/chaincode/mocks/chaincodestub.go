@@ -4,9 +4,9 @@ package mocks
 import (
 	"sync"
 
+	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/hyperledger/fabric-chaincode-go/shim"
 	"github.com/hyperledger/fabric-protos-go/peer"
-	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type ChaincodeStub struct {
@@ -388,16 +388,16 @@ type ChaincodeStub struct {
 	getTxIDReturnsOnCall map[int]struct {
 		result1 string
 	}
-	GetTxTimestampStub        func() (*timestamppb.Timestamp, error)
+	GetTxTimestampStub        func() (*timestamp.Timestamp, error)
 	getTxTimestampMutex       sync.RWMutex
 	getTxTimestampArgsForCall []struct {
 	}
 	getTxTimestampReturns struct {
-		result1 *timestamppb.Timestamp
+		result1 *timestamp.Timestamp
 		result2 error
 	}
 	getTxTimestampReturnsOnCall map[int]struct {
-		result1 *timestamppb.Timestamp
+		result1 *timestamp.Timestamp
 		result2 error
 	}
 	InvokeChaincodeStub        func(string, [][]byte, string) peer.Response
@@ -413,6 +413,18 @@ type ChaincodeStub struct {
 	invokeChaincodeReturnsOnCall map[int]struct {
 		result1 peer.Response
 	}
+	PurgePrivateDataStub        func(string, string) error
+	purgePrivateDataMutex       sync.RWMutex
+	purgePrivateDataArgsForCall []struct {
+		arg1 string
+		arg2 string
+	}
+	purgePrivateDataReturns struct {
+		result1 error
+	}
+	purgePrivateDataReturnsOnCall map[int]struct {
+		result1 error
+	}
 	PutPrivateDataStub        func(string, string, []byte) error
 	putPrivateDataMutex       sync.RWMutex
 	putPrivateDataArgsForCall []struct {
@@ -506,15 +518,16 @@ func (fake *ChaincodeStub) CreateCompositeKey(arg1 string, arg2 []string) (strin
 		arg1 string
 		arg2 []string
 	}{arg1, arg2Copy})
+	stub := fake.CreateCompositeKeyStub
+	fakeReturns := fake.createCompositeKeyReturns
 	fake.recordInvocation("CreateCompositeKey", []interface{}{arg1, arg2Copy})
 	fake.createCompositeKeyMutex.Unlock()
-	if fake.CreateCompositeKeyStub != nil {
-		return fake.CreateCompositeKeyStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.createCompositeKeyReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -570,15 +583,16 @@ func (fake *ChaincodeStub) DelPrivateData(arg1 string, arg2 string) error {
 		arg1 string
 		arg2 string
 	}{arg1, arg2})
+	stub := fake.DelPrivateDataStub
+	fakeReturns := fake.delPrivateDataReturns
 	fake.recordInvocation("DelPrivateData", []interface{}{arg1, arg2})
 	fake.delPrivateDataMutex.Unlock()
-	if fake.DelPrivateDataStub != nil {
-		return fake.DelPrivateDataStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.delPrivateDataReturns
 	return fakeReturns.result1
 }
 
@@ -630,15 +644,16 @@ func (fake *ChaincodeStub) DelState(arg1 string) error {
 	fake.delStateArgsForCall = append(fake.delStateArgsForCall, struct {
 		arg1 string
 	}{arg1})
+	stub := fake.DelStateStub
+	fakeReturns := fake.delStateReturns
 	fake.recordInvocation("DelState", []interface{}{arg1})
 	fake.delStateMutex.Unlock()
-	if fake.DelStateStub != nil {
-		return fake.DelStateStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.delStateReturns
 	return fakeReturns.result1
 }
 
@@ -689,15 +704,16 @@ func (fake *ChaincodeStub) GetArgs() [][]byte {
 	ret, specificReturn := fake.getArgsReturnsOnCall[len(fake.getArgsArgsForCall)]
 	fake.getArgsArgsForCall = append(fake.getArgsArgsForCall, struct {
 	}{})
+	stub := fake.GetArgsStub
+	fakeReturns := fake.getArgsReturns
 	fake.recordInvocation("GetArgs", []interface{}{})
 	fake.getArgsMutex.Unlock()
-	if fake.GetArgsStub != nil {
-		return fake.GetArgsStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.getArgsReturns
 	return fakeReturns.result1
 }
 
@@ -741,15 +757,16 @@ func (fake *ChaincodeStub) GetArgsSlice() ([]byte, error) {
 	ret, specificReturn := fake.getArgsSliceReturnsOnCall[len(fake.getArgsSliceArgsForCall)]
 	fake.getArgsSliceArgsForCall = append(fake.getArgsSliceArgsForCall, struct {
 	}{})
+	stub := fake.GetArgsSliceStub
+	fakeReturns := fake.getArgsSliceReturns
 	fake.recordInvocation("GetArgsSlice", []interface{}{})
 	fake.getArgsSliceMutex.Unlock()
-	if fake.GetArgsSliceStub != nil {
-		return fake.GetArgsSliceStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getArgsSliceReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -796,15 +813,16 @@ func (fake *ChaincodeStub) GetBinding() ([]byte, error) {
 	ret, specificReturn := fake.getBindingReturnsOnCall[len(fake.getBindingArgsForCall)]
 	fake.getBindingArgsForCall = append(fake.getBindingArgsForCall, struct {
 	}{})
+	stub := fake.GetBindingStub
+	fakeReturns := fake.getBindingReturns
 	fake.recordInvocation("GetBinding", []interface{}{})
 	fake.getBindingMutex.Unlock()
-	if fake.GetBindingStub != nil {
-		return fake.GetBindingStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getBindingReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -851,15 +869,16 @@ func (fake *ChaincodeStub) GetChannelID() string {
 	ret, specificReturn := fake.getChannelIDReturnsOnCall[len(fake.getChannelIDArgsForCall)]
 	fake.getChannelIDArgsForCall = append(fake.getChannelIDArgsForCall, struct {
 	}{})
+	stub := fake.GetChannelIDStub
+	fakeReturns := fake.getChannelIDReturns
 	fake.recordInvocation("GetChannelID", []interface{}{})
 	fake.getChannelIDMutex.Unlock()
-	if fake.GetChannelIDStub != nil {
-		return fake.GetChannelIDStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.getChannelIDReturns
 	return fakeReturns.result1
 }
 
@@ -903,15 +922,16 @@ func (fake *ChaincodeStub) GetCreator() ([]byte, error) {
 	ret, specificReturn := fake.getCreatorReturnsOnCall[len(fake.getCreatorArgsForCall)]
 	fake.getCreatorArgsForCall = append(fake.getCreatorArgsForCall, struct {
 	}{})
+	stub := fake.GetCreatorStub
+	fakeReturns := fake.getCreatorReturns
 	fake.recordInvocation("GetCreator", []interface{}{})
 	fake.getCreatorMutex.Unlock()
-	if fake.GetCreatorStub != nil {
-		return fake.GetCreatorStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getCreatorReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -958,15 +978,16 @@ func (fake *ChaincodeStub) GetDecorations() map[string][]byte {
 	ret, specificReturn := fake.getDecorationsReturnsOnCall[len(fake.getDecorationsArgsForCall)]
 	fake.getDecorationsArgsForCall = append(fake.getDecorationsArgsForCall, struct {
 	}{})
+	stub := fake.GetDecorationsStub
+	fakeReturns := fake.getDecorationsReturns
 	fake.recordInvocation("GetDecorations", []interface{}{})
 	fake.getDecorationsMutex.Unlock()
-	if fake.GetDecorationsStub != nil {
-		return fake.GetDecorationsStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.getDecorationsReturns
 	return fakeReturns.result1
 }
 
@@ -1010,15 +1031,16 @@ func (fake *ChaincodeStub) GetFunctionAndParameters() (string, []string) {
 	ret, specificReturn := fake.getFunctionAndParametersReturnsOnCall[len(fake.getFunctionAndParametersArgsForCall)]
 	fake.getFunctionAndParametersArgsForCall = append(fake.getFunctionAndParametersArgsForCall, struct {
 	}{})
+	stub := fake.GetFunctionAndParametersStub
+	fakeReturns := fake.getFunctionAndParametersReturns
 	fake.recordInvocation("GetFunctionAndParameters", []interface{}{})
 	fake.getFunctionAndParametersMutex.Unlock()
-	if fake.GetFunctionAndParametersStub != nil {
-		return fake.GetFunctionAndParametersStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getFunctionAndParametersReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1066,15 +1088,16 @@ func (fake *ChaincodeStub) GetHistoryForKey(arg1 string) (shim.HistoryQueryItera
 	fake.getHistoryForKeyArgsForCall = append(fake.getHistoryForKeyArgsForCall, struct {
 		arg1 string
 	}{arg1})
+	stub := fake.GetHistoryForKeyStub
+	fakeReturns := fake.getHistoryForKeyReturns
 	fake.recordInvocation("GetHistoryForKey", []interface{}{arg1})
 	fake.getHistoryForKeyMutex.Unlock()
-	if fake.GetHistoryForKeyStub != nil {
-		return fake.GetHistoryForKeyStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getHistoryForKeyReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1130,15 +1153,16 @@ func (fake *ChaincodeStub) GetPrivateData(arg1 string, arg2 string) ([]byte, err
 		arg1 string
 		arg2 string
 	}{arg1, arg2})
+	stub := fake.GetPrivateDataStub
+	fakeReturns := fake.getPrivateDataReturns
 	fake.recordInvocation("GetPrivateData", []interface{}{arg1, arg2})
 	fake.getPrivateDataMutex.Unlock()
-	if fake.GetPrivateDataStub != nil {
-		return fake.GetPrivateDataStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getPrivateDataReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1200,15 +1224,16 @@ func (fake *ChaincodeStub) GetPrivateDataByPartialCompositeKey(arg1 string, arg2
 		arg2 string
 		arg3 []string
 	}{arg1, arg2, arg3Copy})
+	stub := fake.GetPrivateDataByPartialCompositeKeyStub
+	fakeReturns := fake.getPrivateDataByPartialCompositeKeyReturns
 	fake.recordInvocation("GetPrivateDataByPartialCompositeKey", []interface{}{arg1, arg2, arg3Copy})
 	fake.getPrivateDataByPartialCompositeKeyMutex.Unlock()
-	if fake.GetPrivateDataByPartialCompositeKeyStub != nil {
-		return fake.GetPrivateDataByPartialCompositeKeyStub(arg1, arg2, arg3)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getPrivateDataByPartialCompositeKeyReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1265,15 +1290,16 @@ func (fake *ChaincodeStub) GetPrivateDataByRange(arg1 string, arg2 string, arg3
 		arg2 string
 		arg3 string
 	}{arg1, arg2, arg3})
+	stub := fake.GetPrivateDataByRangeStub
+	fakeReturns := fake.getPrivateDataByRangeReturns
 	fake.recordInvocation("GetPrivateDataByRange", []interface{}{arg1, arg2, arg3})
 	fake.getPrivateDataByRangeMutex.Unlock()
-	if fake.GetPrivateDataByRangeStub != nil {
-		return fake.GetPrivateDataByRangeStub(arg1, arg2, arg3)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getPrivateDataByRangeReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1329,15 +1355,16 @@ func (fake *ChaincodeStub) GetPrivateDataHash(arg1 string, arg2 string) ([]byte,
 		arg1 string
 		arg2 string
 	}{arg1, arg2})
+	stub := fake.GetPrivateDataHashStub
+	fakeReturns := fake.getPrivateDataHashReturns
 	fake.recordInvocation("GetPrivateDataHash", []interface{}{arg1, arg2})
 	fake.getPrivateDataHashMutex.Unlock()
-	if fake.GetPrivateDataHashStub != nil {
-		return fake.GetPrivateDataHashStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getPrivateDataHashReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1393,15 +1420,16 @@ func (fake *ChaincodeStub) GetPrivateDataQueryResult(arg1 string, arg2 string) (
 		arg1 string
 		arg2 string
 	}{arg1, arg2})
+	stub := fake.GetPrivateDataQueryResultStub
+	fakeReturns := fake.getPrivateDataQueryResultReturns
 	fake.recordInvocation("GetPrivateDataQueryResult", []interface{}{arg1, arg2})
 	fake.getPrivateDataQueryResultMutex.Unlock()
-	if fake.GetPrivateDataQueryResultStub != nil {
-		return fake.GetPrivateDataQueryResultStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getPrivateDataQueryResultReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1457,15 +1485,16 @@ func (fake *ChaincodeStub) GetPrivateDataValidationParameter(arg1 string, arg2 s
 		arg1 string
 		arg2 string
 	}{arg1, arg2})
+	stub := fake.GetPrivateDataValidationParameterStub
+	fakeReturns := fake.getPrivateDataValidationParameterReturns
 	fake.recordInvocation("GetPrivateDataValidationParameter", []interface{}{arg1, arg2})
 	fake.getPrivateDataValidationParameterMutex.Unlock()
-	if fake.GetPrivateDataValidationParameterStub != nil {
-		return fake.GetPrivateDataValidationParameterStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getPrivateDataValidationParameterReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1520,15 +1549,16 @@ func (fake *ChaincodeStub) GetQueryResult(arg1 string) (shim.StateQueryIteratorI
 	fake.getQueryResultArgsForCall = append(fake.getQueryResultArgsForCall, struct {
 		arg1 string
 	}{arg1})
+	stub := fake.GetQueryResultStub
+	fakeReturns := fake.getQueryResultReturns
 	fake.recordInvocation("GetQueryResult", []interface{}{arg1})
 	fake.getQueryResultMutex.Unlock()
-	if fake.GetQueryResultStub != nil {
-		return fake.GetQueryResultStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getQueryResultReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1585,15 +1615,16 @@ func (fake *ChaincodeStub) GetQueryResultWithPagination(arg1 string, arg2 int32,
 		arg2 int32
 		arg3 string
 	}{arg1, arg2, arg3})
+	stub := fake.GetQueryResultWithPaginationStub
+	fakeReturns := fake.getQueryResultWithPaginationReturns
 	fake.recordInvocation("GetQueryResultWithPagination", []interface{}{arg1, arg2, arg3})
 	fake.getQueryResultWithPaginationMutex.Unlock()
-	if fake.GetQueryResultWithPaginationStub != nil {
-		return fake.GetQueryResultWithPaginationStub(arg1, arg2, arg3)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3
 	}
-	fakeReturns := fake.getQueryResultWithPaginationReturns
 	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
 }
 
@@ -1650,15 +1681,16 @@ func (fake *ChaincodeStub) GetSignedProposal() (*peer.SignedProposal, error) {
 	ret, specificReturn := fake.getSignedProposalReturnsOnCall[len(fake.getSignedProposalArgsForCall)]
 	fake.getSignedProposalArgsForCall = append(fake.getSignedProposalArgsForCall, struct {
 	}{})
+	stub := fake.GetSignedProposalStub
+	fakeReturns := fake.getSignedProposalReturns
 	fake.recordInvocation("GetSignedProposal", []interface{}{})
 	fake.getSignedProposalMutex.Unlock()
-	if fake.GetSignedProposalStub != nil {
-		return fake.GetSignedProposalStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getSignedProposalReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1706,15 +1738,16 @@ func (fake *ChaincodeStub) GetState(arg1 string) ([]byte, error) {
 	fake.getStateArgsForCall = append(fake.getStateArgsForCall, struct {
 		arg1 string
 	}{arg1})
+	stub := fake.GetStateStub
+	fakeReturns := fake.getStateReturns
 	fake.recordInvocation("GetState", []interface{}{arg1})
 	fake.getStateMutex.Unlock()
-	if fake.GetStateStub != nil {
-		return fake.GetStateStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getStateReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1775,15 +1808,16 @@ func (fake *ChaincodeStub) GetStateByPartialCompositeKey(arg1 string, arg2 []str
 		arg1 string
 		arg2 []string
 	}{arg1, arg2Copy})
+	stub := fake.GetStateByPartialCompositeKeyStub
+	fakeReturns := fake.getStateByPartialCompositeKeyReturns
 	fake.recordInvocation("GetStateByPartialCompositeKey", []interface{}{arg1, arg2Copy})
 	fake.getStateByPartialCompositeKeyMutex.Unlock()
-	if fake.GetStateByPartialCompositeKeyStub != nil {
-		return fake.GetStateByPartialCompositeKeyStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getStateByPartialCompositeKeyReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1846,15 +1880,16 @@ func (fake *ChaincodeStub) GetStateByPartialCompositeKeyWithPagination(arg1 stri
 		arg3 int32
 		arg4 string
 	}{arg1, arg2Copy, arg3, arg4})
+	stub := fake.GetStateByPartialCompositeKeyWithPaginationStub
+	fakeReturns := fake.getStateByPartialCompositeKeyWithPaginationReturns
 	fake.recordInvocation("GetStateByPartialCompositeKeyWithPagination", []interface{}{arg1, arg2Copy, arg3, arg4})
 	fake.getStateByPartialCompositeKeyWithPaginationMutex.Unlock()
-	if fake.GetStateByPartialCompositeKeyWithPaginationStub != nil {
-		return fake.GetStateByPartialCompositeKeyWithPaginationStub(arg1, arg2, arg3, arg4)
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3
 	}
-	fakeReturns := fake.getStateByPartialCompositeKeyWithPaginationReturns
 	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
 }
 
@@ -1913,15 +1948,16 @@ func (fake *ChaincodeStub) GetStateByRange(arg1 string, arg2 string) (shim.State
 		arg1 string
 		arg2 string
 	}{arg1, arg2})
+	stub := fake.GetStateByRangeStub
+	fakeReturns := fake.getStateByRangeReturns
 	fake.recordInvocation("GetStateByRange", []interface{}{arg1, arg2})
 	fake.getStateByRangeMutex.Unlock()
-	if fake.GetStateByRangeStub != nil {
-		return fake.GetStateByRangeStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getStateByRangeReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -1979,15 +2015,16 @@ func (fake *ChaincodeStub) GetStateByRangeWithPagination(arg1 string, arg2 strin
 		arg3 int32
 		arg4 string
 	}{arg1, arg2, arg3, arg4})
+	stub := fake.GetStateByRangeWithPaginationStub
+	fakeReturns := fake.getStateByRangeWithPaginationReturns
 	fake.recordInvocation("GetStateByRangeWithPagination", []interface{}{arg1, arg2, arg3, arg4})
 	fake.getStateByRangeWithPaginationMutex.Unlock()
-	if fake.GetStateByRangeWithPaginationStub != nil {
-		return fake.GetStateByRangeWithPaginationStub(arg1, arg2, arg3, arg4)
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3
 	}
-	fakeReturns := fake.getStateByRangeWithPaginationReturns
 	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
 }
 
@@ -2045,15 +2082,16 @@ func (fake *ChaincodeStub) GetStateValidationParameter(arg1 string) ([]byte, err
 	fake.getStateValidationParameterArgsForCall = append(fake.getStateValidationParameterArgsForCall, struct {
 		arg1 string
 	}{arg1})
+	stub := fake.GetStateValidationParameterStub
+	fakeReturns := fake.getStateValidationParameterReturns
 	fake.recordInvocation("GetStateValidationParameter", []interface{}{arg1})
 	fake.getStateValidationParameterMutex.Unlock()
-	if fake.GetStateValidationParameterStub != nil {
-		return fake.GetStateValidationParameterStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getStateValidationParameterReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -2107,15 +2145,16 @@ func (fake *ChaincodeStub) GetStringArgs() []string {
 	ret, specificReturn := fake.getStringArgsReturnsOnCall[len(fake.getStringArgsArgsForCall)]
 	fake.getStringArgsArgsForCall = append(fake.getStringArgsArgsForCall, struct {
 	}{})
+	stub := fake.GetStringArgsStub
+	fakeReturns := fake.getStringArgsReturns
 	fake.recordInvocation("GetStringArgs", []interface{}{})
 	fake.getStringArgsMutex.Unlock()
-	if fake.GetStringArgsStub != nil {
-		return fake.GetStringArgsStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.getStringArgsReturns
 	return fakeReturns.result1
 }
 
@@ -2159,15 +2198,16 @@ func (fake *ChaincodeStub) GetTransient() (map[string][]byte, error) {
 	ret, specificReturn := fake.getTransientReturnsOnCall[len(fake.getTransientArgsForCall)]
 	fake.getTransientArgsForCall = append(fake.getTransientArgsForCall, struct {
 	}{})
+	stub := fake.GetTransientStub
+	fakeReturns := fake.getTransientReturns
 	fake.recordInvocation("GetTransient", []interface{}{})
 	fake.getTransientMutex.Unlock()
-	if fake.GetTransientStub != nil {
-		return fake.GetTransientStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getTransientReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -2214,15 +2254,16 @@ func (fake *ChaincodeStub) GetTxID() string {
 	ret, specificReturn := fake.getTxIDReturnsOnCall[len(fake.getTxIDArgsForCall)]
 	fake.getTxIDArgsForCall = append(fake.getTxIDArgsForCall, struct {
 	}{})
+	stub := fake.GetTxIDStub
+	fakeReturns := fake.getTxIDReturns
 	fake.recordInvocation("GetTxID", []interface{}{})
 	fake.getTxIDMutex.Unlock()
-	if fake.GetTxIDStub != nil {
-		return fake.GetTxIDStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.getTxIDReturns
 	return fakeReturns.result1
 }
 
@@ -2261,20 +2302,21 @@ func (fake *ChaincodeStub) GetTxIDReturnsOnCall(i int, result1 string) {
 	}{result1}
 }
 
-func (fake *ChaincodeStub) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+func (fake *ChaincodeStub) GetTxTimestamp() (*timestamp.Timestamp, error) {
 	fake.getTxTimestampMutex.Lock()
 	ret, specificReturn := fake.getTxTimestampReturnsOnCall[len(fake.getTxTimestampArgsForCall)]
 	fake.getTxTimestampArgsForCall = append(fake.getTxTimestampArgsForCall, struct {
 	}{})
+	stub := fake.GetTxTimestampStub
+	fakeReturns := fake.getTxTimestampReturns
 	fake.recordInvocation("GetTxTimestamp", []interface{}{})
 	fake.getTxTimestampMutex.Unlock()
-	if fake.GetTxTimestampStub != nil {
-		return fake.GetTxTimestampStub()
+	if stub != nil {
+		return stub()
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
 	}
-	fakeReturns := fake.getTxTimestampReturns
 	return fakeReturns.result1, fakeReturns.result2
 }
 
@@ -2284,34 +2326,34 @@ func (fake *ChaincodeStub) GetTxTimestampCallCount() int {
 	return len(fake.getTxTimestampArgsForCall)
 }
 
-func (fake *ChaincodeStub) GetTxTimestampCalls(stub func() (*timestamppb.Timestamp, error)) {
+func (fake *ChaincodeStub) GetTxTimestampCalls(stub func() (*timestamp.Timestamp, error)) {
 	fake.getTxTimestampMutex.Lock()
 	defer fake.getTxTimestampMutex.Unlock()
 	fake.GetTxTimestampStub = stub
 }
 
-func (fake *ChaincodeStub) GetTxTimestampReturns(result1 *timestamppb.Timestamp, result2 error) {
+func (fake *ChaincodeStub) GetTxTimestampReturns(result1 *timestamp.Timestamp, result2 error) {
 	fake.getTxTimestampMutex.Lock()
 	defer fake.getTxTimestampMutex.Unlock()
 	fake.GetTxTimestampStub = nil
 	fake.getTxTimestampReturns = struct {
-		result1 *timestamppb.Timestamp
+		result1 *timestamp.Timestamp
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *ChaincodeStub) GetTxTimestampReturnsOnCall(i int, result1 *timestamppb.Timestamp, result2 error) {
+func (fake *ChaincodeStub) GetTxTimestampReturnsOnCall(i int, result1 *timestamp.Timestamp, result2 error) {
 	fake.getTxTimestampMutex.Lock()
 	defer fake.getTxTimestampMutex.Unlock()
 	fake.GetTxTimestampStub = nil
 	if fake.getTxTimestampReturnsOnCall == nil {
 		fake.getTxTimestampReturnsOnCall = make(map[int]struct {
-			result1 *timestamppb.Timestamp
+			result1 *timestamp.Timestamp
 			result2 error
 		})
 	}
 	fake.getTxTimestampReturnsOnCall[i] = struct {
-		result1 *timestamppb.Timestamp
+		result1 *timestamp.Timestamp
 		result2 error
 	}{result1, result2}
 }
@@ -2329,15 +2371,16 @@ func (fake *ChaincodeStub) InvokeChaincode(arg1 string, arg2 [][]byte, arg3 stri
 		arg2 [][]byte
 		arg3 string
 	}{arg1, arg2Copy, arg3})
+	stub := fake.InvokeChaincodeStub
+	fakeReturns := fake.invokeChaincodeReturns
 	fake.recordInvocation("InvokeChaincode", []interface{}{arg1, arg2Copy, arg3})
 	fake.invokeChaincodeMutex.Unlock()
-	if fake.InvokeChaincodeStub != nil {
-		return fake.InvokeChaincodeStub(arg1, arg2, arg3)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.invokeChaincodeReturns
 	return fakeReturns.result1
 }
 
@@ -2383,6 +2426,68 @@ func (fake *ChaincodeStub) InvokeChaincodeReturnsOnCall(i int, result1 peer.Resp
 	}{result1}
 }
 
+func (fake *ChaincodeStub) PurgePrivateData(arg1 string, arg2 string) error {
+	fake.purgePrivateDataMutex.Lock()
+	ret, specificReturn := fake.purgePrivateDataReturnsOnCall[len(fake.purgePrivateDataArgsForCall)]
+	fake.purgePrivateDataArgsForCall = append(fake.purgePrivateDataArgsForCall, struct {
+		arg1 string
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.PurgePrivateDataStub
+	fakeReturns := fake.purgePrivateDataReturns
+	fake.recordInvocation("PurgePrivateData", []interface{}{arg1, arg2})
+	fake.purgePrivateDataMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *ChaincodeStub) PurgePrivateDataCallCount() int {
+	fake.purgePrivateDataMutex.RLock()
+	defer fake.purgePrivateDataMutex.RUnlock()
+	return len(fake.purgePrivateDataArgsForCall)
+}
+
+func (fake *ChaincodeStub) PurgePrivateDataCalls(stub func(string, string) error) {
+	fake.purgePrivateDataMutex.Lock()
+	defer fake.purgePrivateDataMutex.Unlock()
+	fake.PurgePrivateDataStub = stub
+}
+
+func (fake *ChaincodeStub) PurgePrivateDataArgsForCall(i int) (string, string) {
+	fake.purgePrivateDataMutex.RLock()
+	defer fake.purgePrivateDataMutex.RUnlock()
+	argsForCall := fake.purgePrivateDataArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *ChaincodeStub) PurgePrivateDataReturns(result1 error) {
+	fake.purgePrivateDataMutex.Lock()
+	defer fake.purgePrivateDataMutex.Unlock()
+	fake.PurgePrivateDataStub = nil
+	fake.purgePrivateDataReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *ChaincodeStub) PurgePrivateDataReturnsOnCall(i int, result1 error) {
+	fake.purgePrivateDataMutex.Lock()
+	defer fake.purgePrivateDataMutex.Unlock()
+	fake.PurgePrivateDataStub = nil
+	if fake.purgePrivateDataReturnsOnCall == nil {
+		fake.purgePrivateDataReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.purgePrivateDataReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *ChaincodeStub) PutPrivateData(arg1 string, arg2 string, arg3 []byte) error {
 	var arg3Copy []byte
 	if arg3 != nil {
@@ -2396,15 +2501,16 @@ func (fake *ChaincodeStub) PutPrivateData(arg1 string, arg2 string, arg3 []byte)
 		arg2 string
 		arg3 []byte
 	}{arg1, arg2, arg3Copy})
+	stub := fake.PutPrivateDataStub
+	fakeReturns := fake.putPrivateDataReturns
 	fake.recordInvocation("PutPrivateData", []interface{}{arg1, arg2, arg3Copy})
 	fake.putPrivateDataMutex.Unlock()
-	if fake.PutPrivateDataStub != nil {
-		return fake.PutPrivateDataStub(arg1, arg2, arg3)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.putPrivateDataReturns
 	return fakeReturns.result1
 }
 
@@ -2462,15 +2568,16 @@ func (fake *ChaincodeStub) PutState(arg1 string, arg2 []byte) error {
 		arg1 string
 		arg2 []byte
 	}{arg1, arg2Copy})
+	stub := fake.PutStateStub
+	fakeReturns := fake.putStateReturns
 	fake.recordInvocation("PutState", []interface{}{arg1, arg2Copy})
 	fake.putStateMutex.Unlock()
-	if fake.PutStateStub != nil {
-		return fake.PutStateStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.putStateReturns
 	return fakeReturns.result1
 }
 
@@ -2528,15 +2635,16 @@ func (fake *ChaincodeStub) SetEvent(arg1 string, arg2 []byte) error {
 		arg1 string
 		arg2 []byte
 	}{arg1, arg2Copy})
+	stub := fake.SetEventStub
+	fakeReturns := fake.setEventReturns
 	fake.recordInvocation("SetEvent", []interface{}{arg1, arg2Copy})
 	fake.setEventMutex.Unlock()
-	if fake.SetEventStub != nil {
-		return fake.SetEventStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.setEventReturns
 	return fakeReturns.result1
 }
 
@@ -2595,15 +2703,16 @@ func (fake *ChaincodeStub) SetPrivateDataValidationParameter(arg1 string, arg2 s
 		arg2 string
 		arg3 []byte
 	}{arg1, arg2, arg3Copy})
+	stub := fake.SetPrivateDataValidationParameterStub
+	fakeReturns := fake.setPrivateDataValidationParameterReturns
 	fake.recordInvocation("SetPrivateDataValidationParameter", []interface{}{arg1, arg2, arg3Copy})
 	fake.setPrivateDataValidationParameterMutex.Unlock()
-	if fake.SetPrivateDataValidationParameterStub != nil {
-		return fake.SetPrivateDataValidationParameterStub(arg1, arg2, arg3)
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.setPrivateDataValidationParameterReturns
 	return fakeReturns.result1
 }
 
@@ -2661,15 +2770,16 @@ func (fake *ChaincodeStub) SetStateValidationParameter(arg1 string, arg2 []byte)
 		arg1 string
 		arg2 []byte
 	}{arg1, arg2Copy})
+	stub := fake.SetStateValidationParameterStub
+	fakeReturns := fake.setStateValidationParameterReturns
 	fake.recordInvocation("SetStateValidationParameter", []interface{}{arg1, arg2Copy})
 	fake.setStateValidationParameterMutex.Unlock()
-	if fake.SetStateValidationParameterStub != nil {
-		return fake.SetStateValidationParameterStub(arg1, arg2)
+	if stub != nil {
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
 	}
-	fakeReturns := fake.setStateValidationParameterReturns
 	return fakeReturns.result1
 }
 
@@ -2721,15 +2831,16 @@ func (fake *ChaincodeStub) SplitCompositeKey(arg1 string) (string, []string, err
 	fake.splitCompositeKeyArgsForCall = append(fake.splitCompositeKeyArgsForCall, struct {
 		arg1 string
 	}{arg1})
+	stub := fake.SplitCompositeKeyStub
+	fakeReturns := fake.splitCompositeKeyReturns
 	fake.recordInvocation("SplitCompositeKey", []interface{}{arg1})
 	fake.splitCompositeKeyMutex.Unlock()
-	if fake.SplitCompositeKeyStub != nil {
-		return fake.SplitCompositeKeyStub(arg1)
+	if stub != nil {
+		return stub(arg1)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2, ret.result3
 	}
-	fakeReturns := fake.splitCompositeKeyReturns
 	return fakeReturns.result1, fakeReturns.result2, fakeReturns.result3
 }
 
@@ -2846,6 +2957,8 @@ func (fake *ChaincodeStub) Invocations() map[string][][]interface{} {
 	defer fake.getTxTimestampMutex.RUnlock()
 	fake.invokeChaincodeMutex.RLock()
 	defer fake.invokeChaincodeMutex.RUnlock()
+	fake.purgePrivateDataMutex.RLock()
+	defer fake.purgePrivateDataMutex.RUnlock()
 	fake.putPrivateDataMutex.RLock()
 	defer fake.putPrivateDataMutex.RUnlock()
 	fake.putStateMutex.RLock()
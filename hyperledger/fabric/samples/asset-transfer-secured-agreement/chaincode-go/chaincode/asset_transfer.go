@@ -2,14 +2,13 @@
  SPDX-License-Identifier: Apache-2.0
 */
 
-package main
+package chaincode
 
 import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/golang/protobuf/ptypes"
@@ -35,11 +34,21 @@ type Asset struct {
 	ID                string `json:"assetID"`
 	OwnerOrg          string `json:"ownerOrg"`
 	PublicDescription string `json:"publicDescription"`
+	// The arbiter org of the last transfer can freeze and reverse it until the end of the dispute window
+	ArbiterOrg       string `json:"arbiterOrg,omitempty" metadata:",optional"`
+	PreviousOwnerOrg string `json:"previousOwnerOrg,omitempty" metadata:",optional"`
+	DisputeWindowEnd string `json:"disputeWindowEnd,omitempty" metadata:",optional"`
+	Frozen           bool   `json:"frozen,omitempty" metadata:",optional"`
 }
 
-type receipt struct {
-	price     int
-	timestamp time.Time
+// Receipt records the price and date of a transfer in the implicit private data collections of the seller and the buyer
+type Receipt struct {
+	AssetID   string    `json:"assetID"`
+	TxID      string    `json:"txID"`
+	SellerOrg string    `json:"sellerOrg"`
+	BuyerOrg  string    `json:"buyerOrg"`
+	Price     int       `json:"price"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // CreateAsset creates an asset and sets it as owned by the client's org
@@ -111,14 +120,31 @@ func (s *SmartContract) ChangePublicDescription(ctx contractapi.TransactionConte
 	if clientOrgID != asset.OwnerOrg {
 		return fmt.Errorf("a client from %s cannot update the description of a asset owned by %s", clientOrgID, asset.OwnerOrg)
 	}
+	if asset.Frozen {
+		return fmt.Errorf("asset %s is frozen by arbiter %s", assetID, asset.ArbiterOrg)
+	}
+
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
 
 	asset.PublicDescription = newDescription
-	updatedAssetJSON, err := json.Marshal(asset)
+	settled := settleDispute(asset, now)
+	err = putAsset(ctx, asset)
 	if err != nil {
-		return fmt.Errorf("failed to marshal asset: %v", err)
+		return err
 	}
 
-	return ctx.GetStub().PutState(assetID, updatedAssetJSON)
+	// Once the dispute window has passed, the arbiter is no longer required to endorse updates
+	if settled {
+		err = setAssetStateBasedEndorsement(ctx, asset.ID, asset.OwnerOrg)
+		if err != nil {
+			return fmt.Errorf("failed setting state based endorsement for owner: %v", err)
+		}
+	}
+
+	return nil
 }
 
 // AgreeToSell adds seller's asking price to seller's implicit private data collection
@@ -138,16 +164,26 @@ func (s *SmartContract) AgreeToSell(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("a client from %s cannot sell an asset owned by %s", clientOrgID, asset.OwnerOrg)
 	}
 
-	return agreeToPrice(ctx, assetID, typeAssetForSale)
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+	err = checkTransferable(asset, now)
+	if err != nil {
+		return err
+	}
+
+	return agreeToPrice(ctx, assetID, "", typeAssetForSale)
 }
 
 // AgreeToBuy adds buyer's bid price to buyer's implicit private data collection
 func (s *SmartContract) AgreeToBuy(ctx contractapi.TransactionContextInterface, assetID string) error {
-	return agreeToPrice(ctx, assetID, typeAssetBid)
+	return agreeToPrice(ctx, assetID, "", typeAssetBid)
 }
 
-// agreeToPrice adds a bid or ask price to caller's implicit private data collection
-func agreeToPrice(ctx contractapi.TransactionContextInterface, assetID string, priceType string) error {
+// agreeToPrice adds a bid or ask price to caller's implicit private data collection.
+// A price negotiated with a buyer org is kept apart from the price agreed to with AgreeToSell or AgreeToBuy.
+func agreeToPrice(ctx contractapi.TransactionContextInterface, assetID string, buyerOrgID string, priceType string) error {
 	// In this scenario, client is only authorized to read/write private data from its own peer.
 	clientOrgID, err := getClientOrgID(ctx, true)
 	if err != nil {
@@ -165,11 +201,16 @@ func agreeToPrice(ctx contractapi.TransactionContextInterface, assetID string, p
 		return fmt.Errorf("asset_price key not found in the transient map")
 	}
 
+	err = validateAgreement(ctx, price)
+	if err != nil {
+		return err
+	}
+
 	collection := buildCollectionName(clientOrgID)
 
 	// Persist the agreed to price in a collection sub-namespace based on priceType key prefix,
 	// to avoid collisions between private asset properties, sell price, and buy price
-	assetPriceKey, err := ctx.GetStub().CreateCompositeKey(priceType, []string{assetID})
+	assetPriceKey, err := createAssetPriceKey(ctx, priceType, assetID, buyerOrgID)
 	if err != nil {
 		return fmt.Errorf("failed to create composite key: %v", err)
 	}
@@ -184,6 +225,45 @@ func agreeToPrice(ctx contractapi.TransactionContextInterface, assetID string, p
 	return nil
 }
 
+// WithdrawAgreement removes the client org's asking price or bid price for an asset from its implicit private data
+// collection, so that the asset can no longer be transferred at that price. The prices of a negotiation are
+// withdrawn with WithdrawOffer.
+func (s *SmartContract) WithdrawAgreement(ctx contractapi.TransactionContextInterface, assetID string) error {
+	// In this scenario, client is only authorized to read/write private data from its own peer.
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return err
+	}
+
+	withdrawn := false
+	for _, priceType := range []string{typeAssetForSale, typeAssetBid} {
+		assetPriceKey, err := ctx.GetStub().CreateCompositeKey(priceType, []string{assetID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+
+		price, err := ctx.GetStub().GetPrivateData(collection, assetPriceKey)
+		if err != nil {
+			return fmt.Errorf("failed to read asset price from implicit private data collection: %v", err)
+		}
+		if price == nil {
+			continue
+		}
+
+		err = ctx.GetStub().DelPrivateData(collection, assetPriceKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete asset price from implicit private data collection: %v", err)
+		}
+		withdrawn = true
+	}
+
+	if !withdrawn {
+		return fmt.Errorf("no agreement to withdraw for asset %s", assetID)
+	}
+
+	return nil
+}
+
 // VerifyAssetProperties  Allows a buyer to validate the properties of
 // an asset against the owner's implicit private data collection
 func (s *SmartContract) VerifyAssetProperties(ctx contractapi.TransactionContextInterface, assetID string) (bool, error) {
@@ -262,7 +342,25 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 		return fmt.Errorf("failed to get asset: %v", err)
 	}
 
-	err = verifyTransferConditions(ctx, asset, immutablePropertiesJSON, clientOrgID, buyerOrgID, priceJSON)
+	// A negotiation of the buyer with the current owner replaces the prices agreed to with AgreeToSell and AgreeToBuy
+	negotiation, err := readOpenNegotiation(ctx, asset, buyerOrgID)
+	if err != nil {
+		return err
+	}
+	negotiatedBuyerOrgID := ""
+	if negotiation != nil {
+		if !negotiation.Matched {
+			return fmt.Errorf("failed transfer verification: the latest offers of the negotiation of asset %s with %s do not match", assetID, buyerOrgID)
+		}
+		negotiatedBuyerOrgID = buyerOrgID
+	}
+
+	err = verifyTransferConditions(ctx, asset, immutablePropertiesJSON, clientOrgID, buyerOrgID, priceJSON, negotiatedBuyerOrgID)
+	if err != nil {
+		return fmt.Errorf("failed transfer verification: %v", err)
+	}
+
+	err = verifyAgreementTerms(ctx, asset, &agreement, clientOrgID, buyerOrgID)
 	if err != nil {
 		return fmt.Errorf("failed transfer verification: %v", err)
 	}
@@ -272,17 +370,26 @@ func (s *SmartContract) TransferAsset(ctx contractapi.TransactionContextInterfac
 		return fmt.Errorf("failed asset transfer: %v", err)
 	}
 
+	if negotiation != nil {
+		err = closeNegotiation(ctx, negotiation)
+		if err != nil {
+			return fmt.Errorf("failed asset transfer: %v", err)
+		}
+	}
+
 	return nil
 
 }
 
-// verifyTransferConditions checks that client org currently owns asset and that both parties have agreed on price
+// verifyTransferConditions checks that client org currently owns asset and that both parties have agreed on price.
+// If negotiatedBuyerOrgID is set, the prices are the latest offers of the negotiation with that buyer org.
 func verifyTransferConditions(ctx contractapi.TransactionContextInterface,
 	asset *Asset,
 	immutablePropertiesJSON []byte,
 	clientOrgID string,
 	buyerOrgID string,
-	priceJSON []byte) error {
+	priceJSON []byte,
+	negotiatedBuyerOrgID string) error {
 
 	// CHECK1: Auth check to ensure that client's org actually owns the asset
 
@@ -317,7 +424,7 @@ func verifyTransferConditions(ctx contractapi.TransactionContextInterface,
 	// CHECK3: Verify that seller and buyer agreed on the same price
 
 	// Get sellers asking price
-	assetForSaleKey, err := ctx.GetStub().CreateCompositeKey(typeAssetForSale, []string{asset.ID})
+	assetForSaleKey, err := createAssetPriceKey(ctx, typeAssetForSale, asset.ID, negotiatedBuyerOrgID)
 	if err != nil {
 		return fmt.Errorf("failed to create composite key: %v", err)
 	}
@@ -331,7 +438,7 @@ func verifyTransferConditions(ctx contractapi.TransactionContextInterface,
 
 	// Get buyers bid price
 	collectionBuyer := buildCollectionName(buyerOrgID)
-	assetBidKey, err := ctx.GetStub().CreateCompositeKey(typeAssetBid, []string{asset.ID})
+	assetBidKey, err := createAssetPriceKey(ctx, typeAssetBid, asset.ID, negotiatedBuyerOrgID)
 	if err != nil {
 		return fmt.Errorf("failed to create composite key: %v", err)
 	}
@@ -381,8 +488,13 @@ func transferAssetState(ctx contractapi.TransactionContextInterface, asset *Asse
 		return fmt.Errorf("failed to write asset for buyer: %v", err)
 	}
 
-	// Change the endorsement policy to the new owner
-	err = setAssetStateBasedEndorsement(ctx, asset.ID, buyerOrgID)
+	// Change the endorsement policy to the new owner, or to the arbiter of the transfer if there is one
+	// together with the new owner or the seller
+	if asset.ArbiterOrg != "" {
+		err = setAssetDisputeEndorsement(ctx, asset)
+	} else {
+		err = setAssetStateBasedEndorsement(ctx, asset.ID, asset.OwnerOrg)
+	}
 	if err != nil {
 		return fmt.Errorf("failed setting state based endorsement for new owner: %v", err)
 	}
@@ -400,26 +512,27 @@ func transferAssetState(ctx contractapi.TransactionContextInterface, asset *Asse
 		return fmt.Errorf("failed to put Asset private properties for buyer: %v", err)
 	}
 
-	// Delete the price records for seller
-	assetPriceKey, err := ctx.GetStub().CreateCompositeKey(typeAssetForSale, []string{asset.ID})
-	if err != nil {
-		return fmt.Errorf("failed to create composite key for seller: %v", err)
-	}
+	// Delete the price records for seller and buyer, both the agreed to and the negotiated prices
+	for _, negotiatedBuyerOrgID := range []string{"", buyerOrgID} {
+		assetPriceKey, err := createAssetPriceKey(ctx, typeAssetForSale, asset.ID, negotiatedBuyerOrgID)
+		if err != nil {
+			return fmt.Errorf("failed to create composite key for seller: %v", err)
+		}
 
-	err = ctx.GetStub().DelPrivateData(collectionSeller, assetPriceKey)
-	if err != nil {
-		return fmt.Errorf("failed to delete asset price from implicit private data collection for seller: %v", err)
-	}
+		err = ctx.GetStub().DelPrivateData(collectionSeller, assetPriceKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete asset price from implicit private data collection for seller: %v", err)
+		}
 
-	// Delete the price records for buyer
-	assetPriceKey, err = ctx.GetStub().CreateCompositeKey(typeAssetBid, []string{asset.ID})
-	if err != nil {
-		return fmt.Errorf("failed to create composite key for buyer: %v", err)
-	}
+		assetPriceKey, err = createAssetPriceKey(ctx, typeAssetBid, asset.ID, negotiatedBuyerOrgID)
+		if err != nil {
+			return fmt.Errorf("failed to create composite key for buyer: %v", err)
+		}
 
-	err = ctx.GetStub().DelPrivateData(collectionBuyer, assetPriceKey)
-	if err != nil {
-		return fmt.Errorf("failed to delete asset price from implicit private data collection for buyer: %v", err)
+		err = ctx.GetStub().DelPrivateData(collectionBuyer, assetPriceKey)
+		if err != nil {
+			return fmt.Errorf("failed to delete asset price from implicit private data collection for buyer: %v", err)
+		}
 	}
 
 	// Keep record for a 'receipt' in both buyers and sellers private data collection to record the sale price and date.
@@ -438,16 +551,20 @@ func transferAssetState(ctx contractapi.TransactionContextInterface, asset *Asse
 	if err != nil {
 		return err
 	}
-	assetReceipt := receipt{
-		price:     price,
-		timestamp: timestamp,
+	assetReceipt := Receipt{
+		AssetID:   asset.ID,
+		TxID:      ctx.GetStub().GetTxID(),
+		SellerOrg: clientOrgID,
+		BuyerOrg:  buyerOrgID,
+		Price:     price,
+		Timestamp: timestamp,
 	}
-	receipt, err := json.Marshal(assetReceipt)
+	receiptJSON, err := json.Marshal(assetReceipt)
 	if err != nil {
 		return fmt.Errorf("failed to marshal receipt: %v", err)
 	}
 
-	err = ctx.GetStub().PutPrivateData(collectionBuyer, receiptBuyKey, receipt)
+	err = ctx.GetStub().PutPrivateData(collectionBuyer, receiptBuyKey, receiptJSON)
 	if err != nil {
 		return fmt.Errorf("failed to put private asset receipt for buyer: %v", err)
 	}
@@ -457,7 +574,7 @@ func transferAssetState(ctx contractapi.TransactionContextInterface, asset *Asse
 		return fmt.Errorf("failed to create composite key for receipt: %v", err)
 	}
 
-	err = ctx.GetStub().PutPrivateData(collectionSeller, receiptSaleKey, receipt)
+	err = ctx.GetStub().PutPrivateData(collectionSeller, receiptSaleKey, receiptJSON)
 	if err != nil {
 		return fmt.Errorf("failed to put private asset receipt for seller: %v", err)
 	}
@@ -504,13 +621,13 @@ func verifyClientOrgMatchesPeerOrg(clientOrgID string) error {
 }
 
 // setAssetStateBasedEndorsement adds an endorsement policy to a asset so that only a peer from an owning org
-// can update or transfer the asset.
-func setAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface, assetID string, orgToEndorse string) error {
+// can update or transfer the asset. If several orgs are given, peers from all of them must endorse.
+func setAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface, assetID string, orgsToEndorse ...string) error {
 	endorsementPolicy, err := statebased.NewStateEP(nil)
 	if err != nil {
 		return err
 	}
-	err = endorsementPolicy.AddOrgs(statebased.RoleTypePeer, orgToEndorse)
+	err = endorsementPolicy.AddOrgs(statebased.RoleTypePeer, orgsToEndorse...)
 	if err != nil {
 		return fmt.Errorf("failed to add org to endorsement policy: %v", err)
 	}
@@ -526,6 +643,17 @@ func setAssetStateBasedEndorsement(ctx contractapi.TransactionContextInterface,
 	return nil
 }
 
+// createAssetPriceKey creates the key of an asking or bid price for an asset. The prices that the owner and a buyer
+// negotiate with MakeOffer are kept per buyer org, so that negotiations with several buyers do not overwrite each other.
+func createAssetPriceKey(ctx contractapi.TransactionContextInterface, priceType string, assetID string, negotiatedBuyerOrgID string) (string, error) {
+	attributes := []string{assetID}
+	if negotiatedBuyerOrgID != "" {
+		attributes = append(attributes, negotiatedBuyerOrgID)
+	}
+
+	return ctx.GetStub().CreateCompositeKey(priceType, attributes)
+}
+
 func buildCollectionName(clientOrgID string) string {
 	return fmt.Sprintf("_implicit_org_%s", clientOrgID)
 }
@@ -543,14 +671,3 @@ func getClientImplicitCollectionName(ctx contractapi.TransactionContextInterface
 
 	return buildCollectionName(clientOrgID), nil
 }
-
-func main() {
-	chaincode, err := contractapi.NewChaincode(new(SmartContract))
-	if err != nil {
-		log.Panicf("Error create transfer asset chaincode: %v", err)
-	}
-
-	if err := chaincode.Start(); err != nil {
-		log.Panicf("Error starting asset chaincode: %v", err)
-	}
-}
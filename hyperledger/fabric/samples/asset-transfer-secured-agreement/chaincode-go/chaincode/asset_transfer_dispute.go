@@ -0,0 +1,340 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+// FreezeAsset freezes an asset during the dispute window of its last transfer, so that the owner can no longer
+// update, sell or transfer it. Only a client from the arbiter org named in the agreement of the transfer can freeze it.
+func (s *SmartContract) FreezeAsset(ctx contractapi.TransactionContextInterface, assetID string) error {
+	asset, err := s.readAssetAsArbiter(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	if asset.Frozen {
+		return fmt.Errorf("asset %s is already frozen", assetID)
+	}
+	if !inDisputeWindow(asset, now) {
+		return fmt.Errorf("the dispute window of asset %s ended at %s", assetID, asset.DisputeWindowEnd)
+	}
+
+	asset.Frozen = true
+
+	return putAsset(ctx, asset)
+}
+
+// UnfreezeAsset releases a frozen asset back to its owner. Only a client from the arbiter org can unfreeze it.
+func (s *SmartContract) UnfreezeAsset(ctx contractapi.TransactionContextInterface, assetID string) error {
+	asset, err := s.readAssetAsArbiter(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	if !asset.Frozen {
+		return fmt.Errorf("asset %s is not frozen", assetID)
+	}
+
+	asset.Frozen = false
+
+	return putAsset(ctx, asset)
+}
+
+// ReverseTransfer returns an asset to the org that sold it, if the asset is frozen or still in the dispute window
+// of its last transfer. Only a client from the arbiter org can reverse a transfer. Since the arbiter cannot read
+// the private properties of the asset, they must be passed in the transient field by the arbiter, who obtains them
+// from the parties to the dispute. Settling the price of the reversed transfer is left to the parties.
+func (s *SmartContract) ReverseTransfer(ctx contractapi.TransactionContextInterface, assetID string) error {
+	transMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return fmt.Errorf("error getting transient data: %v", err)
+	}
+
+	immutablePropertiesJSON, ok := transMap["asset_properties"]
+	if !ok {
+		return fmt.Errorf("asset_properties key not found in the transient map")
+	}
+
+	asset, err := s.readAssetAsArbiter(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !asset.Frozen && !inDisputeWindow(asset, now) {
+		return fmt.Errorf("the dispute window of asset %s ended at %s", assetID, asset.DisputeWindowEnd)
+	}
+
+	// Verify that the hash of the passed immutable properties matches the on-chain hash in the owner's collection
+	collectionOwner := buildCollectionName(asset.OwnerOrg)
+	immutablePropertiesOnChainHash, err := ctx.GetStub().GetPrivateDataHash(collectionOwner, assetID)
+	if err != nil {
+		return fmt.Errorf("failed to read asset private properties hash from owner's collection: %v", err)
+	}
+	if immutablePropertiesOnChainHash == nil {
+		return fmt.Errorf("asset private properties hash does not exist: %s", assetID)
+	}
+
+	hash := sha256.New()
+	hash.Write(immutablePropertiesJSON)
+	calculatedPropertiesHash := hash.Sum(nil)
+
+	if !bytes.Equal(immutablePropertiesOnChainHash, calculatedPropertiesHash) {
+		return fmt.Errorf("hash %x for passed immutable properties %s does not match on-chain hash %x",
+			calculatedPropertiesHash,
+			immutablePropertiesJSON,
+			immutablePropertiesOnChainHash,
+		)
+	}
+
+	sellerOrgID := asset.PreviousOwnerOrg
+	asset.OwnerOrg = sellerOrgID
+	clearDispute(asset)
+
+	err = putAsset(ctx, asset)
+	if err != nil {
+		return err
+	}
+
+	// Change the endorsement policy back to the seller
+	err = setAssetStateBasedEndorsement(ctx, asset.ID, sellerOrgID)
+	if err != nil {
+		return fmt.Errorf("failed setting state based endorsement for seller: %v", err)
+	}
+
+	// Move the private properties back from the buyer's collection to the seller's collection
+	err = ctx.GetStub().DelPrivateData(collectionOwner, asset.ID)
+	if err != nil {
+		return fmt.Errorf("failed to delete Asset private details from buyer: %v", err)
+	}
+
+	err = ctx.GetStub().PutPrivateData(buildCollectionName(sellerOrgID), asset.ID, immutablePropertiesJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put Asset private properties for seller: %v", err)
+	}
+
+	return nil
+}
+
+// readAssetAsArbiter reads an asset and checks that the client is from the arbiter org of its last transfer
+func (s *SmartContract) readAssetAsArbiter(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, error) {
+	// No need to check client org id matches peer org id, the arbiter does not read private data.
+	clientOrgID, err := getClientOrgID(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verified OrgID: %v", err)
+	}
+
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get asset: %v", err)
+	}
+
+	if asset.ArbiterOrg == "" {
+		return nil, fmt.Errorf("asset %s has no arbiter", assetID)
+	}
+	if clientOrgID != asset.ArbiterOrg {
+		return nil, fmt.Errorf("a client from %s is not the arbiter of asset %s", clientOrgID, assetID)
+	}
+
+	return asset, nil
+}
+
+// validateAgreement checks the optional terms of an agreement when a seller or buyer agrees to it
+func validateAgreement(ctx contractapi.TransactionContextInterface, priceJSON []byte) error {
+	var agreement Agreement
+	err := json.Unmarshal(priceJSON, &agreement)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal price JSON: %v", err)
+	}
+
+	if agreement.Expiry != "" {
+		expiry, err := time.Parse(time.RFC3339, agreement.Expiry)
+		if err != nil {
+			return fmt.Errorf("invalid agreement expiry %q: %v", agreement.Expiry, err)
+		}
+
+		now, err := getTxTimestamp(ctx)
+		if err != nil {
+			return err
+		}
+		if !now.Before(expiry) {
+			return fmt.Errorf("agreement expiry %s is in the past", agreement.Expiry)
+		}
+	}
+
+	if agreement.ArbiterOrg != "" && agreement.DisputeWindowHours <= 0 {
+		return fmt.Errorf("an agreement with an arbiter requires a positive dispute window")
+	}
+	if agreement.ArbiterOrg == "" && agreement.DisputeWindowHours != 0 {
+		return fmt.Errorf("a dispute window requires an arbiter")
+	}
+
+	return nil
+}
+
+// verifyAgreementTerms checks that the asset can be transferred under the agreement that both parties agreed to,
+// and records the arbiter and dispute window of the agreement in the asset
+func verifyAgreementTerms(ctx contractapi.TransactionContextInterface, asset *Asset, agreement *Agreement, clientOrgID string, buyerOrgID string) error {
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = checkTransferable(asset, now)
+	if err != nil {
+		return err
+	}
+
+	if agreement.Expiry != "" {
+		expiry, _ := time.Parse(time.RFC3339, agreement.Expiry) // Error handling not needed since the expiry was validated when the parties agreed to it.
+		if !now.Before(expiry) {
+			return fmt.Errorf("agreement for asset %s expired at %s", asset.ID, agreement.Expiry)
+		}
+	}
+
+	clearDispute(asset)
+	if agreement.ArbiterOrg != "" {
+		if agreement.ArbiterOrg == clientOrgID || agreement.ArbiterOrg == buyerOrgID {
+			return fmt.Errorf("the arbiter of a transfer cannot be the seller or the buyer")
+		}
+		asset.ArbiterOrg = agreement.ArbiterOrg
+		asset.PreviousOwnerOrg = clientOrgID
+		asset.DisputeWindowEnd = now.Add(time.Duration(agreement.DisputeWindowHours) * time.Hour).Format(time.RFC3339)
+	}
+
+	return nil
+}
+
+// checkTransferable checks that an asset is neither frozen nor in the dispute window of its last transfer
+func checkTransferable(asset *Asset, now time.Time) error {
+	if asset.Frozen {
+		return fmt.Errorf("asset %s is frozen by arbiter %s", asset.ID, asset.ArbiterOrg)
+	}
+	if inDisputeWindow(asset, now) {
+		return fmt.Errorf("asset %s cannot be sold until the dispute window of its last transfer ends at %s", asset.ID, asset.DisputeWindowEnd)
+	}
+
+	return nil
+}
+
+// inDisputeWindow returns true if the arbiter of the last transfer of an asset can still freeze or reverse it
+func inDisputeWindow(asset *Asset, now time.Time) bool {
+	if asset.DisputeWindowEnd == "" {
+		return false
+	}
+	disputeWindowEnd, _ := time.Parse(time.RFC3339, asset.DisputeWindowEnd) // Error handling not needed since the end was formatted by verifyAgreementTerms.
+
+	return now.Before(disputeWindowEnd)
+}
+
+// settleDispute removes the arbiter from an asset whose dispute window has ended without it being frozen,
+// and returns true if it did
+func settleDispute(asset *Asset, now time.Time) bool {
+	if asset.ArbiterOrg == "" || asset.Frozen || inDisputeWindow(asset, now) {
+		return false
+	}
+
+	clearDispute(asset)
+
+	return true
+}
+
+// clearDispute removes the arbiter and dispute window of the last transfer from an asset
+func clearDispute(asset *Asset) {
+	asset.ArbiterOrg = ""
+	asset.PreviousOwnerOrg = ""
+	asset.DisputeWindowEnd = ""
+	asset.Frozen = false
+}
+
+// setAssetDisputeEndorsement sets the endorsement policy of an asset during the dispute window of its last transfer.
+// A peer of the arbiter org must endorse updates together with a peer of either the owner org or the seller org,
+// so that the arbiter and the seller can freeze or reverse the transfer without the cooperation of the buyer.
+func setAssetDisputeEndorsement(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	var principals []*msp.MSPPrincipal
+	for _, orgID := range []string{asset.OwnerOrg, asset.PreviousOwnerOrg, asset.ArbiterOrg} {
+		role, err := proto.Marshal(&msp.MSPRole{MspIdentifier: orgID, Role: msp.MSPRole_PEER})
+		if err != nil {
+			return fmt.Errorf("failed to marshal role of org %s: %v", orgID, err)
+		}
+		principals = append(principals, &msp.MSPPrincipal{PrincipalClassification: msp.MSPPrincipal_ROLE, Principal: role})
+	}
+
+	// (owner AND arbiter) OR (seller AND arbiter)
+	policy, err := proto.Marshal(&common.SignaturePolicyEnvelope{
+		Version: 0,
+		Rule: nOutOf(1,
+			nOutOf(2, signedBy(0), signedBy(2)),
+			nOutOf(2, signedBy(1), signedBy(2)),
+		),
+		Identities: principals,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create endorsement policy bytes from orgs: %v", err)
+	}
+
+	err = ctx.GetStub().SetStateValidationParameter(asset.ID, policy)
+	if err != nil {
+		return fmt.Errorf("failed to set validation parameter on asset: %v", err)
+	}
+
+	return nil
+}
+
+func nOutOf(n int32, rules ...*common.SignaturePolicy) *common.SignaturePolicy {
+	return &common.SignaturePolicy{
+		Type: &common.SignaturePolicy_NOutOf_{NOutOf: &common.SignaturePolicy_NOutOf{N: n, Rules: rules}},
+	}
+}
+
+func signedBy(principal int32) *common.SignaturePolicy {
+	return &common.SignaturePolicy{Type: &common.SignaturePolicy_SignedBy{SignedBy: principal}}
+}
+
+// putAsset writes the public data of an asset
+func putAsset(ctx contractapi.TransactionContextInterface, asset *Asset) error {
+	assetJSON, err := json.Marshal(asset)
+	if err != nil {
+		return fmt.Errorf("failed to marshal asset: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(asset.ID, assetJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put asset in public data: %v", err)
+	}
+
+	return nil
+}
+
+// getTxTimestamp returns the timestamp of the transaction, which is the same on all endorsing peers
+func getTxTimestamp(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	txTimestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return ptypes.Timestamp(txTimestamp)
+}
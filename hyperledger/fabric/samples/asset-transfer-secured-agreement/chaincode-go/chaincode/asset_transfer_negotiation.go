@@ -0,0 +1,373 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const (
+	typeNegotiation = "negotiation"
+	typeAssetOffer  = "O"
+)
+
+// Negotiation is the public record of the offers that the owner of an asset and a prospective buyer have made
+// to each other. Only the hashes of the offers are public, the offers themselves are kept in the implicit private
+// data collection of the org that made them. A negotiation is closed by the transfer of the asset at the matched price,
+// or abandoned when either org withdraws from it with WithdrawOffer.
+type Negotiation struct {
+	AssetID      string  `json:"assetID"`
+	SellerOrg    string  `json:"sellerOrg"`
+	BuyerOrg     string  `json:"buyerOrg"`
+	Round        int     `json:"round"`
+	Matched      bool    `json:"matched"`
+	Offers       []Offer `json:"offers"`
+	TransferTxID string  `json:"transferTxID,omitempty" metadata:",optional"`
+	WithdrawnBy  string  `json:"withdrawnBy,omitempty" metadata:",optional"`
+	WithdrawTxID string  `json:"withdrawTxID,omitempty" metadata:",optional"`
+}
+
+// Offer is the public record of an offer in a negotiation
+type Offer struct {
+	Round     int    `json:"round"`
+	OrgID     string `json:"orgID"`
+	PriceHash string `json:"priceHash"`
+	TxID      string `json:"txID"`
+	Timestamp string `json:"timestamp"`
+}
+
+// NegotiationQueryResult structure used for handling result of negotiation history query
+type NegotiationQueryResult struct {
+	Record    *Negotiation `json:"record"`
+	TxId      string       `json:"txId"`
+	Timestamp time.Time    `json:"timestamp"`
+}
+
+// MakeOffer posts an offer or counter-offer in the negotiation between the owner of an asset and a buyer.
+// If the client's org owns the asset, the offer is an asking price to the counterparty org, otherwise it is
+// a bid to the owner org, which must be the counterparty. The price is passed in the transient field like
+// for AgreeToSell and AgreeToBuy, and becomes the org's current asking or bid price in this negotiation, apart from
+// the prices of its other negotiations. Each offer starts a new round. Once the latest offers of both orgs match,
+// the owner can transfer the asset at that price with TransferAsset, and not before.
+func (s *SmartContract) MakeOffer(ctx contractapi.TransactionContextInterface, assetID string, counterpartyOrgID string) (*Negotiation, error) {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// In this scenario, client is only authorized to read/write private data from its own peer.
+	clientOrgID, err := getClientOrgID(ctx, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get verified OrgID: %v", err)
+	}
+
+	now, err := getTxTimestamp(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	buyerOrgID := clientOrgID
+	priceType := typeAssetBid
+	if clientOrgID == asset.OwnerOrg {
+		buyerOrgID = counterpartyOrgID
+		if buyerOrgID == "" {
+			return nil, fmt.Errorf("an offer for asset %s must name the buyer org", assetID)
+		}
+		if buyerOrgID == clientOrgID {
+			return nil, fmt.Errorf("a client from %s cannot make an offer to its own org", clientOrgID)
+		}
+		err = checkTransferable(asset, now)
+		if err != nil {
+			return nil, err
+		}
+		priceType = typeAssetForSale
+	} else if counterpartyOrgID != asset.OwnerOrg {
+		return nil, fmt.Errorf("offers for asset %s must be made to its owner %s", assetID, asset.OwnerOrg)
+	}
+
+	err = agreeToPrice(ctx, assetID, buyerOrgID, priceType)
+	if err != nil {
+		return nil, err
+	}
+
+	transMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("error getting transient: %v", err)
+	}
+	price := transMap["asset_price"] // The price was validated by agreeToPrice.
+
+	negotiation, err := readOpenNegotiation(ctx, asset, buyerOrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A closed or withdrawn negotiation, or a negotiation with a previous owner of the asset, starts over
+	if negotiation == nil {
+		negotiation = &Negotiation{
+			AssetID:   assetID,
+			SellerOrg: asset.OwnerOrg,
+			BuyerOrg:  buyerOrgID,
+			Offers:    []Offer{},
+		}
+	}
+
+	priceHash := sha256.Sum256(price)
+	negotiation.Round++
+	offer := Offer{
+		Round:     negotiation.Round,
+		OrgID:     clientOrgID,
+		PriceHash: hex.EncodeToString(priceHash[:]),
+		TxID:      ctx.GetStub().GetTxID(),
+		Timestamp: now.Format(time.RFC3339),
+	}
+	negotiation.Offers = append(negotiation.Offers, offer)
+	negotiation.Matched = latestOffersMatch(negotiation)
+
+	// Keep the offer of each round in the client org's collection, next to its current price
+	assetOfferKey, err := ctx.GetStub().CreateCompositeKey(typeAssetOffer, []string{assetID, buyerOrgID, formatRound(offer.Round)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().PutPrivateData(buildCollectionName(clientOrgID), assetOfferKey, price)
+	if err != nil {
+		return nil, fmt.Errorf("failed to put asset offer: %v", err)
+	}
+
+	err = putNegotiation(ctx, negotiation)
+	if err != nil {
+		return nil, err
+	}
+
+	return negotiation, nil
+}
+
+// WithdrawOffer abandons the open negotiation between the owner of an asset and a buyer. Either org can withdraw.
+// The client org's current price in the negotiation is deleted from its implicit private data collection, and the
+// negotiation is no longer matched, so that it no longer holds up a transfer to the buyer at a price agreed to with
+// AgreeToSell and AgreeToBuy. The offers of past rounds are kept. The next offer starts a new negotiation.
+func (s *SmartContract) WithdrawOffer(ctx contractapi.TransactionContextInterface, assetID string, buyerOrgID string) error {
+	asset, err := s.ReadAsset(ctx, assetID)
+	if err != nil {
+		return err
+	}
+
+	// In this scenario, client is only authorized to read/write private data from its own peer.
+	clientOrgID, err := getClientOrgID(ctx, true)
+	if err != nil {
+		return fmt.Errorf("failed to get verified OrgID: %v", err)
+	}
+
+	priceType := typeAssetBid
+	if clientOrgID == asset.OwnerOrg {
+		priceType = typeAssetForSale
+	} else if clientOrgID != buyerOrgID {
+		return fmt.Errorf("a client from %s cannot withdraw from the negotiation of asset %s with %s", clientOrgID, assetID, buyerOrgID)
+	}
+
+	negotiation, err := readOpenNegotiation(ctx, asset, buyerOrgID)
+	if err != nil {
+		return err
+	}
+	if negotiation == nil {
+		return fmt.Errorf("no open negotiation for asset %s with %s", assetID, buyerOrgID)
+	}
+
+	assetPriceKey, err := createAssetPriceKey(ctx, priceType, assetID, buyerOrgID)
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	err = ctx.GetStub().DelPrivateData(buildCollectionName(clientOrgID), assetPriceKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete asset price from implicit private data collection: %v", err)
+	}
+
+	negotiation.Matched = false
+	negotiation.WithdrawnBy = clientOrgID
+	negotiation.WithdrawTxID = ctx.GetStub().GetTxID()
+
+	return putNegotiation(ctx, negotiation)
+}
+
+// GetNegotiation returns the public record of the negotiation between the owner of an asset and a buyer
+func (s *SmartContract) GetNegotiation(ctx contractapi.TransactionContextInterface, assetID string, buyerOrgID string) (*Negotiation, error) {
+	negotiation, err := readNegotiation(ctx, assetID, buyerOrgID)
+	if err != nil {
+		return nil, err
+	}
+	if negotiation == nil {
+		return nil, fmt.Errorf("no negotiation for asset %s with %s", assetID, buyerOrgID)
+	}
+
+	return negotiation, nil
+}
+
+// GetNegotiationOffers returns the offers that the client's org made in a negotiation, from its implicit private
+// data collection, in the order of their rounds
+func (s *SmartContract) GetNegotiationOffers(ctx contractapi.TransactionContextInterface, assetID string, buyerOrgID string) ([]Agreement, error) {
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	offersIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collection, typeAssetOffer, []string{assetID, buyerOrgID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from private data collection: %v", err)
+	}
+	defer offersIterator.Close()
+
+	offers := []Agreement{}
+	for offersIterator.HasNext() {
+		resp, err := offersIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var offer Agreement
+		err = json.Unmarshal(resp.Value, &offer)
+		if err != nil {
+			return nil, err
+		}
+
+		offers = append(offers, offer)
+	}
+
+	return offers, nil
+}
+
+// QueryNegotiationHistory returns the timeline of the negotiation between the owner of an asset and a buyer,
+// with the state of the negotiation after each offer
+func (s *SmartContract) QueryNegotiationHistory(ctx contractapi.TransactionContextInterface, assetID string, buyerOrgID string) ([]NegotiationQueryResult, error) {
+	negotiationKey, err := ctx.GetStub().CreateCompositeKey(typeNegotiation, []string{assetID, buyerOrgID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(negotiationKey)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var results []NegotiationQueryResult
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var negotiation *Negotiation
+		err = json.Unmarshal(response.Value, &negotiation)
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp, err := ptypes.Timestamp(response.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		record := NegotiationQueryResult{
+			TxId:      response.TxId,
+			Timestamp: timestamp,
+			Record:    negotiation,
+		}
+		results = append(results, record)
+	}
+
+	return results, nil
+}
+
+// latestOffersMatch returns true if the latest offers of the seller and the buyer have the same hash
+func latestOffersMatch(negotiation *Negotiation) bool {
+	latest := make(map[string]string)
+	for _, offer := range negotiation.Offers {
+		latest[offer.OrgID] = offer.PriceHash
+	}
+
+	sellerHash, ok := latest[negotiation.SellerOrg]
+	if !ok {
+		return false
+	}
+
+	return sellerHash == latest[negotiation.BuyerOrg]
+}
+
+// readNegotiation returns the negotiation between the owner of an asset and a buyer, or nil if there is none
+func readNegotiation(ctx contractapi.TransactionContextInterface, assetID string, buyerOrgID string) (*Negotiation, error) {
+	negotiationKey, err := ctx.GetStub().CreateCompositeKey(typeNegotiation, []string{assetID, buyerOrgID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	negotiationJSON, err := ctx.GetStub().GetState(negotiationKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if negotiationJSON == nil {
+		return nil, nil
+	}
+
+	var negotiation *Negotiation
+	err = json.Unmarshal(negotiationJSON, &negotiation)
+	if err != nil {
+		return nil, err
+	}
+
+	return negotiation, nil
+}
+
+// readOpenNegotiation returns the negotiation between the current owner of an asset and a buyer, or nil if there
+// is none, it was closed by a transfer or it was withdrawn
+func readOpenNegotiation(ctx contractapi.TransactionContextInterface, asset *Asset, buyerOrgID string) (*Negotiation, error) {
+	negotiation, err := readNegotiation(ctx, asset.ID, buyerOrgID)
+	if err != nil {
+		return nil, err
+	}
+	if negotiation == nil || negotiation.SellerOrg != asset.OwnerOrg || negotiation.TransferTxID != "" || negotiation.WithdrawTxID != "" {
+		return nil, nil
+	}
+
+	return negotiation, nil
+}
+
+// closeNegotiation records the transfer of the asset at the matched price of a negotiation
+func closeNegotiation(ctx contractapi.TransactionContextInterface, negotiation *Negotiation) error {
+	negotiation.TransferTxID = ctx.GetStub().GetTxID()
+
+	return putNegotiation(ctx, negotiation)
+}
+
+// putNegotiation writes the public record of a negotiation
+func putNegotiation(ctx contractapi.TransactionContextInterface, negotiation *Negotiation) error {
+	negotiationKey, err := ctx.GetStub().CreateCompositeKey(typeNegotiation, []string{negotiation.AssetID, negotiation.BuyerOrg})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	negotiationJSON, err := json.Marshal(negotiation)
+	if err != nil {
+		return fmt.Errorf("failed to marshal negotiation: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(negotiationKey, negotiationJSON)
+	if err != nil {
+		return fmt.Errorf("failed to put negotiation in public data: %v", err)
+	}
+
+	return nil
+}
+
+// formatRound pads a round number with zeros, so that the offers of a negotiation sort in the order of their rounds
+func formatRound(round int) string {
+	return fmt.Sprintf("%06d", round)
+}
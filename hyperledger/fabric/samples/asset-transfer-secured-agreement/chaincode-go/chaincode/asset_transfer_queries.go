@@ -0,0 +1,317 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package chaincode
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// QueryResult structure used for handling result of query
+type QueryResult struct {
+	Record    *Asset
+	TxId      string    `json:"txId"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Agreement is the price that a seller or buyer agrees to. An agreement can optionally expire at a time in RFC3339
+// format, and can name an arbiter org that can freeze and reverse the transfer for a number of hours after it.
+type Agreement struct {
+	ID                 string `json:"asset_id"`
+	Price              int    `json:"price"`
+	TradeID            string `json:"trade_id"`
+	Expiry             string `json:"expiry,omitempty" metadata:",optional"`
+	ArbiterOrg         string `json:"arbiter_org,omitempty" metadata:",optional"`
+	DisputeWindowHours int    `json:"dispute_window_hours,omitempty" metadata:",optional"`
+}
+
+// ReadAsset returns the public asset data
+func (s *SmartContract) ReadAsset(ctx contractapi.TransactionContextInterface, assetID string) (*Asset, error) {
+	// Since only public data is accessed in this function, no access control is required
+	assetJSON, err := ctx.GetStub().GetState(assetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from world state: %v", err)
+	}
+	if assetJSON == nil {
+		return nil, fmt.Errorf("%s does not exist", assetID)
+	}
+
+	var asset *Asset
+	err = json.Unmarshal(assetJSON, &asset)
+	if err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// GetAssetPrivateProperties returns the immutable asset properties from owner's private data collection
+func (s *SmartContract) GetAssetPrivateProperties(ctx contractapi.TransactionContextInterface, assetID string) (string, error) {
+	// In this scenario, client is only authorized to read/write private data from its own peer.
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	immutableProperties, err := ctx.GetStub().GetPrivateData(collection, assetID)
+	if err != nil {
+		return "", fmt.Errorf("failed to read asset private properties from client org's collection: %v", err)
+	}
+	if immutableProperties == nil {
+		return "", fmt.Errorf("asset private details does not exist in client org's collection: %s", assetID)
+	}
+
+	return string(immutableProperties), nil
+}
+
+// GetAssetSalesPrice returns the sales price
+func (s *SmartContract) GetAssetSalesPrice(ctx contractapi.TransactionContextInterface, assetID string) (string, error) {
+	return getAssetPrice(ctx, assetID, typeAssetForSale)
+}
+
+// GetAssetBidPrice returns the bid price
+func (s *SmartContract) GetAssetBidPrice(ctx contractapi.TransactionContextInterface, assetID string) (string, error) {
+	return getAssetPrice(ctx, assetID, typeAssetBid)
+}
+
+// getAssetPrice gets the bid or ask price from caller's implicit private data collection
+func getAssetPrice(ctx contractapi.TransactionContextInterface, assetID string, priceType string) (string, error) {
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	assetPriceKey, err := ctx.GetStub().CreateCompositeKey(priceType, []string{assetID})
+	if err != nil {
+		return "", fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	price, err := ctx.GetStub().GetPrivateData(collection, assetPriceKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read asset price from implicit private data collection: %v", err)
+	}
+	if price == nil {
+		return "", fmt.Errorf("asset price does not exist: %s", assetID)
+	}
+
+	return string(price), nil
+}
+
+// QueryAssetSaleAgreements returns all of an organization's proposed sales
+func (s *SmartContract) QueryAssetSaleAgreements(ctx contractapi.TransactionContextInterface) ([]Agreement, error) {
+	return queryAgreementsByType(ctx, typeAssetForSale)
+}
+
+// QueryAssetBuyAgreements returns all of an organization's proposed bids
+func (s *SmartContract) QueryAssetBuyAgreements(ctx contractapi.TransactionContextInterface) ([]Agreement, error) {
+	return queryAgreementsByType(ctx, typeAssetBid)
+}
+
+func queryAgreementsByType(ctx contractapi.TransactionContextInterface, agreeType string) ([]Agreement, error) {
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Query for any object type starting with `agreeType`
+	agreementsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collection, agreeType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from private data collection: %v", err)
+	}
+	defer agreementsIterator.Close()
+
+	var agreements []Agreement
+	for agreementsIterator.HasNext() {
+		resp, err := agreementsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var agreement Agreement
+		err = json.Unmarshal(resp.Value, &agreement)
+		if err != nil {
+			return nil, err
+		}
+
+		agreements = append(agreements, agreement)
+	}
+
+	return agreements, nil
+}
+
+// GetSaleReceipts returns the receipts of the assets that the client's org sold between startDate and endDate.
+// The dates are in RFC3339 format, and an empty date leaves that end of the range open.
+func (s *SmartContract) GetSaleReceipts(ctx contractapi.TransactionContextInterface, startDate string, endDate string) ([]Receipt, error) {
+	return queryReceiptsByType(ctx, typeAssetSaleReceipt, startDate, endDate)
+}
+
+// GetPurchaseReceipts returns the receipts of the assets that the client's org bought between startDate and endDate.
+// The dates are in RFC3339 format, and an empty date leaves that end of the range open.
+func (s *SmartContract) GetPurchaseReceipts(ctx contractapi.TransactionContextInterface, startDate string, endDate string) ([]Receipt, error) {
+	return queryReceiptsByType(ctx, typeAssetBuyReceipt, startDate, endDate)
+}
+
+// queryReceiptsByType gets the sale or purchase receipts from the caller's implicit private data collection,
+// with a timestamp from startDate up to, but not including, endDate
+func queryReceiptsByType(ctx contractapi.TransactionContextInterface, receiptType string, startDate string, endDate string) ([]Receipt, error) {
+	start, err := parseDate(startDate)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseDate(endDate)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := getClientImplicitCollectionName(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	receiptsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collection, receiptType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read from private data collection: %v", err)
+	}
+	defer receiptsIterator.Close()
+
+	receipts := []Receipt{}
+	for receiptsIterator.HasNext() {
+		resp, err := receiptsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var receipt Receipt
+		err = json.Unmarshal(resp.Value, &receipt)
+		if err != nil {
+			return nil, err
+		}
+
+		if !start.IsZero() && receipt.Timestamp.Before(start) {
+			continue
+		}
+		if !end.IsZero() && !receipt.Timestamp.Before(end) {
+			continue
+		}
+
+		receipts = append(receipts, receipt)
+	}
+
+	return receipts, nil
+}
+
+// VerifyReceipt allows any org, such as an auditor, to check a receipt of a transfer of an asset against the hashes
+// of the receipts in the implicit private data collections of the seller and the buyer, without access to them.
+// The receipt is the JSON of a receipt returned by GetSaleReceipts or GetPurchaseReceipts.
+func (s *SmartContract) VerifyReceipt(ctx contractapi.TransactionContextInterface, assetID string, receiptJSON string) (bool, error) {
+	var receipt Receipt
+	err := json.Unmarshal([]byte(receiptJSON), &receipt)
+	if err != nil {
+		return false, fmt.Errorf("failed to unmarshal receipt JSON: %v", err)
+	}
+	if receipt.AssetID != assetID {
+		return false, fmt.Errorf("receipt is for asset %s, not %s", receipt.AssetID, assetID)
+	}
+
+	// The receipts were stored as marshaled by the contract, so hash the receipt in the same form
+	// rather than as passed, to be independent of how the client formatted it
+	canonicalReceiptJSON, err := json.Marshal(receipt)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal receipt: %v", err)
+	}
+	calculatedReceiptHash := sha256.Sum256(canonicalReceiptJSON)
+
+	receiptSaleKey, err := ctx.GetStub().CreateCompositeKey(typeAssetSaleReceipt, []string{receipt.TxID, assetID})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key for receipt: %v", err)
+	}
+	receiptBuyKey, err := ctx.GetStub().CreateCompositeKey(typeAssetBuyReceipt, []string{assetID, receipt.TxID})
+	if err != nil {
+		return false, fmt.Errorf("failed to create composite key for receipt: %v", err)
+	}
+
+	for _, party := range []struct {
+		name       string
+		collection string
+		key        string
+	}{
+		{"seller", buildCollectionName(receipt.SellerOrg), receiptSaleKey},
+		{"buyer", buildCollectionName(receipt.BuyerOrg), receiptBuyKey},
+	} {
+		receiptOnChainHash, err := ctx.GetStub().GetPrivateDataHash(party.collection, party.key)
+		if err != nil {
+			return false, fmt.Errorf("failed to read receipt hash from %s's collection: %v", party.name, err)
+		}
+		if receiptOnChainHash == nil {
+			return false, fmt.Errorf("receipt of transaction %s does not exist in %s's collection", receipt.TxID, party.name)
+		}
+
+		if !bytes.Equal(receiptOnChainHash, calculatedReceiptHash[:]) {
+			return false, fmt.Errorf("hash %x for passed receipt %s does not match on-chain hash %x in %s's collection",
+				calculatedReceiptHash,
+				canonicalReceiptJSON,
+				receiptOnChainHash,
+				party.name,
+			)
+		}
+	}
+
+	return true, nil
+}
+
+// parseDate parses a date filter in RFC3339 format, where an empty date is the zero time
+func parseDate(date string) (time.Time, error) {
+	if date == "" {
+		return time.Time{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, date)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected RFC3339 format: %v", date, err)
+	}
+
+	return parsed, nil
+}
+
+// QueryAssetHistory returns the chain of custody for a asset since issuance
+func (s *SmartContract) QueryAssetHistory(ctx contractapi.TransactionContextInterface, assetID string) ([]QueryResult, error) {
+	resultsIterator, err := ctx.GetStub().GetHistoryForKey(assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer resultsIterator.Close()
+
+	var results []QueryResult
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var asset *Asset
+		err = json.Unmarshal(response.Value, &asset)
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp, err := ptypes.Timestamp(response.Timestamp)
+		if err != nil {
+			return nil, err
+		}
+		record := QueryResult{
+			TxId:      response.TxId,
+			Timestamp: timestamp,
+			Record:    asset,
+		}
+		results = append(results, record)
+	}
+
+	return results, nil
+}
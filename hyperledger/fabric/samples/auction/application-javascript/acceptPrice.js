@@ -0,0 +1,119 @@
+/*
+ * Copyright IBM Corp. All Rights Reserved.
+ *
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+'use strict';
+
+const { Gateway, Wallets } = require('fabric-network');
+const path = require('path');
+const { buildCCPOrg1, buildCCPOrg2, buildWallet } = require('../../test-application/javascript/AppUtil.js');
+
+const myChannel = 'mychannel';
+const myChaincodeName = 'auction';
+
+
+function prettyJSONString(inputString) {
+    if (inputString) {
+        return JSON.stringify(JSON.parse(inputString), null, 2);
+    }
+    else {
+        return inputString;
+    }
+}
+
+async function acceptPrice(ccp,wallet,user,auctionID,bidID) {
+    try {
+
+        const gateway = new Gateway();
+      //connect using Discovery enabled
+
+      await gateway.connect(ccp,
+          { wallet: wallet, identity: user, discovery: { enabled: true, asLocalhost: true } });
+
+        const network = await gateway.getNetwork(myChannel);
+        const contract = network.getContract(myChaincodeName);
+
+        console.log('\n--> Evaluate Transaction: read your bid');
+        let bidString = await contract.evaluateTransaction('QueryBid',auctionID,bidID);
+        var bidJSON = JSON.parse(bidString);
+
+        //console.log('\n--> Evaluate Transaction: query the auction you want to join');
+        let auctionString = await contract.evaluateTransaction('QueryAuction',auctionID);
+       // console.log('*** Result:  Bid: ' + prettyJSONString(auctionString.toString()));
+        var auctionJSON = JSON.parse(auctionString);
+
+        let bidData = { objectType: 'bid', price: parseInt(bidJSON.price), org: bidJSON.org, bidder: bidJSON.bidder, quantity: parseInt(bidJSON.quantity)};
+        console.log('*** Result:  Bid: ' + JSON.stringify(bidData,null,2));
+
+        console.log('\n--> Evaluate Transaction: query the current price of the auction');
+        let price = await contract.evaluateTransaction('QueryDutchPrice',auctionID);
+        console.log('*** Result:  Price: ' + price.toString());
+
+        let statefulTxn = contract.createTransaction('AcceptPrice');
+        let tmapData = Buffer.from(JSON.stringify(bidData));
+        statefulTxn.setTransient({
+              bid: tmapData
+            });
+
+        statefulTxn.setEndorsingOrganizations(auctionJSON.organizations[0]);
+
+        await statefulTxn.submit(auctionID,bidID);
+
+        console.log('\n--> Evaluate Transaction: query the auction to see if our bid won');
+        let result = await contract.evaluateTransaction('QueryAuction',auctionID);
+        console.log('*** Result: Auction: ' + prettyJSONString(result.toString()));
+
+        gateway.disconnect();
+    } catch (error) {
+        console.error(`******** FAILED to accept price: ${error}`);
+		process.exit(1);
+	}
+}
+
+async function main() {
+    try {
+
+        if (process.argv[2] == undefined || process.argv[3] == undefined
+            || process.argv[4] == undefined || process.argv[5] == undefined) {
+            console.log("Usage: node acceptPrice.js org userID auctionID bidID");
+            process.exit(1);
+        }
+
+        const org = process.argv[2]
+        const user = process.argv[3];
+        const auctionID = process.argv[4];
+        const bidID = process.argv[5];
+
+        if (org == 'Org1' || org == 'org1') {
+
+            const orgMSP = 'Org1MSP';
+            const ccp = buildCCPOrg1();
+            const walletPath = path.join(__dirname, 'wallet/org1');
+            const wallet = await buildWallet(Wallets, walletPath);
+            await acceptPrice(ccp,wallet,user,auctionID,bidID);
+        }
+        else if (org == 'Org2' || org == 'org2') {
+
+            const orgMSP = 'Org2MSP';
+            const ccp = buildCCPOrg2();
+            const walletPath = path.join(__dirname, 'wallet/org2');
+            const wallet = await buildWallet(Wallets, walletPath);
+            await acceptPrice(ccp,wallet,user,auctionID,bidID);
+        }
+        else {
+            console.log("Usage: node acceptPrice.js org userID auctionID bidID");
+            console.log("Org must be Org1 or Org2");
+          }
+    } catch (error) {
+		console.error(`******** FAILED to run the application: ${error}`);
+    if (error.stack) {
+        console.error(error.stack);
+    }
+    process.exit(1);
+    }
+}
+
+
+main();
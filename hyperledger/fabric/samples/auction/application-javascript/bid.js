@@ -23,7 +23,7 @@ function prettyJSONString(inputString) {
     }
 }
 
-async function bid(ccp,wallet,user,orgMSP,auctionID,price) {
+async function bid(ccp,wallet,user,orgMSP,auctionID,price,quantity) {
     try {
 
         const gateway = new Gateway();
@@ -39,7 +39,7 @@ async function bid(ccp,wallet,user,orgMSP,auctionID,price) {
         let bidder = await contract.evaluateTransaction('GetID');
         console.log('*** Result:  Bidder ID is ' + bidder.toString());
 
-        let bidData = { objectType: 'bid', price: parseInt(price), org: orgMSP, bidder: bidder.toString()};
+        let bidData = { objectType: 'bid', price: parseInt(price), org: orgMSP, bidder: bidder.toString(), quantity: parseInt(quantity)};
 
         let statefulTxn = contract.createTransaction('Bid');
         statefulTxn.setEndorsingOrganizations(orgMSP);
@@ -74,7 +74,7 @@ async function main() {
 
         if (process.argv[2] == undefined || process.argv[3] == undefined
             || process.argv[4] == undefined || process.argv[5] == undefined) {
-            console.log("Usage: node bid.js org userID auctionID price");
+            console.log("Usage: node bid.js org userID auctionID price [quantity]");
             process.exit(1);
         }
 
@@ -82,6 +82,7 @@ async function main() {
         const user = process.argv[3];
         const auctionID = process.argv[4];
         const price = process.argv[5];
+        const quantity = process.argv[6] || '1';
 
         if (org == 'Org1' || org == 'org1') {
 
@@ -89,7 +90,7 @@ async function main() {
             const ccp = buildCCPOrg1();
             const walletPath = path.join(__dirname, 'wallet/org1');
             const wallet = await buildWallet(Wallets, walletPath);
-            await bid(ccp,wallet,user,orgMSP,auctionID,price);
+            await bid(ccp,wallet,user,orgMSP,auctionID,price,quantity);
         }
         else if (org == 'Org2' || org == 'org2') {
 
@@ -97,9 +98,9 @@ async function main() {
             const ccp = buildCCPOrg2();
             const walletPath = path.join(__dirname, 'wallet/org2');
             const wallet = await buildWallet(Wallets, walletPath);
-            await bid(ccp,wallet,user,orgMSP,auctionID,price);
+            await bid(ccp,wallet,user,orgMSP,auctionID,price,quantity);
         }  else {
-            console.log("Usage: node bid.js org userID auctionID price");
+            console.log("Usage: node bid.js org userID auctionID price [quantity]");
             console.log("Org must be Org1 or Org2");
           }
     } catch (error) {
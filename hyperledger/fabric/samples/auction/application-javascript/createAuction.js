@@ -8,6 +8,7 @@
 
 const { Gateway, Wallets } = require('fabric-network');
 const path = require('path');
+const crypto = require('crypto');
 const { buildCCPOrg1, buildCCPOrg2, buildWallet } = require('../../test-application/javascript/AppUtil.js');
 
 const myChannel = 'mychannel';
@@ -23,7 +24,7 @@ function prettyJSONString(inputString) {
     }
 }
 
-async function createAuction(ccp,wallet,user,auctionID,item) {
+async function createAuction(ccp,wallet,user,auctionID,item,settlement,reservePrice,biddingMinutes,revealMinutes) {
     try {
 
         const gateway = new Gateway();
@@ -35,11 +36,32 @@ async function createAuction(ccp,wallet,user,auctionID,item) {
         const network = await gateway.getNetwork(myChannel);
         const contract = network.getContract(myChaincodeName);
 
+        // commit to the reserve price without revealing it. The salt prevents bidders
+        // from guessing the price from the hash
+        let reserveHash = '';
+        let reserveJSON = '';
+        if (reservePrice) {
+            reserveJSON = JSON.stringify({ price: parseInt(reservePrice), salt: crypto.randomBytes(16).toString('hex') });
+            reserveHash = crypto.createHash('sha256').update(reserveJSON).digest('hex');
+        }
+
+        // the deadlines are given in minutes from now
+        let biddingDeadline = '';
+        let revealDeadline = '';
+        if (biddingMinutes != undefined && revealMinutes != undefined) {
+            const now = Date.now();
+            biddingDeadline = new Date(now + parseInt(biddingMinutes) * 60000).toISOString();
+            revealDeadline = new Date(now + parseInt(revealMinutes) * 60000).toISOString();
+        }
+
         let statefulTxn = contract.createTransaction('CreateAuction');
 
         console.log('\n--> Submit Transaction: Propose a new auction');
-        await statefulTxn.submit(auctionID,item);
+        await statefulTxn.submit(auctionID,item,reserveHash,settlement,biddingDeadline,revealDeadline);
         console.log('*** Result: committed');
+        if (reserveJSON) {
+            console.log('*** Result ***SAVE THIS VALUE*** Reserve: ' + reserveJSON);
+        }
 
         console.log('\n--> Evaluate Transaction: query the auction that was just created');
         let result = await contract.evaluateTransaction('QueryAuction',auctionID);
@@ -56,7 +78,7 @@ async function main() {
 
         if (process.argv[2] == undefined || process.argv[3] == undefined
             || process.argv[4] == undefined || process.argv[5] == undefined) {
-            console.log("Usage: node createAuction.js org userID auctionID item");
+            console.log("Usage: node createAuction.js org userID auctionID item [settlement] [reservePrice] [biddingMinutes revealMinutes]");
             process.exit(1);
         }
 
@@ -64,6 +86,10 @@ async function main() {
         const user = process.argv[3];
         const auctionID = process.argv[4];
         const item = process.argv[5];
+        const settlement = process.argv[6] || 'first-price';
+        const reservePrice = process.argv[7];
+        const biddingMinutes = process.argv[8];
+        const revealMinutes = process.argv[9];
 
         if (org == 'Org1' || org == 'org1') {
 
@@ -71,7 +97,7 @@ async function main() {
             const ccp = buildCCPOrg1();
             const walletPath = path.join(__dirname, 'wallet/org1');
             const wallet = await buildWallet(Wallets, walletPath);
-            await createAuction(ccp,wallet,user,auctionID,item);
+            await createAuction(ccp,wallet,user,auctionID,item,settlement,reservePrice,biddingMinutes,revealMinutes);
         }
         else if (org == 'Org2' || org == 'org2') {
 
@@ -79,9 +105,9 @@ async function main() {
             const ccp = buildCCPOrg2();
             const walletPath = path.join(__dirname, 'wallet/org2');
             const wallet = await buildWallet(Wallets, walletPath);
-            await createAuction(ccp,wallet,user,auctionID,item);
+            await createAuction(ccp,wallet,user,auctionID,item,settlement,reservePrice,biddingMinutes,revealMinutes);
         }  else {
-            console.log("Usage: node createAuction.js org userID auctionID item");
+            console.log("Usage: node createAuction.js org userID auctionID item [settlement] [reservePrice] [biddingMinutes revealMinutes]");
             console.log("Org must be Org1 or Org2");
           }
     } catch (error) {
@@ -23,7 +23,7 @@ function prettyJSONString(inputString) {
     }
 }
 
-async function endAuction(ccp,wallet,user,auctionID) {
+async function endAuction(ccp,wallet,user,auctionID,reserveJSON) {
     try {
 
         const gateway = new Gateway();
@@ -49,6 +49,13 @@ async function endAuction(ccp,wallet,user,auctionID) {
             statefulTxn.setEndorsingOrganizations(auctionJSON.organizations[0]);
             }
 
+        // reveal the reserve price that was committed to when the auction was created
+        if (reserveJSON != undefined) {
+            statefulTxn.setTransient({
+                  reserve: Buffer.from(reserveJSON)
+                });
+        }
+
         console.log('\n--> Submit the transaction to end the auction');
         await statefulTxn.submit(auctionID);
         console.log('*** Result: committed');
@@ -69,13 +76,14 @@ async function main() {
 
         if (process.argv[2] == undefined || process.argv[3] == undefined
             || process.argv[4] == undefined) {
-            console.log("Usage: node endAuction.js org userID auctionID");
+            console.log("Usage: node endAuction.js org userID auctionID [reserveJSON]");
             process.exit(1);
         }
 
         const org = process.argv[2]
         const user = process.argv[3];
         const auctionID = process.argv[4];
+        const reserveJSON = process.argv[5];
 
         if (org == 'Org1' || org == 'org1') {
 
@@ -83,7 +91,7 @@ async function main() {
             const ccp = buildCCPOrg1();
             const walletPath = path.join(__dirname, 'wallet/org1');
             const wallet = await buildWallet(Wallets, walletPath);
-            await endAuction(ccp,wallet,user,auctionID);
+            await endAuction(ccp,wallet,user,auctionID,reserveJSON);
         }
         else if (org == 'Org2' || org == 'org2') {
 
@@ -91,9 +99,9 @@ async function main() {
             const ccp = buildCCPOrg2();
             const walletPath = path.join(__dirname, 'wallet/org2');
             const wallet = await buildWallet(Wallets, walletPath);
-            await endAuction(ccp,wallet,user,auctionID);
+            await endAuction(ccp,wallet,user,auctionID,reserveJSON);
         }  else {
-            console.log("Usage: node endAuction.js org userID auctionID");
+            console.log("Usage: node endAuction.js org userID auctionID [reserveJSON]");
             console.log("Org must be Org1 or Org2");
           }
     } catch (error) {
@@ -7,8 +7,11 @@ package auction
 import (
 	"bytes"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
@@ -19,23 +22,45 @@ type SmartContract struct {
 
 // Auction data
 type Auction struct {
-	Type         string             `json:"objectType"`
-	ItemSold     string             `json:"item"`
-	Seller       string             `json:"seller"`
-	Orgs         []string           `json:"organizations"`
-	PrivateBids  map[string]BidHash `json:"privateBids"`
-	RevealedBids map[string]FullBid `json:"revealedBids"`
-	Winner       string             `json:"winner"`
-	Price        int                `json:"price"`
-	Status       string             `json:"status"`
+	Type            string             `json:"objectType"`
+	ItemSold        string             `json:"item"`
+	Seller          string             `json:"seller"`
+	Orgs            []string           `json:"organizations"`
+	PrivateBids     map[string]BidHash `json:"privateBids"`
+	RevealedBids    map[string]FullBid `json:"revealedBids"`
+	Winner          string             `json:"winner"`
+	Price           int                `json:"price"`
+	Status          string             `json:"status"`
+	ReserveHash     string             `json:"reserveHash"`
+	ReservePrice    int                `json:"reservePrice"`
+	Settlement      string             `json:"settlement"`
+	Format          string             `json:"format"`
+	Quantity        int                `json:"quantity"`
+	Allocations     []Allocation       `json:"allocations,omitempty" metadata:",optional"`
+	Schedule        *DutchSchedule     `json:"schedule,omitempty" metadata:",optional"`
+	TokenChaincode  string             `json:"tokenChaincode"`
+	Deposit         int                `json:"deposit"`
+	BiddingDeadline time.Time          `json:"biddingDeadline"`
+	RevealDeadline  time.Time          `json:"revealDeadline"`
+	ReserveRevealed bool               `json:"reserveRevealed"`
+	UnrevealedBids  []string           `json:"unrevealedBids,omitempty" metadata:",optional"`
 }
 
 // FullBid is the structure of a revealed bid
 type FullBid struct {
-	Type     string `json:"objectType"`
-	Price    int    `json:"price"`
-	Org      string `json:"org"`
-	Bidder   string `json:"bidder"`
+	Type        string `json:"objectType"`
+	Price       int    `json:"price"`
+	Org         string `json:"org"`
+	Bidder      string `json:"bidder"`
+	Quantity    int    `json:"quantity"`
+	RevealOrder int    `json:"revealOrder"`
+}
+
+// Reserve is the structure of the reserve price committed to by the seller. The
+// salt prevents bidders from recovering the price from the hash.
+type Reserve struct {
+	Price int    `json:"price"`
+	Salt  string `json:"salt"`
 }
 
 // BidHash is the structure of a private bid
@@ -46,9 +71,64 @@ type BidHash struct {
 
 const bidKeyType = "bid"
 
+// settlement modes of an auction
+const (
+	// the winner pays the price of their bid
+	firstPrice = "first-price"
+	// the winner pays the price of the second highest bid, or the reserve price
+	// if it is higher (Vickrey auction)
+	secondPrice = "second-price"
+)
+
+// auction formats
+const (
+	// a single item is sold to the highest bidder
+	singleItem = "single"
+	// identical units are sold to the highest bidders at a uniform clearing price
+	multiUnit = "multi-unit"
+	// the price drops on a schedule until a bidder accepts it
+	dutch = "dutch"
+)
+
 // CreateAuction creates on auction on the public channel. The identity that
-// submits the transacion becomes the seller of the auction
-func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string) error {
+// submits the transacion becomes the seller of the auction. The optional reserve
+// hash is the hex encoded SHA-256 hash of the reserve JSON, which the seller reveals
+// when the auction is ended. The settlement mode is first-price (the default) or
+// second-price. The optional bidding and reveal deadlines, in RFC 3339 format, end
+// the bidding and the reveal phase of the auction; see AdvanceAuction.
+func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string, reserveHash string, settlement string, biddingDeadline string, revealDeadline string) error {
+
+	if settlement == "" {
+		settlement = firstPrice
+	}
+	if settlement != firstPrice && settlement != secondPrice {
+		return fmt.Errorf("settlement must be %s or %s, not %s", firstPrice, secondPrice, settlement)
+	}
+
+	err := checkReserveHash(reserveHash)
+	if err != nil {
+		return err
+	}
+
+	auction := Auction{
+		ItemSold:    itemsold,
+		ReserveHash: reserveHash,
+		Settlement:  settlement,
+		Format:      singleItem,
+		Quantity:    1,
+	}
+
+	err = setDeadlines(ctx, &auction, biddingDeadline, revealDeadline)
+	if err != nil {
+		return err
+	}
+
+	return createAuction(ctx, auctionID, &auction)
+}
+
+// createAuction completes a new auction of any format with the seller and puts it
+// into state
+func createAuction(ctx contractapi.TransactionContextInterface, auctionID string, auction *Auction) error {
 
 	// get ID of submitting client
 	clientID, err := ctx.GetClientIdentity().GetID()
@@ -63,20 +143,14 @@ func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterfac
 	}
 
 	// Create auction
-	bidders := make(map[string]BidHash)
-	revealedBids := make(map[string]FullBid)
-
-	auction := Auction{
-		Type:         "auction",
-		ItemSold:     itemsold,
-		Price:        0,
-		Seller:       clientID,
-		Orgs:         []string{clientOrgID},
-		PrivateBids:  bidders,
-		RevealedBids: revealedBids,
-		Winner:       "",
-		Status:       "open",
-	}
+	auction.Type = "auction"
+	auction.Price = 0
+	auction.Seller = clientID
+	auction.Orgs = []string{clientOrgID}
+	auction.PrivateBids = make(map[string]BidHash)
+	auction.RevealedBids = make(map[string]FullBid)
+	auction.Winner = ""
+	auction.Status = "open"
 
 	auctionBytes, err := json.Marshal(auction)
 	if err != nil {
@@ -89,6 +163,16 @@ func (s *SmartContract) CreateAuction(ctx contractapi.TransactionContextInterfac
 		return fmt.Errorf("failed to put auction in public data: %v", err)
 	}
 
+	// list the auction in the indexes used by the auction queries
+	err = addSellerIndex(ctx, clientID, auctionID)
+	if err != nil {
+		return err
+	}
+	err = setStatusIndex(ctx, auctionID, auction.Status)
+	if err != nil {
+		return err
+	}
+
 	// set the seller of the auction as an endorser
 	err = setAssetStateBasedEndorsement(ctx, auctionID, clientOrgID)
 	if err != nil {
@@ -114,6 +198,12 @@ func (s *SmartContract) Bid(ctx contractapi.TransactionContextInterface, auction
 		return "", fmt.Errorf("bid key not found in the transient map")
 	}
 
+	// the auction needs to accept bids
+	err = checkBiddingOpen(ctx, auctionID)
+	if err != nil {
+		return "", err
+	}
+
 	// get the implicit collection name using the bidder's organization ID
 	collection, err := getCollectionName(ctx)
 	if err != nil {
@@ -147,7 +237,8 @@ func (s *SmartContract) Bid(ctx contractapi.TransactionContextInterface, auction
 
 // SubmitBid is used by the bidder to add the hash of that bid stored in private data to the
 // auction. Note that this function alters the auction in private state, and needs
-// to meet the auction endorsement policy. Transaction ID is used identify the bid
+// to meet the auction endorsement policy. Transaction ID is used identify the bid.
+// If the auction requires a deposit, it is locked from the bidder's token account.
 func (s *SmartContract) SubmitBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string) error {
 
 	// get the MSP ID of the bidder's org
@@ -169,11 +260,20 @@ func (s *SmartContract) SubmitBid(ctx contractapi.TransactionContextInterface, a
 	}
 
 	// the auction needs to be open for users to add their bid
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
 	Status := auctionJSON.Status
-	if Status != "open" {
+	if Status != "open" || auctionJSON.biddingClosed(now) {
 		return fmt.Errorf("cannot join closed or ended auction")
 	}
 
+	// bids on a Dutch auction are not sealed, the bidder accepts the current price
+	if auctionJSON.Format == dutch {
+		return fmt.Errorf("cannot submit a bid to a Dutch auction, use AcceptPrice")
+	}
+
 	// get the inplicit collection name of bidder's org
 	collection, err := getCollectionName(ctx)
 	if err != nil {
@@ -218,6 +318,14 @@ func (s *SmartContract) SubmitBid(ctx contractapi.TransactionContextInterface, a
 		}
 	}
 
+	// lock the deposit of the bid in escrow
+	if auctionJSON.Deposit > 0 {
+		err = lockDeposit(ctx, auctionID, txID, &auctionJSON)
+		if err != nil {
+			return err
+		}
+	}
+
 	newAuctionBytes, _ := json.Marshal(auctionJSON)
 
 	err = ctx.GetStub().PutState(auctionID, newAuctionBytes)
@@ -231,65 +339,133 @@ func (s *SmartContract) SubmitBid(ctx contractapi.TransactionContextInterface, a
 // RevealBid is used by a bidder to reveal their bid after the auction is closed
 func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string) error {
 
+	// check 1: check that hash of revealed bid matches hash of private bid
+	// on the public ledger, and that the bid is revealed by the bidder
+	bidKey, onChainBidHashString, NewBid, err := getTransientBid(ctx, auctionID, txID)
+	if err != nil {
+		return err
+	}
+
+	// get auction from public state
+	auctionBytes, err := ctx.GetStub().GetState(auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction %v: %v", auctionID, err)
+	}
+	if auctionBytes == nil {
+		return fmt.Errorf("Auction interest object %v not found", auctionID)
+	}
+
+	var auctionJSON Auction
+	err = json.Unmarshal(auctionBytes, &auctionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to create auction object JSON: %v", err)
+	}
+
+	// check 2: check that the auction is closed. We cannot reveal a
+	// bid to an open auction, or after the reveal deadline
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	Status := auctionJSON.Status
+	if Status == "open" && auctionJSON.biddingClosed(now) {
+		Status = "closed"
+	}
+	if Status != "closed" || auctionJSON.revealClosed(now) {
+		return fmt.Errorf("cannot reveal bid for open or ended auction")
+	}
+
+	// check 3; check hash of relealed bid matches hash of private bid that was
+	// added earlier. This ensures that the bid has not changed since it
+	// was added to the auction
+
+	bidders := auctionJSON.PrivateBids
+	privateBidHashString := bidders[bidKey].Hash
+
+	if privateBidHashString != onChainBidHashString {
+		return fmt.Errorf("hash %s for bid %s does not match hash in auction: %s, bidder must have changed bid",
+			privateBidHashString,
+			bidKey,
+			onChainBidHashString,
+		)
+	}
+
+	// check 4: a bid on a multi-unit auction is for at least one unit
+	if auctionJSON.Format == multiUnit && NewBid.Quantity < 1 {
+		return fmt.Errorf("bid %v on a multi-unit auction must have a quantity of at least 1", bidKey)
+	}
+
+	// check 5: the deposit of the bid covers the most it can pay
+	if auctionJSON.Deposit > 0 {
+		err = checkDeposit(ctx, auctionID, txID, NewBid)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the reveal order breaks ties between bids of the same price
+	if _, revealed := auctionJSON.RevealedBids[bidKey]; revealed {
+		return fmt.Errorf("bid %v has already been revealed", bidKey)
+	}
+	NewBid.RevealOrder = len(auctionJSON.RevealedBids) + 1
+
+	revealedBids := make(map[string]FullBid)
+	revealedBids = auctionJSON.RevealedBids
+	revealedBids[bidKey] = *NewBid
+	auctionJSON.RevealedBids = revealedBids
+
+	newAuctionBytes, _ := json.Marshal(auctionJSON)
+
+	// put auction with bid added back into state
+	err = ctx.GetStub().PutState(auctionID, newAuctionBytes)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// getTransientBid reads a bid from the transient map and checks that it matches the
+// hash of the bid stored in the implicit collection of the client's organization, and
+// that the client is the bidder. It returns the bid key, the hex encoded bid hash
+// and the bid.
+func getTransientBid(ctx contractapi.TransactionContextInterface, auctionID string, txID string) (string, string, *FullBid, error) {
+
 	// get bid from transient map
 	transientMap, err := ctx.GetStub().GetTransient()
 	if err != nil {
-		return fmt.Errorf("error getting transient: %v", err)
+		return "", "", nil, fmt.Errorf("error getting transient: %v", err)
 	}
 
 	transientBidJSON, ok := transientMap["bid"]
 	if !ok {
-		return fmt.Errorf("bid key not found in the transient map")
+		return "", "", nil, fmt.Errorf("bid key not found in the transient map")
 	}
 
 	// get implicit collection name of organization ID
 	collection, err := getCollectionName(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get implicit collection name: %v", err)
+		return "", "", nil, fmt.Errorf("failed to get implicit collection name: %v", err)
 	}
 
 	// use transaction ID to create composit bid key
 	bidKey, err := ctx.GetStub().CreateCompositeKey(bidKeyType, []string{auctionID, txID})
 	if err != nil {
-		return fmt.Errorf("failed to create composite key: %v", err)
+		return "", "", nil, fmt.Errorf("failed to create composite key: %v", err)
 	}
 
 	// get bid hash of bid if private bid on the public ledger
 	bidHash, err := ctx.GetStub().GetPrivateDataHash(collection, bidKey)
 	if err != nil {
-		return fmt.Errorf("failed to read bid bash from collection: %v", err)
+		return "", "", nil, fmt.Errorf("failed to read bid bash from collection: %v", err)
 	}
 	if bidHash == nil {
-		return fmt.Errorf("bid hash does not exist: %s", bidKey)
+		return "", "", nil, fmt.Errorf("bid hash does not exist: %s", bidKey)
 	}
 
-	// get auction from public state
-	auctionBytes, err := ctx.GetStub().GetState(auctionID)
-	if err != nil {
-		return fmt.Errorf("failed to get auction %v: %v", auctionID, err)
-	}
-	if auctionBytes == nil {
-		return fmt.Errorf("Auction interest object %v not found", auctionID)
-	}
-
-	var auctionJSON Auction
-	err = json.Unmarshal(auctionBytes, &auctionJSON)
-	if err != nil {
-		return fmt.Errorf("failed to create auction object JSON: %v", err)
-	}
-
-	// Complete a series of three checks before we add the bid to the auction
-
-	// check 1: check that the auction is closed. We cannot reveal a
-	// bid to an open auction
-	Status := auctionJSON.Status
-	if Status != "closed" {
-		return fmt.Errorf("cannot reveal bid for open or ended auction")
-	}
-
-	// check 2: check that hash of revealed bid matches hash of private bid
-	// on the public ledger. This checks that the bidder is telling the truth
-	// about the value of their bid
+	// check that hash of the bid matches hash of private bid on the public
+	// ledger. This checks that the bidder is telling the truth about the
+	// value of their bid
 
 	hash := sha256.New()
 	hash.Write(transientBidJSON)
@@ -297,76 +473,48 @@ func (s *SmartContract) RevealBid(ctx contractapi.TransactionContextInterface, a
 
 	// verify that the hash of the passed immutable properties matches the on-chain hash
 	if !bytes.Equal(calculatedBidJSONHash, bidHash) {
-		return fmt.Errorf("hash %x for bid JSON %s does not match hash in auction: %x",
+		return "", "", nil, fmt.Errorf("hash %x for bid JSON %s does not match hash in auction: %x",
 			calculatedBidJSONHash,
 			transientBidJSON,
 			bidHash,
 		)
 	}
 
-	// check 3; check hash of relealed bid matches hash of private bid that was
-	// added earlier. This ensures that the bid has not changed since it
-	// was added to the auction
-
-	bidders := auctionJSON.PrivateBids
-	privateBidHashString := bidders[bidKey].Hash
-
-	onChainBidHashString := fmt.Sprintf("%x", bidHash)
-	if privateBidHashString != onChainBidHashString {
-		return fmt.Errorf("hash %s for bid JSON %s does not match hash in auction: %s, bidder must have changed bid",
-			privateBidHashString,
-			transientBidJSON,
-			onChainBidHashString,
-		)
-	}
-
-	// we can add the bid to the auction if all checks have passed
 	type transientBidInput struct {
 		Price    int    `json:"price"`
 		Org      string `json:"org"`
 		Bidder   string `json:"bidder"`
+		Quantity int    `json:"quantity"`
 	}
 
 	// unmarshal bid imput
 	var bidInput transientBidInput
 	err = json.Unmarshal(transientBidJSON, &bidInput)
 	if err != nil {
-		return fmt.Errorf("failed to unmarshal JSON: %v", err)
+		return "", "", nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
 	}
 
 	// Get ID of submitting client identity
 	clientID, err := ctx.GetClientIdentity().GetID()
 	if err != nil {
-		return fmt.Errorf("failed to get client identity %v", err)
+		return "", "", nil, fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	// make sure that the transaction is being submitted is the bidder
+	if bidInput.Bidder != clientID {
+		return "", "", nil, fmt.Errorf("Permission denied, client id %v is not the owner of the bid", clientID)
 	}
 
 	// marshal transient parameters and ID and MSPID into bid object
-	NewBid := FullBid{
+	bid := &FullBid{
 		Type:     bidKeyType,
 		Price:    bidInput.Price,
 		Org:      bidInput.Org,
 		Bidder:   bidInput.Bidder,
+		Quantity: bidInput.Quantity,
 	}
 
-	// check 4: make sure that the transaction is being submitted is the bidder
-	if bidInput.Bidder != clientID {
-		return fmt.Errorf("Permission denied, client id %v is not the owner of the bid", clientID)
-	}
-
-	revealedBids := make(map[string]FullBid)
-	revealedBids = auctionJSON.RevealedBids
-	revealedBids[bidKey] = NewBid
-	auctionJSON.RevealedBids = revealedBids
-
-	newAuctionBytes, _ := json.Marshal(auctionJSON)
-
-	// put auction with bid added back into state
-	err = ctx.GetStub().PutState(auctionID, newAuctionBytes)
-	if err != nil {
-		return fmt.Errorf("failed to update auction: %v", err)
-	}
-
-	return nil
+	return bidKey, fmt.Sprintf("%x", bidHash), bid, nil
 }
 
 // CloseAuction can be used by the seller to close the auction. This prevents
@@ -406,6 +554,22 @@ func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface
 		return fmt.Errorf("cannot close auction that is not open")
 	}
 
+	// an auction with a bidding deadline closes when the deadline has passed
+	if !auctionJSON.BiddingDeadline.IsZero() {
+		now, err := getTxTime(ctx)
+		if err != nil {
+			return err
+		}
+		if !auctionJSON.biddingClosed(now) {
+			return fmt.Errorf("cannot close auction before its bidding deadline %s", auctionJSON.BiddingDeadline.Format(time.RFC3339))
+		}
+	}
+
+	// there is no reveal phase in a Dutch auction
+	if auctionJSON.Format == dutch {
+		return fmt.Errorf("cannot close a Dutch auction, end it to withdraw the item")
+	}
+
 	auctionJSON.Status = string("closed")
 
 	closedAuction, _ := json.Marshal(auctionJSON)
@@ -415,11 +579,17 @@ func (s *SmartContract) CloseAuction(ctx contractapi.TransactionContextInterface
 		return fmt.Errorf("failed to close auction: %v", err)
 	}
 
-	return nil
+	return setStatusIndex(ctx, auctionID, auctionJSON.Status)
 }
 
 // EndAuction both changes the auction status to closed and calculates the winners
-// of the auction
+// of the auction. If the auction has a reserve price, the seller reveals it by
+// passing the reserve JSON in the transient map under the key "reserve". The auction
+// ends without a winner if the highest bid does not meet the reserve price.
+// A Dutch auction ends when a bidder accepts the price; ending it before that
+// withdraws the item without a winner. If the auction requires a deposit, the winners
+// pay the seller from their deposits and the rest of the deposits are released.
+// An auction whose bidding deadline has passed does not need to be closed first.
 func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
 
 	auctionBytes, err := ctx.GetStub().GetState(auctionID)
@@ -450,29 +620,94 @@ func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface,
 		return fmt.Errorf("auction can only be ended by seller: %v", err)
 	}
 
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
 	Status := auctionJSON.Status
+	if auctionJSON.Format == dutch {
+		if Status != "open" {
+			return fmt.Errorf("Can only end an open Dutch auction")
+		}
+		Status = "closed"
+	}
+	if Status == "open" && auctionJSON.biddingClosed(now) {
+		Status = "closed"
+	}
 	if Status != "closed" {
 		return fmt.Errorf("Can only end a closed auction")
 	}
 
+	// check that the revealed reserve matches the reserve hash of the auction
+	if auctionJSON.ReserveHash != "" && !auctionJSON.ReserveRevealed {
+		reserve, err := revealReserve(ctx, auctionJSON.ReserveHash)
+		if err != nil {
+			return err
+		}
+		auctionJSON.ReservePrice = reserve.Price
+		auctionJSON.ReserveRevealed = true
+	}
+
+	return endAuction(ctx, auctionID, &auctionJSON, now)
+}
+
+// endAuction calculates the winners of a closed auction, settles the deposits and
+// puts the ended auction into state. Before the reveal deadline every bid that could
+// change the outcome has to be revealed. After the reveal deadline the bids that were
+// not revealed are left out, and if the auction requires a deposit, their deposits are
+// forfeited to the seller. Without a deposit there is nothing to forfeit, so not revealing
+// a bid goes unpunished. A reserve that the seller has not revealed by then is not met.
+func endAuction(ctx contractapi.TransactionContextInterface, auctionID string, auctionJSON *Auction, now time.Time) error {
+
+	revealClosed := auctionJSON.revealClosed(now)
+
 	// get the list of revealed bids
 	revealedBidMap := auctionJSON.RevealedBids
-	if len(auctionJSON.RevealedBids) == 0 {
-		return fmt.Errorf("No bids have been revealed, cannot end auction: %v", err)
+	if len(auctionJSON.RevealedBids) == 0 && auctionJSON.Format != dutch && !revealClosed {
+		return fmt.Errorf("No bids have been revealed, cannot end auction")
+	}
+
+	// determine the winners and the price they pay. If not everything is sold,
+	// any bid that meets the reserve price would change the outcome.
+	unrevealedLimit := 0
+	reserveMet := auctionJSON.ReserveHash == "" || auctionJSON.ReserveRevealed
+	switch {
+	case auctionJSON.Format == dutch:
+		unrevealedLimit = minimumWinningPrice(auctionJSON.ReservePrice) - 1
+	case len(revealedBidMap) == 0 || !reserveMet:
+		// the auction ends without a winner
+	case auctionJSON.Format == multiUnit:
+		var unsold int
+		auctionJSON.Allocations, auctionJSON.Price, unsold = settleMultiUnitAuction(revealedBidMap, auctionJSON.Quantity, auctionJSON.ReservePrice)
+		unrevealedLimit = auctionJSON.Price
+		if unsold > 0 {
+			unrevealedLimit = minimumWinningPrice(auctionJSON.ReservePrice) - 1
+		}
+	default:
+		auctionJSON.Winner, auctionJSON.Price = settleAuction(revealedBidMap, auctionJSON.ReservePrice, auctionJSON.Settlement)
+		unrevealedLimit = auctionJSON.Price
+		if auctionJSON.Winner == "" {
+			unrevealedLimit = minimumWinningPrice(auctionJSON.ReservePrice) - 1
+		}
 	}
 
-	// determine the highest bid
-	for _, bid := range revealedBidMap {
-		if bid.Price > auctionJSON.Price {
-			auctionJSON.Winner = bid.Bidder
-			auctionJSON.Price = bid.Price
+	if revealClosed {
+		// the bids that were not revealed in time are left out, and penalized by settleEscrow
+		// if the auction requires a deposit
+		auctionJSON.UnrevealedBids = unrevealedBids(auctionJSON)
+	} else {
+		// check if there is a winning bid that has yet to be revealed
+		err := queryAllBids(ctx, unrevealedLimit, auctionJSON.RevealedBids, auctionJSON.PrivateBids)
+		if err != nil {
+			return fmt.Errorf("Cannot close auction: %v", err)
 		}
 	}
 
-	// check if there is a winning bid that has yet to be revealed
-	err = queryAllBids(ctx, auctionJSON.Price, auctionJSON.RevealedBids, auctionJSON.PrivateBids)
+	// pay the seller from the deposits of the winners and release the other deposits
+	err := settleEscrow(ctx, auctionID, auctionJSON)
 	if err != nil {
-		return fmt.Errorf("Cannot close auction: %v", err)
+		return err
 	}
 
 	auctionJSON.Status = string("ended")
@@ -483,5 +718,104 @@ func (s *SmartContract) EndAuction(ctx contractapi.TransactionContextInterface,
 	if err != nil {
 		return fmt.Errorf("failed to close auction: %v", err)
 	}
+
+	return setStatusIndex(ctx, auctionID, auctionJSON.Status)
+}
+
+// revealReserve reads the reserve from the transient map and checks it against the
+// reserve hash committed to when the auction was created
+func revealReserve(ctx contractapi.TransactionContextInterface, reserveHash string) (*Reserve, error) {
+
+	transientMap, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("error getting transient: %v", err)
+	}
+
+	reserveJSON, ok := transientMap["reserve"]
+	if !ok {
+		return nil, fmt.Errorf("reserve key not found in the transient map")
+	}
+
+	hash := sha256.Sum256(reserveJSON)
+	if hex.EncodeToString(hash[:]) != reserveHash {
+		return nil, fmt.Errorf("hash %x for reserve JSON %s does not match reserve hash in auction: %s",
+			hash,
+			reserveJSON,
+			reserveHash,
+		)
+	}
+
+	var reserve Reserve
+	err = json.Unmarshal(reserveJSON, &reserve)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON: %v", err)
+	}
+
+	return &reserve, nil
+}
+
+// settleAuction returns the winner of the auction and the price they pay. Bids of the
+// same price are ranked by the order in which they were revealed. There is no winner
+// if the highest bid is below the reserve price.
+func settleAuction(revealedBids map[string]FullBid, reservePrice int, settlement string) (string, int) {
+
+	bidKeys := rankBids(revealedBids)
+	highestBid := revealedBids[bidKeys[0]]
+	if highestBid.Price < minimumWinningPrice(reservePrice) {
+		return "", 0
+	}
+
+	if settlement == secondPrice {
+		price := reservePrice
+		if len(bidKeys) > 1 && revealedBids[bidKeys[1]].Price > price {
+			price = revealedBids[bidKeys[1]].Price
+		}
+		return highestBid.Bidder, price
+	}
+
+	return highestBid.Bidder, highestBid.Price
+}
+
+// rankBids returns the keys of the revealed bids ordered by price, highest first, then
+// by reveal order. The bid key makes the ranking deterministic for bids revealed before
+// the reveal order was recorded.
+func rankBids(revealedBids map[string]FullBid) []string {
+
+	bidKeys := make([]string, 0, len(revealedBids))
+	for bidKey := range revealedBids {
+		bidKeys = append(bidKeys, bidKey)
+	}
+	sort.Slice(bidKeys, func(i, j int) bool {
+		a, b := revealedBids[bidKeys[i]], revealedBids[bidKeys[j]]
+		if a.Price != b.Price {
+			return a.Price > b.Price
+		}
+		if a.RevealOrder != b.RevealOrder {
+			return a.RevealOrder < b.RevealOrder
+		}
+		return bidKeys[i] < bidKeys[j]
+	})
+
+	return bidKeys
+}
+
+// minimumWinningPrice returns the lowest price that can win an auction with the
+// given reserve price. A bid of zero never wins.
+func minimumWinningPrice(reservePrice int) int {
+	if reservePrice < 1 {
+		return 1
+	}
+	return reservePrice
+}
+
+// checkReserveHash checks that the reserve hash is empty or a hex encoded SHA-256 hash
+func checkReserveHash(reserveHash string) error {
+	if reserveHash == "" {
+		return nil
+	}
+	hashBytes, err := hex.DecodeString(reserveHash)
+	if err != nil || len(hashBytes) != sha256.Size {
+		return fmt.Errorf("reserve hash must be a hex encoded SHA-256 hash")
+	}
 	return nil
 }
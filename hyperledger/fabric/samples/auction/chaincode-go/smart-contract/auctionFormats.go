@@ -0,0 +1,234 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Allocation is the number of units of a multi-unit auction won by a bid
+type Allocation struct {
+	Bidder   string `json:"bidder"`
+	Org      string `json:"org"`
+	Quantity int    `json:"quantity"`
+}
+
+// DutchSchedule is the price schedule of a Dutch auction. The price starts at the
+// start price and drops by the decrement every interval (in seconds) after the
+// auction was created, until it reaches the floor price.
+type DutchSchedule struct {
+	StartPrice int       `json:"startPrice"`
+	FloorPrice int       `json:"floorPrice"`
+	Decrement  int       `json:"decrement"`
+	Interval   int       `json:"interval"`
+	StartTime  time.Time `json:"startTime"`
+}
+
+// CreateMultiUnitAuction creates an auction that sells a quantity of identical units.
+// Bids carry the number of units wanted. When the auction is ended the units are
+// allocated to the highest bids, and every winner pays the uniform clearing price,
+// which is the price of the lowest bid that was allocated units. The optional
+// deadlines are the same as those of CreateAuction.
+func (s *SmartContract) CreateMultiUnitAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string, quantity int, reserveHash string, biddingDeadline string, revealDeadline string) error {
+
+	if quantity < 1 {
+		return fmt.Errorf("quantity must be at least 1")
+	}
+
+	err := checkReserveHash(reserveHash)
+	if err != nil {
+		return err
+	}
+
+	auction := Auction{
+		ItemSold:    itemsold,
+		ReserveHash: reserveHash,
+		Settlement:  firstPrice,
+		Format:      multiUnit,
+		Quantity:    quantity,
+	}
+
+	err = setDeadlines(ctx, &auction, biddingDeadline, revealDeadline)
+	if err != nil {
+		return err
+	}
+
+	return createAuction(ctx, auctionID, &auction)
+}
+
+// CreateDutchAuction creates an auction whose price drops on a schedule that starts
+// at the timestamp of this transaction. The first bidder to accept the current price
+// with AcceptPrice wins the auction.
+func (s *SmartContract) CreateDutchAuction(ctx contractapi.TransactionContextInterface, auctionID string, itemsold string, startPrice int, floorPrice int, decrement int, interval int) error {
+
+	if floorPrice < 1 || startPrice < floorPrice {
+		return fmt.Errorf("the start price must not be below the floor price, which must be at least 1")
+	}
+	if decrement < 0 || interval < 1 {
+		return fmt.Errorf("the decrement must not be negative and the interval must be at least 1 second")
+	}
+
+	startTime, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	auction := Auction{
+		ItemSold:   itemsold,
+		Settlement: firstPrice,
+		Format:     dutch,
+		Quantity:   1,
+		Schedule: &DutchSchedule{
+			StartPrice: startPrice,
+			FloorPrice: floorPrice,
+			Decrement:  decrement,
+			Interval:   interval,
+			StartTime:  startTime,
+		},
+	}
+
+	return createAuction(ctx, auctionID, &auction)
+}
+
+// QueryDutchPrice returns the price of a Dutch auction at the time of the query
+func (s *SmartContract) QueryDutchPrice(ctx contractapi.TransactionContextInterface, auctionID string) (int, error) {
+
+	auction, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return 0, err
+	}
+	if auction.Format != dutch {
+		return 0, fmt.Errorf("auction %v is not a Dutch auction", auctionID)
+	}
+
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return auction.Schedule.priceAt(now), nil
+}
+
+// AcceptPrice is used by a bidder to buy the item of a Dutch auction at the current
+// price. The bid is created with Bid, like a sealed bid, and passed in the transient
+// map; its price is the highest price the bidder is willing to pay. The first
+// accepted bid wins the auction.
+func (s *SmartContract) AcceptPrice(ctx contractapi.TransactionContextInterface, auctionID string, txID string) error {
+
+	bidKey, _, bid, err := getTransientBid(ctx, auctionID, txID)
+	if err != nil {
+		return err
+	}
+
+	auctionBytes, err := ctx.GetStub().GetState(auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction %v: %v", auctionID, err)
+	}
+	if auctionBytes == nil {
+		return fmt.Errorf("Auction interest object %v not found", auctionID)
+	}
+
+	var auctionJSON Auction
+	err = json.Unmarshal(auctionBytes, &auctionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to create auction object JSON: %v", err)
+	}
+
+	if auctionJSON.Format != dutch {
+		return fmt.Errorf("auction %v is not a Dutch auction", auctionID)
+	}
+	if auctionJSON.Status != "open" {
+		return fmt.Errorf("cannot accept the price of a closed or ended auction")
+	}
+
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	price := auctionJSON.Schedule.priceAt(now)
+	if bid.Price < price {
+		return fmt.Errorf("bid of %d is below the current price of %d", bid.Price, price)
+	}
+
+	// the auction records the price paid, not the highest price the bidder accepted
+	bid.Price = price
+	bid.Quantity = 1
+	bid.RevealOrder = 1
+	auctionJSON.RevealedBids[bidKey] = *bid
+	auctionJSON.Winner = bid.Bidder
+	auctionJSON.Price = price
+	auctionJSON.Status = string("ended")
+
+	newAuctionBytes, _ := json.Marshal(auctionJSON)
+
+	err = ctx.GetStub().PutState(auctionID, newAuctionBytes)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return setStatusIndex(ctx, auctionID, auctionJSON.Status)
+}
+
+// settleMultiUnitAuction allocates the units of a multi-unit auction to the highest
+// bids that meet the reserve price. The last bid to be allocated units may receive
+// fewer than it asked for. It returns the allocations, the clearing price and the
+// number of units left unsold.
+func settleMultiUnitAuction(revealedBids map[string]FullBid, quantity int, reservePrice int) ([]Allocation, int, int) {
+
+	var allocations []Allocation
+	price := 0
+	unsold := quantity
+	for _, bidKey := range rankBids(revealedBids) {
+		bid := revealedBids[bidKey]
+		if unsold == 0 || bid.Price < minimumWinningPrice(reservePrice) {
+			break
+		}
+
+		units := bid.Quantity
+		if units > unsold {
+			units = unsold
+		}
+		allocations = append(allocations, Allocation{
+			Bidder:   bid.Bidder,
+			Org:      bid.Org,
+			Quantity: units,
+		})
+		unsold -= units
+		price = bid.Price
+	}
+
+	return allocations, price, unsold
+}
+
+// priceAt returns the price of the schedule at the given time
+func (schedule *DutchSchedule) priceAt(now time.Time) int {
+
+	if !now.After(schedule.StartTime) {
+		return schedule.StartPrice
+	}
+
+	steps := int(now.Sub(schedule.StartTime) / (time.Duration(schedule.Interval) * time.Second))
+	price := schedule.StartPrice - steps*schedule.Decrement
+	if price < schedule.FloorPrice || (schedule.Decrement > 0 && steps > schedule.StartPrice/schedule.Decrement) {
+		return schedule.FloorPrice
+	}
+
+	return price
+}
+
+// getTxTime returns the timestamp of the transaction, which is the same on every endorser
+func getTxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC(), nil
+}
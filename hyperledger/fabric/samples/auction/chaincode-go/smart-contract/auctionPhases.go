@@ -0,0 +1,192 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// AdvanceAuction moves an auction with deadlines to its next phase once a deadline
+// has passed. It can be submitted by anyone, so that the seller cannot keep the auction
+// open. After the bidding deadline the auction is closed. After the reveal deadline it
+// is ended like EndAuction, except that the bids that were not revealed are left out and
+// their deposits are forfeited to the seller. If the seller has not revealed the reserve
+// with RevealReserve by then, the auction ends without a winner.
+func (s *SmartContract) AdvanceAuction(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	auctionJSON, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+
+	advanced := false
+	if auctionJSON.Status == "open" && auctionJSON.biddingClosed(now) {
+		auctionJSON.Status = string("closed")
+		advanced = true
+	}
+	if auctionJSON.Status == "closed" && auctionJSON.revealClosed(now) {
+		return endAuction(ctx, auctionID, auctionJSON, now)
+	}
+	if !advanced {
+		return fmt.Errorf("auction %v has no deadline that has passed", auctionID)
+	}
+
+	newAuctionBytes, _ := json.Marshal(auctionJSON)
+
+	err = ctx.GetStub().PutState(auctionID, newAuctionBytes)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return setStatusIndex(ctx, auctionID, auctionJSON.Status)
+}
+
+// RevealReserve can be used by the seller to reveal the reserve price of an auction
+// with deadlines after the bidding deadline, by passing the reserve JSON in the
+// transient map under the key "reserve". The reserve then applies when the auction
+// is advanced past its reveal deadline.
+func (s *SmartContract) RevealReserve(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	auctionJSON, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+
+	// get ID of submitting client
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	if auctionJSON.Seller != clientID {
+		return fmt.Errorf("reserve can only be revealed by the seller")
+	}
+	if auctionJSON.ReserveHash == "" || auctionJSON.ReserveRevealed {
+		return fmt.Errorf("auction %v has no reserve to reveal", auctionID)
+	}
+
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if auctionJSON.Status == "ended" || !auctionJSON.biddingClosed(now) || auctionJSON.revealClosed(now) {
+		return fmt.Errorf("reserve can only be revealed between the bidding and the reveal deadline")
+	}
+
+	reserve, err := revealReserve(ctx, auctionJSON.ReserveHash)
+	if err != nil {
+		return err
+	}
+	auctionJSON.ReservePrice = reserve.Price
+	auctionJSON.ReserveRevealed = true
+
+	newAuctionBytes, _ := json.Marshal(auctionJSON)
+
+	err = ctx.GetStub().PutState(auctionID, newAuctionBytes)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// setDeadlines parses the bidding and reveal deadlines of a new auction. Either both
+// deadlines are given or neither.
+func setDeadlines(ctx contractapi.TransactionContextInterface, auction *Auction, biddingDeadline string, revealDeadline string) error {
+
+	if biddingDeadline == "" && revealDeadline == "" {
+		return nil
+	}
+	if biddingDeadline == "" || revealDeadline == "" {
+		return fmt.Errorf("both the bidding and the reveal deadline must be given")
+	}
+
+	bidding, err := time.Parse(time.RFC3339, biddingDeadline)
+	if err != nil {
+		return fmt.Errorf("invalid bidding deadline %q: %v", biddingDeadline, err)
+	}
+	reveal, err := time.Parse(time.RFC3339, revealDeadline)
+	if err != nil {
+		return fmt.Errorf("invalid reveal deadline %q: %v", revealDeadline, err)
+	}
+
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if !bidding.After(now) {
+		return fmt.Errorf("the bidding deadline must be in the future")
+	}
+	if !reveal.After(bidding) {
+		return fmt.Errorf("the reveal deadline must be after the bidding deadline")
+	}
+
+	auction.BiddingDeadline = bidding.UTC()
+	auction.RevealDeadline = reveal.UTC()
+
+	return nil
+}
+
+// checkBiddingOpen checks that an auction exists and accepts bids
+func checkBiddingOpen(ctx contractapi.TransactionContextInterface, auctionID string) error {
+
+	auctionBytes, err := ctx.GetStub().GetState(auctionID)
+	if err != nil {
+		return fmt.Errorf("failed to get auction %v: %v", auctionID, err)
+	}
+	if auctionBytes == nil {
+		return fmt.Errorf("Auction not found: %v", auctionID)
+	}
+
+	var auctionJSON Auction
+	err = json.Unmarshal(auctionBytes, &auctionJSON)
+	if err != nil {
+		return fmt.Errorf("failed to create auction object JSON: %v", err)
+	}
+
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return err
+	}
+	if auctionJSON.Status != "open" || auctionJSON.biddingClosed(now) {
+		return fmt.Errorf("cannot bid on closed or ended auction")
+	}
+
+	return nil
+}
+
+// biddingClosed returns true if the auction has a bidding deadline that has passed
+func (auction *Auction) biddingClosed(now time.Time) bool {
+	return !auction.BiddingDeadline.IsZero() && !now.Before(auction.BiddingDeadline)
+}
+
+// revealClosed returns true if the auction has a reveal deadline that has passed
+func (auction *Auction) revealClosed(now time.Time) bool {
+	return !auction.RevealDeadline.IsZero() && !now.Before(auction.RevealDeadline)
+}
+
+// unrevealedBids returns the keys of the bids added to the auction that were not revealed
+func unrevealedBids(auction *Auction) []string {
+
+	bidKeys := []string{}
+	for bidKey := range auction.PrivateBids {
+		if _, revealed := auction.RevealedBids[bidKey]; !revealed {
+			bidKeys = append(bidKeys, bidKey)
+		}
+	}
+	sort.Strings(bidKeys)
+
+	return bidKeys
+}
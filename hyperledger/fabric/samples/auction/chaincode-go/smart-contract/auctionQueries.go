@@ -12,6 +12,38 @@ import (
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// indexes of the auctions, which can be queried by partial composite key on LevelDB
+// as well as on CouchDB
+const (
+	statusIndex = "status~auction"
+	sellerIndex = "seller~auction"
+)
+
+// indexValue is the value of index entries. An empty value would delete the key.
+var indexValue = []byte{0x00}
+
+// PaginatedAuctions is a page of auctions returned by the auction queries
+type PaginatedAuctions struct {
+	Records             []*Auction `json:"records"`
+	FetchedRecordsCount int32      `json:"fetchedRecordsCount"`
+	Bookmark            string     `json:"bookmark"`
+}
+
+// MyBid is a bid of the submitting client, read from private data
+type MyBid struct {
+	AuctionID string `json:"auctionID"`
+	TxID      string `json:"txID"`
+	Price     int    `json:"price"`
+	Quantity  int    `json:"quantity"`
+}
+
+// PaginatedBids is a page of bids returned by QueryMyBids
+type PaginatedBids struct {
+	Records             []*MyBid `json:"records"`
+	FetchedRecordsCount int32    `json:"fetchedRecordsCount"`
+	Bookmark            string   `json:"bookmark"`
+}
+
 // QueryAuction allows all members of the channel to read a public auction
 func (s *SmartContract) QueryAuction(ctx contractapi.TransactionContextInterface, auctionID string) (*Auction, error) {
 
@@ -89,6 +121,132 @@ func (s *SmartContract) GetID(ctx contractapi.TransactionContextInterface) (stri
 	return clientID, nil
 }
 
+// QueryAuctionsByStatus returns a page of the auctions with the given status, open,
+// closed or ended, ordered by auction ID. Pass the bookmark of the previous page to
+// get the next page.
+func (s *SmartContract) QueryAuctionsByStatus(ctx contractapi.TransactionContextInterface, status string, pageSize int, bookmark string) (*PaginatedAuctions, error) {
+
+	return queryAuctionIndex(ctx, statusIndex, status, pageSize, bookmark)
+}
+
+// QueryAuctionsBySeller returns a page of the auctions of the given seller, ordered by
+// auction ID. The seller is identified by the ID returned by GetID.
+func (s *SmartContract) QueryAuctionsBySeller(ctx contractapi.TransactionContextInterface, seller string, pageSize int, bookmark string) (*PaginatedAuctions, error) {
+
+	return queryAuctionIndex(ctx, sellerIndex, seller, pageSize, bookmark)
+}
+
+// QueryAuctionsWithPagination runs a rich query against the auctions with pagination.
+// It requires CouchDB as the state database; the indexes in META-INF support queries
+// by status and by seller, for example
+// {"selector":{"objectType":"auction","status":"open"},"use_index":["_design/indexStatusDoc","indexStatus"]}
+func (s *SmartContract) QueryAuctionsWithPagination(ctx contractapi.TransactionContextInterface, queryString string, pageSize int, bookmark string) (*PaginatedAuctions, error) {
+
+	resultsIterator, responseMetadata, err := ctx.GetStub().GetQueryResultWithPagination(queryString, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query auctions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	auctions := []*Auction{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var auction Auction
+		err = json.Unmarshal(queryResponse.Value, &auction)
+		if err != nil {
+			return nil, err
+		}
+		auctions = append(auctions, &auction)
+	}
+
+	return &PaginatedAuctions{
+		Records:             auctions,
+		FetchedRecordsCount: responseMetadata.FetchedRecordsCount,
+		Bookmark:            responseMetadata.Bookmark,
+	}, nil
+}
+
+// QueryMyBids returns a page of the bids of the submitting client, read from the
+// implicit private data collection of the client's organization. The client has to
+// target a peer of their own organization. Private data queries cannot be paginated
+// by the peer, so the bookmark is the key of the last bid of the previous page.
+func (s *SmartContract) QueryMyBids(ctx contractapi.TransactionContextInterface, pageSize int, bookmark string) (*PaginatedBids, error) {
+
+	if pageSize < 1 {
+		return nil, fmt.Errorf("page size must be at least 1")
+	}
+
+	err := verifyClientOrgMatchesPeerOrg(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read bids on this peer, not a member of this org: Error %v", err)
+	}
+
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	collection, err := getCollectionName(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get implicit collection name: %v", err)
+	}
+
+	resultsIterator, err := ctx.GetStub().GetPrivateDataByPartialCompositeKey(collection, bidKeyType, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query bids: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	result := &PaginatedBids{Records: []*MyBid{}}
+	lastKey := ""
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+		if queryResponse.Key <= bookmark {
+			continue
+		}
+
+		var bid FullBid
+		err = json.Unmarshal(queryResponse.Value, &bid)
+		if err != nil {
+			return nil, err
+		}
+		if bid.Bidder != clientID {
+			continue
+		}
+
+		if len(result.Records) == pageSize {
+			// there are more bids, continue after the last bid of this page
+			result.Bookmark = lastKey
+			break
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("invalid bid key %q", queryResponse.Key)
+		}
+		result.Records = append(result.Records, &MyBid{
+			AuctionID: keyParts[0],
+			TxID:      keyParts[1],
+			Price:     bid.Price,
+			Quantity:  bid.Quantity,
+		})
+		lastKey = queryResponse.Key
+	}
+	result.FetchedRecordsCount = int32(len(result.Records))
+
+	return result, nil
+}
+
 // queryAllBids is an internal function that is used to determine if a winning bid has yet to be revealed
 func queryAllBids(ctx contractapi.TransactionContextInterface, auctionPrice int, revealedBidders map[string]FullBid, bidders map[string]BidHash) error {
 
@@ -146,3 +304,87 @@ func queryAllBids(ctx contractapi.TransactionContextInterface, auctionPrice int,
 
 	return error
 }
+
+// queryAuctionIndex returns a page of the auctions listed in an index under the given value
+func queryAuctionIndex(ctx contractapi.TransactionContextInterface, index string, value string, pageSize int, bookmark string) (*PaginatedAuctions, error) {
+
+	resultsIterator, responseMetadata, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(index, []string{value}, int32(pageSize), bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query auctions: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	auctions := []*Auction{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(keyParts) != 2 {
+			return nil, fmt.Errorf("invalid index key %q", queryResponse.Key)
+		}
+
+		auctionBytes, err := ctx.GetStub().GetState(keyParts[1])
+		if err != nil {
+			return nil, fmt.Errorf("failed to get auction %v: %v", keyParts[1], err)
+		}
+		if auctionBytes == nil {
+			continue
+		}
+
+		var auction Auction
+		err = json.Unmarshal(auctionBytes, &auction)
+		if err != nil {
+			return nil, err
+		}
+		auctions = append(auctions, &auction)
+	}
+
+	return &PaginatedAuctions{
+		Records:             auctions,
+		FetchedRecordsCount: responseMetadata.FetchedRecordsCount,
+		Bookmark:            responseMetadata.Bookmark,
+	}, nil
+}
+
+// setStatusIndex lists the auction in the status index under its new status, and
+// removes it from the other statuses
+func setStatusIndex(ctx contractapi.TransactionContextInterface, auctionID string, status string) error {
+
+	for _, indexStatus := range []string{"open", "closed", "ended"} {
+		indexKey, err := ctx.GetStub().CreateCompositeKey(statusIndex, []string{indexStatus, auctionID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		if indexStatus == status {
+			err = ctx.GetStub().PutState(indexKey, indexValue)
+		} else {
+			err = ctx.GetStub().DelState(indexKey)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update status index: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// addSellerIndex lists a new auction in the seller index
+func addSellerIndex(ctx contractapi.TransactionContextInterface, seller string, auctionID string) error {
+
+	indexKey, err := ctx.GetStub().CreateCompositeKey(sellerIndex, []string{seller, auctionID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+	err = ctx.GetStub().PutState(indexKey, indexValue)
+	if err != nil {
+		return fmt.Errorf("failed to update seller index: %v", err)
+	}
+
+	return nil
+}
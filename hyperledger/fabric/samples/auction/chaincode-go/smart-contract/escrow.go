@@ -0,0 +1,375 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const escrowKeyType = "escrow"
+
+// status of an escrow entry
+const (
+	// the deposit is held by the auction
+	escrowLocked = "locked"
+	// the auction has ended, the payment was made to the seller and the refund is due
+	escrowSettled = "settled"
+	// the refund was paid back to the bidder
+	escrowReleased = "released"
+	// the bid was not revealed in time and the deposit was paid to the seller
+	escrowForfeited = "forfeited"
+)
+
+// EscrowEntry is the deposit locked by a bid. The tokens are held in the account
+// of the auction chaincode on the token chaincode.
+type EscrowEntry struct {
+	Type    string `json:"objectType"`
+	TxID    string `json:"txID"`
+	Bidder  string `json:"bidder"`
+	Org     string `json:"org"`
+	Amount  int    `json:"amount"`
+	Paid    int    `json:"paid"`
+	Refund  int    `json:"refund"`
+	Status  string `json:"status"`
+	Account string `json:"account"`
+}
+
+// RequireDeposit can be used by the seller, before any bid is submitted, to require
+// every bid to lock a deposit of tokens from the given ERC-20 token chaincode. The
+// deposit is the highest amount a bid can pay, so that the winner's payment is always
+// covered. It is also the only penalty for a bid that is not revealed by the reveal
+// deadline: an auction without a deposit just leaves such bids out. Bidders approve an allowance for the auction chaincode's token account
+// before they submit their bid.
+func (s *SmartContract) RequireDeposit(ctx contractapi.TransactionContextInterface, auctionID string, tokenChaincode string, deposit int) error {
+
+	auctionJSON, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+
+	// get ID of submitting client
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	if auctionJSON.Seller != clientID {
+		return fmt.Errorf("deposit can only be required by the seller")
+	}
+	if auctionJSON.Status != "open" || len(auctionJSON.PrivateBids) > 0 {
+		return fmt.Errorf("deposit can only be required by an open auction without bids")
+	}
+	if auctionJSON.Format == dutch {
+		return fmt.Errorf("cannot require a deposit for a Dutch auction")
+	}
+	if tokenChaincode == "" || deposit < 1 {
+		return fmt.Errorf("the token chaincode must be given and the deposit must be at least 1")
+	}
+
+	auctionJSON.TokenChaincode = tokenChaincode
+	auctionJSON.Deposit = deposit
+
+	newAuctionBytes, _ := json.Marshal(auctionJSON)
+
+	err = ctx.GetStub().PutState(auctionID, newAuctionBytes)
+	if err != nil {
+		return fmt.Errorf("failed to update auction: %v", err)
+	}
+
+	return nil
+}
+
+// ReleaseDeposit pays the refund of a settled escrow entry back to the bidder. It can
+// be submitted by anyone once the auction has ended. Every release debits the token
+// account of the auction chaincode, so releases need to be submitted one at a time.
+func (s *SmartContract) ReleaseDeposit(ctx contractapi.TransactionContextInterface, auctionID string, txID string) error {
+
+	auctionJSON, err := s.QueryAuction(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+	if auctionJSON.Status != "ended" {
+		return fmt.Errorf("deposits can only be released after the auction has ended")
+	}
+
+	escrowKey, entry, err := getEscrowEntry(ctx, auctionID, txID)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("no deposit was locked by bid %s", txID)
+	}
+	if entry.Status != escrowSettled {
+		return fmt.Errorf("deposit of bid %s is %s, only a settled deposit can be released", txID, entry.Status)
+	}
+
+	_, err = invokeTokenChaincode(ctx, auctionJSON.TokenChaincode, "Transfer", entry.Bidder, strconv.Itoa(entry.Refund))
+	if err != nil {
+		return err
+	}
+
+	entry.Status = escrowReleased
+
+	return putEscrowEntry(ctx, escrowKey, entry)
+}
+
+// QueryEscrow returns the deposits locked by the bids of an auction
+func (s *SmartContract) QueryEscrow(ctx contractapi.TransactionContextInterface, auctionID string) ([]*EscrowEntry, error) {
+
+	entries, err := getEscrowEntries(ctx, auctionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// lockDeposit transfers the deposit of an auction from the bidder's token account to
+// the token account of the auction chaincode, using the allowance approved by the bidder
+func lockDeposit(ctx contractapi.TransactionContextInterface, auctionID string, txID string, auctionJSON *Auction) error {
+
+	escrowKey, entry, err := getEscrowEntry(ctx, auctionID, txID)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		// the bid was submitted before
+		return nil
+	}
+
+	// get ID of submitting client
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	// get org of submitting client
+	clientOrgID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get client identity %v", err)
+	}
+
+	// the token chaincode gives the auction chaincode an account of its own
+	account, err := invokeTokenChaincode(ctx, auctionJSON.TokenChaincode, "ClientAccountID")
+	if err != nil {
+		return err
+	}
+
+	_, err = invokeTokenChaincode(ctx, auctionJSON.TokenChaincode, "TransferFrom", clientID, string(account), strconv.Itoa(auctionJSON.Deposit))
+	if err != nil {
+		return fmt.Errorf("failed to lock deposit: %v", err)
+	}
+
+	entry = &EscrowEntry{
+		Type:    escrowKeyType,
+		TxID:    txID,
+		Bidder:  clientID,
+		Org:     clientOrgID,
+		Amount:  auctionJSON.Deposit,
+		Status:  escrowLocked,
+		Account: string(account),
+	}
+
+	return putEscrowEntry(ctx, escrowKey, entry)
+}
+
+// checkDeposit checks that a revealed bid was submitted by the bidder with a deposit
+// that covers the most the bid can pay
+func checkDeposit(ctx contractapi.TransactionContextInterface, auctionID string, txID string, bid *FullBid) error {
+
+	_, entry, err := getEscrowEntry(ctx, auctionID, txID)
+	if err != nil {
+		return err
+	}
+	if entry == nil || entry.Bidder != bid.Bidder {
+		return fmt.Errorf("bid %s was not submitted with a deposit of the bidder", txID)
+	}
+
+	quantity := bid.Quantity
+	if quantity < 1 {
+		quantity = 1
+	}
+	if bid.Price*quantity > entry.Amount {
+		return fmt.Errorf("bid %s of %d for %d units exceeds its deposit of %d", txID, bid.Price, quantity, entry.Amount)
+	}
+
+	return nil
+}
+
+// settleEscrow pays the winners' share of their deposits and the deposits of bids that
+// were not revealed in time to the seller, and records the refund due to every bidder.
+// The payment is made with a single transfer, because the token chaincode does not see
+// its own writes within a transaction. The refunds are paid by ReleaseDeposit.
+func settleEscrow(ctx contractapi.TransactionContextInterface, auctionID string, auctionJSON *Auction) error {
+
+	if auctionJSON.Deposit == 0 {
+		return nil
+	}
+
+	// what each bidder pays for the units they won
+	owed := make(map[string]int)
+	if auctionJSON.Winner != "" {
+		owed[auctionJSON.Winner] = auctionJSON.Price
+	}
+	for _, allocation := range auctionJSON.Allocations {
+		owed[allocation.Bidder] += allocation.Quantity * auctionJSON.Price
+	}
+
+	entries, err := getEscrowEntries(ctx, auctionID)
+	if err != nil {
+		return err
+	}
+
+	payment := 0
+	for _, entry := range entries {
+		if entry.Status != escrowLocked {
+			continue
+		}
+
+		bidKey, err := ctx.GetStub().CreateCompositeKey(bidKeyType, []string{auctionID, entry.TxID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		if contains(auctionJSON.UnrevealedBids, bidKey) {
+			entry.Paid = entry.Amount
+			entry.Status = escrowForfeited
+			payment += entry.Paid
+		} else {
+			entry.Paid = owed[entry.Bidder]
+			if entry.Paid > entry.Amount {
+				entry.Paid = entry.Amount
+			}
+			owed[entry.Bidder] -= entry.Paid
+			payment += entry.Paid
+
+			entry.Refund = entry.Amount - entry.Paid
+			entry.Status = escrowSettled
+			if entry.Refund == 0 {
+				entry.Status = escrowReleased
+			}
+		}
+
+		escrowKey, err := ctx.GetStub().CreateCompositeKey(escrowKeyType, []string{auctionID, entry.TxID})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+		err = putEscrowEntry(ctx, escrowKey, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	// a revealed bid never pays more than its deposit
+	bidders := make([]string, 0, len(owed))
+	for bidder := range owed {
+		bidders = append(bidders, bidder)
+	}
+	sort.Strings(bidders)
+	for _, bidder := range bidders {
+		if owed[bidder] > 0 {
+			return fmt.Errorf("deposits of bidder %s do not cover the payment, %d is missing", bidder, owed[bidder])
+		}
+	}
+
+	if payment > 0 {
+		_, err = invokeTokenChaincode(ctx, auctionJSON.TokenChaincode, "Transfer", auctionJSON.Seller, strconv.Itoa(payment))
+		if err != nil {
+			return fmt.Errorf("failed to pay seller: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// getEscrowEntries returns the escrow entries of an auction in the order of their keys
+func getEscrowEntries(ctx contractapi.TransactionContextInterface, auctionID string) ([]*EscrowEntry, error) {
+
+	resultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(escrowKeyType, []string{auctionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query escrow: %v", err)
+	}
+	defer resultsIterator.Close()
+
+	entries := []*EscrowEntry{}
+	for resultsIterator.HasNext() {
+		queryResponse, err := resultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		var entry EscrowEntry
+		err = json.Unmarshal(queryResponse.Value, &entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal escrow entry: %v", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+// getEscrowEntry returns the key of the escrow entry of a bid and the entry, or nil
+// if the bid has not locked a deposit
+func getEscrowEntry(ctx contractapi.TransactionContextInterface, auctionID string, txID string) (string, *EscrowEntry, error) {
+
+	escrowKey, err := ctx.GetStub().CreateCompositeKey(escrowKeyType, []string{auctionID, txID})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	entryBytes, err := ctx.GetStub().GetState(escrowKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read escrow entry: %v", err)
+	}
+	if entryBytes == nil {
+		return escrowKey, nil, nil
+	}
+
+	var entry EscrowEntry
+	err = json.Unmarshal(entryBytes, &entry)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to unmarshal escrow entry: %v", err)
+	}
+
+	return escrowKey, &entry, nil
+}
+
+func putEscrowEntry(ctx contractapi.TransactionContextInterface, escrowKey string, entry *EscrowEntry) error {
+
+	entryBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.GetStub().PutState(escrowKey, entryBytes)
+	if err != nil {
+		return fmt.Errorf("failed to put escrow entry: %v", err)
+	}
+
+	return nil
+}
+
+// invokeTokenChaincode calls a function of the token chaincode on the same channel
+// and returns its payload
+func invokeTokenChaincode(ctx contractapi.TransactionContextInterface, tokenChaincode string, function string, args ...string) ([]byte, error) {
+
+	invokeArgs := [][]byte{[]byte(function)}
+	for _, arg := range args {
+		invokeArgs = append(invokeArgs, []byte(arg))
+	}
+
+	response := ctx.GetStub().InvokeChaincode(tokenChaincode, invokeArgs, "")
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("token chaincode %s failed to %s: %s", tokenChaincode, function, response.Message)
+	}
+
+	return response.Payload, nil
+}
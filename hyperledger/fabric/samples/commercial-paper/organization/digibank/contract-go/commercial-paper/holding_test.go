@@ -0,0 +1,69 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package commercialpaper
+
+import (
+	"testing"
+
+	ledgerapi "github.com/hyperledger/fabric-samples/commercial-paper/organization/digibank/contract-go/ledger-api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateHoldingKey(t *testing.T) {
+	assert.Equal(t, ledgerapi.MakeKey("someissuer", "somepaper", "someowner"), CreateHoldingKey("someissuer", "somepaper", "someowner"), "should return key comprised of passed values")
+}
+
+func TestHoldingGetSplitKey(t *testing.T) {
+	h := new(Holding)
+	h.Issuer = "someissuer"
+	h.PaperNumber = "somepaper"
+	h.Owner = "someowner"
+
+	assert.Equal(t, []string{"someissuer", "somepaper", "someowner"}, h.GetSplitKey(), "should return issuer, paper number and owner as split key")
+}
+
+func TestHoldingSerialize(t *testing.T) {
+	h := &Holding{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someowner", FaceValue: 1000, PurchasePrice: 950, PurchaseDateTime: "sometime"}
+
+	bytes, err := h.Serialize()
+	assert.Nil(t, err, "should not error on serialize")
+	assert.Equal(t, `{"issuer":"someissuer","paperNumber":"somepaper","owner":"someowner","faceValue":1000,"purchasePrice":950,"purchaseDateTime":"sometime"}`, string(bytes), "should return JSON formatted value")
+}
+
+func TestDeserializeHolding(t *testing.T) {
+	var h *Holding
+	var err error
+
+	goodJSON := `{"issuer":"someissuer","paperNumber":"somepaper","owner":"someowner","faceValue":1000,"purchasePrice":950,"purchaseDateTime":"sometime"}`
+	expectedHolding := &Holding{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someowner", FaceValue: 1000, PurchasePrice: 950, PurchaseDateTime: "sometime"}
+	h = new(Holding)
+	err = DeserializeHolding([]byte(goodJSON), h)
+	assert.Nil(t, err, "should not return error for deserialize")
+	assert.Equal(t, expectedHolding, h, "should create expected holding")
+
+	badJSON := `{"issuer":"someissuer","paperNumber":"somepaper","owner":"someowner","faceValue":"NaN"}`
+	h = new(Holding)
+	err = DeserializeHolding([]byte(badJSON), h)
+	assert.EqualError(t, err, "Error deserializing holding. json: cannot unmarshal string into Go struct field Holding.faceValue of type int", "should return error for bad data")
+}
+
+func TestCalculateYield(t *testing.T) {
+	yield, err := CalculateYield(1000, 950, "2020-05-31", "2020-11-30")
+	assert.Nil(t, err, "should not error for good values")
+	assert.InDelta(t, 0.1050, yield, 0.0001, "should return annualised yield")
+
+	yield, err = CalculateYield(1000, 950, "2020-05-31:10:00", "2021-05-31:10:00")
+	assert.Nil(t, err, "should accept date times with hours and minutes")
+	assert.InDelta(t, 0.0526, yield, 0.0001, "should return yield of one year")
+
+	_, err = CalculateYield(1000, 0, "2020-05-31", "2020-11-30")
+	assert.EqualError(t, err, "Purchase price must be positive to calculate yield", "should error for free papers")
+
+	_, err = CalculateYield(1000, 950, "2020-11-30", "2020-05-31")
+	assert.EqualError(t, err, "Maturity 2020-05-31 is not after purchase 2020-11-30", "should error when bought after maturity")
+
+	_, err = CalculateYield(1000, 950, "sometime", "2020-11-30")
+	assert.EqualError(t, err, "Invalid date time sometime", "should error for bad date time")
+}
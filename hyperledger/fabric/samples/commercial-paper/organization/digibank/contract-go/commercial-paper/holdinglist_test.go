@@ -0,0 +1,107 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package commercialpaper
+
+import (
+	"errors"
+	"testing"
+
+	ledgerapi "github.com/hyperledger/fabric-samples/commercial-paper/organization/digibank/contract-go/ledger-api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAddHolding(t *testing.T) {
+	holding := new(Holding)
+
+	list := new(holdingList)
+	msl := new(MockStateList)
+	msl.On("AddState", holding).Return(errors.New("Called add state correctly"))
+	list.stateList = msl
+
+	err := list.AddHolding(holding)
+	assert.EqualError(t, err, "Called add state correctly", "should call state list add state with holding")
+}
+
+func TestGetHolding(t *testing.T) {
+	var h *Holding
+	var err error
+
+	list := new(holdingList)
+	msl := new(MockStateList)
+	msl.On("GetState", CreateHoldingKey("someissuer", "somepaper", "someowner"), mock.MatchedBy(func(state ledgerapi.StateInterface) bool { _, ok := state.(*Holding); return ok })).Return(nil)
+	msl.On("GetState", CreateHoldingKey("someotherissuer", "someotherpaper", "someowner"), mock.MatchedBy(func(state ledgerapi.StateInterface) bool { _, ok := state.(*Holding); return ok })).Return(errors.New("GetState error"))
+	list.stateList = msl
+
+	h, err = list.GetHolding("someissuer", "somepaper", "someowner")
+	assert.Nil(t, err, "should not error when get state on state list does not error")
+	assert.Equal(t, "somepaper", h.PaperNumber, "should use state list GetState to fill holding")
+
+	h, err = list.GetHolding("someotherissuer", "someotherpaper", "someowner")
+	assert.EqualError(t, err, "GetState error", "should return error when state list get state errors")
+	assert.Nil(t, h, "should not return holding on error")
+}
+
+func TestUpdateHolding(t *testing.T) {
+	holding := new(Holding)
+
+	list := new(holdingList)
+	msl := new(MockStateList)
+	msl.On("UpdateState", holding).Return(errors.New("Called update state correctly"))
+	list.stateList = msl
+
+	err := list.UpdateHolding(holding)
+	assert.EqualError(t, err, "Called update state correctly", "should call state list update state with holding")
+}
+
+func TestDeleteHolding(t *testing.T) {
+	list := new(holdingList)
+	msl := new(MockStateList)
+	msl.On("DeleteState", CreateHoldingKey("someissuer", "somepaper", "someowner")).Return(errors.New("Called delete state correctly"))
+	list.stateList = msl
+
+	err := list.DeleteHolding("someissuer", "somepaper", "someowner")
+	assert.EqualError(t, err, "Called delete state correctly", "should call state list delete state with holding key")
+}
+
+func TestGetHoldings(t *testing.T) {
+	var holdings []*Holding
+	var err error
+
+	holding := &Holding{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someowner"}
+
+	list := new(holdingList)
+	msl := new(MockStateList)
+	msl.On("GetStates", []string{"someissuer", "somepaper"}).Return([]ledgerapi.StateInterface{holding}, nil)
+	msl.On("GetStates", []string{"someotherissuer", "someotherpaper"}).Return([]ledgerapi.StateInterface(nil), errors.New("GetStates error"))
+	msl.On("GetStates", []string{}).Return([]ledgerapi.StateInterface{holding}, nil)
+	list.stateList = msl
+
+	holdings, err = list.GetHoldings("someissuer", "somepaper")
+	assert.Nil(t, err, "should not error when get states on state list does not error")
+	assert.Equal(t, []*Holding{holding}, holdings, "should return holdings of the paper from state list GetStates")
+
+	holdings, err = list.GetHoldings("someotherissuer", "someotherpaper")
+	assert.EqualError(t, err, "GetStates error", "should return error when state list get states errors")
+	assert.Nil(t, holdings, "should not return holdings on error")
+
+	holdings, err = list.GetAllHoldings()
+	assert.Nil(t, err, "should not error when get states on state list does not error")
+	assert.Equal(t, []*Holding{holding}, holdings, "should return all holdings from state list GetStates")
+}
+
+func TestNewHoldingList(t *testing.T) {
+	ctx := new(TransactionContext)
+	list := newHoldingList(ctx)
+	stateList, ok := list.stateList.(*ledgerapi.StateList)
+
+	assert.True(t, ok, "should make statelist of type ledgerapi.StateList")
+	assert.Equal(t, ctx, stateList.Ctx, "should set the context to passed context")
+	assert.Equal(t, "org.papernet.commercialpaperholdinglist", stateList.Name, "should set the name for the list")
+
+	expectedErr := DeserializeHolding([]byte("bad json"), new(Holding))
+	err := stateList.Deserialize([]byte("bad json"), new(Holding))
+	assert.EqualError(t, err, expectedErr.Error(), "should call DeserializeHolding when stateList.Deserialize called")
+}
@@ -6,9 +6,11 @@ package commercialpaper
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	ledgerapi "github.com/hyperledger/fabric-samples/commercial-paper/organization/digibank/contract-go/ledger-api"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -38,20 +40,83 @@ func (mpl *MockPaperList) UpdatePaper(paper *CommercialPaper) error {
 	return args.Error(0)
 }
 
+func (mpl *MockPaperList) GetAllPapers() ([]*CommercialPaper, error) {
+	args := mpl.Called()
+
+	return args.Get(0).([]*CommercialPaper), args.Error(1)
+}
+
+type MockHoldingList struct {
+	mock.Mock
+}
+
+func (mhl *MockHoldingList) AddHolding(holding *Holding) error {
+	args := mhl.Called(holding)
+
+	return args.Error(0)
+}
+
+func (mhl *MockHoldingList) GetHolding(issuer string, papernumber string, owner string) (*Holding, error) {
+	args := mhl.Called(issuer, papernumber, owner)
+
+	return args.Get(0).(*Holding), args.Error(1)
+}
+
+func (mhl *MockHoldingList) UpdateHolding(holding *Holding) error {
+	args := mhl.Called(holding)
+
+	return args.Error(0)
+}
+
+func (mhl *MockHoldingList) DeleteHolding(issuer string, papernumber string, owner string) error {
+	args := mhl.Called(issuer, papernumber, owner)
+
+	return args.Error(0)
+}
+
+func (mhl *MockHoldingList) GetHoldings(issuer string, papernumber string) ([]*Holding, error) {
+	args := mhl.Called(issuer, papernumber)
+
+	return args.Get(0).([]*Holding), args.Error(1)
+}
+
+func (mhl *MockHoldingList) GetAllHoldings() ([]*Holding, error) {
+	args := mhl.Called()
+
+	return args.Get(0).([]*Holding), args.Error(1)
+}
+
 type MockTransactionContext struct {
 	contractapi.TransactionContext
-	paperList *MockPaperList
+	paperList   *MockPaperList
+	holdingList *MockHoldingList
 }
 
 func (mtc *MockTransactionContext) GetPaperList() ListInterface {
 	return mtc.paperList
 }
 
+func (mtc *MockTransactionContext) GetHoldingList() HoldingListInterface {
+	return mtc.holdingList
+}
+
 func resetPaper(paper *CommercialPaper) {
+	paper.Issuer = "someissuer"
+	paper.PaperNumber = "somepaper"
 	paper.Owner = "someowner"
+	paper.FaceValue = 1000
 	paper.SetTrading()
 }
 
+func resetHolding(holding *Holding) {
+	holding.Issuer = "someissuer"
+	holding.PaperNumber = "somepaper"
+	holding.Owner = "someowner"
+	holding.FaceValue = 1000
+	holding.PurchasePrice = 950
+	holding.PurchaseDateTime = "2020-05-31"
+}
+
 // #########
 // TESTS
 // #########
@@ -61,25 +126,37 @@ func TestIssue(t *testing.T) {
 	var err error
 
 	mpl := new(MockPaperList)
+	mhl := new(MockHoldingList)
 	ctx := new(MockTransactionContext)
 	ctx.paperList = mpl
+	ctx.holdingList = mhl
 
 	contract := new(Contract)
 
 	var sentPaper *CommercialPaper
+	var sentHolding *Holding
 
 	mpl.On("AddPaper", mock.MatchedBy(func(paper *CommercialPaper) bool { sentPaper = paper; return paper.Issuer == "someissuer" })).Return(nil)
 	mpl.On("AddPaper", mock.MatchedBy(func(paper *CommercialPaper) bool { sentPaper = paper; return paper.Issuer == "someotherissuer" })).Return(errors.New("AddPaper error"))
+	mpl.On("AddPaper", mock.MatchedBy(func(paper *CommercialPaper) bool { return paper.Issuer == "yetanotherissuer" })).Return(nil)
+	mhl.On("AddHolding", mock.MatchedBy(func(holding *Holding) bool { sentHolding = holding; return holding.Issuer == "someissuer" })).Return(nil)
+	mhl.On("AddHolding", mock.MatchedBy(func(holding *Holding) bool { return holding.Issuer == "yetanotherissuer" })).Return(errors.New("AddHolding error"))
 
 	expectedPaper := CommercialPaper{PaperNumber: "somepaper", Issuer: "someissuer", IssueDateTime: "someissuedate", FaceValue: 1000, MaturityDateTime: "somematuritydate", Owner: "someissuer", state: 1}
+	expectedHolding := Holding{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someissuer", FaceValue: 1000, PurchaseDateTime: "someissuedate"}
 	paper, err = contract.Issue(ctx, "someissuer", "somepaper", "someissuedate", "somematuritydate", 1000)
 	assert.Nil(t, err, "should not error when add paper does not error")
 	assert.Equal(t, sentPaper, paper, "should send the same paper as it returns to add paper")
 	assert.Equal(t, expectedPaper, *paper, "should correctly configure paper")
+	assert.Equal(t, expectedHolding, *sentHolding, "should add holding of whole face value for issuer")
 
 	paper, err = contract.Issue(ctx, "someotherissuer", "somepaper", "someissuedate", "somematuritydate", 1000)
 	assert.EqualError(t, err, "AddPaper error", "should return error when add paper fails")
 	assert.Nil(t, paper, "should not return paper when fails")
+
+	paper, err = contract.Issue(ctx, "yetanotherissuer", "somepaper", "someissuedate", "somematuritydate", 1000)
+	assert.EqualError(t, err, "AddHolding error", "should return error when add holding fails")
+	assert.Nil(t, paper, "should not return paper when add holding fails")
 }
 
 func TestBuy(t *testing.T) {
@@ -87,22 +164,32 @@ func TestBuy(t *testing.T) {
 	var err error
 
 	mpl := new(MockPaperList)
+	mhl := new(MockHoldingList)
 	ctx := new(MockTransactionContext)
 	ctx.paperList = mpl
+	ctx.holdingList = mhl
 
 	contract := new(Contract)
 
 	wsPaper := new(CommercialPaper)
 	resetPaper(wsPaper)
+	wsHolding := new(Holding)
 
 	var sentPaper *CommercialPaper
+	var sentHolding *Holding
 	var emptyPaper *CommercialPaper
+	var emptyHolding *Holding
 	shouldError := false
 
 	mpl.On("GetPaper", "someissuer", "somepaper").Return(wsPaper, nil)
 	mpl.On("GetPaper", "someotherissuer", "someotherpaper").Return(emptyPaper, errors.New("GetPaper error"))
 	mpl.On("UpdatePaper", mock.MatchedBy(func(paper *CommercialPaper) bool { return shouldError })).Return(errors.New("UpdatePaper error"))
 	mpl.On("UpdatePaper", mock.MatchedBy(func(paper *CommercialPaper) bool { sentPaper = paper; return !shouldError })).Return(nil)
+	mhl.On("GetHolding", "someissuer", "somepaper", "someowner").Return(wsHolding, nil)
+	mhl.On("GetHolding", "someissuer", "somepaper", "someotherowner").Return(emptyHolding, fmt.Errorf("%w for someissuer:somepaper:someotherowner", ledgerapi.ErrStateNotFound))
+	mhl.On("GetHolding", "someissuer", "somepaper", "brokenowner").Return(emptyHolding, errors.New("GetHolding error"))
+	mhl.On("UpdateHolding", mock.MatchedBy(func(holding *Holding) bool { sentHolding = holding; return true })).Return(nil)
+	mhl.On("DeleteHolding", "someissuer", "somepaper", "someowner").Return(nil)
 
 	paper, err = contract.Buy(ctx, "someotherissuer", "someotherpaper", "someowner", "someotherowner", 100, "2019-12-10:10:00")
 	assert.EqualError(t, err, "GetPaper error", "should return error when GetPaper errors")
@@ -119,6 +206,19 @@ func TestBuy(t *testing.T) {
 	assert.Nil(t, paper, "should not return paper for bad state error")
 
 	resetPaper(wsPaper)
+	resetHolding(wsHolding)
+	paper, err = contract.Buy(ctx, "someissuer", "somepaper", "someowner", "someotherowner", -1, "2019-12-10:10:00")
+	assert.EqualError(t, err, "Price must not be negative", "should error when price is negative")
+	assert.Nil(t, paper, "should not return paper for bad price error")
+
+	resetPaper(wsPaper)
+	resetHolding(wsHolding)
+	paper, err = contract.Buy(ctx, "someissuer", "somepaper", "someowner", "brokenowner", 100, "2019-12-10:10:00")
+	assert.EqualError(t, err, "GetHolding error", "should error when GetHolding errors for the new owner other than for a missing holding")
+	assert.Nil(t, paper, "should not return paper when GetHolding errors")
+
+	resetPaper(wsPaper)
+	resetHolding(wsHolding)
 	shouldError = true
 	paper, err = contract.Buy(ctx, "someissuer", "somepaper", "someowner", "someotherowner", 100, "2019-12-10:10:00")
 	assert.EqualError(t, err, "UpdatePaper error", "should error when update paper fails")
@@ -126,12 +226,105 @@ func TestBuy(t *testing.T) {
 	shouldError = false
 
 	resetPaper(wsPaper)
+	resetHolding(wsHolding)
 	wsPaper.SetIssued()
 	paper, err = contract.Buy(ctx, "someissuer", "somepaper", "someowner", "someotherowner", 100, "2019-12-10:10:00")
 	assert.Nil(t, err, "should not error when good paper and owner")
 	assert.Equal(t, "someotherowner", paper.Owner, "should update the owner of the paper")
 	assert.True(t, paper.IsTrading(), "should mark issued paper as trading")
 	assert.Equal(t, sentPaper, paper, "should update same paper as it returns in the world state")
+	assert.Equal(t, Holding{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someotherowner", FaceValue: 1000, PurchasePrice: 100, PurchaseDateTime: "2019-12-10:10:00"}, *sentHolding, "should record the purchase price in the holding of the new owner")
+	mhl.AssertCalled(t, "DeleteHolding", "someissuer", "somepaper", "someowner")
+}
+
+func TestBuyFraction(t *testing.T) {
+	var paper *CommercialPaper
+	var err error
+
+	mpl := new(MockPaperList)
+	mhl := new(MockHoldingList)
+	ctx := new(MockTransactionContext)
+	ctx.paperList = mpl
+	ctx.holdingList = mhl
+
+	contract := new(Contract)
+
+	wsPaper := new(CommercialPaper)
+	resetPaper(wsPaper)
+	wsHolding := new(Holding)
+	resetHolding(wsHolding)
+	otherHolding := new(Holding)
+
+	var sentHoldings []Holding
+	var emptyPaper *CommercialPaper
+	var emptyHolding *Holding
+
+	mpl.On("GetPaper", "someissuer", "somepaper").Return(wsPaper, nil)
+	mpl.On("GetPaper", "someotherissuer", "someotherpaper").Return(emptyPaper, errors.New("GetPaper error"))
+	mpl.On("UpdatePaper", wsPaper).Return(nil)
+	mhl.On("GetHolding", "someissuer", "somepaper", "someowner").Return(wsHolding, nil)
+	mhl.On("GetHolding", "someissuer", "somepaper", "someotherowner").Return(emptyHolding, fmt.Errorf("%w for someissuer:somepaper:someotherowner", ledgerapi.ErrStateNotFound))
+	mhl.On("GetHolding", "someissuer", "somepaper", "yetanotherowner").Return(otherHolding, nil)
+	mhl.On("GetHolding", "someissuer", "somepaper", "brokenowner").Return(emptyHolding, errors.New("GetHolding error"))
+	mhl.On("UpdateHolding", mock.MatchedBy(func(holding *Holding) bool { sentHoldings = append(sentHoldings, *holding); return true })).Return(nil)
+	mhl.On("DeleteHolding", "someissuer", "somepaper", "someowner").Return(nil)
+
+	paper, err = contract.BuyFraction(ctx, "someotherissuer", "someotherpaper", "someowner", "someotherowner", 400, 380, "2020-05-31")
+	assert.EqualError(t, err, "GetPaper error", "should return error when GetPaper errors")
+	assert.Nil(t, paper, "should return nil for paper when GetPaper errors")
+
+	wsPaper.SetRedeemed()
+	paper, err = contract.BuyFraction(ctx, "someissuer", "somepaper", "someowner", "someotherowner", 400, 380, "2020-05-31")
+	assert.EqualError(t, err, "Paper someissuer:somepaper is not trading. Current state = REDEEMED")
+	assert.Nil(t, paper, "should not return paper for bad state error")
+
+	resetPaper(wsPaper)
+	paper, err = contract.BuyFraction(ctx, "someissuer", "somepaper", "someowner", "someowner", 400, 380, "2020-05-31")
+	assert.EqualError(t, err, "Paper someissuer:somepaper cannot be sold by someowner to itself", "should error when sold to the current owner")
+	assert.Nil(t, paper, "should not return paper when sold to the current owner")
+
+	paper, err = contract.BuyFraction(ctx, "someissuer", "somepaper", "someotherowner", "someowner", 400, 380, "2020-05-31")
+	assert.EqualError(t, err, "Paper someissuer:somepaper is not owned by someotherowner", "should error when current owner has no holding")
+	assert.Nil(t, paper, "should not return paper when current owner has no holding")
+
+	paper, err = contract.BuyFraction(ctx, "someissuer", "somepaper", "brokenowner", "someowner", 400, 380, "2020-05-31")
+	assert.EqualError(t, err, "GetHolding error", "should error when GetHolding errors for the current owner other than for a missing holding")
+	assert.Nil(t, paper, "should not return paper when GetHolding errors for the current owner")
+
+	paper, err = contract.BuyFraction(ctx, "someissuer", "somepaper", "someowner", "brokenowner", 400, 380, "2020-05-31")
+	assert.EqualError(t, err, "GetHolding error", "should error when GetHolding errors for the new owner other than for a missing holding")
+	assert.Nil(t, paper, "should not return paper when GetHolding errors for the new owner")
+
+	paper, err = contract.BuyFraction(ctx, "someissuer", "somepaper", "someowner", "someotherowner", 2000, 1900, "2020-05-31")
+	assert.EqualError(t, err, "Paper someissuer:somepaper holding of someowner has face value 1000, cannot sell 2000", "should error when selling more than held")
+	assert.Nil(t, paper, "should not return paper when selling more than held")
+
+	paper, err = contract.BuyFraction(ctx, "someissuer", "somepaper", "someowner", "someotherowner", 0, 0, "2020-05-31")
+	assert.EqualError(t, err, "Paper someissuer:somepaper holding of someowner has face value 1000, cannot sell 0", "should error when selling nothing")
+	assert.Nil(t, paper, "should not return paper when selling nothing")
+
+	sentHoldings = nil
+	paper, err = contract.BuyFraction(ctx, "someissuer", "somepaper", "someowner", "someotherowner", 400, 380, "2020-06-30")
+	assert.Nil(t, err, "should not error when good paper and holding")
+	assert.Equal(t, "", paper.Owner, "should clear the owner of a paper held in fractions")
+	assert.Equal(t, []Holding{
+		{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someotherowner", FaceValue: 400, PurchasePrice: 380, PurchaseDateTime: "2020-06-30"},
+		{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someowner", FaceValue: 600, PurchasePrice: 570, PurchaseDateTime: "2020-05-31"},
+	}, sentHoldings, "should add holding for the new owner and reduce holding of the current owner in proportion")
+	mhl.AssertNotCalled(t, "DeleteHolding", "someissuer", "somepaper", "someowner")
+
+	resetHolding(wsHolding)
+	wsHolding.FaceValue = 400
+	wsHolding.PurchasePrice = 380
+	*otherHolding = Holding{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "yetanotherowner", FaceValue: 600, PurchasePrice: 570, PurchaseDateTime: "2020-05-31"}
+	sentHoldings = nil
+	paper, err = contract.BuyFraction(ctx, "someissuer", "somepaper", "someowner", "yetanotherowner", 400, 390, "2020-06-30")
+	assert.Nil(t, err, "should not error when buying the rest of a paper")
+	assert.Equal(t, "yetanotherowner", paper.Owner, "should set the owner of a paper once held whole")
+	assert.Equal(t, []Holding{
+		{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "yetanotherowner", FaceValue: 1000, PurchasePrice: 960, PurchaseDateTime: "2020-06-30"},
+	}, sentHoldings, "should add to the holding of the new owner")
+	mhl.AssertCalled(t, "DeleteHolding", "someissuer", "somepaper", "someowner")
 }
 
 func TestRedeem(t *testing.T) {
@@ -139,22 +332,31 @@ func TestRedeem(t *testing.T) {
 	var err error
 
 	mpl := new(MockPaperList)
+	mhl := new(MockHoldingList)
 	ctx := new(MockTransactionContext)
 	ctx.paperList = mpl
+	ctx.holdingList = mhl
 
 	contract := new(Contract)
 
 	var sentPaper *CommercialPaper
 	wsPaper := new(CommercialPaper)
 	resetPaper(wsPaper)
+	wsHolding := new(Holding)
+	resetHolding(wsHolding)
 
 	var emptyPaper *CommercialPaper
+	var emptyHolding *Holding
 	shouldError := false
 
 	mpl.On("GetPaper", "someissuer", "somepaper").Return(wsPaper, nil)
 	mpl.On("GetPaper", "someotherissuer", "someotherpaper").Return(emptyPaper, errors.New("GetPaper error"))
 	mpl.On("UpdatePaper", mock.MatchedBy(func(paper *CommercialPaper) bool { return shouldError })).Return(errors.New("UpdatePaper error"))
 	mpl.On("UpdatePaper", mock.MatchedBy(func(paper *CommercialPaper) bool { sentPaper = paper; return !shouldError })).Return(nil)
+	mhl.On("GetHolding", "someissuer", "somepaper", "someowner").Return(wsHolding, nil)
+	mhl.On("GetHolding", "someissuer", "somepaper", "someotherowner").Return(emptyHolding, errors.New("GetHolding error"))
+	mhl.On("GetHoldings", "someissuer", "somepaper").Return([]*Holding{wsHolding}, nil)
+	mhl.On("DeleteHolding", "someissuer", "somepaper", "someowner").Return(nil)
 
 	paper, err = contract.Redeem(ctx, "someotherissuer", "someotherpaper", "someowner", "2021-12-10:10:00")
 	assert.EqualError(t, err, "GetPaper error", "should error when GetPaper errors")
@@ -181,5 +383,341 @@ func TestRedeem(t *testing.T) {
 	paper, err = contract.Redeem(ctx, "someissuer", "somepaper", "someowner", "2021-12-10:10:00")
 	assert.Nil(t, err, "should not error on good redeem")
 	assert.True(t, paper.IsRedeemed(), "should return redeemed paper")
+	assert.Equal(t, "someissuer", paper.Owner, "should return redeemed paper to the issuer")
 	assert.Equal(t, sentPaper, paper, "should update same paper as it returns in the world state")
+	mhl.AssertCalled(t, "DeleteHolding", "someissuer", "somepaper", "someowner")
+}
+
+func TestRedeemFraction(t *testing.T) {
+	var paper *CommercialPaper
+	var err error
+
+	mpl := new(MockPaperList)
+	mhl := new(MockHoldingList)
+	ctx := new(MockTransactionContext)
+	ctx.paperList = mpl
+	ctx.holdingList = mhl
+
+	contract := new(Contract)
+
+	wsPaper := new(CommercialPaper)
+	resetPaper(wsPaper)
+	wsPaper.Owner = ""
+	wsHolding := new(Holding)
+	resetHolding(wsHolding)
+	otherHolding := &Holding{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someotherowner"}
+
+	mpl.On("GetPaper", "someissuer", "somepaper").Return(wsPaper, nil)
+	mpl.On("UpdatePaper", wsPaper).Return(nil)
+	mhl.On("GetHolding", "someissuer", "somepaper", "someowner").Return(wsHolding, nil)
+	mhl.On("GetHoldings", "someissuer", "somepaper").Return([]*Holding{wsHolding, otherHolding}, nil).Once()
+	mhl.On("GetHoldings", "someissuer", "somepaper").Return([]*Holding(nil), errors.New("GetHoldings error"))
+	mhl.On("DeleteHolding", "someissuer", "somepaper", "someowner").Return(nil)
+
+	paper, err = contract.Redeem(ctx, "someissuer", "somepaper", "someowner", "2021-12-10:10:00")
+	assert.Nil(t, err, "should not error when redeeming a holding")
+	assert.True(t, paper.IsTrading(), "should not redeem paper while other holdings remain")
+	assert.Equal(t, "", paper.Owner, "should not change the owner of a paper held in fractions")
+	mhl.AssertCalled(t, "DeleteHolding", "someissuer", "somepaper", "someowner")
+
+	paper, err = contract.Redeem(ctx, "someissuer", "somepaper", "someowner", "2021-12-10:10:00")
+	assert.EqualError(t, err, "GetHoldings error", "should error when GetHoldings errors")
+	assert.Nil(t, paper, "should not return paper when GetHoldings errors")
+}
+
+func TestScheduleCoupon(t *testing.T) {
+	var paper *CommercialPaper
+	var err error
+
+	mpl := new(MockPaperList)
+	ctx := new(MockTransactionContext)
+	ctx.paperList = mpl
+
+	contract := new(Contract)
+
+	wsPaper := new(CommercialPaper)
+	resetPaper(wsPaper)
+	wsPaper.IssueDateTime = "2020-05-31"
+	wsPaper.MaturityDateTime = "2020-11-30"
+	var emptyPaper *CommercialPaper
+
+	mpl.On("GetPaper", "someissuer", "somepaper").Return(wsPaper, nil)
+	mpl.On("GetPaper", "someotherissuer", "someotherpaper").Return(emptyPaper, errors.New("GetPaper error"))
+	mpl.On("UpdatePaper", wsPaper).Return(nil)
+
+	paper, err = contract.ScheduleCoupon(ctx, "someotherissuer", "someotherpaper", "2020-08-31", 10)
+	assert.EqualError(t, err, "GetPaper error", "should error when GetPaper errors")
+	assert.Nil(t, paper, "should not return paper when GetPaper errors")
+
+	wsPaper.SetRedeemed()
+	paper, err = contract.ScheduleCoupon(ctx, "someissuer", "somepaper", "2020-08-31", 10)
+	assert.EqualError(t, err, "Paper someissuer:somepaper is already redeemed", "should error when paper already redeemed")
+	assert.Nil(t, paper, "should not return paper when already redeemed")
+
+	wsPaper.SetTrading()
+	paper, err = contract.ScheduleCoupon(ctx, "someissuer", "somepaper", "2020-08-31", 0)
+	assert.EqualError(t, err, "Coupon amount must be positive", "should error for coupon of nothing")
+	assert.Nil(t, paper, "should not return paper for bad amount")
+
+	paper, err = contract.ScheduleCoupon(ctx, "someissuer", "somepaper", "sometime", 10)
+	assert.EqualError(t, err, "Invalid date time sometime", "should error for bad date time")
+	assert.Nil(t, paper, "should not return paper for bad date time")
+
+	paper, err = contract.ScheduleCoupon(ctx, "someissuer", "somepaper", "2020-12-31", 10)
+	assert.EqualError(t, err, "Coupon date time 2020-12-31 is not between issue 2020-05-31 and maturity 2020-11-30", "should error for coupon after maturity")
+	assert.Nil(t, paper, "should not return paper for coupon after maturity")
+
+	paper, err = contract.ScheduleCoupon(ctx, "someissuer", "somepaper", "2020-11-30", 20)
+	assert.Nil(t, err, "should not error for coupon at maturity")
+	paper, err = contract.ScheduleCoupon(ctx, "someissuer", "somepaper", "2020-08-31", 10)
+	assert.Nil(t, err, "should not error for coupon before maturity")
+	assert.Equal(t, []*Coupon{{PaymentDateTime: "2020-08-31", Amount: 10}, {PaymentDateTime: "2020-11-30", Amount: 20}}, paper.Coupons, "should keep the schedule in order of payment")
+	mpl.AssertCalled(t, "UpdatePaper", wsPaper)
+
+	paper, err = contract.ScheduleCoupon(ctx, "someissuer", "somepaper", "2020-08-31", 30)
+	assert.EqualError(t, err, "Paper someissuer:somepaper already has a coupon at 2020-08-31", "should error for second coupon at same date time")
+	assert.Nil(t, paper, "should not return paper for second coupon at same date time")
+}
+
+func TestPayCoupon(t *testing.T) {
+	var paper *CommercialPaper
+	var err error
+
+	mpl := new(MockPaperList)
+	mhl := new(MockHoldingList)
+	ctx := new(MockTransactionContext)
+	ctx.paperList = mpl
+	ctx.holdingList = mhl
+
+	contract := new(Contract)
+
+	wsPaper := new(CommercialPaper)
+	resetPaper(wsPaper)
+	wsPaper.Owner = ""
+	wsPaper.Coupons = []*Coupon{{PaymentDateTime: "2020-08-31", Amount: 10}, {PaymentDateTime: "2020-11-30", Amount: 10}}
+	holdings := []*Holding{
+		{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someowner", FaceValue: 600},
+		{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someotherowner", FaceValue: 250},
+		{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "yetanotherowner", FaceValue: 150},
+	}
+
+	mpl.On("GetPaper", "someissuer", "somepaper").Return(wsPaper, nil)
+	mpl.On("UpdatePaper", wsPaper).Return(nil)
+	mhl.On("GetHoldings", "someissuer", "somepaper").Return([]*Holding(nil), errors.New("GetHoldings error")).Once()
+	mhl.On("GetHoldings", "someissuer", "somepaper").Return(holdings, nil)
+
+	paper, err = contract.PayCoupon(ctx, "someissuer", "somepaper", "2020-09-30")
+	assert.EqualError(t, err, "Paper someissuer:somepaper has no coupon at 2020-09-30", "should error when no coupon scheduled")
+	assert.Nil(t, paper, "should not return paper when no coupon scheduled")
+
+	paper, err = contract.PayCoupon(ctx, "someissuer", "somepaper", "2020-08-31")
+	assert.EqualError(t, err, "GetHoldings error", "should error when GetHoldings errors")
+	assert.Nil(t, paper, "should not return paper when GetHoldings errors")
+
+	paper, err = contract.PayCoupon(ctx, "someissuer", "somepaper", "2020-08-31")
+	assert.Nil(t, err, "should not error for scheduled coupon")
+	assert.Equal(t, &Coupon{PaymentDateTime: "2020-08-31", Amount: 10, Paid: true, Payments: []*CouponPayment{
+		{Owner: "someowner", FaceValue: 600, Amount: 6},
+		{Owner: "someotherowner", FaceValue: 250, Amount: 2},
+		{Owner: "yetanotherowner", FaceValue: 150, Amount: 1},
+	}}, paper.Coupons[0], "should accrue coupon to each holding in proportion to its face value, rounded down")
+	assert.Equal(t, &Coupon{PaymentDateTime: "2020-11-30", Amount: 10}, paper.Coupons[1], "should not pay other coupons")
+	mpl.AssertCalled(t, "UpdatePaper", wsPaper)
+
+	paper, err = contract.PayCoupon(ctx, "someissuer", "somepaper", "2020-08-31")
+	assert.EqualError(t, err, "Coupon of paper someissuer:somepaper at 2020-08-31 is already paid", "should error when coupon already paid")
+	assert.Nil(t, paper, "should not return paper when coupon already paid")
+
+	wsPaper.SetRedeemed()
+	paper, err = contract.PayCoupon(ctx, "someissuer", "somepaper", "2020-11-30")
+	assert.EqualError(t, err, "Paper someissuer:somepaper is already redeemed", "should error when paper already redeemed")
+	assert.Nil(t, paper, "should not return paper when already redeemed")
+}
+
+func TestQueryHoldings(t *testing.T) {
+	mhl := new(MockHoldingList)
+	ctx := new(MockTransactionContext)
+	ctx.holdingList = mhl
+
+	contract := new(Contract)
+
+	holdings := []*Holding{{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someowner"}}
+	mhl.On("GetHoldings", "someissuer", "somepaper").Return(holdings, nil)
+
+	result, err := contract.QueryHoldings(ctx, "someissuer", "somepaper")
+	assert.Nil(t, err, "should not error when GetHoldings does not error")
+	assert.Equal(t, holdings, result, "should return holdings from holding list")
+}
+
+func TestQueryPapersByOwner(t *testing.T) {
+	var papers []*CommercialPaper
+	var err error
+
+	mpl := new(MockPaperList)
+	mhl := new(MockHoldingList)
+	ctx := new(MockTransactionContext)
+	ctx.paperList = mpl
+	ctx.holdingList = mhl
+
+	contract := new(Contract)
+
+	wsPaper := new(CommercialPaper)
+	resetPaper(wsPaper)
+	var emptyPaper *CommercialPaper
+
+	mhl.On("GetAllHoldings").Return([]*Holding(nil), errors.New("GetAllHoldings error")).Once()
+	mhl.On("GetAllHoldings").Return([]*Holding{
+		{Issuer: "someissuer", PaperNumber: "somepaper", Owner: "someowner"},
+		{Issuer: "someotherissuer", PaperNumber: "someotherpaper", Owner: "someotherowner"},
+	}, nil)
+	mpl.On("GetPaper", "someissuer", "somepaper").Return(wsPaper, nil)
+	mpl.On("GetPaper", "someotherissuer", "someotherpaper").Return(emptyPaper, errors.New("GetPaper error"))
+
+	papers, err = contract.QueryPapersByOwner(ctx, "someowner")
+	assert.EqualError(t, err, "GetAllHoldings error", "should error when GetAllHoldings errors")
+	assert.Nil(t, papers, "should not return papers when GetAllHoldings errors")
+
+	papers, err = contract.QueryPapersByOwner(ctx, "someowner")
+	assert.Nil(t, err, "should not error when holdings and papers found")
+	assert.Equal(t, []*CommercialPaper{wsPaper}, papers, "should return papers held by owner")
+
+	papers, err = contract.QueryPapersByOwner(ctx, "nobody")
+	assert.Nil(t, err, "should not error when owner holds nothing")
+	assert.Equal(t, []*CommercialPaper{}, papers, "should return no papers when owner holds nothing")
+
+	papers, err = contract.QueryPapersByOwner(ctx, "someotherowner")
+	assert.EqualError(t, err, "GetPaper error", "should error when GetPaper errors")
+	assert.Nil(t, papers, "should not return papers when GetPaper errors")
+}
+
+func TestQueryPapersByState(t *testing.T) {
+	var papers []*CommercialPaper
+	var err error
+
+	mpl := new(MockPaperList)
+	ctx := new(MockTransactionContext)
+	ctx.paperList = mpl
+
+	contract := new(Contract)
+
+	issuedPaper := new(CommercialPaper)
+	issuedPaper.SetIssued()
+	tradingPaper := new(CommercialPaper)
+	tradingPaper.SetTrading()
+
+	mpl.On("GetAllPapers").Return([]*CommercialPaper{issuedPaper, tradingPaper}, nil).Once()
+	mpl.On("GetAllPapers").Return([]*CommercialPaper(nil), errors.New("GetAllPapers error"))
+
+	papers, err = contract.QueryPapersByState(ctx, "UNKNOWN")
+	assert.EqualError(t, err, "Unknown state UNKNOWN", "should error for unknown state")
+	assert.Nil(t, papers, "should not return papers for unknown state")
+
+	papers, err = contract.QueryPapersByState(ctx, "TRADING")
+	assert.Nil(t, err, "should not error when GetAllPapers does not error")
+	assert.Equal(t, []*CommercialPaper{tradingPaper}, papers, "should return papers in the state")
+
+	papers, err = contract.QueryPapersByState(ctx, "TRADING")
+	assert.EqualError(t, err, "GetAllPapers error", "should error when GetAllPapers errors")
+	assert.Nil(t, papers, "should not return papers when GetAllPapers errors")
+}
+
+func TestQueryPapersByMaturity(t *testing.T) {
+	var papers []*CommercialPaper
+	var err error
+
+	mpl := new(MockPaperList)
+	ctx := new(MockTransactionContext)
+	ctx.paperList = mpl
+
+	contract := new(Contract)
+
+	earlyPaper := &CommercialPaper{MaturityDateTime: "2020-11-30"}
+	latePaper := &CommercialPaper{MaturityDateTime: "2021-05-31:10:00"}
+	badPaper := &CommercialPaper{MaturityDateTime: "somematuritydate"}
+
+	mpl.On("GetAllPapers").Return([]*CommercialPaper{earlyPaper, latePaper, badPaper}, nil)
+
+	papers, err = contract.QueryPapersByMaturity(ctx, "sometime", "2020-12-31")
+	assert.EqualError(t, err, "Invalid date time sometime", "should error for bad from date time")
+	assert.Nil(t, papers, "should not return papers for bad from date time")
+
+	papers, err = contract.QueryPapersByMaturity(ctx, "2020-01-01", "sometime")
+	assert.EqualError(t, err, "Invalid date time sometime", "should error for bad to date time")
+	assert.Nil(t, papers, "should not return papers for bad to date time")
+
+	papers, err = contract.QueryPapersByMaturity(ctx, "2020-01-01", "2020-11-30")
+	assert.Nil(t, err, "should not error for good date times")
+	assert.Equal(t, []*CommercialPaper{earlyPaper}, papers, "should return papers maturing in the range, inclusive")
+
+	papers, err = contract.QueryPapersByMaturity(ctx, "2020-01-01", "2021-12-31")
+	assert.Nil(t, err, "should not error for good date times")
+	assert.Equal(t, []*CommercialPaper{earlyPaper, latePaper}, papers, "should skip papers with bad maturity")
+}
+
+func TestGetYield(t *testing.T) {
+	var yield float64
+	var err error
+
+	mpl := new(MockPaperList)
+	mhl := new(MockHoldingList)
+	ctx := new(MockTransactionContext)
+	ctx.paperList = mpl
+	ctx.holdingList = mhl
+
+	contract := new(Contract)
+
+	wsPaper := new(CommercialPaper)
+	resetPaper(wsPaper)
+	wsPaper.MaturityDateTime = "2020-11-30"
+	wsHolding := new(Holding)
+	resetHolding(wsHolding)
+
+	var emptyPaper *CommercialPaper
+	var emptyHolding *Holding
+
+	mpl.On("GetPaper", "someissuer", "somepaper").Return(wsPaper, nil)
+	mpl.On("GetPaper", "someotherissuer", "someotherpaper").Return(emptyPaper, errors.New("GetPaper error"))
+	mhl.On("GetHolding", "someissuer", "somepaper", "someowner").Return(wsHolding, nil)
+	mhl.On("GetHolding", "someissuer", "somepaper", "someotherowner").Return(emptyHolding, errors.New("GetHolding error"))
+
+	_, err = contract.GetYield(ctx, "someotherissuer", "someotherpaper", "someowner")
+	assert.EqualError(t, err, "GetPaper error", "should error when GetPaper errors")
+
+	_, err = contract.GetYield(ctx, "someissuer", "somepaper", "someotherowner")
+	assert.EqualError(t, err, "Paper someissuer:somepaper is not owned by someotherowner", "should error when owner has no holding")
+
+	yield, err = contract.GetYield(ctx, "someissuer", "somepaper", "someowner")
+	assert.Nil(t, err, "should not error for good holding")
+	assert.InDelta(t, 0.1050, yield, 0.0001, "should return yield of holding to maturity of paper")
+}
+
+func TestGetAccruedCoupons(t *testing.T) {
+	mpl := new(MockPaperList)
+	ctx := new(MockTransactionContext)
+	ctx.paperList = mpl
+
+	contract := new(Contract)
+
+	wsPaper := new(CommercialPaper)
+	resetPaper(wsPaper)
+	wsPaper.Coupons = []*Coupon{
+		{PaymentDateTime: "2020-08-31", Amount: 10, Paid: true, Payments: []*CouponPayment{{Owner: "someowner", FaceValue: 1000, Amount: 10}}},
+		{PaymentDateTime: "2020-09-30", Amount: 10, Paid: true, Payments: []*CouponPayment{{Owner: "someowner", FaceValue: 400, Amount: 4}, {Owner: "someotherowner", FaceValue: 600, Amount: 6}}},
+		{PaymentDateTime: "2020-10-31", Amount: 10},
+	}
+	var emptyPaper *CommercialPaper
+
+	mpl.On("GetPaper", "someissuer", "somepaper").Return(wsPaper, nil)
+	mpl.On("GetPaper", "someotherissuer", "someotherpaper").Return(emptyPaper, errors.New("GetPaper error"))
+
+	_, err := contract.GetAccruedCoupons(ctx, "someotherissuer", "someotherpaper", "someowner")
+	assert.EqualError(t, err, "GetPaper error", "should error when GetPaper errors")
+
+	accrued, err := contract.GetAccruedCoupons(ctx, "someissuer", "somepaper", "someowner")
+	assert.Nil(t, err, "should not error for good paper")
+	assert.Equal(t, 14, accrued, "should sum the coupons paid to the owner")
+
+	accrued, err = contract.GetAccruedCoupons(ctx, "someissuer", "somepaper", "nobody")
+	assert.Nil(t, err, "should not error when owner was paid nothing")
+	assert.Equal(t, 0, accrued, "should return nothing when owner was paid nothing")
 }
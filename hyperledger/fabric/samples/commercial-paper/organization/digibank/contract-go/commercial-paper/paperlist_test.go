@@ -30,7 +30,12 @@ func (msl *MockStateList) AddState(state ledgerapi.StateInterface) error {
 func (msl *MockStateList) GetState(key string, state ledgerapi.StateInterface) error {
 	args := msl.Called(key, state)
 
-	state.(*CommercialPaper).PaperNumber = "somepaper"
+	switch s := state.(type) {
+	case *CommercialPaper:
+		s.PaperNumber = "somepaper"
+	case *Holding:
+		s.PaperNumber = "somepaper"
+	}
 
 	return args.Error(0)
 }
@@ -41,6 +46,18 @@ func (msl *MockStateList) UpdateState(state ledgerapi.StateInterface) error {
 	return args.Error(0)
 }
 
+func (msl *MockStateList) DeleteState(key string) error {
+	args := msl.Called(key)
+
+	return args.Error(0)
+}
+
+func (msl *MockStateList) GetStates(keyParts []string, newState func() ledgerapi.StateInterface) ([]ledgerapi.StateInterface, error) {
+	args := msl.Called(keyParts)
+
+	return args.Get(0).([]ledgerapi.StateInterface), args.Error(1)
+}
+
 // #########
 // TESTS
 // #########
@@ -88,6 +105,28 @@ func TestUpdatePaper(t *testing.T) {
 	assert.EqualError(t, err, "Called update state correctly", "should call state list update state with paper")
 }
 
+func TestGetAllPapers(t *testing.T) {
+	var papers []*CommercialPaper
+	var err error
+
+	paper := new(CommercialPaper)
+	paper.PaperNumber = "somepaper"
+
+	list := new(list)
+	msl := new(MockStateList)
+	msl.On("GetStates", []string{}).Return([]ledgerapi.StateInterface{paper}, nil).Once()
+	msl.On("GetStates", []string{}).Return([]ledgerapi.StateInterface(nil), errors.New("GetStates error"))
+	list.stateList = msl
+
+	papers, err = list.GetAllPapers()
+	assert.Nil(t, err, "should not error when get states on state list does not error")
+	assert.Equal(t, []*CommercialPaper{paper}, papers, "should return papers from state list GetStates")
+
+	papers, err = list.GetAllPapers()
+	assert.EqualError(t, err, "GetStates error", "should return error when state list get states errors")
+	assert.Nil(t, papers, "should not return papers on error")
+}
+
 func TestNewStateList(t *testing.T) {
 	ctx := new(TransactionContext)
 	list := newList(ctx)
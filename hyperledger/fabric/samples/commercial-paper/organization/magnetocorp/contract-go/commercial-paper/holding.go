@@ -0,0 +1,95 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package commercialpaper
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	ledgerapi "github.com/hyperledger/fabric-samples/commercial-paper/organization/magnetocorp/contract-go/ledger-api"
+)
+
+// dateTimeLayouts are the layouts accepted for the date times of papers and holdings
+var dateTimeLayouts = []string{time.RFC3339, "2006-01-02:15:04", "2006-01-02"}
+
+// CreateHoldingKey creates a key for holdings of commercial papers
+func CreateHoldingKey(issuer string, paperNumber string, owner string) string {
+	return ledgerapi.MakeKey(issuer, paperNumber, owner)
+}
+
+// Holding defines the part of the face value of a commercial paper
+// held by an owner, with the price the owner paid for it
+type Holding struct {
+	Issuer           string `json:"issuer"`
+	PaperNumber      string `json:"paperNumber"`
+	Owner            string `json:"owner"`
+	FaceValue        int    `json:"faceValue"`
+	PurchasePrice    int    `json:"purchasePrice"`
+	PurchaseDateTime string `json:"purchaseDateTime"`
+}
+
+// GetSplitKey returns values which should be used to form key
+func (h *Holding) GetSplitKey() []string {
+	return []string{h.Issuer, h.PaperNumber, h.Owner}
+}
+
+// Serialize formats the holding as JSON bytes
+func (h *Holding) Serialize() ([]byte, error) {
+	return json.Marshal(h)
+}
+
+// DeserializeHolding formats the holding from JSON bytes
+func DeserializeHolding(bytes []byte, h *Holding) error {
+	err := json.Unmarshal(bytes, h)
+
+	if err != nil {
+		return fmt.Errorf("Error deserializing holding. %s", err.Error())
+	}
+
+	return nil
+}
+
+// CalculateYield returns the annualised yield of a commercial paper
+// bought at a discount to its face value, which is the return of the
+// face value on the purchase price over the days from purchase to
+// maturity, on a 365 day basis
+func CalculateYield(faceValue int, purchasePrice int, purchaseDateTime string, maturityDateTime string) (float64, error) {
+	if purchasePrice <= 0 {
+		return 0, fmt.Errorf("Purchase price must be positive to calculate yield")
+	}
+
+	purchased, err := parseDateTime(purchaseDateTime)
+
+	if err != nil {
+		return 0, err
+	}
+
+	matures, err := parseDateTime(maturityDateTime)
+
+	if err != nil {
+		return 0, err
+	}
+
+	days := matures.Sub(purchased).Hours() / 24
+
+	if days <= 0 {
+		return 0, fmt.Errorf("Maturity %s is not after purchase %s", maturityDateTime, purchaseDateTime)
+	}
+
+	return float64(faceValue-purchasePrice) / float64(purchasePrice) * 365 / days, nil
+}
+
+func parseDateTime(value string) (time.Time, error) {
+	for _, layout := range dateTimeLayouts {
+		t, err := time.Parse(layout, value)
+
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("Invalid date time %s", value)
+}
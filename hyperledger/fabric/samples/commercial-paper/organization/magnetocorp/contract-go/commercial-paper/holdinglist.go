@@ -0,0 +1,86 @@
+/*
+ * SPDX-License-Identifier: Apache-2.0
+ */
+
+package commercialpaper
+
+import ledgerapi "github.com/hyperledger/fabric-samples/commercial-paper/organization/magnetocorp/contract-go/ledger-api"
+
+// HoldingListInterface defines functionality needed
+// to interact with the world state on behalf
+// of the holdings of commercial papers
+type HoldingListInterface interface {
+	AddHolding(*Holding) error
+	GetHolding(string, string, string) (*Holding, error)
+	UpdateHolding(*Holding) error
+	DeleteHolding(string, string, string) error
+	GetHoldings(string, string) ([]*Holding, error)
+	GetAllHoldings() ([]*Holding, error)
+}
+
+type holdingList struct {
+	stateList ledgerapi.StateListInterface
+}
+
+func (hl *holdingList) AddHolding(holding *Holding) error {
+	return hl.stateList.AddState(holding)
+}
+
+func (hl *holdingList) GetHolding(issuer string, paperNumber string, owner string) (*Holding, error) {
+	h := new(Holding)
+
+	err := hl.stateList.GetState(CreateHoldingKey(issuer, paperNumber, owner), h)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (hl *holdingList) UpdateHolding(holding *Holding) error {
+	return hl.stateList.UpdateState(holding)
+}
+
+func (hl *holdingList) DeleteHolding(issuer string, paperNumber string, owner string) error {
+	return hl.stateList.DeleteState(CreateHoldingKey(issuer, paperNumber, owner))
+}
+
+func (hl *holdingList) GetHoldings(issuer string, paperNumber string) ([]*Holding, error) {
+	return hl.getHoldings([]string{issuer, paperNumber})
+}
+
+func (hl *holdingList) GetAllHoldings() ([]*Holding, error) {
+	return hl.getHoldings([]string{})
+}
+
+func (hl *holdingList) getHoldings(keyParts []string) ([]*Holding, error) {
+	states, err := hl.stateList.GetStates(keyParts, func() ledgerapi.StateInterface { return new(Holding) })
+
+	if err != nil {
+		return nil, err
+	}
+
+	holdings := []*Holding{}
+
+	for _, state := range states {
+		holdings = append(holdings, state.(*Holding))
+	}
+
+	return holdings, nil
+}
+
+// newHoldingList create a new holding list from context
+func newHoldingList(ctx TransactionContextInterface) *holdingList {
+	stateList := new(ledgerapi.StateList)
+	stateList.Ctx = ctx
+	stateList.Name = "org.papernet.commercialpaperholdinglist"
+	stateList.Deserialize = func(bytes []byte, state ledgerapi.StateInterface) error {
+		return DeserializeHolding(bytes, state.(*Holding))
+	}
+
+	list := new(holdingList)
+	list.stateList = stateList
+
+	return list
+}
@@ -47,17 +47,51 @@ type jsonCommercialPaper struct {
 	Key   string `json:"key"`
 }
 
-// CommercialPaper defines a commercial paper
+// CommercialPaper defines a commercial paper. The owner is
+// the holder of its whole face value, and is empty while the
+// paper is held in fractions
 type CommercialPaper struct {
-	PaperNumber      string `json:"paperNumber"`
-	Issuer           string `json:"issuer"`
-	IssueDateTime    string `json:"issueDateTime"`
-	FaceValue        int    `json:"faceValue"`
-	MaturityDateTime string `json:"maturityDateTime"`
-	Owner            string `json:"owner"`
-	state            State  `metadata:"currentState"`
-	class            string `metadata:"class"`
-	key              string `metadata:"key"`
+	PaperNumber      string    `json:"paperNumber"`
+	Issuer           string    `json:"issuer"`
+	IssueDateTime    string    `json:"issueDateTime"`
+	FaceValue        int       `json:"faceValue"`
+	MaturityDateTime string    `json:"maturityDateTime"`
+	Owner            string    `json:"owner"`
+	Coupons          []*Coupon `json:"coupons,omitempty" metadata:",optional"`
+	state            State     `metadata:"currentState"`
+	class            string    `metadata:"class"`
+	key              string    `metadata:"key"`
+}
+
+// Coupon defines a scheduled coupon of a commercial paper. The
+// amount is paid on the whole face value, and is shared among
+// the holdings of the paper in proportion to their face value
+// when the coupon is paid
+type Coupon struct {
+	PaymentDateTime string           `json:"paymentDateTime"`
+	Amount          int              `json:"amount"`
+	Paid            bool             `json:"paid"`
+	Payments        []*CouponPayment `json:"payments,omitempty" metadata:",optional"`
+}
+
+// CouponPayment defines the part of a coupon accrued to the
+// owner of a holding, with the face value it was held for
+type CouponPayment struct {
+	Owner     string `json:"owner"`
+	FaceValue int    `json:"faceValue"`
+	Amount    int    `json:"amount"`
+}
+
+// GetCoupon returns the coupon of the paper scheduled at a
+// payment date time, or nil if there is none
+func (cp *CommercialPaper) GetCoupon(paymentDateTime string) *Coupon {
+	for _, coupon := range cp.Coupons {
+		if coupon.PaymentDateTime == paymentDateTime {
+			return coupon
+		}
+	}
+
+	return nil
 }
 
 // UnmarshalJSON special handler for managing JSON marshalling
@@ -85,6 +85,15 @@ func TestGetSplitKey(t *testing.T) {
 	assert.Equal(t, []string{"someissuer", "somepaper"}, cp.GetSplitKey(), "should return issuer and paper number as split key")
 }
 
+func TestGetCoupon(t *testing.T) {
+	cp := new(CommercialPaper)
+	coupon := &Coupon{PaymentDateTime: "2020-08-31", Amount: 10}
+	cp.Coupons = []*Coupon{{PaymentDateTime: "2020-07-31", Amount: 10}, coupon}
+
+	assert.Equal(t, coupon, cp.GetCoupon("2020-08-31"), "should return coupon at payment date time")
+	assert.Nil(t, cp.GetCoupon("2020-09-30"), "should return nil when no coupon at payment date time")
+}
+
 func TestSerialize(t *testing.T) {
 	cp := new(CommercialPaper)
 	cp.PaperNumber = "somepaper"
@@ -98,6 +107,11 @@ func TestSerialize(t *testing.T) {
 	bytes, err := cp.Serialize()
 	assert.Nil(t, err, "should not error on serialize")
 	assert.Equal(t, `{"paperNumber":"somepaper","issuer":"someissuer","issueDateTime":"sometime","faceValue":1000,"maturityDateTime":"somelatertime","owner":"someowner","currentState":2,"class":"org.papernet.commercialpaper","key":"someissuer:somepaper"}`, string(bytes), "should return JSON formatted value")
+
+	cp.Coupons = []*Coupon{{PaymentDateTime: "sometime", Amount: 10, Paid: true, Payments: []*CouponPayment{{Owner: "someowner", FaceValue: 1000, Amount: 10}}}}
+	bytes, err = cp.Serialize()
+	assert.Nil(t, err, "should not error on serialize")
+	assert.Equal(t, `{"paperNumber":"somepaper","issuer":"someissuer","issueDateTime":"sometime","faceValue":1000,"maturityDateTime":"somelatertime","owner":"someowner","coupons":[{"paymentDateTime":"sometime","amount":10,"paid":true,"payments":[{"owner":"someowner","faceValue":1000,"amount":10}]}],"currentState":2,"class":"org.papernet.commercialpaper","key":"someissuer:somepaper"}`, string(bytes), "should include coupons in JSON")
 }
 
 func TestDeserialize(t *testing.T) {
@@ -5,9 +5,12 @@
 package commercialpaper
 
 import (
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	ledgerapi "github.com/hyperledger/fabric-samples/commercial-paper/organization/magnetocorp/contract-go/ledger-api"
 )
 
 // Contract chaincode that defines
@@ -22,7 +25,8 @@ func (c *Contract) Instantiate() {
 	fmt.Println("Instantiated")
 }
 
-// Issue creates a new commercial paper and stores it in the world state
+// Issue creates a new commercial paper and stores it in the world state,
+// with the issuer holding its whole face value
 func (c *Contract) Issue(ctx TransactionContextInterface, issuer string, paperNumber string, issueDateTime string, maturityDateTime string, faceValue int) (*CommercialPaper, error) {
 	paper := CommercialPaper{PaperNumber: paperNumber, Issuer: issuer, IssueDateTime: issueDateTime, FaceValue: faceValue, MaturityDateTime: maturityDateTime, Owner: issuer}
 	paper.SetIssued()
@@ -33,10 +37,19 @@ func (c *Contract) Issue(ctx TransactionContextInterface, issuer string, paperNu
 		return nil, err
 	}
 
+	holding := Holding{Issuer: issuer, PaperNumber: paperNumber, Owner: issuer, FaceValue: faceValue, PurchaseDateTime: issueDateTime}
+
+	err = ctx.GetHoldingList().AddHolding(&holding)
+
+	if err != nil {
+		return nil, err
+	}
+
 	return &paper, nil
 }
 
-// Buy updates a commercial paper to be in trading status and sets the new owner
+// Buy updates a commercial paper to be in trading status and sets the new owner,
+// recording the price the new owner paid for it
 func (c *Contract) Buy(ctx TransactionContextInterface, issuer string, paperNumber string, currentOwner string, newOwner string, price int, purchaseDateTime string) (*CommercialPaper, error) {
 	paper, err := ctx.GetPaperList().GetPaper(issuer, paperNumber)
 
@@ -56,7 +69,44 @@ func (c *Contract) Buy(ctx TransactionContextInterface, issuer string, paperNumb
 		return nil, fmt.Errorf("Paper %s:%s is not trading. Current state = %s", issuer, paperNumber, paper.GetState())
 	}
 
-	paper.Owner = newOwner
+	err = transferHolding(ctx, paper, currentOwner, newOwner, paper.FaceValue, price, purchaseDateTime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.GetPaperList().UpdatePaper(paper)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return paper, nil
+}
+
+// BuyFraction updates a commercial paper to be in trading status and moves part
+// of the face value held by the current owner to the new owner, recording the
+// price the new owner paid for it
+func (c *Contract) BuyFraction(ctx TransactionContextInterface, issuer string, paperNumber string, currentOwner string, newOwner string, faceValue int, price int, purchaseDateTime string) (*CommercialPaper, error) {
+	paper, err := ctx.GetPaperList().GetPaper(issuer, paperNumber)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if paper.IsIssued() {
+		paper.SetTrading()
+	}
+
+	if !paper.IsTrading() {
+		return nil, fmt.Errorf("Paper %s:%s is not trading. Current state = %s", issuer, paperNumber, paper.GetState())
+	}
+
+	err = transferHolding(ctx, paper, currentOwner, newOwner, faceValue, price, purchaseDateTime)
+
+	if err != nil {
+		return nil, err
+	}
 
 	err = ctx.GetPaperList().UpdatePaper(paper)
 
@@ -67,7 +117,8 @@ func (c *Contract) Buy(ctx TransactionContextInterface, issuer string, paperNumb
 	return paper, nil
 }
 
-// Redeem updates a commercial paper status to be redeemed
+// Redeem redeems the holding of the redeeming owner in a commercial paper, and
+// updates the paper status to be redeemed once all of its holdings are redeemed
 func (c *Contract) Redeem(ctx TransactionContextInterface, issuer string, paperNumber string, redeemingOwner string, redeenDateTime string) (*CommercialPaper, error) {
 	paper, err := ctx.GetPaperList().GetPaper(issuer, paperNumber)
 
@@ -75,16 +126,152 @@ func (c *Contract) Redeem(ctx TransactionContextInterface, issuer string, paperN
 		return nil, err
 	}
 
-	if paper.Owner != redeemingOwner {
+	if paper.IsRedeemed() {
+		return nil, fmt.Errorf("Paper %s:%s is already redeemed", issuer, paperNumber)
+	}
+
+	_, err = ctx.GetHoldingList().GetHolding(issuer, paperNumber, redeemingOwner)
+
+	if err != nil {
 		return nil, fmt.Errorf("Paper %s:%s is not owned by %s", issuer, paperNumber, redeemingOwner)
 	}
 
+	holdings, err := ctx.GetHoldingList().GetHoldings(issuer, paperNumber)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = ctx.GetHoldingList().DeleteHolding(issuer, paperNumber, redeemingOwner)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The deleted holding is still in the world state until the transaction commits
+	outstanding := 0
+	for _, holding := range holdings {
+		if holding.Owner != redeemingOwner {
+			outstanding++
+		}
+	}
+
+	if outstanding == 0 {
+		paper.Owner = paper.Issuer
+		paper.SetRedeemed()
+	}
+
+	err = ctx.GetPaperList().UpdatePaper(paper)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return paper, nil
+}
+
+// ScheduleCoupon adds a coupon of an amount on the whole face value of a
+// commercial paper to its schedule, payable at a date time between the
+// issue and the maturity of the paper
+func (c *Contract) ScheduleCoupon(ctx TransactionContextInterface, issuer string, paperNumber string, paymentDateTime string, amount int) (*CommercialPaper, error) {
+	paper, err := ctx.GetPaperList().GetPaper(issuer, paperNumber)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if paper.IsRedeemed() {
+		return nil, fmt.Errorf("Paper %s:%s is already redeemed", issuer, paperNumber)
+	}
+
+	if amount <= 0 {
+		return nil, fmt.Errorf("Coupon amount must be positive")
+	}
+
+	payment, err := parseDateTime(paymentDateTime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	issued, err := parseDateTime(paper.IssueDateTime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	matures, err := parseDateTime(paper.MaturityDateTime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Before(issued) || payment.After(matures) {
+		return nil, fmt.Errorf("Coupon date time %s is not between issue %s and maturity %s", paymentDateTime, paper.IssueDateTime, paper.MaturityDateTime)
+	}
+
+	if paper.GetCoupon(paymentDateTime) != nil {
+		return nil, fmt.Errorf("Paper %s:%s already has a coupon at %s", issuer, paperNumber, paymentDateTime)
+	}
+
+	paper.Coupons = append(paper.Coupons, &Coupon{PaymentDateTime: paymentDateTime, Amount: amount})
+
+	// The date times of the coupons were parsed when they were scheduled
+	sort.SliceStable(paper.Coupons, func(i, j int) bool {
+		a, _ := parseDateTime(paper.Coupons[i].PaymentDateTime)
+		b, _ := parseDateTime(paper.Coupons[j].PaymentDateTime)
+		return a.Before(b)
+	})
+
+	err = ctx.GetPaperList().UpdatePaper(paper)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return paper, nil
+}
+
+// PayCoupon pays a scheduled coupon of a commercial paper to the owners of
+// its holdings. Each holding accrues the part of the coupon amount in
+// proportion to its face value, rounded down, which is recorded with the
+// coupon so that it is kept when the holding is later sold or redeemed
+func (c *Contract) PayCoupon(ctx TransactionContextInterface, issuer string, paperNumber string, paymentDateTime string) (*CommercialPaper, error) {
+	paper, err := ctx.GetPaperList().GetPaper(issuer, paperNumber)
+
+	if err != nil {
+		return nil, err
+	}
+
 	if paper.IsRedeemed() {
 		return nil, fmt.Errorf("Paper %s:%s is already redeemed", issuer, paperNumber)
 	}
 
-	paper.Owner = paper.Issuer
-	paper.SetRedeemed()
+	coupon := paper.GetCoupon(paymentDateTime)
+
+	if coupon == nil {
+		return nil, fmt.Errorf("Paper %s:%s has no coupon at %s", issuer, paperNumber, paymentDateTime)
+	}
+
+	if coupon.Paid {
+		return nil, fmt.Errorf("Coupon of paper %s:%s at %s is already paid", issuer, paperNumber, paymentDateTime)
+	}
+
+	holdings, err := ctx.GetHoldingList().GetHoldings(issuer, paperNumber)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, holding := range holdings {
+		coupon.Payments = append(coupon.Payments, &CouponPayment{
+			Owner:     holding.Owner,
+			FaceValue: holding.FaceValue,
+			Amount:    coupon.Amount * holding.FaceValue / paper.FaceValue,
+		})
+	}
+
+	coupon.Paid = true
 
 	err = ctx.GetPaperList().UpdatePaper(paper)
 
@@ -94,3 +281,206 @@ func (c *Contract) Redeem(ctx TransactionContextInterface, issuer string, paperN
 
 	return paper, nil
 }
+
+// QueryHoldings returns the holdings of a commercial paper, with the
+// prices their owners paid for them
+func (c *Contract) QueryHoldings(ctx TransactionContextInterface, issuer string, paperNumber string) ([]*Holding, error) {
+	return ctx.GetHoldingList().GetHoldings(issuer, paperNumber)
+}
+
+// QueryPapersByOwner returns the commercial papers in which an owner holds
+// all or part of the face value
+func (c *Contract) QueryPapersByOwner(ctx TransactionContextInterface, owner string) ([]*CommercialPaper, error) {
+	holdings, err := ctx.GetHoldingList().GetAllHoldings()
+
+	if err != nil {
+		return nil, err
+	}
+
+	papers := []*CommercialPaper{}
+
+	for _, holding := range holdings {
+		if holding.Owner != owner {
+			continue
+		}
+
+		paper, err := ctx.GetPaperList().GetPaper(holding.Issuer, holding.PaperNumber)
+
+		if err != nil {
+			return nil, err
+		}
+
+		papers = append(papers, paper)
+	}
+
+	return papers, nil
+}
+
+// QueryPapersByState returns the commercial papers in a state, which is
+// one of ISSUED, TRADING or REDEEMED
+func (c *Contract) QueryPapersByState(ctx TransactionContextInterface, state string) ([]*CommercialPaper, error) {
+	if state != ISSUED.String() && state != TRADING.String() && state != REDEEMED.String() {
+		return nil, fmt.Errorf("Unknown state %s", state)
+	}
+
+	papers, err := ctx.GetPaperList().GetAllPapers()
+
+	if err != nil {
+		return nil, err
+	}
+
+	matching := []*CommercialPaper{}
+
+	for _, paper := range papers {
+		if paper.GetState().String() == state {
+			matching = append(matching, paper)
+		}
+	}
+
+	return matching, nil
+}
+
+// QueryPapersByMaturity returns the commercial papers that mature between
+// two date times, inclusive
+func (c *Contract) QueryPapersByMaturity(ctx TransactionContextInterface, fromDateTime string, toDateTime string) ([]*CommercialPaper, error) {
+	from, err := parseDateTime(fromDateTime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := parseDateTime(toDateTime)
+
+	if err != nil {
+		return nil, err
+	}
+
+	papers, err := ctx.GetPaperList().GetAllPapers()
+
+	if err != nil {
+		return nil, err
+	}
+
+	matching := []*CommercialPaper{}
+
+	for _, paper := range papers {
+		maturity, err := parseDateTime(paper.MaturityDateTime)
+
+		if err != nil {
+			continue
+		}
+
+		if !maturity.Before(from) && !maturity.After(to) {
+			matching = append(matching, paper)
+		}
+	}
+
+	return matching, nil
+}
+
+// GetYield returns the annualised yield of the holding of an owner in a
+// commercial paper, from its face value, purchase price and the maturity
+// of the paper
+func (c *Contract) GetYield(ctx TransactionContextInterface, issuer string, paperNumber string, owner string) (float64, error) {
+	paper, err := ctx.GetPaperList().GetPaper(issuer, paperNumber)
+
+	if err != nil {
+		return 0, err
+	}
+
+	holding, err := ctx.GetHoldingList().GetHolding(issuer, paperNumber, owner)
+
+	if err != nil {
+		return 0, fmt.Errorf("Paper %s:%s is not owned by %s", issuer, paperNumber, owner)
+	}
+
+	return CalculateYield(holding.FaceValue, holding.PurchasePrice, holding.PurchaseDateTime, paper.MaturityDateTime)
+}
+
+// GetAccruedCoupons returns the total of the coupons of a commercial paper
+// paid to an owner for its holdings
+func (c *Contract) GetAccruedCoupons(ctx TransactionContextInterface, issuer string, paperNumber string, owner string) (int, error) {
+	paper, err := ctx.GetPaperList().GetPaper(issuer, paperNumber)
+
+	if err != nil {
+		return 0, err
+	}
+
+	accrued := 0
+
+	for _, coupon := range paper.Coupons {
+		for _, payment := range coupon.Payments {
+			if payment.Owner == owner {
+				accrued += payment.Amount
+			}
+		}
+	}
+
+	return accrued, nil
+}
+
+// transferHolding moves part of the face value of a paper held by the current
+// owner to the new owner. The purchase price of the current owner is reduced in
+// proportion, and the price is added to the purchase price of the new owner.
+// The paper is owned by the new owner if it now holds the whole face value.
+func transferHolding(ctx TransactionContextInterface, paper *CommercialPaper, currentOwner string, newOwner string, faceValue int, price int, purchaseDateTime string) error {
+	if currentOwner == newOwner {
+		return fmt.Errorf("Paper %s:%s cannot be sold by %s to itself", paper.Issuer, paper.PaperNumber, currentOwner)
+	}
+
+	seller, err := ctx.GetHoldingList().GetHolding(paper.Issuer, paper.PaperNumber, currentOwner)
+
+	if errors.Is(err, ledgerapi.ErrStateNotFound) {
+		return fmt.Errorf("Paper %s:%s is not owned by %s", paper.Issuer, paper.PaperNumber, currentOwner)
+	} else if err != nil {
+		return err
+	}
+
+	if faceValue <= 0 || faceValue > seller.FaceValue {
+		return fmt.Errorf("Paper %s:%s holding of %s has face value %d, cannot sell %d", paper.Issuer, paper.PaperNumber, currentOwner, seller.FaceValue, faceValue)
+	}
+
+	if price < 0 {
+		return fmt.Errorf("Price must not be negative")
+	}
+
+	buyer, err := ctx.GetHoldingList().GetHolding(paper.Issuer, paper.PaperNumber, newOwner)
+
+	if errors.Is(err, ledgerapi.ErrStateNotFound) {
+		buyer = &Holding{Issuer: paper.Issuer, PaperNumber: paper.PaperNumber, Owner: newOwner}
+	} else if err != nil {
+		return err
+	}
+
+	buyer.FaceValue += faceValue
+	buyer.PurchasePrice += price
+	buyer.PurchaseDateTime = purchaseDateTime
+
+	err = ctx.GetHoldingList().UpdateHolding(buyer)
+
+	if err != nil {
+		return err
+	}
+
+	remaining := seller.FaceValue - faceValue
+
+	if remaining == 0 {
+		err = ctx.GetHoldingList().DeleteHolding(paper.Issuer, paper.PaperNumber, currentOwner)
+	} else {
+		seller.PurchasePrice = seller.PurchasePrice * remaining / seller.FaceValue
+		seller.FaceValue = remaining
+		err = ctx.GetHoldingList().UpdateHolding(seller)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if buyer.FaceValue == paper.FaceValue {
+		paper.Owner = newOwner
+	} else {
+		paper.Owner = ""
+	}
+
+	return nil
+}
@@ -13,6 +13,7 @@ type ListInterface interface {
 	AddPaper(*CommercialPaper) error
 	GetPaper(string, string) (*CommercialPaper, error)
 	UpdatePaper(*CommercialPaper) error
+	GetAllPapers() ([]*CommercialPaper, error)
 }
 
 type list struct {
@@ -39,6 +40,22 @@ func (cpl *list) UpdatePaper(paper *CommercialPaper) error {
 	return cpl.stateList.UpdateState(paper)
 }
 
+func (cpl *list) GetAllPapers() ([]*CommercialPaper, error) {
+	states, err := cpl.stateList.GetStates([]string{}, func() ledgerapi.StateInterface { return new(CommercialPaper) })
+
+	if err != nil {
+		return nil, err
+	}
+
+	papers := []*CommercialPaper{}
+
+	for _, state := range states {
+		papers = append(papers, state.(*CommercialPaper))
+	}
+
+	return papers, nil
+}
+
 // NewList create a new list from context
 func newList(ctx TransactionContextInterface) *list {
 	stateList := new(ledgerapi.StateList)
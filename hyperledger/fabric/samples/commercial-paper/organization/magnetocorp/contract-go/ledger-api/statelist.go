@@ -5,17 +5,24 @@
 package ledgerapi
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
 
+// ErrStateNotFound is returned by GetState when there is
+// no state for the key
+var ErrStateNotFound = errors.New("No state found")
+
 // StateListInterface functions that a state list
 // should have
 type StateListInterface interface {
 	AddState(StateInterface) error
 	GetState(string, StateInterface) error
 	UpdateState(StateInterface) error
+	DeleteState(string) error
+	GetStates([]string, func() StateInterface) ([]StateInterface, error)
 }
 
 // StateList useful for managing putting data in and out
@@ -48,7 +55,7 @@ func (sl *StateList) GetState(key string, state StateInterface) error {
 	if err != nil {
 		return err
 	} else if data == nil {
-		return fmt.Errorf("No state found for %s", key)
+		return fmt.Errorf("%w for %s", ErrStateNotFound, key)
 	}
 
 	return sl.Deserialize(data, state)
@@ -59,3 +66,45 @@ func (sl *StateList) GetState(key string, state StateInterface) error {
 func (sl *StateList) UpdateState(state StateInterface) error {
 	return sl.AddState(state)
 }
+
+// DeleteState removes state from world state. Key is the
+// split key value used in Add/Update joined using a colon
+func (sl *StateList) DeleteState(key string) error {
+	ledgerKey, _ := sl.Ctx.GetStub().CreateCompositeKey(sl.Name, SplitKey(key))
+
+	return sl.Ctx.GetStub().DelState(ledgerKey)
+}
+
+// GetStates returns the states whose split keys start with
+// the passed key parts. Each state is created with newState
+// and unmarshalled from its JSON
+func (sl *StateList) GetStates(keyParts []string, newState func() StateInterface) ([]StateInterface, error) {
+	iterator, err := sl.Ctx.GetStub().GetStateByPartialCompositeKey(sl.Name, keyParts)
+
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+
+	states := []StateInterface{}
+
+	for iterator.HasNext() {
+		result, err := iterator.Next()
+
+		if err != nil {
+			return nil, err
+		}
+
+		state := newState()
+
+		err = sl.Deserialize(result.Value, state)
+
+		if err != nil {
+			return nil, err
+		}
+
+		states = append(states, state)
+	}
+
+	return states, nil
+}
@@ -0,0 +1,164 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assettransferabac
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	abac "github.com/hyperledger/fabric-samples/asset-transfer-abac/chaincode-go/smart-contract"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+// start is the time of the first transaction, at noon UTC
+var start = time.Date(2021, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// network is a channel with the ABAC contract, on which identities submit transactions one after the other
+type network struct {
+	t      *testing.T
+	ledger *memstub.Ledger
+	tx     int
+}
+
+func newNetwork(t *testing.T) *network {
+	ledger := memstub.NewLedger()
+	ledger.SetTime(start)
+	return &network{t: t, ledger: ledger}
+}
+
+// newIdentity returns an identity of Org1 with a certificate for the name, the attributes and the organizational units.
+// Its ID is base64 encoded, like the client identity library returns it.
+func newIdentity(name string, attributes map[string]string, ous ...string) *memstub.ClientIdentity {
+	identity := memstub.NewClientIdentity(base64.StdEncoding.EncodeToString([]byte(clientID(name))), "Org1MSP")
+	for attribute, value := range attributes {
+		identity.Attributes[attribute] = value
+	}
+	identity.Certificate = &x509.Certificate{Subject: pkix.Name{CommonName: name, OrganizationalUnit: ous}}
+	return identity
+}
+
+// clientID returns the decoded ID of an identity created by newIdentity, which the contract records as the owner
+func clientID(name string) string {
+	return fmt.Sprintf("x509::CN=%s::CN=ca.org1.example.com", name)
+}
+
+var (
+	admin   = newIdentity("admin", map[string]string{"abac.admin": "true"})
+	creator = newIdentity("creator", map[string]string{"abac.creator": "true"})
+	user    = newIdentity("user", nil, "client")
+)
+
+func (n *network) newTransactionContext(identity *memstub.ClientIdentity) (*contractapi.TransactionContext, *memstub.Stub) {
+	n.tx++
+	stub := n.ledger.NewStub(fmt.Sprintf("tx%d", n.tx))
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(identity)
+	return ctx, stub
+}
+
+// submit runs fn as a transaction of the identity and commits it
+func (n *network) submit(identity *memstub.ClientIdentity, fn func(ctx contractapi.TransactionContextInterface) error) error {
+	ctx, stub := n.newTransactionContext(identity)
+	err := fn(ctx)
+	if err != nil {
+		return err
+	}
+	require.NoError(n.t, stub.Commit())
+	return nil
+}
+
+// query returns the context of a transaction of the identity that is not committed
+func (n *network) query(identity *memstub.ClientIdentity) contractapi.TransactionContextInterface {
+	ctx, _ := n.newTransactionContext(identity)
+	return ctx
+}
+
+// createAsset creates an asset owned by the creator
+func (n *network) createAsset(id string, appraisedValue int) {
+	contract := &abac.SmartContract{}
+	err := n.submit(creator, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAsset(ctx, id, "blue", 5, appraisedValue)
+	})
+	require.NoError(n.t, err)
+}
+
+// updateAsset sets the appraised value of an asset as the identity
+func (n *network) updateAsset(identity *memstub.ClientIdentity, id string, appraisedValue int) error {
+	contract := &abac.SmartContract{}
+	return n.submit(identity, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.UpdateAsset(ctx, id, "blue", 5, appraisedValue)
+	})
+}
+
+// putRule stores a rule as the admin
+func (n *network) putRule(rule abac.Rule) {
+	contract := &abac.SmartContract{}
+	err := n.submit(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.PutRule(ctx, rule)
+	})
+	require.NoError(n.t, err)
+}
+
+func TestCreateAsset(t *testing.T) {
+	n := newNetwork(t)
+	contract := &abac.SmartContract{}
+
+	err := n.submit(user, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAsset(ctx, "asset1", "blue", 5, 100)
+	})
+	require.EqualError(t, err, "submitting client not authorized to CreateAsset, no rule allows the action")
+
+	n.createAsset("asset1", 100)
+	asset, err := contract.ReadAsset(n.query(user), "asset1")
+	require.NoError(t, err)
+	require.Equal(t, clientID("creator"), asset.Owner)
+}
+
+func TestGetAllAssets(t *testing.T) {
+	n := newNetwork(t)
+	contract := &abac.SmartContract{}
+	assetIDs := func(identity *memstub.ClientIdentity) []string {
+		assets, err := contract.GetAllAssets(n.query(identity))
+		require.NoError(t, err)
+		ids := []string{}
+		for _, asset := range assets {
+			ids = append(ids, asset.ID)
+		}
+		return ids
+	}
+
+	n.createAsset("asset1", 100)
+	n.createAsset("asset2", 2000)
+	n.createAsset("asset3", 300)
+	require.Equal(t, []string{"asset1", "asset2", "asset3"}, assetIDs(user))
+
+	// Only the owner can read valuable assets, so they are left out of the list of other clients. The rule
+	// itself is stored under a composite key, which is not listed as an asset.
+	n.putRule(abac.Rule{
+		ID:      "deny-valuable-to-others",
+		Effect:  abac.EffectDeny,
+		Actions: []string{"ReadAsset"},
+		Conditions: []abac.Condition{
+			{Type: abac.ConditionField, Name: "appraisedValue", Operator: abac.OperatorGt, Values: []string{"1000"}},
+			{Type: abac.ConditionField, Name: "owner", Operator: abac.OperatorNe, Values: []string{"$client.id"}},
+		},
+	})
+	_, err := contract.ReadAsset(n.query(user), "asset2")
+	require.EqualError(t, err, "submitting client not authorized to ReadAsset, denied by rule deny-valuable-to-others")
+	require.Equal(t, []string{"asset1", "asset3"}, assetIDs(user))
+	require.Equal(t, []string{"asset1", "asset2", "asset3"}, assetIDs(creator))
+
+	// Listing assets can also be denied altogether
+	n.putRule(abac.Rule{ID: "deny-list", Effect: abac.EffectDeny, Actions: []string{"GetAllAssets"}})
+	_, err = contract.GetAllAssets(n.query(creator))
+	require.EqualError(t, err, "submitting client not authorized to GetAllAssets, denied by rule deny-list")
+}
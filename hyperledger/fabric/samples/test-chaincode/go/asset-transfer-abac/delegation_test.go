@@ -0,0 +1,235 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assettransferabac
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	abac "github.com/hyperledger/fabric-samples/asset-transfer-abac/chaincode-go/smart-contract"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+// delegate is the identity that the owner of the assets delegates actions to
+var delegate = newIdentity("delegate", nil)
+
+// delegate delegates actions on an asset to the delegate identity as the identity, until a day after start
+func (n *network) delegate(identity *memstub.ClientIdentity, assetID string, actions ...string) error {
+	contract := &abac.SmartContract{}
+	return n.submit(identity, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.Delegate(ctx, assetID, clientID("delegate"), actions, "2021-01-02T12:00:00Z")
+	})
+}
+
+// delegatedAssets returns the IDs of the assets delegated to the delegate identity
+func (n *network) delegatedAssets() []string {
+	contract := &abac.SmartContract{}
+	delegations, err := contract.GetDelegationsByDelegate(n.query(user), clientID("delegate"))
+	require.NoError(n.t, err)
+	ids := []string{}
+	for _, delegation := range delegations {
+		ids = append(ids, delegation.AssetID)
+	}
+	return ids
+}
+
+// delegationsOf returns the delegations of an asset
+func (n *network) delegationsOf(assetID string) []*abac.Delegation {
+	contract := &abac.SmartContract{}
+	delegations, err := contract.GetDelegationsByAsset(n.query(user), assetID)
+	require.NoError(n.t, err)
+	return delegations
+}
+
+func TestDelegate(t *testing.T) {
+	n := newNetwork(t)
+	contract := &abac.SmartContract{}
+	n.createAsset("asset1", 100)
+
+	require.EqualError(t, n.delegate(user, "asset1", "UpdateAsset"), "submitting client not authorized to Delegate, no rule allows the action")
+	require.EqualError(t, n.delegate(creator, "asset1", "ReadAsset"), "the action ReadAsset cannot be delegated, must be one of [UpdateAsset TransferAsset DeleteAsset]")
+	require.EqualError(t, n.delegate(creator, "asset1"), "at least one action must be delegated")
+	err := n.submit(creator, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.Delegate(ctx, "asset1", clientID("creator"), []string{"UpdateAsset"}, "2021-01-02T12:00:00Z")
+	})
+	require.EqualError(t, err, "the owner of asset asset1 cannot be its delegate")
+	err = n.submit(creator, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.Delegate(ctx, "asset1", clientID("delegate"), []string{"UpdateAsset"}, "2021-01-01T00:00:00Z")
+	})
+	require.EqualError(t, err, "the expiry 2021-01-01T00:00:00Z is in the past")
+
+	require.EqualError(t, n.updateAsset(delegate, "asset1", 200), "submitting client not authorized to UpdateAsset, no rule allows the action")
+	require.NoError(t, n.delegate(creator, "asset1", "UpdateAsset"))
+	require.NoError(t, n.updateAsset(delegate, "asset1", 200))
+
+	decision := n.explain(delegate, "UpdateAsset")
+	require.True(t, decision.Allowed)
+	require.Equal(t, "allowed by delegation from the owner until 2021-01-02T12:00:00Z", decision.Reason)
+	require.Equal(t, &abac.Delegation{
+		AssetID:    "asset1",
+		DelegateID: clientID("delegate"),
+		Delegator:  clientID("creator"),
+		Actions:    []string{"UpdateAsset"},
+		Expiry:     "2021-01-02T12:00:00Z",
+	}, decision.Delegation)
+}
+
+func TestDelegationScope(t *testing.T) {
+	n := newNetwork(t)
+	contract := &abac.SmartContract{}
+	n.createAsset("asset1", 100)
+	n.createAsset("asset2", 100)
+	require.NoError(t, n.delegate(creator, "asset1", "UpdateAsset"))
+
+	// The delegate can only perform the delegated actions, on the delegated asset
+	require.NoError(t, n.updateAsset(delegate, "asset1", 200))
+	require.EqualError(t, n.updateAsset(delegate, "asset2", 200), "submitting client not authorized to UpdateAsset, no rule allows the action")
+	err := n.submit(delegate, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.TransferAsset(ctx, "asset1", clientID("delegate"))
+	})
+	require.EqualError(t, err, "submitting client not authorized to TransferAsset, no rule allows the action")
+	err = n.submit(delegate, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.DeleteAsset(ctx, "asset1")
+	})
+	require.EqualError(t, err, "submitting client not authorized to DeleteAsset, no rule allows the action")
+
+	// The delegate cannot delegate further, nor revoke its own delegation
+	require.EqualError(t, n.delegate(delegate, "asset1", "UpdateAsset"), "submitting client not authorized to Delegate, no rule allows the action")
+	err = n.submit(delegate, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RevokeDelegation(ctx, "asset1", clientID("delegate"))
+	})
+	require.EqualError(t, err, "submitting client not authorized to RevokeDelegation, no rule allows the action")
+
+	// A new delegation replaces the previous one
+	require.NoError(t, n.delegate(creator, "asset1", "DeleteAsset"))
+	require.EqualError(t, n.updateAsset(delegate, "asset1", 300), "submitting client not authorized to UpdateAsset, no rule allows the action")
+	require.Len(t, n.delegationsOf("asset1"), 1)
+	err = n.submit(delegate, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.DeleteAsset(ctx, "asset1")
+	})
+	require.NoError(t, err)
+}
+
+func TestDelegationExpiry(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+	require.NoError(t, n.delegate(creator, "asset1", "UpdateAsset"))
+
+	n.ledger.SetTime(time.Date(2021, time.January, 2, 11, 59, 59, 0, time.UTC))
+	require.NoError(t, n.updateAsset(delegate, "asset1", 200))
+
+	// The delegation ends at the expiry, but is kept until it is revoked
+	require.EqualError(t, n.updateAsset(delegate, "asset1", 300), "submitting client not authorized to UpdateAsset, no rule allows the action")
+	require.Len(t, n.delegationsOf("asset1"), 1)
+}
+
+func TestRevokeDelegation(t *testing.T) {
+	n := newNetwork(t)
+	contract := &abac.SmartContract{}
+	revoke := func() error {
+		return n.submit(creator, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.RevokeDelegation(ctx, "asset1", clientID("delegate"))
+		})
+	}
+
+	n.createAsset("asset1", 100)
+	require.NoError(t, n.delegate(creator, "asset1", "UpdateAsset"))
+	require.NoError(t, revoke())
+	require.EqualError(t, revoke(), "asset asset1 is not delegated to "+clientID("delegate"))
+	require.EqualError(t, n.updateAsset(delegate, "asset1", 200), "submitting client not authorized to UpdateAsset, no rule allows the action")
+	require.Empty(t, n.delegationsOf("asset1"))
+	require.Empty(t, n.delegatedAssets())
+}
+
+func TestDelegationsAreClearedOnTransfer(t *testing.T) {
+	n := newNetwork(t)
+	contract := &abac.SmartContract{}
+	n.createAsset("asset1", 100)
+	n.createAsset("asset2", 100)
+	require.NoError(t, n.delegate(creator, "asset1", "UpdateAsset", "TransferAsset"))
+	require.NoError(t, n.delegate(creator, "asset2", "UpdateAsset"))
+
+	// The delegate transfers the asset on behalf of the owner, which ends its own delegation
+	err := n.submit(delegate, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.TransferAsset(ctx, "asset1", clientID("user"))
+	})
+	require.NoError(t, err)
+	require.Empty(t, n.delegationsOf("asset1"))
+	require.Equal(t, []string{"asset2"}, n.delegatedAssets())
+	require.EqualError(t, n.updateAsset(delegate, "asset1", 200), "submitting client not authorized to UpdateAsset, no rule allows the action")
+	require.NoError(t, n.updateAsset(user, "asset1", 200))
+}
+
+func TestDelegationsAreClearedOnDelete(t *testing.T) {
+	n := newNetwork(t)
+	contract := &abac.SmartContract{}
+	n.createAsset("asset1", 100)
+	require.NoError(t, n.delegate(creator, "asset1", "UpdateAsset"))
+
+	err := n.submit(creator, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.DeleteAsset(ctx, "asset1")
+	})
+	require.NoError(t, err)
+	require.Empty(t, n.delegationsOf("asset1"))
+	require.Empty(t, n.delegatedAssets())
+
+	// A delegation of a deleted asset does not carry over to a new asset with the same ID
+	n.createAsset("asset1", 100)
+	require.EqualError(t, n.updateAsset(delegate, "asset1", 200), "submitting client not authorized to UpdateAsset, no rule allows the action")
+}
+
+func TestDenyOverridesDelegation(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+	require.NoError(t, n.delegate(creator, "asset1", "UpdateAsset"))
+	n.putRule(abac.Rule{ID: "deny-valuable", Effect: abac.EffectDeny, Actions: []string{"UpdateAsset"},
+		Conditions: []abac.Condition{{Type: abac.ConditionField, Name: "appraisedValue", Operator: abac.OperatorGt, Values: []string{"1000"}}}})
+
+	require.NoError(t, n.updateAsset(delegate, "asset1", 5000))
+	require.EqualError(t, n.updateAsset(delegate, "asset1", 200), "submitting client not authorized to UpdateAsset, denied by rule deny-valuable")
+
+	decision := n.explain(delegate, "UpdateAsset")
+	require.False(t, decision.Allowed)
+	require.Nil(t, decision.Delegation)
+}
+
+func TestDelegationQueries(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+	n.createAsset("asset2", 100)
+	n.createAsset("asset3", 100)
+	require.NoError(t, n.delegate(creator, "asset1", "UpdateAsset"))
+	require.NoError(t, n.delegate(creator, "asset3", "TransferAsset"))
+
+	contract := &abac.SmartContract{}
+	err := n.submit(creator, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.Delegate(ctx, "asset1", clientID("user"), []string{"DeleteAsset"}, "2021-01-03T00:00:00Z")
+	})
+	require.NoError(t, err)
+
+	// The asset index lists the delegates of an asset, and the delegate index the assets of a delegate
+	delegations := n.delegationsOf("asset1")
+	require.Len(t, delegations, 2)
+	require.ElementsMatch(t, []string{clientID("delegate"), clientID("user")}, []string{delegations[0].DelegateID, delegations[1].DelegateID})
+	require.Empty(t, n.delegationsOf("asset2"))
+	require.Equal(t, []string{"asset1", "asset3"}, n.delegatedAssets())
+
+	delegations, err = contract.GetDelegationsByDelegate(n.query(user), clientID("user"))
+	require.NoError(t, err)
+	require.Len(t, delegations, 1)
+	require.Equal(t, []string{"DeleteAsset"}, delegations[0].Actions)
+
+	// The queries are subject to the rules
+	n.putRule(abac.Rule{ID: "deny-queries", Effect: abac.EffectDeny, Actions: []string{"GetDelegationsByAsset", "GetDelegationsByDelegate"},
+		Conditions: []abac.Condition{{Type: abac.ConditionAttribute, Name: "abac.creator", Operator: abac.OperatorNe, Values: []string{"true"}}}})
+	_, err = contract.GetDelegationsByAsset(n.query(user), "asset1")
+	require.EqualError(t, err, "submitting client not authorized to GetDelegationsByAsset, denied by rule deny-queries")
+	_, err = contract.GetDelegationsByDelegate(n.query(user), clientID("delegate"))
+	require.EqualError(t, err, "submitting client not authorized to GetDelegationsByDelegate, denied by rule deny-queries")
+	_, err = contract.GetDelegationsByDelegate(n.query(creator), clientID("delegate"))
+	require.NoError(t, err)
+}
@@ -0,0 +1,303 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assettransferabac
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	abac "github.com/hyperledger/fabric-samples/asset-transfer-abac/chaincode-go/smart-contract"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+// ruleIDs returns the IDs of the rules in effect
+func (n *network) ruleIDs() []string {
+	contract := &abac.SmartContract{}
+	rules, err := contract.GetRules(n.query(user))
+	require.NoError(n.t, err)
+	ids := []string{}
+	for _, rule := range rules {
+		ids = append(ids, rule.ID)
+	}
+	return ids
+}
+
+// deleteRule deletes a rule as the admin
+func (n *network) deleteRule(ruleID string) error {
+	contract := &abac.SmartContract{}
+	return n.submit(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.DeleteRule(ctx, ruleID)
+	})
+}
+
+// explain returns the decision on an action of the identity on asset1
+func (n *network) explain(identity *memstub.ClientIdentity, action string) *abac.Decision {
+	contract := &abac.SmartContract{}
+	decision, err := contract.ExplainDecision(n.query(identity), action, "asset1")
+	require.NoError(n.t, err)
+	return decision
+}
+
+func TestManageRules(t *testing.T) {
+	n := newNetwork(t)
+	contract := &abac.SmartContract{}
+	rule := abac.Rule{ID: "ops-update", Effect: abac.EffectAllow, Actions: []string{"UpdateAsset"},
+		Conditions: []abac.Condition{{Type: abac.ConditionOU, Operator: abac.OperatorEq, Values: []string{"ops"}}}}
+
+	err := n.submit(creator, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.PutRule(ctx, rule)
+	})
+	require.EqualError(t, err, "submitting client not authorized to manage rules, does not have abac.admin role")
+	err = n.submit(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.PutRule(ctx, abac.Rule{ID: "no-actions", Effect: abac.EffectAllow})
+	})
+	require.EqualError(t, err, "rule no-actions must have at least one action")
+	err = n.submit(admin, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.PutRule(ctx, abac.Rule{ID: "bad-window", Effect: abac.EffectDeny, Actions: []string{"*"},
+			Conditions: []abac.Condition{{Type: abac.ConditionTime, Values: []string{"9am", "17:00"}}}})
+	})
+	require.EqualError(t, err, `time condition 0 of rule bad-window: invalid time "9am", must be an RFC3339 time or a daily time such as 09:00`)
+
+	// The first rule is added to the default rules, so the owner can still update the asset
+	require.Equal(t, []string{"default-create", "default-owner", "default-read"}, n.ruleIDs())
+	n.createAsset("asset1", 100)
+	n.putRule(rule)
+	require.Equal(t, []string{"default-create", "default-owner", "default-read", "ops-update"}, n.ruleIDs())
+	require.NoError(t, n.updateAsset(creator, "asset1", 200))
+	require.EqualError(t, n.updateAsset(user, "asset1", 300), "submitting client not authorized to UpdateAsset, no rule allows the action")
+
+	// A default rule is only removed when it is replaced or deleted explicitly
+	n.putRule(abac.Rule{ID: "default-read", Effect: abac.EffectAllow, Actions: []string{"ReadAsset", "GetRules"}})
+	_, err = contract.GetAllAssets(n.query(user))
+	require.EqualError(t, err, "submitting client not authorized to GetAllAssets, no rule allows the action")
+	require.NoError(t, n.deleteRule("default-owner"))
+	require.Equal(t, []string{"default-create", "default-read", "ops-update"}, n.ruleIDs())
+	require.EqualError(t, n.updateAsset(creator, "asset1", 300), "submitting client not authorized to UpdateAsset, no rule allows the action")
+	require.EqualError(t, n.deleteRule("default-owner"), "the rule default-owner does not exist")
+
+	// Once all rules are deleted, the default rules apply again
+	for _, ruleID := range []string{"default-create", "default-read", "ops-update"} {
+		require.NoError(t, n.deleteRule(ruleID))
+	}
+	require.Equal(t, []string{"default-create", "default-owner", "default-read"}, n.ruleIDs())
+	require.NoError(t, n.updateAsset(creator, "asset1", 300))
+}
+
+func TestDeleteDefaultRuleBeforeAnyRuleIsStored(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+
+	require.NoError(t, n.deleteRule("default-read"))
+	contract := &abac.SmartContract{}
+	_, err := contract.ReadAsset(n.query(user), "asset1")
+	require.EqualError(t, err, "submitting client not authorized to ReadAsset, no rule allows the action")
+	require.NoError(t, n.updateAsset(creator, "asset1", 200))
+
+	// The rules can no longer be read either, until a rule allows it
+	_, err = contract.GetRules(n.query(user))
+	require.EqualError(t, err, "submitting client not authorized to GetRules, no rule allows the action")
+	n.putRule(abac.Rule{ID: "read-rules", Effect: abac.EffectAllow, Actions: []string{"GetRules"}})
+	require.Equal(t, []string{"default-create", "default-owner", "read-rules"}, n.ruleIDs())
+}
+
+func TestDenyTakesPrecedence(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+	n.createAsset("asset2", 2000)
+
+	n.putRule(abac.Rule{ID: "deny-valuable", Effect: abac.EffectDeny, Actions: []string{"UpdateAsset", "DeleteAsset"},
+		Conditions: []abac.Condition{{Type: abac.ConditionField, Name: "appraisedValue", Operator: abac.OperatorGt, Values: []string{"1000"}}}})
+	n.putRule(abac.Rule{ID: "allow-all", Effect: abac.EffectAllow, Actions: []string{"*"}})
+
+	// The owner is allowed by two rules and the user by one, but the deny rule applies to both
+	require.EqualError(t, n.updateAsset(creator, "asset2", 2500), "submitting client not authorized to UpdateAsset, denied by rule deny-valuable")
+	require.EqualError(t, n.updateAsset(user, "asset2", 2500), "submitting client not authorized to UpdateAsset, denied by rule deny-valuable")
+	require.NoError(t, n.updateAsset(user, "asset1", 500))
+
+	// The deny rule applies to the asset as it is before the update
+	require.NoError(t, n.updateAsset(user, "asset1", 5000))
+	require.EqualError(t, n.updateAsset(creator, "asset1", 500), "submitting client not authorized to UpdateAsset, denied by rule deny-valuable")
+}
+
+func TestTimeWindowSpanningMidnight(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+	n.putRule(abac.Rule{ID: "deny-at-night", Effect: abac.EffectDeny, Actions: []string{"UpdateAsset"},
+		Conditions: []abac.Condition{{Type: abac.ConditionTime, Values: []string{"22:00", "06:00"}}}})
+
+	at := func(t time.Time) error {
+		n.ledger.SetTime(t)
+		return n.updateAsset(creator, "asset1", 200)
+	}
+	day := time.Date(2021, time.January, 2, 0, 0, 0, 0, time.UTC)
+	denied := "submitting client not authorized to UpdateAsset, denied by rule deny-at-night"
+
+	// The window is open from 22:00 until 06:00 the next day, its start is included and its end is excluded
+	require.EqualError(t, at(day.Add(3*time.Hour)), denied)
+	require.EqualError(t, at(day.Add(5*time.Hour+59*time.Minute)), denied)
+	require.NoError(t, at(day.Add(6*time.Hour)))
+	require.NoError(t, at(day.Add(12*time.Hour)))
+	require.NoError(t, at(day.Add(21*time.Hour+59*time.Minute)))
+	require.EqualError(t, at(day.Add(22*time.Hour)), denied)
+	require.EqualError(t, at(day.Add(23*time.Hour+30*time.Minute)), denied)
+	require.EqualError(t, at(day.Add(24*time.Hour)), denied)
+}
+
+func TestTimeWindowOfDates(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+	n.putRule(abac.Rule{ID: "freeze", Effect: abac.EffectDeny, Actions: []string{"*"},
+		Conditions: []abac.Condition{{Type: abac.ConditionTime, Values: []string{"2021-02-01T00:00:00Z", "2021-02-02T00:00:00Z"}}}})
+
+	n.ledger.SetTime(time.Date(2021, time.January, 31, 23, 0, 0, 0, time.UTC))
+	require.NoError(t, n.updateAsset(creator, "asset1", 200))
+	n.ledger.SetTime(time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC))
+	require.EqualError(t, n.updateAsset(creator, "asset1", 300), "submitting client not authorized to UpdateAsset, denied by rule freeze")
+
+	// A window of dates does not repeat daily
+	n.ledger.SetTime(time.Date(2021, time.February, 3, 0, 30, 0, 0, time.UTC))
+	require.NoError(t, n.updateAsset(creator, "asset1", 300))
+}
+
+func TestNotEqualAndPresentOperators(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+	n.putRule(abac.Rule{ID: "deny-outside-audit", Effect: abac.EffectDeny, Actions: []string{"ReadAsset"},
+		Conditions: []abac.Condition{{Type: abac.ConditionAttribute, Name: "department", Operator: abac.OperatorNe, Values: []string{"audit"}}}})
+	n.putRule(abac.Rule{ID: "allow-auditors", Effect: abac.EffectAllow, Actions: []string{"TransferAsset"},
+		Conditions: []abac.Condition{{Type: abac.ConditionAttribute, Name: "abac.auditor", Operator: abac.OperatorPresent}}})
+
+	// ne is met if the attribute has another value or is not set
+	require.False(t, n.explain(newIdentity("sales", map[string]string{"department": "sales"}), "ReadAsset").Allowed)
+	require.False(t, n.explain(user, "ReadAsset").Allowed)
+	require.True(t, n.explain(newIdentity("auditor", map[string]string{"department": "audit"}), "ReadAsset").Allowed)
+
+	// present is met if the attribute is set, whatever its value
+	require.True(t, n.explain(newIdentity("auditor", map[string]string{"abac.auditor": "false"}), "TransferAsset").Allowed)
+	require.False(t, n.explain(user, "TransferAsset").Allowed)
+}
+
+func TestOUCondition(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+	n.putRule(abac.Rule{ID: "ops-update", Effect: abac.EffectAllow, Actions: []string{"UpdateAsset"},
+		Conditions: []abac.Condition{{Type: abac.ConditionOU, Operator: abac.OperatorIn, Values: []string{"ops", "admin"}}}})
+
+	// The condition is met if any of the organizational units of the client matches
+	operator := newIdentity("operator", nil, "client", "ops")
+	require.NoError(t, n.updateAsset(operator, "asset1", 200))
+	require.EqualError(t, n.updateAsset(user, "asset1", 300), "submitting client not authorized to UpdateAsset, no rule allows the action")
+
+	decision := n.explain(operator, "UpdateAsset")
+	require.Equal(t, "client,ops", decision.Trace[1].Conditions[0].Actual)
+	require.True(t, decision.Trace[1].Conditions[0].Met)
+}
+
+func TestExplainDecision(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+	n.putRule(abac.Rule{ID: "deny-valuable", Effect: abac.EffectDeny, Actions: []string{"CreateAsset", "UpdateAsset"},
+		Conditions: []abac.Condition{{Type: abac.ConditionField, Name: "appraisedValue", Operator: abac.OperatorGt, Values: []string{"1000"}}}})
+
+	// Only the rules for the action are traced, with the values their conditions were evaluated against
+	decision := n.explain(user, "UpdateAsset")
+	require.Equal(t, &abac.Decision{
+		Action:   "UpdateAsset",
+		Resource: "asset1",
+		Allowed:  false,
+		Reason:   "no rule allows the action",
+		Trace: []abac.RuleTrace{
+			{
+				RuleID:  "default-owner",
+				Effect:  abac.EffectAllow,
+				Applied: false,
+				Conditions: []abac.ConditionTrace{{
+					Condition: abac.Condition{Type: abac.ConditionField, Name: "owner", Operator: abac.OperatorEq, Values: []string{"$client.id"}},
+					Actual:    clientID("creator"),
+					Met:       false,
+				}},
+			},
+			{
+				RuleID:  "deny-valuable",
+				Effect:  abac.EffectDeny,
+				Applied: false,
+				Conditions: []abac.ConditionTrace{{
+					Condition: abac.Condition{Type: abac.ConditionField, Name: "appraisedValue", Operator: abac.OperatorGt, Values: []string{"1000"}},
+					Actual:    "100",
+					Met:       false,
+				}},
+			},
+		},
+	}, decision)
+
+	decision = n.explain(creator, "UpdateAsset")
+	require.True(t, decision.Allowed)
+	require.Equal(t, "allowed by rule default-owner", decision.Reason)
+	require.True(t, decision.Trace[0].Applied)
+
+	require.NoError(t, n.updateAsset(creator, "asset1", 5000))
+	decision = n.explain(creator, "UpdateAsset")
+	require.False(t, decision.Allowed)
+	require.Equal(t, "denied by rule deny-valuable", decision.Reason)
+	require.True(t, decision.Trace[0].Applied)
+	require.True(t, decision.Trace[1].Applied)
+	require.Equal(t, "5000", decision.Trace[1].Conditions[0].Actual)
+
+	// Actions that are not on an asset have no fields to evaluate
+	contract := &abac.SmartContract{}
+	decision, err := contract.ExplainDecision(n.query(creator), "CreateAsset", "")
+	require.NoError(t, err)
+	require.True(t, decision.Allowed)
+	require.Empty(t, decision.Resource)
+	require.Equal(t, []string{"default-create", "deny-valuable"}, []string{decision.Trace[0].RuleID, decision.Trace[1].RuleID})
+	require.False(t, decision.Trace[1].Applied)
+	require.Empty(t, decision.Trace[1].Conditions[0].Actual)
+}
+
+func TestNotEqualWithSeveralOUs(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset("asset1", 100)
+	n.putRule(abac.Rule{ID: "unblocked-update", Effect: abac.EffectAllow, Actions: []string{"UpdateAsset"},
+		Conditions: []abac.Condition{{Type: abac.ConditionOU, Operator: abac.OperatorNe, Values: []string{"blocked"}}}})
+
+	// ne is only met if none of the organizational units of the client is equal to the value, so another OU
+	// of a blocked client does not meet it
+	blocked := newIdentity("blocked", nil, "blocked", "client")
+	require.EqualError(t, n.updateAsset(blocked, "asset1", 200), "submitting client not authorized to UpdateAsset, no rule allows the action")
+	decision := n.explain(blocked, "UpdateAsset")
+	require.Equal(t, "blocked,client", decision.Trace[1].Conditions[0].Actual)
+	require.False(t, decision.Trace[1].Conditions[0].Met)
+
+	require.NoError(t, n.updateAsset(newIdentity("operator", nil, "client", "ops"), "asset1", 300))
+	require.NoError(t, n.updateAsset(newIdentity("nobody", nil), "asset1", 400))
+}
+
+func TestQueriesAreAuthorized(t *testing.T) {
+	n := newNetwork(t)
+	contract := &abac.SmartContract{}
+	n.createAsset("asset1", 100)
+	n.putRule(abac.Rule{ID: "deny-blocked", Effect: abac.EffectDeny, Actions: []string{"*"},
+		Conditions: []abac.Condition{{Type: abac.ConditionOU, Operator: abac.OperatorEq, Values: []string{"blocked"}}}})
+	blocked := newIdentity("blocked", nil, "blocked", "client")
+
+	// The default rules allow all clients to read the rules, explain decisions and read their identity
+	_, err := contract.GetRules(n.query(user))
+	require.NoError(t, err)
+	_, err = contract.ExplainDecision(n.query(user), "UpdateAsset", "asset1")
+	require.NoError(t, err)
+	id, err := contract.GetSubmittingClientIdentity(n.query(user))
+	require.NoError(t, err)
+	require.Equal(t, clientID("user"), id)
+
+	// but the rules can deny them like any other action
+	_, err = contract.GetRules(n.query(blocked))
+	require.EqualError(t, err, "submitting client not authorized to GetRules, denied by rule deny-blocked")
+	_, err = contract.ExplainDecision(n.query(blocked), "UpdateAsset", "asset1")
+	require.EqualError(t, err, "submitting client not authorized to ExplainDecision, denied by rule deny-blocked")
+	_, err = contract.GetSubmittingClientIdentity(n.query(blocked))
+	require.EqualError(t, err, "submitting client not authorized to GetSubmittingClientIdentity, denied by rule deny-blocked")
+}
@@ -0,0 +1,69 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assettransferbasic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransactionContext(stub *memstub.Stub) *contractapi.TransactionContext {
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	return ctx
+}
+
+func TestAssetLifecycleOnLedger(t *testing.T) {
+	ledger := memstub.NewLedger()
+	assetTransfer := chaincode.SmartContract{}
+
+	// submit runs fn as a transaction against a fresh in-memory stub and commits it
+	submit := func(txID string, fn func(ctx contractapi.TransactionContextInterface) error) error {
+		stub := ledger.NewStub(txID)
+		if err := fn(newTransactionContext(stub)); err != nil {
+			return err
+		}
+		return stub.Commit()
+	}
+
+	err := submit("init", func(ctx contractapi.TransactionContextInterface) error {
+		return assetTransfer.InitLedger(ctx)
+	})
+	require.NoError(t, err)
+
+	err = submit("transfer", func(ctx contractapi.TransactionContextInterface) error {
+		return assetTransfer.TransferAsset(ctx, "asset1", "Christopher")
+	})
+	require.NoError(t, err)
+
+	err = submit("delete", func(ctx contractapi.TransactionContextInterface) error {
+		return assetTransfer.DeleteAsset(ctx, "asset6")
+	})
+	require.NoError(t, err)
+
+	transactionContext := newTransactionContext(ledger.NewStub("query"))
+	assets, err := assetTransfer.GetAllAssets(transactionContext)
+	require.NoError(t, err)
+	require.Len(t, assets, 5)
+	require.Equal(t, "asset1", assets[0].ID)
+	require.Equal(t, "Christopher", assets[0].Owner)
+
+	err = assetTransfer.CreateAsset(transactionContext, "asset2", "red", 5, "Brad", 400)
+	require.EqualError(t, err, "the asset asset2 already exists")
+
+	// two transfers of the same asset endorsed concurrently: only the first commits
+	first, second := ledger.NewStub("transfer1"), ledger.NewStub("transfer2")
+	for owner, stub := range map[string]*memstub.Stub{"Dave": first, "Erin": second} {
+		require.NoError(t, assetTransfer.TransferAsset(newTransactionContext(stub), "asset2", owner))
+	}
+	require.NoError(t, first.Commit())
+	err = second.Commit()
+	require.True(t, errors.Is(err, memstub.ErrMVCCReadConflict), "unexpected error: %v", err)
+}
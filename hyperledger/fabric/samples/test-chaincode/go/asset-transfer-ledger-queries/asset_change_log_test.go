@@ -0,0 +1,61 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerqueries
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-ledger-queries/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAssetChangeLog(t *testing.T) {
+	ledger := memstub.NewLedger()
+	contract := &chaincode.SimpleChaincode{}
+	submit := func(txID string, identity *memstub.ClientIdentity, fn func(ctx contractapi.TransactionContextInterface) error) {
+		stub := ledger.NewStub(txID)
+		ctx := &contractapi.TransactionContext{}
+		ctx.SetStub(stub)
+		ctx.SetClientIdentity(identity)
+		require.NoError(t, fn(ctx))
+		require.NoError(t, stub.Commit())
+	}
+	org1User := memstub.NewClientIdentity("org1user", "Org1MSP")
+	org2User := memstub.NewClientIdentity("org2user", "Org2MSP")
+
+	submit("create", org1User, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAsset(ctx, "asset1", "blue", 5, "tom", 35)
+	})
+	submit("transfer", org2User, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.TransferAsset(ctx, "asset1", "jerry")
+	})
+	submit("delete", org1User, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.DeleteAsset(ctx, "asset1")
+	})
+
+	ctx := newTransactionContext(ledger.NewStub("query"))
+	entries, err := contract.GetAssetChangeLog(ctx, "asset1")
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	require.Equal(t, "create", entries[0].TxId)
+	require.Equal(t, "Org1MSP", entries[0].MSPID)
+	require.Equal(t, "org1user", entries[0].ClientID)
+	require.Len(t, entries[0].Patch, 1)
+	require.Equal(t, "add", entries[0].Patch[0].Op)
+	require.Equal(t, "", entries[0].Patch[0].Path)
+
+	require.Equal(t, "transfer", entries[1].TxId)
+	require.Equal(t, "Org2MSP", entries[1].MSPID)
+	require.Equal(t, "org2user", entries[1].ClientID)
+	require.Equal(t, []chaincode.PatchOperation{{Op: "replace", Path: "/owner", Value: "jerry"}}, entries[1].Patch)
+	require.True(t, entries[1].Timestamp.After(entries[0].Timestamp))
+
+	require.Equal(t, "delete", entries[2].TxId)
+	require.True(t, entries[2].IsDelete)
+	require.Equal(t, []chaincode.PatchOperation{{Op: "remove", Path: ""}}, entries[2].Patch)
+}
@@ -0,0 +1,182 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerqueries
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-ledger-queries/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssetExpiry(t *testing.T) {
+	ledger := memstub.NewLedger()
+	contract := &chaincode.SimpleChaincode{}
+	submit := func(txID string, fn func(ctx contractapi.TransactionContextInterface) error) error {
+		stub := ledger.NewStub(txID)
+		err := fn(newTransactionContext(stub))
+		if err != nil {
+			return err
+		}
+		return stub.Commit()
+	}
+
+	require.NoError(t, submit("init", contract.InitLedger))
+	require.NoError(t, submit("expiry", func(ctx contractapi.TransactionContextInterface) error {
+		if err := contract.SetAssetExpiry(ctx, "asset1", "2021-02-01T00:00:00Z", true); err != nil {
+			return err
+		}
+		if err := contract.SetAssetExpiry(ctx, "asset2", "2021-03-01T00:00:00+01:00", false); err != nil {
+			return err
+		}
+		return contract.SetAssetExpiry(ctx, "asset3", "2021-04-01T00:00:00Z", false)
+	}))
+	require.EqualError(t, submit("invalid", func(ctx contractapi.TransactionContextInterface) error {
+		return contract.SetAssetExpiry(ctx, "asset4", "tomorrow", false)
+	}), `invalid expiry time "tomorrow": parsing time "tomorrow" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "tomorrow" as "2006"`)
+
+	// before the expiry nothing is purged
+	require.NoError(t, submit("purge0", func(ctx contractapi.TransactionContextInterface) error {
+		result, err := contract.PurgeExpired(ctx, 10)
+		require.NoError(t, err)
+		require.Empty(t, result.Purged)
+		require.False(t, result.More)
+		return nil
+	}))
+
+	ledger.SetTime(time.Date(2021, time.March, 15, 0, 0, 0, 0, time.UTC))
+	ctx := newTransactionContext(ledger.NewStub("read"))
+	_, err := contract.ReadAsset(ctx, "asset1")
+	require.EqualError(t, err, "asset asset1 does not exist")
+	exists, err := contract.AssetExists(ctx, "asset2")
+	require.NoError(t, err)
+	require.False(t, exists)
+	exists, err = contract.AssetExists(ctx, "asset3")
+	require.NoError(t, err)
+	require.True(t, exists)
+	assets, err := contract.GetAssetsByRange(ctx, "", "")
+	require.NoError(t, err)
+	require.Len(t, assets, 4)
+	require.Equal(t, "asset3", assets[0].ID)
+
+	require.NoError(t, submit("purge1", func(ctx contractapi.TransactionContextInterface) error {
+		result, err := contract.PurgeExpired(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, []string{"asset1"}, result.Purged)
+		require.Equal(t, []string{"asset1"}, result.Archived)
+		require.True(t, result.More)
+		return nil
+	}))
+	require.NoError(t, submit("purge2", func(ctx contractapi.TransactionContextInterface) error {
+		result, err := contract.PurgeExpired(ctx, 1)
+		require.NoError(t, err)
+		require.Equal(t, []string{"asset2"}, result.Purged)
+		require.Empty(t, result.Archived)
+		require.False(t, result.More)
+		return nil
+	}))
+
+	ctx = newTransactionContext(ledger.NewStub("archive"))
+	archived, err := contract.GetArchivedAsset(ctx, "asset1")
+	require.NoError(t, err)
+	require.Equal(t, "Tomoko", archived.Owner)
+	require.Equal(t, "2021-02-01T00:00:00.000000000Z", archived.ExpiresAt)
+	_, err = contract.GetArchivedAsset(ctx, "asset2")
+	require.EqualError(t, err, "archived asset asset2 does not exist")
+
+	// only the expiry index entry of asset3 and the archived asset1 remain, and
+	// the color index entries of the purged assets are gone
+	records, _ := exportAll(t, ledger, 100)
+	var expiryKeys, archiveKeys, colorKeys int
+	for _, record := range records {
+		switch record.ObjectType {
+		case "expiry~timestamp~key":
+			expiryKeys++
+		case "archive~key":
+			archiveKeys++
+		case "color~name":
+			colorKeys++
+		}
+	}
+	require.Equal(t, 1, expiryKeys)
+	require.Equal(t, 1, archiveKeys)
+	require.Equal(t, 4, colorKeys)
+}
+
+func TestCreateAssetReplacesExpiredAsset(t *testing.T) {
+	ledger := memstub.NewLedger()
+	contract := &chaincode.SimpleChaincode{}
+
+	stub := ledger.NewStub("create")
+	require.NoError(t, contract.CreateAsset(newTransactionContext(stub), "asset1", "blue", 5, "tom", 35))
+	require.NoError(t, stub.Commit())
+	stub = ledger.NewStub("expiry")
+	require.NoError(t, contract.SetAssetExpiry(newTransactionContext(stub), "asset1", "2021-01-02T00:00:00Z", false))
+	require.NoError(t, stub.Commit())
+
+	ledger.SetTime(time.Date(2021, time.January, 3, 0, 0, 0, 0, time.UTC))
+	stub = ledger.NewStub("recreate")
+	require.NoError(t, contract.CreateAsset(newTransactionContext(stub), "asset1", "red", 4, "jerry", 50))
+	require.NoError(t, stub.Commit())
+
+	stub = ledger.NewStub("transfer")
+	require.NoError(t, contract.TransferAssetByColor(newTransactionContext(stub), "blue", "max"))
+	require.NoError(t, stub.Commit())
+
+	asset, err := contract.ReadAsset(newTransactionContext(ledger.NewStub("read")), "asset1")
+	require.NoError(t, err)
+	require.Equal(t, "red", asset.Color)
+	require.Equal(t, "jerry", asset.Owner)
+	require.Empty(t, asset.ExpiresAt)
+
+	stub = ledger.NewStub("purge")
+	result, err := contract.PurgeExpired(newTransactionContext(stub), 10)
+	require.NoError(t, err)
+	require.Empty(t, result.Purged)
+}
+
+func TestDeleteExpiredAsset(t *testing.T) {
+	ledger := memstub.NewLedger()
+	contract := &chaincode.SimpleChaincode{}
+
+	stub := ledger.NewStub("create")
+	require.NoError(t, contract.CreateAsset(newTransactionContext(stub), "asset1", "blue", 5, "tom", 35))
+	require.NoError(t, stub.Commit())
+	stub = ledger.NewStub("expiry")
+	require.NoError(t, contract.SetAssetExpiry(newTransactionContext(stub), "asset1", "2021-01-02T00:00:00Z", true))
+	require.NoError(t, stub.Commit())
+
+	ledger.SetTime(time.Date(2021, time.January, 3, 0, 0, 0, 0, time.UTC))
+	stub = ledger.NewStub("delete")
+	require.NoError(t, contract.DeleteAsset(newTransactionContext(stub), "asset1"))
+	require.NoError(t, stub.Commit())
+
+	stub = ledger.NewStub("read")
+	value, err := stub.GetState("asset1")
+	require.NoError(t, err)
+	require.Nil(t, value)
+	iterator, err := stub.GetStateByPartialCompositeKey("expiry~timestamp~key", []string{})
+	require.NoError(t, err)
+	require.False(t, iterator.HasNext())
+	require.NoError(t, iterator.Close())
+
+	result, err := contract.PurgeExpired(newTransactionContext(ledger.NewStub("purge")), 10)
+	require.NoError(t, err)
+	require.Empty(t, result.Purged)
+
+	_, err = contract.GetArchivedAsset(newTransactionContext(ledger.NewStub("archive")), "asset1")
+	require.Error(t, err)
+
+	err = contract.DeleteAsset(newTransactionContext(ledger.NewStub("delete2")), "asset1")
+	require.EqualError(t, err, "asset asset1 does not exist")
+}
+
+func TestSimpleChaincodeMetadata(t *testing.T) {
+	_, err := contractapi.NewChaincode(&chaincode.SimpleChaincode{})
+	require.NoError(t, err)
+}
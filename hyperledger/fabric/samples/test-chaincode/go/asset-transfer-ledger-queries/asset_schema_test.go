@@ -0,0 +1,90 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerqueries
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/asset-transfer-ledger-queries/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSchema(t *testing.T) {
+	contract := &chaincode.SimpleChaincode{}
+	ctx := newTransactionContext(memstub.NewLedger().NewStub("schema"))
+
+	document, err := contract.GetSchema(ctx, "asset")
+	require.NoError(t, err)
+	var schema map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(document), &schema))
+	require.Equal(t, "asset", schema["title"])
+
+	_, err = contract.GetSchema(ctx, "employee")
+	require.EqualError(t, err, `no schema for object type "employee", known types are asset`)
+}
+
+func TestCreateAssetValidation(t *testing.T) {
+	contract := &chaincode.SimpleChaincode{}
+	ctx := newTransactionContext(memstub.NewLedger().NewStub("create"))
+
+	err := contract.CreateAsset(ctx, "asset 1", "", -5, strings.Repeat("x", 129), 35)
+	require.Error(t, err)
+	validationError, ok := err.(*chaincode.ValidationError)
+	require.True(t, ok)
+	require.Equal(t, "asset", validationError.ObjectType)
+
+	var rules []string
+	for _, fieldError := range validationError.Errors {
+		rules = append(rules, fieldError.Field+":"+fieldError.Rule)
+	}
+	require.Equal(t, []string{"ID:pattern", "color:string_gte", "owner:string_lte", "size:number_gte"}, rules)
+
+	var decoded chaincode.ValidationError
+	require.NoError(t, json.Unmarshal([]byte(err.Error()), &decoded))
+	require.Equal(t, *validationError, decoded)
+
+	require.NoError(t, contract.CreateAsset(ctx, "asset1", "blue", 5, "tom", 35))
+}
+
+func TestImportStateValidatesAssets(t *testing.T) {
+	contract := &chaincode.SimpleChaincode{}
+	ctx := newTransactionContext(memstub.NewLedger().NewStub("import"))
+
+	records := []*chaincode.ExportRecord{
+		{Key: "asset1", Value: base64.StdEncoding.EncodeToString([]byte(`{"docType":"asset","ID":"asset1","color":"blue","size":-1,"owner":"tom","appraisedValue":35}`)), ObjectType: "asset"},
+	}
+	recordsJSON, err := json.Marshal(records)
+	require.NoError(t, err)
+
+	_, err = contract.ImportState(ctx, "import1", 1, string(recordsJSON))
+	require.EqualError(t, err, `invalid value of key "asset1": {"objectType":"asset","errors":[{"field":"size","rule":"number_gte","message":"Must be greater than or equal to 0"}]}`)
+}
+
+func TestImportStateRejectsUnknownDocTypes(t *testing.T) {
+	contract := &chaincode.SimpleChaincode{}
+
+	for _, test := range []struct {
+		value string
+		err   string
+	}{
+		{`{"ID":"asset1"}`, `invalid value of key "asset1": missing docType`},
+		{`not json`, `invalid value of key "asset1": missing docType`},
+		{`{"docType":"car","ID":"asset1"}`, `invalid value of key "asset1": no schema for object type "car"`},
+	} {
+		records := []*chaincode.ExportRecord{
+			{Key: "asset1", Value: base64.StdEncoding.EncodeToString([]byte(test.value))},
+		}
+		recordsJSON, err := json.Marshal(records)
+		require.NoError(t, err)
+
+		ctx := newTransactionContext(memstub.NewLedger().NewStub("import"))
+		_, err = contract.ImportState(ctx, "import1", 1, string(recordsJSON))
+		require.EqualError(t, err, test.err)
+	}
+}
@@ -0,0 +1,180 @@
+/*
+ SPDX-License-Identifier: Apache-2.0
+*/
+
+package ledgerqueries
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-ledger-queries/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+func newTransactionContext(stub *memstub.Stub) *contractapi.TransactionContext {
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(memstub.NewClientIdentity("user1", "Org1MSP"))
+	return ctx
+}
+
+func exportAll(t *testing.T, ledger *memstub.Ledger, pageSize int) ([]*chaincode.ExportRecord, int) {
+	contract := &chaincode.SimpleChaincode{}
+	ctx := newTransactionContext(ledger.NewStub("export"))
+
+	var records []*chaincode.ExportRecord
+	pages := 0
+	bookmark := ""
+	for {
+		page, err := contract.ExportState(ctx, pageSize, bookmark)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(page.Records), pageSize)
+		records = append(records, page.Records...)
+		pages++
+		if page.Bookmark == "" {
+			return records, pages
+		}
+		bookmark = page.Bookmark
+	}
+}
+
+func TestExportState(t *testing.T) {
+	ledger := memstub.NewLedger()
+	stub := ledger.NewStub("init")
+	require.NoError(t, (&chaincode.SimpleChaincode{}).InitLedger(newTransactionContext(stub)))
+	require.NoError(t, stub.Commit())
+
+	records, pages := exportAll(t, ledger, 4)
+	require.Len(t, records, 18)
+	require.Equal(t, 5, pages)
+
+	require.Equal(t, "asset1", records[0].Key)
+	require.Equal(t, "asset", records[0].ObjectType)
+	for _, record := range records[6:12] {
+		require.Equal(t, "attribution~assetID~txID", record.ObjectType)
+	}
+	for _, record := range records[12:] {
+		require.Equal(t, "color~name", record.ObjectType)
+		require.Equal(t, "AA==", record.Value)
+	}
+
+	_, err := (&chaincode.SimpleChaincode{}).ExportState(newTransactionContext(ledger.NewStub("export")), 4, "9:")
+	require.EqualError(t, err, `invalid export bookmark "9:"`)
+}
+
+func TestExportStateVisitsEveryObjectType(t *testing.T) {
+	ledger := memstub.NewLedger()
+	contract := &chaincode.SimpleChaincode{}
+	stub := ledger.NewStub("init")
+	require.NoError(t, contract.InitLedger(newTransactionContext(stub)))
+	require.NoError(t, stub.Commit())
+
+	stub = ledger.NewStub("expiry")
+	require.NoError(t, contract.SetAssetExpiry(newTransactionContext(stub), "asset1", "2030-01-01T00:00:00Z", true))
+	require.NoError(t, stub.Commit())
+
+	records, _ := exportAll(t, ledger, 4)
+	require.Len(t, records, 20)
+	require.Equal(t, "expiry~timestamp~key", records[19].ObjectType)
+
+	// composite keys of an object type the chaincode does not know are exported once imported
+	key, err := stub.CreateCompositeKey("custom~key", []string{"k1"})
+	require.NoError(t, err)
+	batchJSON, err := json.Marshal([]*chaincode.ExportRecord{{Key: key, Value: "AA==", ObjectType: "custom~key"}})
+	require.NoError(t, err)
+	stub = ledger.NewStub("import")
+	_, err = contract.ImportState(newTransactionContext(stub), "migration1", 1, string(batchJSON))
+	require.NoError(t, err)
+	require.NoError(t, stub.Commit())
+
+	records, _ = exportAll(t, ledger, 4)
+	require.Len(t, records, 21)
+	require.Equal(t, key, records[19].Key)
+	require.Equal(t, "custom~key", records[19].ObjectType)
+}
+
+func TestImportStateRequiresImporterOrg(t *testing.T) {
+	ledger := memstub.NewLedger()
+	stub := ledger.NewStub("import")
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(memstub.NewClientIdentity("user2", "Org2MSP"))
+
+	_, err := (&chaincode.SimpleChaincode{}).ImportState(ctx, "migration1", 1, `[{"key":"asset1","value":"e30=","objectType":""}]`)
+	require.EqualError(t, err, "client of Org2MSP is not authorized to import state, only Org1MSP can import")
+
+	value, err := ledger.NewStub("read").GetState("asset1")
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestImportStateRejectsReservedKeys(t *testing.T) {
+	ledger := memstub.NewLedger()
+	stub := ledger.NewStub("import")
+	key, err := stub.CreateCompositeKey("importCheckpoint", []string{"migration2"})
+	require.NoError(t, err)
+	batchJSON, err := json.Marshal([]*chaincode.ExportRecord{{Key: key, Value: "e30=", ObjectType: "importCheckpoint"}})
+	require.NoError(t, err)
+
+	_, err = (&chaincode.SimpleChaincode{}).ImportState(newTransactionContext(stub), "migration1", 1, string(batchJSON))
+	require.EqualError(t, err, `key "\x00importCheckpoint\x00migration2\x00" of object type importCheckpoint cannot be imported`)
+}
+
+func TestImportStateResumes(t *testing.T) {
+	source := memstub.NewLedger()
+	stub := source.NewStub("init")
+	require.NoError(t, (&chaincode.SimpleChaincode{}).InitLedger(newTransactionContext(stub)))
+	require.NoError(t, stub.Commit())
+	records, _ := exportAll(t, source, 5)
+
+	var batches []string
+	for start := 0; start < len(records); start += 5 {
+		end := start + 5
+		if end > len(records) {
+			end = len(records)
+		}
+		batchJSON, err := json.Marshal(records[start:end])
+		require.NoError(t, err)
+		batches = append(batches, string(batchJSON))
+	}
+
+	target := memstub.NewLedger()
+	contract := &chaincode.SimpleChaincode{}
+	importBatch := func(batch int) (*chaincode.ImportCheckpoint, error) {
+		stub := target.NewStub("import")
+		checkpoint, err := contract.ImportState(newTransactionContext(stub), "migration1", batch, batches[batch-1])
+		if err != nil {
+			return nil, err
+		}
+		return checkpoint, stub.Commit()
+	}
+
+	checkpoint, err := importBatch(1)
+	require.NoError(t, err)
+	require.Equal(t, 1, checkpoint.LastBatch)
+
+	_, err = importBatch(3)
+	require.EqualError(t, err, "import migration1 expects batch 2, got batch 3")
+
+	// restarting the import from the first batch skips what was already applied
+	for batch := 1; batch <= len(batches); batch++ {
+		checkpoint, err = importBatch(batch)
+		require.NoError(t, err)
+	}
+	require.Equal(t, &chaincode.ImportCheckpoint{
+		ImportID:        "migration1",
+		LastBatch:       4,
+		LastKey:         records[len(records)-1].Key,
+		RecordsImported: 18,
+	}, checkpoint)
+
+	imported, _ := exportAll(t, target, 5)
+	require.Equal(t, records, imported)
+
+	asset, err := contract.ReadAsset(newTransactionContext(target.NewStub("read")), "asset3")
+	require.NoError(t, err)
+	require.Equal(t, "Jin Soo", asset.Owner)
+}
@@ -0,0 +1,192 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assettransferprivatedata
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+// setRetentionPolicy sets the retention policy as the identity
+func (n *network) setRetentionPolicy(identity *memstub.ClientIdentity, retentionDays int) error {
+	contract := &chaincode.SmartContract{}
+	return n.submit(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.SetRetentionPolicy(ctx, retentionDays)
+	})
+}
+
+// purgeAsset purges an asset of the identity
+func (n *network) purgeAsset(identity *memstub.ClientIdentity, assetID string) error {
+	contract := &chaincode.SmartContract{}
+	transient := map[string]interface{}{"asset_purge": map[string]string{"assetID": assetID}}
+	return n.submit(identity, transient, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.PurgeAsset(ctx)
+	})
+}
+
+// purgeExpiredAssets submits PurgeExpiredAssets as the identity and returns the IDs of the purged assets
+func (n *network) purgeExpiredAssets(identity *memstub.ClientIdentity, assetIDs ...string) ([]string, error) {
+	contract := &chaincode.SmartContract{}
+	var purged []string
+	err := n.submit(identity, nil, func(ctx contractapi.TransactionContextInterface) error {
+		var err error
+		purged, err = contract.PurgeExpiredAssets(ctx, assetIDs)
+		return err
+	})
+	return purged, err
+}
+
+// requireAssetPurged checks that nothing of an asset is left in the collections, except in the public consent records
+func requireAssetPurged(t *testing.T, n *network, assetID string, ownerCollection string) {
+	require.Nil(t, n.privateData(assetCollection, assetID))
+	require.Nil(t, n.privateData(ownerCollection, assetID))
+	require.Nil(t, n.privateData(assetCollection, compositeKey(t, "transferAgreement", assetID)))
+	require.Nil(t, n.privateData(assetCollection, compositeKey(t, "accessGrantSalt", assetID)))
+	require.Nil(t, n.retentionRecord(assetID))
+}
+
+func TestSetRetentionPolicy(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+
+	err := n.setRetentionPolicy(owner, 30)
+	require.EqualError(t, err, "submitting client not authorized to set the retention policy, does not have retention.admin role")
+
+	err = n.setRetentionPolicy(admin, 0)
+	require.EqualError(t, err, "retentionDays must be a positive integer")
+
+	_, err = contract.RetentionReport(n.query(owner))
+	require.EqualError(t, err, "no retention policy has been set")
+
+	require.NoError(t, n.setRetentionPolicy(admin, 30))
+	require.Equal(t, []byte(`{"retentionDays":30}`), n.privateData(assetCollection, "retentionPolicy"))
+}
+
+func TestPurgeAsset(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+
+	n.createAsset(owner, "asset1", 100)
+	require.NoError(t, n.grantAccess(owner, "asset1", org2))
+
+	err := n.purgeAsset(owner, "asset2")
+	require.EqualError(t, err, "asset not found: asset2")
+
+	err = n.purgeAsset(buyer, "asset1")
+	require.EqualError(t, err, "asset not found in owner's private Collection Org2MSPPrivateCollection: asset1")
+
+	n.agreeToTransfer(buyer, "asset1", 100)
+	require.NoError(t, n.purgeAsset(owner, "asset1"))
+
+	asset, err := contract.ReadAsset(n.query(owner), "asset1")
+	require.NoError(t, err)
+	require.Nil(t, asset)
+	requireAssetPurged(t, n, "asset1", "Org1MSPPrivateCollection")
+
+	// the copy shared with the grantee is purged and the consent record revoked, but the record can no longer be
+	// linked to the asset
+	sharedDetails, err := contract.ReadSharedAssetDetails(n.query(buyer), "asset1", org1)
+	require.NoError(t, err)
+	require.Nil(t, sharedDetails)
+	grants := n.accessGrants()
+	require.Len(t, grants, 1)
+	require.Equal(t, "revoked", grants[0].Status)
+	require.Empty(t, grants[0].DetailsHash)
+	grantsOfAsset, err := contract.ListGrants(n.query(owner), "asset1")
+	require.NoError(t, err)
+	require.Empty(t, grantsOfAsset)
+}
+
+func TestRetentionReport(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+
+	require.NoError(t, n.setRetentionPolicy(admin, 30))
+
+	n.ledger.SetTime(time.Date(2021, time.January, 15, 0, 0, 0, 0, time.UTC))
+	n.createAsset(owner, "asset2", 100)
+	n.ledger.SetTime(time.Date(2021, time.January, 2, 0, 0, 0, 0, time.UTC))
+	n.createAsset(buyer, "asset1", 200)
+
+	n.ledger.SetTime(time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC))
+	report, err := contract.RetentionReport(n.query(owner))
+	require.NoError(t, err)
+	require.Equal(t, []chaincode.RetentionReportEntry{
+		{ID: "asset1", OwnerCollection: "Org2MSPPrivateCollection", CreatedAt: "2021-01-02T00:00:00Z", PurgeAt: "2021-02-01T00:00:00Z", Eligible: true},
+		{ID: "asset2", OwnerCollection: "Org1MSPPrivateCollection", CreatedAt: "2021-01-15T00:00:00Z", PurgeAt: "2021-02-14T00:00:00Z", Eligible: false},
+	}, report)
+}
+
+func TestPurgeExpiredAssets(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+
+	n.createAsset(buyer, "asset1", 200)
+
+	_, err := n.purgeExpiredAssets(owner, "asset1")
+	require.EqualError(t, err, "no retention policy has been set")
+
+	require.NoError(t, n.setRetentionPolicy(admin, 30))
+	n.ledger.SetTime(time.Date(2021, time.January, 15, 0, 0, 0, 0, time.UTC))
+	n.createAsset(owner, "asset2", 100)
+
+	n.ledger.SetTime(time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC))
+	_, err = n.purgeExpiredAssets(owner, "asset1", "asset2")
+	require.EqualError(t, err, "the retention period of asset asset2 ends at 2021-02-14T00:00:00Z")
+	require.NotNil(t, n.retentionRecord("asset1"))
+
+	_, err = n.purgeExpiredAssets(owner, "asset3")
+	require.EqualError(t, err, "asset asset3 has no retention record")
+
+	// the expired asset of either org can be purged by a client of the other org
+	purged, err := n.purgeExpiredAssets(owner, "asset1", "asset1")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1"}, purged)
+	requireAssetPurged(t, n, "asset1", "Org2MSPPrivateCollection")
+
+	asset, err := contract.ReadAsset(n.query(owner), "asset2")
+	require.NoError(t, err)
+	require.NotNil(t, asset)
+	require.NotNil(t, n.retentionRecord("asset2"))
+
+	_, err = n.purgeExpiredAssets(owner, "asset1")
+	require.EqualError(t, err, "asset asset1 has no retention record")
+
+	n.ledger.SetTime(time.Date(2021, time.February, 14, 0, 0, 0, 0, time.UTC))
+	purged, err = n.purgeExpiredAssets(owner, "asset2")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset2"}, purged)
+	requireAssetPurged(t, n, "asset2", "Org1MSPPrivateCollection")
+}
+
+func TestPurgeExpiredAssetsAfterRetentionReport(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+
+	require.NoError(t, n.setRetentionPolicy(admin, 30))
+	n.createAsset(owner, "asset1", 100)
+
+	// a peer does not allow a transaction to purge private data after it has run a range query on private data,
+	// so the eligible assets must be looked up with RetentionReport in a separate query
+	n.ledger.SetTime(time.Date(2021, time.February, 1, 0, 0, 0, 0, time.UTC))
+	ctx, stub := n.newTransactionContext(owner, nil)
+	report, err := contract.RetentionReport(ctx)
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	require.True(t, report[0].Eligible)
+
+	_, err = contract.PurgeExpiredAssets(ctx, []string{report[0].ID})
+	require.EqualError(t, err, "failed to purge asset: txid ["+stub.TxID+"]: transaction has already performed queries on pvt data. Writes are not allowed")
+
+	purged, err := n.purgeExpiredAssets(owner, report[0].ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1"}, purged)
+	requireAssetPurged(t, n, "asset1", "Org1MSPPrivateCollection")
+}
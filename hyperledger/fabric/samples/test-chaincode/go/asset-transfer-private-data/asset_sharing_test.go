@@ -0,0 +1,232 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assettransferprivatedata
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+var auditor = newIdentity(org3, "auditor", nil)
+
+// revokeAccess stops sharing the private details of an asset of the identity with the grantee org
+func (n *network) revokeAccess(identity *memstub.ClientIdentity, assetID string, granteeMSP string) error {
+	contract := &chaincode.SmartContract{}
+	transient := map[string]interface{}{"access_revoke": map[string]string{"assetID": assetID, "granteeMSP": granteeMSP}}
+	return n.submit(identity, transient, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RevokeAccess(ctx)
+	})
+}
+
+// deleteAsset deletes an asset of the identity
+func (n *network) deleteAsset(identity *memstub.ClientIdentity, assetID string) error {
+	contract := &chaincode.SmartContract{}
+	transient := map[string]interface{}{"asset_delete": map[string]string{"assetID": assetID}}
+	return n.submit(identity, transient, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.DeleteAsset(ctx)
+	})
+}
+
+// sharedDetails returns the decrypted private details of an asset shared with the grantee, or nil if none are shared
+func (n *network) sharedDetails(grantee *memstub.ClientIdentity, assetID string, ownerMSP string) []byte {
+	contract := &chaincode.SmartContract{}
+	sharedDetails, err := contract.ReadSharedAssetDetails(n.query(grantee), assetID, ownerMSP)
+	require.NoError(n.t, err)
+	if sharedDetails == nil {
+		return nil
+	}
+	require.Equal(n.t, assetID, sharedDetails.ID)
+	require.Equal(n.t, ownerMSP, sharedDetails.OwnerMSP)
+
+	ciphertext, err := base64.StdEncoding.DecodeString(sharedDetails.Ciphertext)
+	require.NoError(n.t, err)
+	block, err := aes.NewCipher(testKey)
+	require.NoError(n.t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(n.t, err)
+	plaintext, err := gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+	require.NoError(n.t, err)
+	return plaintext
+}
+
+// listGrants returns the consent records of an asset as read by the identity
+func (n *network) listGrants(identity *memstub.ClientIdentity, assetID string) []*chaincode.AccessGrant {
+	contract := &chaincode.SmartContract{}
+	grants, err := contract.ListGrants(n.query(identity), assetID)
+	require.NoError(n.t, err)
+	return grants
+}
+
+// grantStatuses returns the status of each consent record of an asset by grantee org
+func (n *network) grantStatuses(assetID string) map[string]string {
+	statuses := make(map[string]string)
+	for _, grant := range n.listGrants(owner, assetID) {
+		statuses[grant.GranteeMSP] = grant.Status
+	}
+	return statuses
+}
+
+func TestGrantAccess(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset(owner, "asset1", 100)
+
+	err := n.submit(owner, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.SmartContract{}).GrantAccess(ctx)
+	})
+	require.EqualError(t, err, "access_grant key not found in the transient map")
+
+	err = n.submit(owner, map[string]interface{}{"access_grant": map[string]string{
+		"assetID": "asset1", "granteeMSP": org2, "encryptionKey": base64.StdEncoding.EncodeToString([]byte("short")),
+	}}, func(ctx contractapi.TransactionContextInterface) error {
+		return (&chaincode.SmartContract{}).GrantAccess(ctx)
+	})
+	require.EqualError(t, err, "encryptionKey field must be a 256 bit key")
+
+	err = n.grantAccess(buyer, "asset1", org3)
+	require.EqualError(t, err, "GrantAccess cannot be performed: Error submitting client identity does not own asset")
+
+	err = n.grantAccess(owner, "asset1", org1)
+	require.EqualError(t, err, "access cannot be granted to the owner org Org1MSP")
+
+	require.NoError(t, n.grantAccess(owner, "asset1", org2))
+
+	// the grantee decrypts the same private details that the owner holds
+	ownerDetails := n.privateData("Org1MSPPrivateCollection", "asset1")
+	require.Equal(t, ownerDetails, n.sharedDetails(buyer, "asset1", org1))
+	require.NotNil(t, n.privateData("Org1MSP_Org2MSPSharedCollection", "asset1"))
+	require.Nil(t, n.sharedDetails(auditor, "asset1", org1))
+
+	// the public consent record identifies the asset by its ID hashed with the salt in assetCollection, rather than
+	// by a hash that anyone could compute from a guessed ID
+	salt := n.privateData(assetCollection, compositeKey(t, "accessGrantSalt", "asset1"))
+	require.Len(t, salt, sha256.Size)
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte("asset1"))
+	assetHash := hex.EncodeToString(mac.Sum(nil))
+	unsaltedHash := sha256.Sum256([]byte("asset1"))
+	require.NotEqual(t, hex.EncodeToString(unsaltedHash[:]), assetHash)
+
+	grants := n.accessGrants()
+	require.Len(t, grants, 1)
+	require.Equal(t, assetHash, grants[0].AssetHash)
+	require.Equal(t, org1, grants[0].OwnerMSP)
+	require.Equal(t, org2, grants[0].GranteeMSP)
+	require.Equal(t, "Org1MSP_Org2MSPSharedCollection", grants[0].Collection)
+	require.Equal(t, "granted", grants[0].Status)
+	require.NotEmpty(t, grants[0].DetailsHash)
+
+	// a second grant keeps the salt, so that all the consent records of the asset have the same hash
+	require.NoError(t, n.grantAccess(owner, "asset1", org3))
+	require.Equal(t, salt, n.privateData(assetCollection, compositeKey(t, "accessGrantSalt", "asset1")))
+	require.Equal(t, ownerDetails, n.sharedDetails(auditor, "asset1", org1))
+	require.Equal(t, map[string]string{org2: "granted", org3: "granted"}, n.grantStatuses("asset1"))
+	for _, grant := range n.accessGrants() {
+		require.Equal(t, assetHash, grant.AssetHash)
+	}
+}
+
+func TestRevokeAccess(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset(owner, "asset1", 100)
+
+	err := n.revokeAccess(owner, "asset1", org2)
+	require.EqualError(t, err, "access to asset1 has not been granted to Org2MSP")
+
+	require.NoError(t, n.grantAccess(owner, "asset1", org2))
+	require.NoError(t, n.grantAccess(owner, "asset1", org3))
+
+	err = n.revokeAccess(buyer, "asset1", org2)
+	require.EqualError(t, err, "RevokeAccess cannot be performed: Error submitting client identity does not own asset")
+
+	require.NoError(t, n.revokeAccess(owner, "asset1", org2))
+
+	require.Nil(t, n.sharedDetails(buyer, "asset1", org1))
+	require.Nil(t, n.privateData("Org1MSP_Org2MSPSharedCollection", "asset1"))
+	require.NotNil(t, n.sharedDetails(auditor, "asset1", org1))
+	for _, grant := range n.listGrants(owner, "asset1") {
+		if grant.GranteeMSP == org2 {
+			require.Equal(t, "revoked", grant.Status)
+			require.Empty(t, grant.DetailsHash)
+		} else {
+			require.Equal(t, "granted", grant.Status)
+			require.NotEmpty(t, grant.DetailsHash)
+		}
+	}
+
+	err = n.revokeAccess(owner, "asset1", org2)
+	require.EqualError(t, err, "access to asset1 has not been granted to Org2MSP")
+
+	// access can be granted again after it was revoked
+	require.NoError(t, n.grantAccess(owner, "asset1", org2))
+	require.NotNil(t, n.sharedDetails(buyer, "asset1", org1))
+	require.Equal(t, map[string]string{org2: "granted", org3: "granted"}, n.grantStatuses("asset1"))
+}
+
+func TestListGrants(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset(owner, "asset1", 100)
+	n.createAsset(owner, "asset2", 100)
+
+	require.Empty(t, n.listGrants(owner, "asset1"))
+
+	require.NoError(t, n.grantAccess(owner, "asset1", org2))
+	require.NoError(t, n.grantAccess(owner, "asset2", org3))
+
+	// the grants of other assets are not listed
+	grants := n.listGrants(buyer, "asset1")
+	require.Len(t, grants, 1)
+	require.Equal(t, org2, grants[0].GranteeMSP)
+	require.Equal(t, map[string]string{org3: "granted"}, n.grantStatuses("asset2"))
+}
+
+func TestTransferAssetRevokesGrants(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset(owner, "asset1", 100)
+	require.NoError(t, n.grantAccess(owner, "asset1", org2))
+	require.NoError(t, n.grantAccess(owner, "asset1", org3))
+	require.NoError(t, n.revokeAccess(owner, "asset1", org3))
+
+	n.agreeToTransfer(buyer, "asset1", 100)
+	require.NoError(t, n.transfer(owner, "asset1", org2))
+
+	// the seller no longer has the details to share, so the copies are purged and the grants revoked
+	require.Nil(t, n.sharedDetails(buyer, "asset1", org1))
+	require.Nil(t, n.sharedDetails(auditor, "asset1", org1))
+	require.Equal(t, map[string]string{org2: "revoked", org3: "revoked"}, n.grantStatuses("asset1"))
+	require.Equal(t, "Org2MSPPrivateCollection", n.retentionRecord("asset1").OwnerCollection)
+
+	// the buyer can share the details that it now owns
+	err := n.grantAccess(owner, "asset1", org3)
+	require.EqualError(t, err, "GrantAccess cannot be performed: Error submitting client identity does not own asset")
+	require.NoError(t, n.grantAccess(buyer, "asset1", org3))
+	require.Equal(t, n.privateData("Org2MSPPrivateCollection", "asset1"), n.sharedDetails(auditor, "asset1", org2))
+}
+
+func TestDeleteAssetRevokesGrants(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset(owner, "asset1", 100)
+	require.NoError(t, n.grantAccess(owner, "asset1", org2))
+
+	require.NoError(t, n.deleteAsset(owner, "asset1"))
+
+	require.Nil(t, n.privateData(assetCollection, "asset1"))
+	require.Nil(t, n.privateData("Org1MSPPrivateCollection", "asset1"))
+	require.Nil(t, n.retentionRecord("asset1"))
+	require.Nil(t, n.sharedDetails(buyer, "asset1", org1))
+
+	// unlike a purge, a deletion keeps the salt, so that the revoked grants of the asset can still be audited
+	require.NotNil(t, n.privateData(assetCollection, compositeKey(t, "accessGrantSalt", "asset1")))
+	require.Equal(t, map[string]string{org2: "revoked"}, n.grantStatuses("asset1"))
+}
@@ -0,0 +1,211 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package assettransferprivatedata
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	org1 = "Org1MSP"
+	org2 = "Org2MSP"
+	org3 = "Org3MSP"
+
+	assetCollection = "assetCollection"
+)
+
+var start = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// testKey is the key that owners encrypt the details they share with
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+// network is an in-memory channel where each client submits its transactions to a peer of its own org
+type network struct {
+	t      *testing.T
+	ledger *memstub.Ledger
+	tx     int
+}
+
+func newNetwork(t *testing.T) *network {
+	ledger := memstub.NewLedger()
+	ledger.SetTime(start)
+	return &network{t: t, ledger: ledger}
+}
+
+// newIdentity returns an identity of a client of an org with the attributes. Its ID is base64 encoded, like the
+// client identity library returns it.
+func newIdentity(mspID string, name string, attributes map[string]string) *memstub.ClientIdentity {
+	identity := memstub.NewClientIdentity(base64.StdEncoding.EncodeToString([]byte(clientID(name))), mspID)
+	for attribute, value := range attributes {
+		identity.Attributes[attribute] = value
+	}
+	return identity
+}
+
+// clientID returns the decoded ID of an identity created by newIdentity, which the contract records as the owner
+func clientID(name string) string {
+	return fmt.Sprintf("x509::CN=%s::CN=ca.example.com", name)
+}
+
+var (
+	owner = newIdentity(org1, "owner", nil)
+	buyer = newIdentity(org2, "buyer", nil)
+	admin = newIdentity(org1, "admin", map[string]string{"retention.admin": "true"})
+)
+
+// newTransactionContext returns the context of a transaction of the identity, with the transient values encoded as JSON
+func (n *network) newTransactionContext(identity *memstub.ClientIdentity, transient map[string]interface{}) (*contractapi.TransactionContext, *memstub.Stub) {
+	// the contract checks that clients only access the private data of their own org's peer
+	os.Setenv("CORE_PEER_LOCALMSPID", identity.MSPID)
+
+	n.tx++
+	stub := n.ledger.NewStub(fmt.Sprintf("tx%d", n.tx))
+	for key, value := range transient {
+		valueJSON, err := json.Marshal(value)
+		require.NoError(n.t, err)
+		stub.Transient[key] = valueJSON
+	}
+
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(identity)
+	return ctx, stub
+}
+
+// submit runs fn as a transaction of the identity and commits it if it succeeds
+func (n *network) submit(identity *memstub.ClientIdentity, transient map[string]interface{}, fn func(ctx contractapi.TransactionContextInterface) error) error {
+	ctx, stub := n.newTransactionContext(identity, transient)
+	err := fn(ctx)
+	if err != nil {
+		return err
+	}
+	return stub.Commit()
+}
+
+// query returns the context of a transaction of the identity that is not committed
+func (n *network) query(identity *memstub.ClientIdentity) contractapi.TransactionContextInterface {
+	ctx, _ := n.newTransactionContext(identity, nil)
+	return ctx
+}
+
+// privateData returns the committed value of a key in a collection, or nil if there is none
+func (n *network) privateData(collection string, key string) []byte {
+	value, err := n.query(owner).GetStub().GetPrivateData(collection, key)
+	require.NoError(n.t, err)
+	return value
+}
+
+// compositeKey returns a composite key of the contract
+func compositeKey(t *testing.T, objectType string, attributes ...string) string {
+	key, err := (&memstub.Stub{}).CreateCompositeKey(objectType, attributes)
+	require.NoError(t, err)
+	return key
+}
+
+// createAsset creates an asset owned by the identity, with the appraised value in the collection of its org
+func (n *network) createAsset(identity *memstub.ClientIdentity, assetID string, appraisedValue int) {
+	contract := &chaincode.SmartContract{}
+	transient := map[string]interface{}{"asset_properties": map[string]interface{}{
+		"objectType": "asset", "assetID": assetID, "color": "blue", "size": 5, "appraisedValue": appraisedValue,
+	}}
+	err := n.submit(identity, transient, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAsset(ctx)
+	})
+	require.NoError(n.t, err)
+}
+
+// agreeToTransfer agrees to buy an asset at the appraised value as the identity
+func (n *network) agreeToTransfer(identity *memstub.ClientIdentity, assetID string, appraisedValue int) {
+	contract := &chaincode.SmartContract{}
+	transient := map[string]interface{}{"asset_value": chaincode.AssetPrivateDetails{ID: assetID, AppraisedValue: appraisedValue}}
+	err := n.submit(identity, transient, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.AgreeToTransfer(ctx)
+	})
+	require.NoError(n.t, err)
+}
+
+// transfer transfers an asset of the identity to the buyer org that agreed to buy it
+func (n *network) transfer(identity *memstub.ClientIdentity, assetID string, buyerMSP string) error {
+	contract := &chaincode.SmartContract{}
+	transient := map[string]interface{}{"asset_owner": map[string]string{"assetID": assetID, "buyerMSP": buyerMSP}}
+	return n.submit(identity, transient, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.TransferAsset(ctx)
+	})
+}
+
+// grantAccess shares the private details of an asset of the identity with the grantee org, encrypted with testKey
+func (n *network) grantAccess(identity *memstub.ClientIdentity, assetID string, granteeMSP string) error {
+	contract := &chaincode.SmartContract{}
+	transient := map[string]interface{}{"access_grant": map[string]string{
+		"assetID": assetID, "granteeMSP": granteeMSP, "encryptionKey": base64.StdEncoding.EncodeToString(testKey),
+	}}
+	return n.submit(identity, transient, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.GrantAccess(ctx)
+	})
+}
+
+// accessGrants returns all the consent records in the public state
+func (n *network) accessGrants() []*chaincode.AccessGrant {
+	resultsIterator, err := n.query(owner).GetStub().GetStateByPartialCompositeKey("accessGrant", []string{})
+	require.NoError(n.t, err)
+	defer resultsIterator.Close()
+
+	grants := []*chaincode.AccessGrant{}
+	for resultsIterator.HasNext() {
+		response, err := resultsIterator.Next()
+		require.NoError(n.t, err)
+		var grant chaincode.AccessGrant
+		require.NoError(n.t, json.Unmarshal(response.Value, &grant))
+		grants = append(grants, &grant)
+	}
+	return grants
+}
+
+// retentionRecord returns the retention record of an asset, or nil if it has none
+func (n *network) retentionRecord(assetID string) *chaincode.RetentionRecord {
+	recordJSON := n.privateData(assetCollection, compositeKey(n.t, "retention", assetID))
+	if recordJSON == nil {
+		return nil
+	}
+	var record chaincode.RetentionRecord
+	require.NoError(n.t, json.Unmarshal(recordJSON, &record))
+	return &record
+}
+
+func TestTransferAsset(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+
+	n.createAsset(owner, "asset1", 100)
+	require.Equal(t, &chaincode.RetentionRecord{ID: "asset1", OwnerCollection: "Org1MSPPrivateCollection", CreatedAt: "2021-01-01T00:00:00Z"}, n.retentionRecord("asset1"))
+
+	err := n.transfer(owner, "asset1", org2)
+	require.EqualError(t, err, "failed transfer verification: hash of appraised value for asset1 does not exist in collection Org2MSPPrivateCollection. AgreeToTransfer must be called by the buyer first")
+
+	n.agreeToTransfer(buyer, "asset1", 100)
+	require.NoError(t, n.transfer(owner, "asset1", org2))
+
+	asset, err := contract.ReadAsset(n.query(buyer), "asset1")
+	require.NoError(t, err)
+	require.Equal(t, clientID("buyer"), asset.Owner)
+	require.Nil(t, n.privateData("Org1MSPPrivateCollection", "asset1"))
+	require.NotNil(t, n.privateData("Org2MSPPrivateCollection", "asset1"))
+	agreement, err := contract.ReadTransferAgreement(n.query(buyer), "asset1")
+	require.NoError(t, err)
+	require.Nil(t, agreement)
+
+	// the retention period still counts from the creation of the asset, but its details are now in the buyer's collection
+	require.Equal(t, &chaincode.RetentionRecord{ID: "asset1", OwnerCollection: "Org2MSPPrivateCollection", CreatedAt: "2021-01-01T00:00:00Z"}, n.retentionRecord("asset1"))
+}
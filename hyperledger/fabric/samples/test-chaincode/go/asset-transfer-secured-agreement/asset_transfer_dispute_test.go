@@ -0,0 +1,205 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package securedagreement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode/tradingMarbles/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// arbitratedPrice is an agreement with Org3 as the arbiter for a day after the transfer
+const arbitratedPrice = `{"asset_id":"asset1","trade_id":"trade1","price":100,"arbiter_org":"Org3MSP","dispute_window_hours":24}`
+
+// transferWithArbiter transfers asset1 from Org1 to Org2 under arbitratedPrice
+func transferWithArbiter(t *testing.T, n *network) {
+	n.createAsset(org1)
+	require.NoError(t, n.agreeToSell(org1, arbitratedPrice))
+	require.NoError(t, n.agreeToBuy(org2, arbitratedPrice))
+	_, err := n.transfer(org1, org2, arbitratedPrice)
+	require.NoError(t, err)
+}
+
+func TestAgreementExpiry(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset(org1)
+
+	err := n.agreeToSell(org1, `{"asset_id":"asset1","trade_id":"trade1","price":100,"expiry":"2020-12-31T00:00:00Z"}`)
+	require.EqualError(t, err, "agreement expiry 2020-12-31T00:00:00Z is in the past")
+	err = n.agreeToSell(org1, `{"asset_id":"asset1","trade_id":"trade1","price":100,"expiry":"tomorrow"}`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid agreement expiry "tomorrow"`)
+
+	price := `{"asset_id":"asset1","trade_id":"trade1","price":100,"expiry":"2021-01-02T00:00:00Z"}`
+	require.NoError(t, n.agreeToSell(org1, price))
+	require.NoError(t, n.agreeToBuy(org2, price))
+
+	n.ledger.SetTime(start.Add(24 * time.Hour))
+	_, err = n.transfer(org1, org2, price)
+	require.EqualError(t, err, "failed transfer verification: agreement for asset asset1 expired at 2021-01-02T00:00:00Z")
+	require.Equal(t, org1, n.readAsset().OwnerOrg)
+}
+
+func TestWithdrawAgreement(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+	price := `{"asset_id":"asset1","trade_id":"trade1","price":100}`
+	withdraw := func(orgID string) error {
+		_, err := n.submit(orgID, nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.WithdrawAgreement(ctx, "asset1")
+		})
+		return err
+	}
+
+	n.createAsset(org1)
+	require.NoError(t, n.agreeToSell(org1, price))
+	require.NoError(t, n.agreeToBuy(org2, price))
+
+	require.NoError(t, withdraw(org1))
+	require.EqualError(t, withdraw(org1), "no agreement to withdraw for asset asset1")
+	_, err := n.transfer(org1, org2, price)
+	require.EqualError(t, err, "failed transfer verification: seller price for asset1 does not exist")
+
+	// the buyer's bid is withdrawn separately
+	require.NoError(t, n.agreeToSell(org1, price))
+	require.NoError(t, withdraw(org2))
+	_, err = contract.GetAssetBidPrice(n.query(org2), "asset1")
+	require.EqualError(t, err, "asset price does not exist: asset1")
+	_, err = n.transfer(org1, org2, price)
+	require.EqualError(t, err, "failed transfer verification: buyer price for asset1 does not exist")
+}
+
+func TestAgreementArbiterTerms(t *testing.T) {
+	n := newNetwork(t)
+	n.createAsset(org1)
+
+	err := n.agreeToSell(org1, `{"asset_id":"asset1","trade_id":"trade1","price":100,"arbiter_org":"Org3MSP"}`)
+	require.EqualError(t, err, "an agreement with an arbiter requires a positive dispute window")
+	err = n.agreeToSell(org1, `{"asset_id":"asset1","trade_id":"trade1","price":100,"dispute_window_hours":24}`)
+	require.EqualError(t, err, "a dispute window requires an arbiter")
+
+	price := `{"asset_id":"asset1","trade_id":"trade1","price":100,"arbiter_org":"Org2MSP","dispute_window_hours":24}`
+	require.NoError(t, n.agreeToSell(org1, price))
+	require.NoError(t, n.agreeToBuy(org2, price))
+	_, err = n.transfer(org1, org2, price)
+	require.EqualError(t, err, "failed transfer verification: the arbiter of a transfer cannot be the seller or the buyer")
+}
+
+func TestFreezeAsset(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+	freeze := func(orgID string) error {
+		_, err := n.submit(orgID, nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.FreezeAsset(ctx, "asset1")
+		})
+		return err
+	}
+	unfreeze := func(orgID string) error {
+		_, err := n.submit(orgID, nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.UnfreezeAsset(ctx, "asset1")
+		})
+		return err
+	}
+	changeDescription := func() error {
+		_, err := n.submit(org2, nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.ChangePublicDescription(ctx, "asset1", "a disputed asset")
+		})
+		return err
+	}
+
+	transferWithArbiter(t, n)
+	asset := n.readAsset()
+	require.Equal(t, org3, asset.ArbiterOrg)
+	require.Equal(t, org1, asset.PreviousOwnerOrg)
+	require.Equal(t, "2021-01-02T00:00:03Z", asset.DisputeWindowEnd)
+
+	// the arbiter can update the asset with either the buyer or the seller, but the parties cannot without it
+	require.True(t, n.endorsedBy(org2, org3))
+	require.True(t, n.endorsedBy(org1, org3))
+	require.False(t, n.endorsedBy(org1, org2))
+	require.False(t, n.endorsedBy(org3))
+
+	// the new owner cannot sell the asset during the dispute window
+	require.EqualError(t, n.agreeToSell(org2, `{"asset_id":"asset1","trade_id":"trade2","price":150}`),
+		"asset asset1 cannot be sold until the dispute window of its last transfer ends at 2021-01-02T00:00:03Z")
+
+	require.EqualError(t, freeze(org1), "a client from Org1MSP is not the arbiter of asset asset1")
+	require.EqualError(t, unfreeze(org3), "asset asset1 is not frozen")
+	require.NoError(t, freeze(org3))
+	require.EqualError(t, freeze(org3), "asset asset1 is already frozen")
+	require.True(t, n.readAsset().Frozen)
+	require.EqualError(t, changeDescription(), "asset asset1 is frozen by arbiter Org3MSP")
+
+	// a frozen asset stays frozen after the dispute window, until the arbiter releases it
+	n.ledger.SetTime(start.Add(48 * time.Hour))
+	require.EqualError(t, changeDescription(), "asset asset1 is frozen by arbiter Org3MSP")
+	require.NoError(t, unfreeze(org3))
+	require.EqualError(t, freeze(org3), "the dispute window of asset asset1 ended at 2021-01-02T00:00:03Z")
+
+	// the first update after the dispute window removes the arbiter
+	require.NoError(t, changeDescription())
+	asset = n.readAsset()
+	require.Empty(t, asset.ArbiterOrg)
+	require.Empty(t, asset.DisputeWindowEnd)
+	require.True(t, n.endorsedBy(org2))
+	require.False(t, n.endorsedBy(org1, org3))
+
+	_, err := n.submit(org3, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.FreezeAsset(ctx, "asset1")
+	})
+	require.EqualError(t, err, "asset asset1 has no arbiter")
+}
+
+func TestReverseTransfer(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+	reverse := func(orgID string, properties string) error {
+		_, err := n.submit(orgID, map[string]string{"asset_properties": properties}, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.ReverseTransfer(ctx, "asset1")
+		})
+		return err
+	}
+
+	transferWithArbiter(t, n)
+
+	require.EqualError(t, reverse(org2, assetProperties), "a client from Org2MSP is not the arbiter of asset asset1")
+	err := reverse(org3, `{"object_type":"asset_properties","asset_id":"asset1","color":"red"}`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match on-chain hash")
+
+	require.NoError(t, reverse(org3, assetProperties))
+	asset := n.readAsset()
+	require.Equal(t, org1, asset.OwnerOrg)
+	require.Empty(t, asset.ArbiterOrg)
+	require.Empty(t, asset.PreviousOwnerOrg)
+	require.False(t, asset.Frozen)
+	require.True(t, n.endorsedBy(org1))
+	require.False(t, n.endorsedBy(org2, org3))
+
+	properties, err := contract.GetAssetPrivateProperties(n.query(org1), "asset1")
+	require.NoError(t, err)
+	require.Equal(t, assetProperties, properties)
+	_, err = contract.GetAssetPrivateProperties(n.query(org2), "asset1")
+	require.EqualError(t, err, "asset private details does not exist in client org's collection: asset1")
+
+	require.EqualError(t, reverse(org3, assetProperties), "asset asset1 has no arbiter")
+}
+
+func TestReverseTransferAfterDisputeWindow(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+
+	transferWithArbiter(t, n)
+
+	n.ledger.SetTime(start.Add(25 * time.Hour))
+	_, err := n.submit(org3, map[string]string{"asset_properties": assetProperties}, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.ReverseTransfer(ctx, "asset1")
+	})
+	require.EqualError(t, err, "the dispute window of asset asset1 ended at 2021-01-02T00:00:03Z")
+	require.Equal(t, org2, n.readAsset().OwnerOrg)
+}
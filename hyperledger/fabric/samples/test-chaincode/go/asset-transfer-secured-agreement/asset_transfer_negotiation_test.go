@@ -0,0 +1,212 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package securedagreement
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode/tradingMarbles/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// makeOffer submits an offer for asset1 of a client of an org to the counterparty org
+func (n *network) makeOffer(orgID string, counterpartyOrgID string, price string) (*chaincode.Negotiation, error) {
+	contract := &chaincode.SmartContract{}
+	var negotiation *chaincode.Negotiation
+	_, err := n.submit(orgID, map[string]string{"asset_price": price}, func(ctx contractapi.TransactionContextInterface) (err error) {
+		negotiation, err = contract.MakeOffer(ctx, "asset1", counterpartyOrgID)
+		return err
+	})
+	return negotiation, err
+}
+
+func TestNegotiation(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+	ask := `{"asset_id":"asset1","trade_id":"trade1","price":120}`
+	bid := `{"asset_id":"asset1","trade_id":"trade1","price":100}`
+
+	n.createAsset(org1)
+
+	_, err := n.makeOffer(org1, org1, ask)
+	require.EqualError(t, err, "a client from Org1MSP cannot make an offer to its own org")
+	_, err = n.makeOffer(org1, "", ask)
+	require.EqualError(t, err, "an offer for asset asset1 must name the buyer org")
+	_, err = n.makeOffer(org2, org3, bid)
+	require.EqualError(t, err, "offers for asset asset1 must be made to its owner Org1MSP")
+
+	negotiation, err := n.makeOffer(org1, org2, ask)
+	require.NoError(t, err)
+	require.Equal(t, 1, negotiation.Round)
+	require.False(t, negotiation.Matched)
+	negotiation, err = n.makeOffer(org2, org1, bid)
+	require.NoError(t, err)
+	require.Equal(t, 2, negotiation.Round)
+	require.False(t, negotiation.Matched)
+	require.NotEqual(t, negotiation.Offers[0].PriceHash, negotiation.Offers[1].PriceHash)
+
+	// the negotiation must match before the asset can be transferred to the buyer, even at a price that both
+	// orgs agreed to out-of-band
+	_, err = n.transfer(org1, org2, bid)
+	require.EqualError(t, err, "failed transfer verification: the latest offers of the negotiation of asset asset1 with Org2MSP do not match")
+	require.NoError(t, n.agreeToSell(org1, bid))
+	require.NoError(t, n.agreeToBuy(org2, bid))
+	_, err = n.transfer(org1, org2, bid)
+	require.EqualError(t, err, "failed transfer verification: the latest offers of the negotiation of asset asset1 with Org2MSP do not match")
+
+	negotiation, err = n.makeOffer(org2, org1, ask)
+	require.NoError(t, err)
+	require.Equal(t, 3, negotiation.Round)
+	require.True(t, negotiation.Matched)
+
+	// the transfer must be at the matched price
+	_, err = n.transfer(org1, org2, bid)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "seller hasn't agreed to the passed trade id and price")
+	transferTxID, err := n.transfer(org1, org2, ask)
+	require.NoError(t, err)
+	require.Equal(t, org2, n.readAsset().OwnerOrg)
+
+	negotiation, err = contract.GetNegotiation(n.query(org2), "asset1", org2)
+	require.NoError(t, err)
+	require.Equal(t, transferTxID, negotiation.TransferTxID)
+
+	offers, err := contract.GetNegotiationOffers(n.query(org2), "asset1", org2)
+	require.NoError(t, err)
+	require.Equal(t, []chaincode.Agreement{
+		{ID: "asset1", TradeID: "trade1", Price: 100},
+		{ID: "asset1", TradeID: "trade1", Price: 120},
+	}, offers)
+
+	history, err := contract.QueryNegotiationHistory(n.query(org2), "asset1", org2)
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	require.Equal(t, transferTxID, history[0].TxId, "the latest update comes first")
+	require.Equal(t, 3, history[1].Record.Round)
+
+	// the transfer closes the negotiation, so the next offer starts a new one with the new owner
+	negotiation, err = n.makeOffer(org1, org2, ask)
+	require.NoError(t, err)
+	require.Equal(t, org2, negotiation.SellerOrg)
+	require.Equal(t, org1, negotiation.BuyerOrg)
+	require.Equal(t, 1, negotiation.Round)
+}
+
+func TestNegotiationsWithSeveralBuyers(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+	askOrg2 := `{"asset_id":"asset1","trade_id":"trade2","price":120}`
+	askOrg3 := `{"asset_id":"asset1","trade_id":"trade3","price":110}`
+
+	n.createAsset(org1)
+	_, err := n.makeOffer(org1, org2, askOrg2)
+	require.NoError(t, err)
+	_, err = n.makeOffer(org1, org3, askOrg3)
+	require.NoError(t, err)
+
+	// the offer to Org3 does not replace the asking price in the negotiation with Org2
+	_, err = n.makeOffer(org2, org1, askOrg3)
+	require.NoError(t, err)
+	_, err = n.transfer(org1, org2, askOrg3)
+	require.EqualError(t, err, "failed transfer verification: the latest offers of the negotiation of asset asset1 with Org2MSP do not match")
+
+	negotiation, err := n.makeOffer(org2, org1, askOrg2)
+	require.NoError(t, err)
+	require.True(t, negotiation.Matched)
+	_, err = n.makeOffer(org3, org1, askOrg3)
+	require.NoError(t, err)
+
+	// both negotiations matched, and the asking price to Org2 was kept although Org3 got a later offer,
+	// but the asset can only be transferred once
+	_, err = n.transfer(org1, org2, askOrg2)
+	require.NoError(t, err)
+	_, err = n.transfer(org1, org3, askOrg3)
+	require.EqualError(t, err, "failed transfer verification: a client from Org1MSP cannot transfer a asset owned by Org2MSP")
+
+	// the negotiation with Org3 was with the previous owner, so Org3 can no longer continue it
+	_, err = n.makeOffer(org3, org1, askOrg3)
+	require.EqualError(t, err, "offers for asset asset1 must be made to its owner Org2MSP")
+	negotiation, err = contract.GetNegotiation(n.query(org3), "asset1", org3)
+	require.NoError(t, err)
+	require.Equal(t, org1, negotiation.SellerOrg)
+	require.True(t, negotiation.Matched)
+	require.Empty(t, negotiation.TransferTxID)
+}
+
+func TestWithdrawOffer(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+	ask := `{"asset_id":"asset1","trade_id":"trade1","price":120}`
+	bid := `{"asset_id":"asset1","trade_id":"trade1","price":100}`
+	withdraw := func(orgID string, buyerOrgID string) error {
+		_, err := n.submit(orgID, nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.WithdrawOffer(ctx, "asset1", buyerOrgID)
+		})
+		return err
+	}
+
+	n.createAsset(org1)
+	require.EqualError(t, withdraw(org1, org2), "no open negotiation for asset asset1 with Org2MSP")
+	_, err := n.makeOffer(org1, org2, ask)
+	require.NoError(t, err)
+	negotiation, err := n.makeOffer(org2, org1, ask)
+	require.NoError(t, err)
+	require.True(t, negotiation.Matched)
+
+	// only the two orgs of the negotiation can withdraw from it
+	require.EqualError(t, withdraw(org3, org2), "a client from Org3MSP cannot withdraw from the negotiation of asset asset1 with Org2MSP")
+
+	// the buyer abandons the negotiation, which deletes its negotiated price, so the matched price can no
+	// longer be used
+	require.NoError(t, withdraw(org2, org2))
+	require.EqualError(t, withdraw(org1, org2), "no open negotiation for asset asset1 with Org2MSP")
+	negotiation, err = contract.GetNegotiation(n.query(org1), "asset1", org2)
+	require.NoError(t, err)
+	require.False(t, negotiation.Matched)
+	require.Equal(t, org2, negotiation.WithdrawnBy)
+	require.NotEmpty(t, negotiation.WithdrawTxID)
+	_, err = n.transfer(org1, org2, ask)
+	require.EqualError(t, err, "failed transfer verification: seller price for asset1 does not exist")
+
+	// the withdrawn negotiation no longer holds up a transfer at a price agreed to with AgreeToSell and AgreeToBuy
+	require.NoError(t, n.agreeToSell(org1, bid))
+	require.NoError(t, n.agreeToBuy(org2, bid))
+	_, err = n.transfer(org1, org2, bid)
+	require.NoError(t, err)
+	require.Equal(t, org2, n.readAsset().OwnerOrg)
+}
+
+func TestOfferAfterWithdrawal(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+	ask := `{"asset_id":"asset1","trade_id":"trade1","price":120}`
+	bid := `{"asset_id":"asset1","trade_id":"trade1","price":100}`
+
+	n.createAsset(org1)
+	_, err := n.makeOffer(org1, org2, ask)
+	require.NoError(t, err)
+	_, err = n.makeOffer(org2, org1, bid)
+	require.NoError(t, err)
+	_, err = n.submit(org1, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.WithdrawOffer(ctx, "asset1", org2)
+	})
+	require.NoError(t, err)
+
+	// the next offer starts a new negotiation, in which the buyer's bid from before the withdrawal does not count
+	negotiation, err := n.makeOffer(org1, org2, bid)
+	require.NoError(t, err)
+	require.Equal(t, 1, negotiation.Round)
+	require.Empty(t, negotiation.WithdrawTxID)
+	require.False(t, negotiation.Matched)
+	_, err = n.transfer(org1, org2, bid)
+	require.EqualError(t, err, "failed transfer verification: the latest offers of the negotiation of asset asset1 with Org2MSP do not match")
+
+	negotiation, err = n.makeOffer(org2, org1, bid)
+	require.NoError(t, err)
+	require.True(t, negotiation.Matched)
+	_, err = n.transfer(org1, org2, bid)
+	require.NoError(t, err)
+}
@@ -0,0 +1,172 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package securedagreement
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/chaincode/tradingMarbles/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// sell transfers asset1 from the seller org to the buyer org at a price that both agree to
+func (n *network) sell(sellerOrgID string, buyerOrgID string, price int) string {
+	priceJSON := fmt.Sprintf(`{"asset_id":"asset1","trade_id":"trade%d","price":%d}`, n.tx, price)
+	require.NoError(n.t, n.agreeToSell(sellerOrgID, priceJSON))
+	require.NoError(n.t, n.agreeToBuy(buyerOrgID, priceJSON))
+	txID, err := n.transfer(sellerOrgID, buyerOrgID, priceJSON)
+	require.NoError(n.t, err)
+	return txID
+}
+
+// verifyReceipt verifies a receipt of asset1 as a client of Org3, which is not a party to the transfer
+func (n *network) verifyReceipt(assetID string, receiptJSON string) error {
+	contract := &chaincode.SmartContract{}
+	_, err := contract.VerifyReceipt(n.query(org3), assetID, receiptJSON)
+	return err
+}
+
+func TestReceipts(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+
+	// Org1 sells the asset on the first day, buys it back on the third day and sells it again on the fifth day
+	n.createAsset(org1)
+	firstSale := n.sell(org1, org2, 100)
+	n.ledger.SetTime(start.Add(48 * time.Hour))
+	purchase := n.sell(org2, org1, 150)
+	n.ledger.SetTime(start.Add(96 * time.Hour))
+	secondSale := n.sell(org1, org2, 200)
+
+	saleReceipts := func(startDate, endDate string) []string {
+		receipts, err := contract.GetSaleReceipts(n.query(org1), startDate, endDate)
+		require.NoError(t, err)
+		txIDs := []string{}
+		for _, receipt := range receipts {
+			require.Equal(t, org1, receipt.SellerOrg)
+			txIDs = append(txIDs, receipt.TxID)
+		}
+		return txIDs
+	}
+	require.ElementsMatch(t, []string{firstSale, secondSale}, saleReceipts("", ""))
+	require.Equal(t, []string{secondSale}, saleReceipts("2021-01-02T00:00:00Z", ""))
+	require.Equal(t, []string{firstSale}, saleReceipts("", "2021-01-02T00:00:00Z"))
+	require.Empty(t, saleReceipts("2021-01-02T00:00:00Z", "2021-01-04T00:00:00Z"))
+
+	receipts, err := contract.GetSaleReceipts(n.query(org1), "", "")
+	require.NoError(t, err)
+	var receipt chaincode.Receipt
+	for _, receipt = range receipts {
+		if receipt.TxID == secondSale {
+			break
+		}
+	}
+	require.Equal(t, 200, receipt.Price)
+
+	// the start date is included and the end date is excluded
+	timestamp := receipt.Timestamp.Format(time.RFC3339)
+	require.Equal(t, []string{secondSale}, saleReceipts(timestamp, ""))
+	require.Equal(t, []string{firstSale}, saleReceipts("", timestamp))
+
+	purchases, err := contract.GetPurchaseReceipts(n.query(org1), "2021-01-02T00:00:00+01:00", "2021-01-04T00:00:00Z")
+	require.NoError(t, err)
+	require.Len(t, purchases, 1)
+	require.Equal(t, purchase, purchases[0].TxID)
+	require.Equal(t, org2, purchases[0].SellerOrg)
+	require.Equal(t, 150, purchases[0].Price)
+
+	_, err = contract.GetPurchaseReceipts(n.query(org1), "yesterday", "")
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid date "yesterday", expected RFC3339 format`)
+
+	// receipts are in the implicit private data collection of the client's org on its own peer
+	org2Identity := n.query(org2).GetClientIdentity()
+	ctx, _ := n.newTransactionContext(org1, nil)
+	ctx.SetClientIdentity(org2Identity)
+	_, err = contract.GetSaleReceipts(ctx, "", "")
+	require.EqualError(t, err, "failed to get verified OrgID: client from org Org2MSP is not authorized to read or write private data from an org Org1MSP peer")
+}
+
+func TestVerifyReceipt(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+
+	n.createAsset(org1)
+	txID := n.sell(org1, org2, 100)
+
+	receipts, err := contract.GetPurchaseReceipts(n.query(org2), "", "")
+	require.NoError(t, err)
+	require.Len(t, receipts, 1)
+	receiptJSON, err := json.Marshal(receipts[0])
+	require.NoError(t, err)
+	require.NoError(t, n.verifyReceipt("asset1", string(receiptJSON)))
+
+	// the receipt matches the on-chain hashes however the client formats it
+	reformatted, err := json.MarshalIndent(map[string]interface{}{
+		"txID":      txID,
+		"price":     100,
+		"buyerOrg":  org2,
+		"sellerOrg": org1,
+		"assetID":   "asset1",
+		"timestamp": receipts[0].Timestamp,
+	}, "", "  ")
+	require.NoError(t, err)
+	require.NoError(t, n.verifyReceipt("asset1", string(reformatted)))
+
+	// a receipt with a different price does not match the hash in the seller's collection
+	forged := receipts[0]
+	forged.Price = 90
+	forgedJSON, err := json.Marshal(forged)
+	require.NoError(t, err)
+	err = n.verifyReceipt("asset1", string(forgedJSON))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match on-chain hash")
+	require.Contains(t, err.Error(), "in seller's collection")
+
+	// the receipt of the seller must exist, and so must the receipt of the buyer
+	forged = receipts[0]
+	forged.TxID = "tx99"
+	forgedJSON, err = json.Marshal(forged)
+	require.NoError(t, err)
+	require.EqualError(t, n.verifyReceipt("asset1", string(forgedJSON)), "receipt of transaction tx99 does not exist in seller's collection")
+
+	forged = receipts[0]
+	forged.BuyerOrg = org3
+	forgedJSON, err = json.Marshal(forged)
+	require.NoError(t, err)
+	err = n.verifyReceipt("asset1", string(forgedJSON))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "in seller's collection")
+
+	require.EqualError(t, n.verifyReceipt("asset2", string(receiptJSON)), "receipt is for asset asset1, not asset2")
+	require.Error(t, n.verifyReceipt("asset1", "not json"))
+}
+
+func TestVerifyReceiptOfBuyer(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+
+	n.createAsset(org1)
+	n.sell(org1, org2, 100)
+	receipts, err := contract.GetSaleReceipts(n.query(org1), "", "")
+	require.NoError(t, err)
+	receiptJSON, err := json.Marshal(receipts[0])
+	require.NoError(t, err)
+
+	// the seller's receipt alone does not verify a transfer when the buyer's receipt is missing
+	buyReceiptKey, err := n.query(org2).GetStub().CreateCompositeKey("BR", []string{"asset1", receipts[0].TxID})
+	require.NoError(t, err)
+	_, err = n.submit(org2, nil, func(ctx contractapi.TransactionContextInterface) error {
+		return ctx.GetStub().DelPrivateData("_implicit_org_Org2MSP", buyReceiptKey)
+	})
+	require.NoError(t, err)
+
+	err = n.verifyReceipt("asset1", string(receiptJSON))
+	require.EqualError(t, err, fmt.Sprintf("receipt of transaction %s does not exist in buyer's collection", receipts[0].TxID))
+}
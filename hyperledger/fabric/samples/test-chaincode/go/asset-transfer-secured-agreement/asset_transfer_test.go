@@ -0,0 +1,207 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package securedagreement
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-samples/chaincode/tradingMarbles/chaincode"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	org1 = "Org1MSP"
+	org2 = "Org2MSP"
+	org3 = "Org3MSP"
+
+	assetProperties = `{"object_type":"asset_properties","asset_id":"asset1","color":"blue","size":35,"salt":"a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"}`
+)
+
+var start = time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// network is an in-memory channel where the client of each org submits its transactions to a peer of its own org
+type network struct {
+	t      *testing.T
+	ledger *memstub.Ledger
+	tx     int
+}
+
+func newNetwork(t *testing.T) *network {
+	ledger := memstub.NewLedger()
+	ledger.SetTime(start)
+	return &network{t: t, ledger: ledger}
+}
+
+func (n *network) newTransactionContext(orgID string, transient map[string]string) (*contractapi.TransactionContext, *memstub.Stub) {
+	// the contract checks that clients only access the private data of their own org's peer
+	os.Setenv("CORE_PEER_LOCALMSPID", orgID)
+
+	n.tx++
+	stub := n.ledger.NewStub(fmt.Sprintf("tx%d", n.tx))
+	for key, value := range transient {
+		stub.Transient[key] = []byte(value)
+	}
+
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(memstub.NewClientIdentity("client of "+orgID, orgID))
+	return ctx, stub
+}
+
+// submit simulates a transaction of a client of an org and commits it if it succeeds. It returns the transaction ID.
+func (n *network) submit(orgID string, transient map[string]string, fn func(ctx contractapi.TransactionContextInterface) error) (string, error) {
+	ctx, stub := n.newTransactionContext(orgID, transient)
+	err := fn(ctx)
+	if err != nil {
+		return stub.TxID, err
+	}
+	return stub.TxID, stub.Commit()
+}
+
+// query returns a transaction context of a client of an org for reading the ledger
+func (n *network) query(orgID string) contractapi.TransactionContextInterface {
+	ctx, _ := n.newTransactionContext(orgID, nil)
+	return ctx
+}
+
+// createAsset creates asset1 with assetProperties as its private properties
+func (n *network) createAsset(orgID string) {
+	contract := &chaincode.SmartContract{}
+	_, err := n.submit(orgID, map[string]string{"asset_properties": assetProperties}, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAsset(ctx, "asset1", "a blue asset")
+	})
+	require.NoError(n.t, err)
+}
+
+// agreeToSell submits the asking price of the owner of asset1
+func (n *network) agreeToSell(orgID string, price string) error {
+	contract := &chaincode.SmartContract{}
+	_, err := n.submit(orgID, map[string]string{"asset_price": price}, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.AgreeToSell(ctx, "asset1")
+	})
+	return err
+}
+
+// agreeToBuy submits the bid price of a buyer of asset1
+func (n *network) agreeToBuy(orgID string, price string) error {
+	contract := &chaincode.SmartContract{}
+	_, err := n.submit(orgID, map[string]string{"asset_price": price}, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.AgreeToBuy(ctx, "asset1")
+	})
+	return err
+}
+
+// transfer transfers asset1 from its owner to a buyer at the passed price. It returns the transaction ID.
+func (n *network) transfer(sellerOrgID string, buyerOrgID string, price string) (string, error) {
+	contract := &chaincode.SmartContract{}
+	transient := map[string]string{"asset_properties": assetProperties, "asset_price": price}
+	return n.submit(sellerOrgID, transient, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.TransferAsset(ctx, "asset1", buyerOrgID)
+	})
+}
+
+// readAsset returns the public data of asset1
+func (n *network) readAsset() *chaincode.Asset {
+	contract := &chaincode.SmartContract{}
+	asset, err := contract.ReadAsset(n.query(org1), "asset1")
+	require.NoError(n.t, err)
+	return asset
+}
+
+// endorsedBy returns true if endorsements of peers of the given orgs satisfy the endorsement policy of asset1
+func (n *network) endorsedBy(orgIDs ...string) bool {
+	stub := n.ledger.NewStub("policy")
+	policyBytes, err := stub.GetStateValidationParameter("asset1")
+	require.NoError(n.t, err)
+	require.NotNil(n.t, policyBytes)
+
+	policy := &common.SignaturePolicyEnvelope{}
+	require.NoError(n.t, proto.Unmarshal(policyBytes, policy))
+
+	endorsers := map[int32]bool{}
+	for i, identity := range policy.Identities {
+		require.Equal(n.t, msp.MSPPrincipal_ROLE, identity.PrincipalClassification)
+		role := &msp.MSPRole{}
+		require.NoError(n.t, proto.Unmarshal(identity.Principal, role))
+		require.Equal(n.t, msp.MSPRole_PEER, role.Role)
+		for _, orgID := range orgIDs {
+			if role.MspIdentifier == orgID {
+				endorsers[int32(i)] = true
+			}
+		}
+	}
+
+	return satisfied(policy.Rule, endorsers)
+}
+
+func satisfied(rule *common.SignaturePolicy, endorsers map[int32]bool) bool {
+	switch rule := rule.Type.(type) {
+	case *common.SignaturePolicy_SignedBy:
+		return endorsers[rule.SignedBy]
+	case *common.SignaturePolicy_NOutOf_:
+		var count int32
+		for _, subRule := range rule.NOutOf.Rules {
+			if satisfied(subRule, endorsers) {
+				count++
+			}
+		}
+		return count >= rule.NOutOf.N
+	}
+	return false
+}
+
+func TestTransferAsset(t *testing.T) {
+	n := newNetwork(t)
+	contract := &chaincode.SmartContract{}
+	price := `{"asset_id":"asset1","trade_id":"trade1","price":100}`
+
+	n.createAsset(org1)
+	require.True(t, n.endorsedBy(org1))
+
+	require.EqualError(t, n.agreeToSell(org2, price), "a client from Org2MSP cannot sell an asset owned by Org1MSP")
+	require.NoError(t, n.agreeToSell(org1, price))
+	_, err := n.transfer(org1, org2, price)
+	require.EqualError(t, err, "failed transfer verification: buyer price for asset1 does not exist")
+
+	require.NoError(t, n.agreeToBuy(org2, `{"asset_id":"asset1","trade_id":"trade1","price":90}`))
+	_, err = n.transfer(org1, org2, price)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "buyer hasn't agreed to the passed trade id and price")
+
+	require.NoError(t, n.agreeToBuy(org2, price))
+	_, err = n.transfer(org2, org2, price)
+	require.EqualError(t, err, "failed transfer verification: a client from Org2MSP cannot transfer a asset owned by Org1MSP")
+	_, err = n.transfer(org1, org2, price)
+	require.NoError(t, err)
+
+	require.Equal(t, org2, n.readAsset().OwnerOrg)
+	require.True(t, n.endorsedBy(org2))
+	require.False(t, n.endorsedBy(org1))
+
+	properties, err := contract.GetAssetPrivateProperties(n.query(org2), "asset1")
+	require.NoError(t, err)
+	require.Equal(t, assetProperties, properties)
+	_, err = contract.GetAssetPrivateProperties(n.query(org1), "asset1")
+	require.EqualError(t, err, "asset private details does not exist in client org's collection: asset1")
+
+	// the prices are removed with the transfer
+	_, err = contract.GetAssetSalesPrice(n.query(org1), "asset1")
+	require.EqualError(t, err, "asset price does not exist: asset1")
+	_, err = contract.GetAssetBidPrice(n.query(org2), "asset1")
+	require.EqualError(t, err, "asset price does not exist: asset1")
+}
+
+func TestContractMetadata(t *testing.T) {
+	_, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,203 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	smartcontract "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+	"github.com/stretchr/testify/require"
+)
+
+// runMultiUnitAuction creates a multi-unit auction, submits bids of the given prices
+// and quantities, reveals them and closes the auction. It returns the bidders.
+func runMultiUnitAuction(n *network, seller []byte, auctionID string, quantity int, reserveHash string, prices []int, quantities []int) [][]byte {
+	contract := &smartcontract.SmartContract{}
+	_, err := n.submit(seller, "CreateMultiUnitAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateMultiUnitAuction(ctx, auctionID, "tickets", quantity, reserveHash, "", "")
+	})
+	require.NoError(n.t, err)
+
+	bidders := make([][]byte, len(prices))
+	txIDs := make([]string, len(prices))
+	bids := make([][]byte, len(prices))
+	for i := range prices {
+		bidders[i] = n.user(fmt.Sprintf("%s-bidder%d", auctionID, i+1))
+		txIDs[i], bids[i], err = n.bidUnits(bidders[i], auctionID, prices[i], quantities[i])
+		require.NoError(n.t, err)
+	}
+
+	_, err = n.submit(seller, "CloseAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CloseAuction(ctx, auctionID)
+	})
+	require.NoError(n.t, err)
+	for i := range bidders {
+		i := i
+		_, err = n.submit(bidders[i], "RevealBid", map[string][]byte{"bid": bids[i]}, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.RevealBid(ctx, auctionID, txIDs[i])
+		})
+		require.NoError(n.t, err)
+	}
+
+	return bidders
+}
+
+func TestMultiUnitAuction(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller := n.user("seller")
+
+	_, err := n.submit(seller, "CreateMultiUnitAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateMultiUnitAuction(ctx, "auction0", "tickets", 0, "", "", "")
+	})
+	require.EqualError(t, err, "quantity must be at least 1")
+
+	// the third bid is only partly filled and sets the clearing price, the fourth gets nothing
+	bidders := runMultiUnitAuction(n, seller, "auction1", 5, "", []int{100, 80, 70, 50}, []int{2, 2, 3, 1})
+	auction, err := endAuction(n, seller, "auction1", nil)
+	require.NoError(t, err)
+	require.Equal(t, "ended", auction.Status)
+	require.Equal(t, []smartcontract.Allocation{
+		{Bidder: n.id(bidders[0]), Org: "Org1MSP", Quantity: 2},
+		{Bidder: n.id(bidders[1]), Org: "Org1MSP", Quantity: 2},
+		{Bidder: n.id(bidders[2]), Org: "Org1MSP", Quantity: 1},
+	}, auction.Allocations)
+	require.Equal(t, 70, auction.Price, "every winner pays the price of the lowest allocated bid")
+
+	// when there are fewer units bid for than for sale, every bid is filled
+	bidders = runMultiUnitAuction(n, seller, "auction2", 10, "", []int{60, 90}, []int{3, 2})
+	auction, err = endAuction(n, seller, "auction2", nil)
+	require.NoError(t, err)
+	require.Equal(t, []smartcontract.Allocation{
+		{Bidder: n.id(bidders[1]), Org: "Org1MSP", Quantity: 2},
+		{Bidder: n.id(bidders[0]), Org: "Org1MSP", Quantity: 3},
+	}, auction.Allocations)
+	require.Equal(t, 60, auction.Price)
+}
+
+func TestMultiUnitAuctionReserve(t *testing.T) {
+	n := newNetwork(t)
+	seller := n.user("seller")
+	reserveJSON := []byte(`{"price":75,"salt":"c0ffee"}`)
+
+	// the bid below the reserve is not filled and a unit is left unsold
+	bidders := runMultiUnitAuction(n, seller, "auction1", 5, reserveHash(reserveJSON), []int{100, 80, 70}, []int{2, 2, 3})
+	auction, err := endAuction(n, seller, "auction1", reserveJSON)
+	require.NoError(t, err)
+	require.Equal(t, []smartcontract.Allocation{
+		{Bidder: n.id(bidders[0]), Org: "Org1MSP", Quantity: 2},
+		{Bidder: n.id(bidders[1]), Org: "Org1MSP", Quantity: 2},
+	}, auction.Allocations)
+	require.Equal(t, 80, auction.Price)
+}
+
+func TestDutchAuction(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller, bidder1, bidder2 := n.user("seller"), n.user("bidder1"), n.user("bidder2")
+	bidder1ID, bidder2ID := n.id(bidder1), n.id(bidder2)
+
+	for _, tc := range []struct {
+		start, floor, decrement, interval int
+		err                               string
+	}{
+		{100, 0, 10, 60, "the start price must not be below the floor price, which must be at least 1"},
+		{30, 40, 10, 60, "the start price must not be below the floor price, which must be at least 1"},
+		{100, 40, -1, 60, "the decrement must not be negative and the interval must be at least 1 second"},
+		{100, 40, 10, 0, "the decrement must not be negative and the interval must be at least 1 second"},
+	} {
+		tc := tc
+		_, err := n.submit(seller, "CreateDutchAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.CreateDutchAuction(ctx, "auction1", "painting", tc.start, tc.floor, tc.decrement, tc.interval)
+		})
+		require.EqualError(t, err, tc.err)
+	}
+
+	// the price drops by 10 every minute from 100 to the floor of 40
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	n.ledger.SetTime(start)
+	_, err := n.submit(seller, "CreateDutchAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateDutchAuction(ctx, "auction1", "painting", 100, 40, 10, 60)
+	})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		elapsed time.Duration
+		price   int
+	}{
+		{30 * time.Second, 100},
+		{time.Minute, 90},
+		{5*time.Minute + 59*time.Second, 50},
+		{6 * time.Minute, 40},
+		{time.Hour, 40},
+	} {
+		n.ledger.SetTime(start.Add(tc.elapsed))
+		_, err = n.submit(seller, "QueryDutchPrice", nil, func(ctx contractapi.TransactionContextInterface) error {
+			price, err := contract.QueryDutchPrice(ctx, "auction1")
+			require.NoError(t, err)
+			require.Equal(t, tc.price, price, "price after %s", tc.elapsed)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	// bids are placed privately and accepted at the price of the accepting transaction
+	placeBid := func(creator []byte, bidderID string, price int) (string, []byte) {
+		bidJSON := []byte(fmt.Sprintf(`{"objectType":"bid","price":%d,"org":"Org1MSP","bidder":"%s","quantity":1}`, price, bidderID))
+		txID, err := n.submit(creator, "Bid", map[string][]byte{"bid": bidJSON}, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := contract.Bid(ctx, "auction1")
+			return err
+		})
+		require.NoError(t, err)
+		return txID, bidJSON
+	}
+	acceptPrice := func(creator []byte, txID string, bidJSON []byte) error {
+		_, err := n.submit(creator, "AcceptPrice", map[string][]byte{"bid": bidJSON}, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.AcceptPrice(ctx, "auction1", txID)
+		})
+		return err
+	}
+	bid1, bid1JSON := placeBid(bidder1, bidder1ID, 70)
+	bid2, bid2JSON := placeBid(bidder2, bidder2ID, 90)
+
+	n.ledger.SetTime(start.Add(2*time.Minute + 30*time.Second))
+	require.EqualError(t, acceptPrice(bidder1, bid1, bid1JSON), "bid of 70 is below the current price of 80")
+	require.NoError(t, acceptPrice(bidder2, bid2, bid2JSON))
+
+	n.ledger.SetTime(start.Add(4 * time.Minute))
+	require.EqualError(t, acceptPrice(bidder1, bid1, bid1JSON), "cannot accept the price of a closed or ended auction")
+
+	_, err = n.submit(seller, "QueryAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		auction, err := contract.QueryAuction(ctx, "auction1")
+		require.NoError(t, err)
+		require.Equal(t, "ended", auction.Status)
+		require.Equal(t, bidder2ID, auction.Winner)
+		require.Equal(t, 80, auction.Price, "the winner pays the current price, not its bid")
+		for _, bid := range auction.RevealedBids {
+			require.Equal(t, 80, bid.Price)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestQueryDutchPriceOfSealedBidAuction(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller := n.user("seller")
+
+	_, err := n.submit(seller, "CreateAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAuction(ctx, "auction1", "painting", "", "", "", "")
+	})
+	require.NoError(t, err)
+	_, err = n.submit(seller, "QueryDutchPrice", nil, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := contract.QueryDutchPrice(ctx, "auction1")
+		return err
+	})
+	require.EqualError(t, err, "auction auction1 is not a Dutch auction")
+}
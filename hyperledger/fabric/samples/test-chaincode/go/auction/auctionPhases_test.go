@@ -0,0 +1,241 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	smartcontract "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuctionDeadlines(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller, bidder1, bidder2, anyone := n.user("seller"), n.user("bidder1"), n.user("bidder2"), n.user("anyone")
+
+	_, err := n.submit(seller, "CreateAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAuction(ctx, "auction1", "painting", "", "", "2021-01-01T01:00:00Z", "2021-01-01T02:00:00Z")
+	})
+	require.NoError(t, err)
+	_, err = n.submit(seller, "RequireDeposit", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RequireDeposit(ctx, "auction1", tokenChaincodeName, 100)
+	})
+	require.NoError(t, err)
+
+	for _, bidder := range [][]byte{bidder1, bidder2} {
+		n.fund(bidder, 500)
+		n.invokeToken(bidder, "Approve", "chaincode:auction", "100")
+	}
+	bid1, bid1JSON, err := n.bid(bidder1, "auction1", 80)
+	require.NoError(t, err)
+	bid2, _, err := n.bid(bidder2, "auction1", 90)
+	require.NoError(t, err)
+
+	// the seller cannot close the auction early, and nobody can advance it
+	_, err = n.submit(seller, "CloseAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CloseAuction(ctx, "auction1")
+	})
+	require.EqualError(t, err, "cannot close auction before its bidding deadline 2021-01-01T01:00:00Z")
+	_, err = n.submit(anyone, "AdvanceAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.AdvanceAuction(ctx, "auction1")
+	})
+	require.EqualError(t, err, "auction auction1 has no deadline that has passed")
+
+	// after the bidding deadline no more bids are accepted, and bids can be revealed
+	// before the auction has been advanced
+	n.ledger.SetTime(time.Date(2021, time.January, 1, 1, 30, 0, 0, time.UTC))
+	_, err = n.submit(anyone, "Bid", map[string][]byte{"bid": []byte(`{}`)}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := contract.Bid(ctx, "auction1")
+		return err
+	})
+	require.EqualError(t, err, "cannot bid on closed or ended auction")
+	_, err = n.submit(bidder1, "RevealBid", map[string][]byte{"bid": bid1JSON}, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RevealBid(ctx, "auction1", bid1)
+	})
+	require.NoError(t, err)
+	_, err = n.submit(anyone, "AdvanceAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.AdvanceAuction(ctx, "auction1")
+	})
+	require.NoError(t, err)
+
+	// the higher bid of bidder2 prevents the seller from ending the auction early
+	_, err = n.submit(seller, "EndAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.EndAuction(ctx, "auction1")
+	})
+	require.Error(t, err)
+
+	// after the reveal deadline the unrevealed bid is left out and its deposit forfeited
+	n.ledger.SetTime(time.Date(2021, time.January, 1, 2, 0, 0, 0, time.UTC))
+	_, err = n.submit(anyone, "AdvanceAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.AdvanceAuction(ctx, "auction1")
+	})
+	require.NoError(t, err)
+
+	_, err = n.submit(anyone, "QueryAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		auction, err := contract.QueryAuction(ctx, "auction1")
+		require.NoError(t, err)
+		require.Equal(t, "ended", auction.Status)
+		require.Equal(t, n.id(bidder1), auction.Winner)
+		require.Equal(t, 80, auction.Price)
+		bidKey, err := ctx.GetStub().CreateCompositeKey("bid", []string{"auction1", bid2})
+		require.NoError(t, err)
+		require.Equal(t, []string{bidKey}, auction.UnrevealedBids)
+
+		entries, err := contract.QueryEscrow(ctx, "auction1")
+		require.NoError(t, err)
+		for _, entry := range entries {
+			if entry.TxID == bid2 {
+				require.Equal(t, "forfeited", entry.Status)
+				require.Equal(t, 100, entry.Paid)
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 180, n.balance(n.id(seller)))
+	require.Equal(t, 20, n.balance("chaincode:auction"))
+}
+
+// Without a deposit an unrevealed bid is left out when the reveal deadline passes, but
+// its bidder is not penalized
+func TestUnrevealedBidWithoutDeposit(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller, bidder1, bidder2, anyone := n.user("seller"), n.user("bidder1"), n.user("bidder2"), n.user("anyone")
+
+	_, err := n.submit(seller, "CreateAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAuction(ctx, "auction1", "painting", "", "", "2021-01-01T01:00:00Z", "2021-01-01T02:00:00Z")
+	})
+	require.NoError(t, err)
+	n.fund(bidder2, 500)
+
+	bid1, bid1JSON, err := n.bid(bidder1, "auction1", 80)
+	require.NoError(t, err)
+	bid2, _, err := n.bid(bidder2, "auction1", 90)
+	require.NoError(t, err)
+
+	n.ledger.SetTime(time.Date(2021, time.January, 1, 1, 30, 0, 0, time.UTC))
+	_, err = n.submit(bidder1, "RevealBid", map[string][]byte{"bid": bid1JSON}, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RevealBid(ctx, "auction1", bid1)
+	})
+	require.NoError(t, err)
+
+	n.ledger.SetTime(time.Date(2021, time.January, 1, 2, 0, 0, 0, time.UTC))
+	_, err = n.submit(anyone, "AdvanceAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.AdvanceAuction(ctx, "auction1")
+	})
+	require.NoError(t, err)
+
+	_, err = n.submit(anyone, "QueryAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		auction, err := contract.QueryAuction(ctx, "auction1")
+		require.NoError(t, err)
+		require.Equal(t, "ended", auction.Status)
+		require.Equal(t, n.id(bidder1), auction.Winner)
+		require.Equal(t, 80, auction.Price)
+		bidKey, err := ctx.GetStub().CreateCompositeKey("bid", []string{"auction1", bid2})
+		require.NoError(t, err)
+		require.Equal(t, []string{bidKey}, auction.UnrevealedBids)
+
+		entries, err := contract.QueryEscrow(ctx, "auction1")
+		require.NoError(t, err)
+		require.Empty(t, entries)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, 500, n.balance(n.id(bidder2)))
+}
+
+func TestAdvanceAuctionReserve(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller, bidder, anyone := n.user("seller"), n.user("bidder"), n.user("anyone")
+
+	reserveJSON := []byte(`{"price":50,"salt":"5b5e"}`)
+	reserveHash := sha256.Sum256(reserveJSON)
+
+	for _, auctionID := range []string{"auction1", "auction2"} {
+		auctionID := auctionID
+		_, err := n.submit(seller, "CreateAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.CreateAuction(ctx, auctionID, "painting", hex.EncodeToString(reserveHash[:]), "second-price", "2021-01-01T01:00:00Z", "2021-01-01T02:00:00Z")
+		})
+		require.NoError(t, err)
+	}
+
+	bidTxIDs := make(map[string]string)
+	bids := make(map[string][]byte)
+	for _, auctionID := range []string{"auction1", "auction2"} {
+		bidTxID, bidJSON, err := n.bid(bidder, auctionID, 80)
+		require.NoError(t, err)
+		bidTxIDs[auctionID], bids[auctionID] = bidTxID, bidJSON
+	}
+
+	n.ledger.SetTime(time.Date(2021, time.January, 1, 1, 0, 0, 0, time.UTC))
+	for _, auctionID := range []string{"auction1", "auction2"} {
+		auctionID := auctionID
+		_, err := n.submit(bidder, "RevealBid", map[string][]byte{"bid": bids[auctionID]}, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.RevealBid(ctx, auctionID, bidTxIDs[auctionID])
+		})
+		require.NoError(t, err)
+	}
+
+	// the seller reveals the reserve of auction1 only
+	n.ledger.SetTime(time.Date(2021, time.January, 1, 1, 30, 0, 0, time.UTC))
+	_, err := n.submit(seller, "RevealReserve", map[string][]byte{"reserve": reserveJSON}, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RevealReserve(ctx, "auction1")
+	})
+	require.NoError(t, err)
+
+	n.ledger.SetTime(time.Date(2021, time.January, 1, 3, 0, 0, 0, time.UTC))
+	for _, auctionID := range []string{"auction1", "auction2"} {
+		auctionID := auctionID
+		_, err = n.submit(anyone, "AdvanceAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.AdvanceAuction(ctx, auctionID)
+		})
+		require.NoError(t, err)
+	}
+
+	_, err = n.submit(anyone, "QueryAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		auction1, err := contract.QueryAuction(ctx, "auction1")
+		require.NoError(t, err)
+		require.Equal(t, n.id(bidder), auction1.Winner)
+		require.Equal(t, 50, auction1.Price, "the winner pays the reserve price")
+
+		auction2, err := contract.QueryAuction(ctx, "auction2")
+		require.NoError(t, err)
+		require.Equal(t, "ended", auction2.Status)
+		require.Empty(t, auction2.Winner, "an unrevealed reserve is not met")
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestCreateAuctionDeadlines(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller := n.user("seller")
+
+	for _, tc := range []struct {
+		bidding, reveal, err string
+	}{
+		{"2021-01-01T01:00:00Z", "", "both the bidding and the reveal deadline must be given"},
+		{"tomorrow", "2021-01-01T02:00:00Z", `invalid bidding deadline "tomorrow": parsing time "tomorrow" as "2006-01-02T15:04:05Z07:00": cannot parse "tomorrow" as "2006"`},
+		{"2020-12-31T00:00:00Z", "2021-01-01T02:00:00Z", "the bidding deadline must be in the future"},
+		{"2021-01-01T02:00:00Z", "2021-01-01T03:00:00+02:00", "the reveal deadline must be after the bidding deadline"},
+	} {
+		tc := tc
+		_, err := n.submit(seller, "CreateAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.CreateAuction(ctx, "auction1", "painting", "", "", tc.bidding, tc.reveal)
+		})
+		require.EqualError(t, err, tc.err, fmt.Sprintf("deadlines %s and %s", tc.bidding, tc.reveal))
+	}
+}
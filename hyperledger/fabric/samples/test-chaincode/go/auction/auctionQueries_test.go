@@ -0,0 +1,100 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	smartcontract "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryAuctions(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller1, seller2 := n.user("seller1"), n.user("seller2")
+
+	for _, auction := range []struct {
+		seller []byte
+		id     string
+	}{{seller1, "auction1"}, {seller2, "auction2"}, {seller1, "auction3"}} {
+		auction := auction
+		_, err := n.submit(auction.seller, "CreateAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.CreateAuction(ctx, auction.id, "item of "+auction.id, "", "", "", "")
+		})
+		require.NoError(t, err)
+	}
+	_, err := n.submit(seller1, "CloseAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CloseAuction(ctx, "auction1")
+	})
+	require.NoError(t, err)
+
+	items := func(page *smartcontract.PaginatedAuctions) []string {
+		items := []string{}
+		for _, auction := range page.Records {
+			items = append(items, auction.ItemSold)
+		}
+		return items
+	}
+
+	_, err = n.submit(seller1, "QueryAuctions", nil, func(ctx contractapi.TransactionContextInterface) error {
+		page, err := contract.QueryAuctionsByStatus(ctx, "open", 1, "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"item of auction2"}, items(page))
+		require.NotEmpty(t, page.Bookmark)
+
+		page, err = contract.QueryAuctionsByStatus(ctx, "open", 1, page.Bookmark)
+		require.NoError(t, err)
+		require.Equal(t, []string{"item of auction3"}, items(page))
+		require.Empty(t, page.Bookmark)
+
+		page, err = contract.QueryAuctionsByStatus(ctx, "closed", 10, "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"item of auction1"}, items(page))
+		require.EqualValues(t, 1, page.FetchedRecordsCount)
+
+		page, err = contract.QueryAuctionsBySeller(ctx, n.id(seller1), 10, "")
+		require.NoError(t, err)
+		require.Equal(t, []string{"item of auction1", "item of auction3"}, items(page))
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestQueryMyBids(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller, bidder1, bidder2 := n.user("seller"), n.user("bidder1"), n.user("bidder2")
+
+	for _, auctionID := range []string{"auction1", "auction2"} {
+		auctionID := auctionID
+		_, err := n.submit(seller, "CreateAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.CreateAuction(ctx, auctionID, "painting", "", "", "", "")
+		})
+		require.NoError(t, err)
+	}
+
+	bid1, _, err := n.bid(bidder1, "auction1", 80)
+	require.NoError(t, err)
+	_, _, err = n.bid(bidder2, "auction1", 90)
+	require.NoError(t, err)
+	bid3, _, err := n.bid(bidder1, "auction2", 70)
+	require.NoError(t, err)
+
+	_, err = n.submit(bidder1, "QueryMyBids", nil, func(ctx contractapi.TransactionContextInterface) error {
+		page, err := contract.QueryMyBids(ctx, 1, "")
+		require.NoError(t, err)
+		require.Equal(t, []*smartcontract.MyBid{{AuctionID: "auction1", TxID: bid1, Price: 80, Quantity: 1}}, page.Records)
+		require.NotEmpty(t, page.Bookmark)
+
+		page, err = contract.QueryMyBids(ctx, 1, page.Bookmark)
+		require.NoError(t, err)
+		require.Equal(t, []*smartcontract.MyBid{{AuctionID: "auction2", TxID: bid3, Price: 70, Quantity: 1}}, page.Records)
+		require.Empty(t, page.Bookmark)
+		return nil
+	})
+	require.NoError(t, err)
+}
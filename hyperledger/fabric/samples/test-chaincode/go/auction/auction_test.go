@@ -0,0 +1,169 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	smartcontract "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+	"github.com/stretchr/testify/require"
+)
+
+// sealedBid is a bid submitted to an auction, with the JSON needed to reveal it
+type sealedBid struct {
+	bidder []byte
+	txID   string
+	json   []byte
+}
+
+// runSealedBidAuction creates an auction, submits bids of the given prices and reveals
+// them in the given order. It returns the bids in the order they were submitted.
+func runSealedBidAuction(n *network, seller []byte, auctionID string, reserveHash string, settlement string, prices []int, revealOrder []int) []sealedBid {
+	contract := &smartcontract.SmartContract{}
+	_, err := n.submit(seller, "CreateAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAuction(ctx, auctionID, "painting", reserveHash, settlement, "", "")
+	})
+	require.NoError(n.t, err)
+
+	bids := make([]sealedBid, len(prices))
+	for i, price := range prices {
+		bidder := n.user(fmt.Sprintf("%s-bidder%d", auctionID, i+1))
+		txID, bidJSON, err := n.bid(bidder, auctionID, price)
+		require.NoError(n.t, err)
+		bids[i] = sealedBid{bidder: bidder, txID: txID, json: bidJSON}
+	}
+
+	_, err = n.submit(seller, "CloseAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CloseAuction(ctx, auctionID)
+	})
+	require.NoError(n.t, err)
+
+	for _, i := range revealOrder {
+		bid := bids[i]
+		_, err = n.submit(bid.bidder, "RevealBid", map[string][]byte{"bid": bid.json}, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.RevealBid(ctx, auctionID, bid.txID)
+		})
+		require.NoError(n.t, err)
+	}
+
+	return bids
+}
+
+// endAuction ends an auction, revealing the reserve if it is not nil, and returns the ended auction
+func endAuction(n *network, seller []byte, auctionID string, reserveJSON []byte) (*smartcontract.Auction, error) {
+	contract := &smartcontract.SmartContract{}
+	var transient map[string][]byte
+	if reserveJSON != nil {
+		transient = map[string][]byte{"reserve": reserveJSON}
+	}
+	_, err := n.submit(seller, "EndAuction", transient, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.EndAuction(ctx, auctionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var auction *smartcontract.Auction
+	_, err = n.submit(seller, "QueryAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		auction, err = contract.QueryAuction(ctx, auctionID)
+		return err
+	})
+	return auction, err
+}
+
+func reserveHash(reserveJSON []byte) string {
+	hash := sha256.Sum256(reserveJSON)
+	return hex.EncodeToString(hash[:])
+}
+
+func TestSecondPriceSettlement(t *testing.T) {
+	n := newNetwork(t)
+	seller := n.user("seller")
+
+	bids := runSealedBidAuction(n, seller, "auction1", "", "second-price", []int{80, 60, 70}, []int{0, 1, 2})
+	auction, err := endAuction(n, seller, "auction1", nil)
+	require.NoError(t, err)
+	require.Equal(t, "ended", auction.Status)
+	require.Equal(t, n.id(bids[0].bidder), auction.Winner)
+	require.Equal(t, 70, auction.Price, "the winner pays the second highest bid")
+
+	// the same bids in a first-price auction
+	bids = runSealedBidAuction(n, seller, "auction2", "", "", []int{80, 60, 70}, []int{2, 1, 0})
+	auction, err = endAuction(n, seller, "auction2", nil)
+	require.NoError(t, err)
+	require.Equal(t, n.id(bids[0].bidder), auction.Winner)
+	require.Equal(t, 80, auction.Price)
+
+	// a single bid in a second-price auction without a reserve pays nothing more than the reserve
+	bids = runSealedBidAuction(n, seller, "auction3", "", "second-price", []int{80}, []int{0})
+	auction, err = endAuction(n, seller, "auction3", nil)
+	require.NoError(t, err)
+	require.Equal(t, n.id(bids[0].bidder), auction.Winner)
+	require.Equal(t, 0, auction.Price)
+}
+
+func TestReservePrice(t *testing.T) {
+	n := newNetwork(t)
+	seller := n.user("seller")
+	reserveJSON := []byte(`{"price":75,"salt":"9a1c"}`)
+
+	// the second highest bid is below the reserve, so the winner pays the reserve
+	bids := runSealedBidAuction(n, seller, "auction1", reserveHash(reserveJSON), "second-price", []int{80, 60}, []int{0, 1})
+	_, err := endAuction(n, seller, "auction1", nil)
+	require.EqualError(t, err, "reserve key not found in the transient map")
+	_, err = endAuction(n, seller, "auction1", []byte(`{"price":10,"salt":"9a1c"}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not match reserve hash in auction")
+
+	auction, err := endAuction(n, seller, "auction1", reserveJSON)
+	require.NoError(t, err)
+	require.Equal(t, n.id(bids[0].bidder), auction.Winner)
+	require.Equal(t, 75, auction.Price)
+	require.Equal(t, 75, auction.ReservePrice)
+	require.True(t, auction.ReserveRevealed)
+
+	// a bid equal to the reserve wins, a bid below it does not
+	bids = runSealedBidAuction(n, seller, "auction2", reserveHash(reserveJSON), "", []int{75}, []int{0})
+	auction, err = endAuction(n, seller, "auction2", reserveJSON)
+	require.NoError(t, err)
+	require.Equal(t, n.id(bids[0].bidder), auction.Winner)
+	require.Equal(t, 75, auction.Price)
+
+	runSealedBidAuction(n, seller, "auction3", reserveHash(reserveJSON), "second-price", []int{74, 50}, []int{0, 1})
+	auction, err = endAuction(n, seller, "auction3", reserveJSON)
+	require.NoError(t, err)
+	require.Equal(t, "ended", auction.Status)
+	require.Empty(t, auction.Winner)
+	require.Equal(t, 0, auction.Price)
+}
+
+func TestRevealOrderBreaksTies(t *testing.T) {
+	n := newNetwork(t)
+	seller := n.user("seller")
+
+	// of two bids of the same price the one revealed first wins
+	bids := runSealedBidAuction(n, seller, "auction1", "", "", []int{80, 80, 50}, []int{1, 2, 0})
+	auction, err := endAuction(n, seller, "auction1", nil)
+	require.NoError(t, err)
+	require.Equal(t, n.id(bids[1].bidder), auction.Winner)
+	require.Equal(t, 80, auction.Price)
+
+	revealOrders := map[string]int{}
+	for _, bid := range auction.RevealedBids {
+		revealOrders[bid.Bidder] = bid.RevealOrder
+	}
+	require.Equal(t, map[string]int{n.id(bids[1].bidder): 1, n.id(bids[2].bidder): 2, n.id(bids[0].bidder): 3}, revealOrders)
+
+	// in a second-price auction the tied bid sets the price
+	bids = runSealedBidAuction(n, seller, "auction2", "", "second-price", []int{80, 80}, []int{0, 1})
+	auction, err = endAuction(n, seller, "auction2", nil)
+	require.NoError(t, err)
+	require.Equal(t, n.id(bids[0].bidder), auction.Winner)
+	require.Equal(t, 80, auction.Price)
+}
@@ -0,0 +1,254 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package auction
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	smartcontract "github.com/hyperledger/fabric-samples/auction/chaincode-go/smart-contract"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+const tokenChaincodeName = "token_erc20"
+
+// network is an in-memory channel with the auction and the token chaincode
+type network struct {
+	t      *testing.T
+	ledger *memstub.Ledger
+	token  *contractapi.ContractChaincode
+	tx     int
+}
+
+func newNetwork(t *testing.T) *network {
+	// the auction stores bids on the peer of the bidder's organization
+	os.Setenv("CORE_PEER_LOCALMSPID", "Org1MSP")
+
+	token, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	require.NoError(t, err)
+	ledger := memstub.NewLedger()
+	ledger.RegisterChaincode(tokenChaincodeName, token)
+
+	n := &network{t: t, ledger: ledger, token: token}
+	n.invokeToken(n.user("bank"), "Initialize", "Auction Token", "AUC", "0")
+	return n
+}
+
+// user returns the creator of a client of Org1
+func (n *network) user(name string) []byte {
+	creator, err := memstub.NewCreator("Org1MSP", name)
+	require.NoError(n.t, err)
+	return creator
+}
+
+// id returns the client ID of a creator, which is also its token account
+func (n *network) id(creator []byte) string {
+	stub := n.ledger.NewStub("id")
+	stub.Creator = creator
+	identity, err := cid.New(stub)
+	require.NoError(n.t, err)
+	id, err := identity.GetID()
+	require.NoError(n.t, err)
+	return id
+}
+
+// submit simulates and commits an auction transaction. It returns the transaction ID.
+func (n *network) submit(creator []byte, function string, transient map[string][]byte, fn func(ctx contractapi.TransactionContextInterface) error) (string, error) {
+	n.tx++
+	txID := fmt.Sprintf("tx%d", n.tx)
+	stub := n.ledger.NewChaincodeStub("auction", txID)
+	stub.Creator = creator
+	stub.Args = [][]byte{[]byte(function)}
+	for key, value := range transient {
+		stub.Transient[key] = value
+	}
+
+	identity, err := cid.New(stub)
+	require.NoError(n.t, err)
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(identity)
+
+	err = fn(ctx)
+	if err != nil {
+		return txID, err
+	}
+	return txID, stub.Commit()
+}
+
+// invokeToken simulates and commits a transaction sent by the client directly to the token chaincode
+func (n *network) invokeToken(creator []byte, args ...string) string {
+	n.tx++
+	stub := n.ledger.NewChaincodeStub(tokenChaincodeName, fmt.Sprintf("tx%d", n.tx))
+	stub.Creator = creator
+	for _, arg := range args {
+		stub.Args = append(stub.Args, []byte(arg))
+	}
+
+	response := n.token.Invoke(stub)
+	require.EqualValues(n.t, shim.OK, response.Status, response.Message)
+	require.NoError(n.t, stub.Commit())
+	return string(response.Payload)
+}
+
+// fund mints tokens from the bank, which initialized the token chaincode, and transfers them to a client
+func (n *network) fund(creator []byte, amount int) {
+	n.invokeToken(n.user("bank"), "Mint", strconv.Itoa(amount))
+	n.invokeToken(n.user("bank"), "Transfer", n.id(creator), strconv.Itoa(amount))
+}
+
+func (n *network) balance(account string) int {
+	balance, err := strconv.Atoi(n.invokeToken(n.user("reader"), "BalanceOf", account))
+	require.NoError(n.t, err)
+	return balance
+}
+
+// bid creates a private bid for one unit and submits it to the auction. It returns the bid's transaction ID.
+func (n *network) bid(creator []byte, auctionID string, price int) (string, []byte, error) {
+	return n.bidUnits(creator, auctionID, price, 1)
+}
+
+// bidUnits creates a private bid for a number of units and submits it to the auction.
+// It returns the bid's transaction ID.
+func (n *network) bidUnits(creator []byte, auctionID string, price int, quantity int) (string, []byte, error) {
+	bidJSON := []byte(fmt.Sprintf(`{"objectType":"bid","price":%d,"org":"Org1MSP","bidder":"%s","quantity":%d}`, price, n.id(creator), quantity))
+	contract := &smartcontract.SmartContract{}
+	bidTxID, err := n.submit(creator, "Bid", map[string][]byte{"bid": bidJSON}, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := contract.Bid(ctx, auctionID)
+		return err
+	})
+	require.NoError(n.t, err)
+
+	_, err = n.submit(creator, "SubmitBid", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.SubmitBid(ctx, auctionID, bidTxID)
+	})
+	return bidTxID, bidJSON, err
+}
+
+func TestEscrowSettlement(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller, bidder1, bidder2, bidder3 := n.user("seller"), n.user("bidder1"), n.user("bidder2"), n.user("bidder3")
+
+	_, err := n.submit(seller, "CreateAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAuction(ctx, "auction1", "painting", "", "", "", "")
+	})
+	require.NoError(t, err)
+	_, err = n.submit(seller, "RequireDeposit", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RequireDeposit(ctx, "auction1", tokenChaincodeName, 100)
+	})
+	require.NoError(t, err)
+
+	for _, bidder := range [][]byte{bidder1, bidder2, bidder3} {
+		n.fund(bidder, 500)
+	}
+	n.invokeToken(bidder1, "Approve", "chaincode:auction", "100")
+	n.invokeToken(bidder2, "Approve", "chaincode:auction", "100")
+
+	bid1, bid1JSON, err := n.bid(bidder1, "auction1", 80)
+	require.NoError(t, err)
+	bid2, bid2JSON, err := n.bid(bidder2, "auction1", 60)
+	require.NoError(t, err)
+	_, _, err = n.bid(bidder3, "auction1", 90)
+	require.EqualError(t, err, "failed to lock deposit: token chaincode token_erc20 failed to TransferFrom: spender does not have enough allowance for transfer")
+
+	require.Equal(t, 400, n.balance(n.id(bidder1)))
+	require.Equal(t, 200, n.balance("chaincode:auction"))
+
+	_, err = n.submit(seller, "CloseAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CloseAuction(ctx, "auction1")
+	})
+	require.NoError(t, err)
+	_, err = n.submit(bidder1, "RevealBid", map[string][]byte{"bid": bid1JSON}, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RevealBid(ctx, "auction1", bid1)
+	})
+	require.NoError(t, err)
+	_, err = n.submit(bidder2, "RevealBid", map[string][]byte{"bid": bid2JSON}, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RevealBid(ctx, "auction1", bid2)
+	})
+	require.NoError(t, err)
+	_, err = n.submit(seller, "EndAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.EndAuction(ctx, "auction1")
+	})
+	require.NoError(t, err)
+
+	// the winner's payment went to the seller, the refunds are still held
+	require.Equal(t, 80, n.balance(n.id(seller)))
+	require.Equal(t, 120, n.balance("chaincode:auction"))
+
+	_, err = n.submit(seller, "QueryEscrow", nil, func(ctx contractapi.TransactionContextInterface) error {
+		entries, err := contract.QueryEscrow(ctx, "auction1")
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		byBid := map[string]*smartcontract.EscrowEntry{entries[0].TxID: entries[0], entries[1].TxID: entries[1]}
+		require.Equal(t, smartcontract.EscrowEntry{Type: "escrow", TxID: bid1, Bidder: n.id(bidder1), Org: "Org1MSP", Amount: 100, Paid: 80, Refund: 20, Status: "settled", Account: "chaincode:auction"}, *byBid[bid1])
+		require.Equal(t, 0, byBid[bid2].Paid)
+		require.Equal(t, 100, byBid[bid2].Refund)
+		return nil
+	})
+	require.NoError(t, err)
+
+	for _, bidTxID := range []string{bid1, bid2} {
+		bidTxID := bidTxID
+		_, err = n.submit(bidder3, "ReleaseDeposit", nil, func(ctx contractapi.TransactionContextInterface) error {
+			return contract.ReleaseDeposit(ctx, "auction1", bidTxID)
+		})
+		require.NoError(t, err)
+	}
+	_, err = n.submit(bidder3, "ReleaseDeposit", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.ReleaseDeposit(ctx, "auction1", bid1)
+	})
+	require.EqualError(t, err, fmt.Sprintf("deposit of bid %s is released, only a settled deposit can be released", bid1))
+
+	require.Equal(t, 420, n.balance(n.id(bidder1)))
+	require.Equal(t, 500, n.balance(n.id(bidder2)))
+	require.Equal(t, 0, n.balance("chaincode:auction"))
+}
+
+func TestEscrowRejectsBidAboveDeposit(t *testing.T) {
+	n := newNetwork(t)
+	contract := &smartcontract.SmartContract{}
+	seller, bidder := n.user("seller"), n.user("bidder")
+
+	_, err := n.submit(seller, "CreateAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CreateAuction(ctx, "auction1", "painting", "", "", "", "")
+	})
+	require.NoError(t, err)
+	_, err = n.submit(seller, "RequireDeposit", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RequireDeposit(ctx, "auction1", tokenChaincodeName, 100)
+	})
+	require.NoError(t, err)
+
+	n.fund(bidder, 500)
+	n.invokeToken(bidder, "Approve", "chaincode:auction", "100")
+	bidTxID, bidJSON, err := n.bid(bidder, "auction1", 150)
+	require.NoError(t, err)
+
+	_, err = n.submit(seller, "RequireDeposit", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RequireDeposit(ctx, "auction1", tokenChaincodeName, 200)
+	})
+	require.EqualError(t, err, "deposit can only be required by an open auction without bids")
+
+	_, err = n.submit(seller, "CloseAuction", nil, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.CloseAuction(ctx, "auction1")
+	})
+	require.NoError(t, err)
+	_, err = n.submit(bidder, "RevealBid", map[string][]byte{"bid": bidJSON}, func(ctx contractapi.TransactionContextInterface) error {
+		return contract.RevealBid(ctx, "auction1", bidTxID)
+	})
+	require.EqualError(t, err, fmt.Sprintf("bid %s of 150 for 1 units exceeds its deposit of 100", bidTxID))
+}
+
+func TestAuctionMetadata(t *testing.T) {
+	_, err := contractapi.NewChaincode(&smartcontract.SmartContract{})
+	require.NoError(t, err)
+}
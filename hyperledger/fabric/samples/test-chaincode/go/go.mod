@@ -0,0 +1,38 @@
+module github.com/hyperledger/fabric-samples/test-chaincode/go
+
+go 1.14
+
+require (
+	github.com/golang/protobuf v1.5.2
+	github.com/hyperledger/fabric-chaincode-go v0.0.0-20230731094759-d626e9ab09b9
+	github.com/hyperledger/fabric-contract-api-go v1.1.1
+	github.com/hyperledger/fabric-protos-go v0.3.0
+	github.com/hyperledger/fabric-samples/asset-transfer-abac/chaincode-go v0.0.0-00010101000000-000000000000
+	github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go v0.0.0-00010101000000-000000000000
+	github.com/hyperledger/fabric-samples/asset-transfer-ledger-queries/chaincode-go v0.0.0-00010101000000-000000000000
+	github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go v0.0.0-00010101000000-000000000000
+	github.com/hyperledger/fabric-samples/auction/chaincode-go v0.0.0-00010101000000-000000000000
+	github.com/hyperledger/fabric-samples/chaincode/tradingMarbles v0.0.0-00010101000000-000000000000
+	github.com/hyperledger/fabric-samples/token-erc-1155/chaincode-go v0.0.0-00010101000000-000000000000
+	github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go v0.0.0-00010101000000-000000000000
+	github.com/hyperledger/fabric-samples/token-utxo/chaincode-go v0.0.0-00010101000000-000000000000
+	github.com/stretchr/testify v1.8.2
+)
+
+replace github.com/hyperledger/fabric-samples/asset-transfer-abac/chaincode-go => ../../asset-transfer-abac/chaincode-go
+
+replace github.com/hyperledger/fabric-samples/asset-transfer-basic/chaincode-go => ../../asset-transfer-basic/chaincode-go
+
+replace github.com/hyperledger/fabric-samples/asset-transfer-ledger-queries/chaincode-go => ../../asset-transfer-ledger-queries/chaincode-go
+
+replace github.com/hyperledger/fabric-samples/auction/chaincode-go => ../../auction/chaincode-go
+
+replace github.com/hyperledger/fabric-samples/chaincode/tradingMarbles => ../../asset-transfer-secured-agreement/chaincode-go
+
+replace github.com/hyperledger/fabric-samples/token-erc-1155/chaincode-go => ../../token-erc-1155/chaincode-go
+
+replace github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go => ../../token-erc-20/chaincode-go
+
+replace github.com/hyperledger/fabric-samples/token-utxo/chaincode-go => ../../token-utxo/chaincode-go
+
+replace github.com/hyperledger/fabric-samples/asset-transfer-private-data/chaincode-go => ../../asset-transfer-private-data/chaincode-go
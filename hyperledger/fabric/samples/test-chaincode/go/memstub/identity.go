@@ -0,0 +1,100 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package memstub
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+// ClientIdentity is a fixed cid.ClientIdentity for use alongside a Stub. On a
+// peer the ID is derived from the client certificate; here it is returned as set.
+type ClientIdentity struct {
+	ID          string
+	MSPID       string
+	Attributes  map[string]string
+	Certificate *x509.Certificate
+}
+
+var _ cid.ClientIdentity = (*ClientIdentity)(nil)
+
+// NewClientIdentity returns an identity with the given ID and MSP ID and no attributes
+func NewClientIdentity(id, mspID string) *ClientIdentity {
+	return &ClientIdentity{ID: id, MSPID: mspID, Attributes: make(map[string]string)}
+}
+
+// GetID returns the ID of the client
+func (c *ClientIdentity) GetID() (string, error) {
+	return c.ID, nil
+}
+
+// GetMSPID returns the MSP ID of the client
+func (c *ClientIdentity) GetMSPID() (string, error) {
+	return c.MSPID, nil
+}
+
+// GetAttributeValue returns the value of a certificate attribute of the client
+func (c *ClientIdentity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, found := c.Attributes[attrName]
+	return value, found, nil
+}
+
+// AssertAttributeValue returns an error unless the client has the attribute with the given value
+func (c *ClientIdentity) AssertAttributeValue(attrName, attrValue string) error {
+	value, found := c.Attributes[attrName]
+	if !found {
+		return fmt.Errorf("attribute '%s' was not found", attrName)
+	}
+	if value != attrValue {
+		return fmt.Errorf("attribute '%s' equals '%s', not '%s'", attrName, value, attrValue)
+	}
+	return nil
+}
+
+// GetX509Certificate returns the certificate of the client, which may be nil
+func (c *ClientIdentity) GetX509Certificate() (*x509.Certificate, error) {
+	return c.Certificate, nil
+}
+
+// NewCreator returns the serialized identity of a client of the MSP with a
+// self-signed certificate for the common name. Set it as the Creator of a Stub for
+// chaincodes that derive the client identity from the creator, such as contract API
+// chaincodes called through InvokeChaincode; cid.New returns the identity of a stub.
+func NewCreator(mspID, commonName string) ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	name := pkix.Name{CommonName: commonName}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      name,
+		Issuer:       name,
+		NotBefore:    time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+		NotAfter:     time.Date(2100, time.January, 1, 0, 0, 0, 0, time.UTC),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   mspID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate}),
+	})
+}
@@ -0,0 +1,101 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package memstub
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+type rangeResult struct {
+	key     string
+	value   []byte
+	version version
+}
+
+// rangeRead records the keys a transaction iterated over so that the range
+// can be re-checked for phantom reads at commit
+type rangeRead struct {
+	namespace string
+	startKey  string
+	endKey    string
+	results   []*rangeResult
+	exhausted bool
+}
+
+// queryIterator implements shim.StateQueryIteratorInterface over a snapshot
+// of the committed state taken when the query was executed
+type queryIterator struct {
+	namespace string
+	results   []*rangeResult
+	next      int
+	closed    bool
+	rangeRead *rangeRead
+}
+
+// HasNext returns true if the range query iterator contains additional keys
+func (it *queryIterator) HasNext() bool {
+	hasNext := !it.closed && it.next < len(it.results)
+	if !hasNext && it.rangeRead != nil {
+		it.rangeRead.exhausted = true
+	}
+	return hasNext
+}
+
+// Next returns the next key and value in the range query iterator
+func (it *queryIterator) Next() (*queryresult.KV, error) {
+	if it.closed {
+		return nil, fmt.Errorf("iterator is closed")
+	}
+	if it.next >= len(it.results) {
+		return nil, fmt.Errorf("no more results in iterator")
+	}
+	result := it.results[it.next]
+	it.next++
+	if it.rangeRead != nil {
+		it.rangeRead.results = append(it.rangeRead.results, result)
+	}
+
+	return &queryresult.KV{Namespace: it.namespace, Key: result.key, Value: result.value}, nil
+}
+
+// Close closes the iterator
+func (it *queryIterator) Close() error {
+	it.closed = true
+	return nil
+}
+
+// historyIterator implements shim.HistoryQueryIteratorInterface
+type historyIterator struct {
+	results []*queryresult.KeyModification
+	next    int
+	closed  bool
+}
+
+// HasNext returns true if the history iterator contains additional modifications
+func (it *historyIterator) HasNext() bool {
+	return !it.closed && it.next < len(it.results)
+}
+
+// Next returns the next modification of the key, newest first
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if it.closed {
+		return nil, fmt.Errorf("iterator is closed")
+	}
+	if it.next >= len(it.results) {
+		return nil, fmt.Errorf("no more results in iterator")
+	}
+	result := it.results[it.next]
+	it.next++
+
+	return result, nil
+}
+
+// Close closes the iterator
+func (it *historyIterator) Close() error {
+	it.closed = true
+	return nil
+}
@@ -0,0 +1,334 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package memstub provides an in-memory implementation of
+// shim.ChaincodeStubInterface so that chaincode can be unit tested end to end
+// without a Fabric network.
+//
+// A Ledger holds the committed world state, private data collections and key
+// history of every chaincode namespace on a simulated channel. Each Stub
+// simulates a single transaction against that ledger: reads come from the
+// committed state, writes are buffered in the transaction's write set, and
+// nothing becomes visible to other transactions until Commit validates the
+// read set (MVCC and phantom read checks) and applies the writes as a new block.
+package memstub
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+)
+
+const (
+	// DefaultChannelID is the channel name reported by stubs created by NewLedger
+	DefaultChannelID = "mychannel"
+	// DefaultChaincodeName is the namespace used by Ledger.NewStub
+	DefaultChaincodeName = "chaincode"
+)
+
+// Validation codes returned (wrapped) by Stub.Commit when a transaction is
+// invalidated. Use errors.Is to test for them.
+var (
+	ErrMVCCReadConflict    = errors.New("MVCC_READ_CONFLICT")
+	ErrPhantomReadConflict = errors.New("PHANTOM_READ_CONFLICT")
+	ErrAlreadyCommitted    = errors.New("transaction already committed")
+)
+
+// version identifies the block and transaction that last wrote a key
+type version struct {
+	blockNum uint64
+	txNum    uint64
+}
+
+type versionedValue struct {
+	value               []byte
+	validationParameter []byte
+	version             version
+}
+
+// namespaceState is the committed state of a single chaincode
+type namespaceState struct {
+	public  map[string]*versionedValue
+	private map[string]map[string]*versionedValue
+	history map[string][]*queryresult.KeyModification
+}
+
+func newNamespaceState() *namespaceState {
+	return &namespaceState{
+		public:  make(map[string]*versionedValue),
+		private: make(map[string]map[string]*versionedValue),
+		history: make(map[string][]*queryresult.KeyModification),
+	}
+}
+
+// Ledger is the committed state of a simulated channel
+type Ledger struct {
+	mu         sync.Mutex
+	channelID  string
+	height     uint64
+	clock      time.Time
+	namespaces map[string]*namespaceState
+	chaincodes map[string]shim.Chaincode
+}
+
+// NewLedger returns an empty ledger for DefaultChannelID. The transaction clock
+// starts at a fixed instant so that tests are deterministic.
+func NewLedger() *Ledger {
+	return &Ledger{
+		channelID:  DefaultChannelID,
+		clock:      time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC),
+		namespaces: make(map[string]*namespaceState),
+		chaincodes: make(map[string]shim.Chaincode),
+	}
+}
+
+// Height returns the number of blocks committed to the ledger
+func (l *Ledger) Height() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.height
+}
+
+// SetTime sets the timestamp that will be given to the next transaction.
+// Each new transaction advances the clock by one second.
+func (l *Ledger) SetTime(t time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clock = t
+}
+
+// RegisterChaincode makes a chaincode available to InvokeChaincode calls under
+// the given name
+func (l *Ledger) RegisterChaincode(name string, cc shim.Chaincode) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.chaincodes[name] = cc
+}
+
+// NewStub starts simulating a new transaction in the DefaultChaincodeName namespace
+func (l *Ledger) NewStub(txID string) *Stub {
+	return l.NewChaincodeStub(DefaultChaincodeName, txID)
+}
+
+// NewChaincodeStub starts simulating a new transaction in the namespace of the
+// named chaincode
+func (l *Ledger) NewChaincodeStub(chaincodeName string, txID string) *Stub {
+	l.mu.Lock()
+	timestamp, err := ptypes.TimestampProto(l.clock)
+	if err != nil {
+		panic(err)
+	}
+	l.clock = l.clock.Add(time.Second)
+	l.mu.Unlock()
+
+	return &Stub{
+		TxID:        txID,
+		ChannelID:   l.channelID,
+		Timestamp:   timestamp,
+		Transient:   make(map[string][]byte),
+		Decorations: make(map[string][]byte),
+		ledger:      l,
+		namespace:   chaincodeName,
+		tx:          newTransaction(),
+	}
+}
+
+func (l *Ledger) chaincode(name string) (shim.Chaincode, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	cc, ok := l.chaincodes[name]
+	return cc, ok
+}
+
+// namespace returns the committed state of a chaincode. The caller must hold l.mu.
+func (l *Ledger) namespace(name string) *namespaceState {
+	ns, ok := l.namespaces[name]
+	if !ok {
+		ns = newNamespaceState()
+		l.namespaces[name] = ns
+	}
+	return ns
+}
+
+// entries returns the committed public or private state map of a namespace.
+// The caller must hold l.mu.
+func (l *Ledger) entries(namespace, collection string) map[string]*versionedValue {
+	ns := l.namespace(namespace)
+	if collection == "" {
+		return ns.public
+	}
+	entries, ok := ns.private[collection]
+	if !ok {
+		entries = make(map[string]*versionedValue)
+		ns.private[collection] = entries
+	}
+	return entries
+}
+
+// get returns a copy of a committed value
+func (l *Ledger) get(namespace, collection, key string) (*versionedValue, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	vv, ok := l.entries(namespace, collection)[key]
+	if !ok {
+		return nil, false
+	}
+	return &versionedValue{
+		value:               copyBytes(vv.value),
+		validationParameter: copyBytes(vv.validationParameter),
+		version:             vv.version,
+	}, true
+}
+
+// scan returns the committed keys in [startKey, endKey) in key order. An empty
+// endKey leaves the range unbounded.
+func (l *Ledger) scan(namespace, collection, startKey, endKey string) []*rangeResult {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var results []*rangeResult
+	for key, vv := range l.entries(namespace, collection) {
+		if key < startKey || (endKey != "" && key >= endKey) {
+			continue
+		}
+		results = append(results, &rangeResult{key: key, value: copyBytes(vv.value), version: vv.version})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].key < results[j].key })
+
+	return results
+}
+
+// history returns the committed modifications of a key, newest first
+func (l *Ledger) history(namespace, key string) []*queryresult.KeyModification {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	modifications := l.namespace(namespace).history[key]
+	results := make([]*queryresult.KeyModification, 0, len(modifications))
+	for i := len(modifications) - 1; i >= 0; i-- {
+		m := modifications[i]
+		results = append(results, &queryresult.KeyModification{
+			TxId:      m.TxId,
+			Value:     copyBytes(m.Value),
+			Timestamp: m.Timestamp,
+			IsDelete:  m.IsDelete,
+		})
+	}
+	return results
+}
+
+// commit validates the read set of a transaction against the committed state
+// and, if it is still valid, applies its write set as a new block
+func (l *Ledger) commit(txID string, stub *Stub) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	tx := stub.tx
+	if tx.committed {
+		return fmt.Errorf("%w: %s", ErrAlreadyCommitted, txID)
+	}
+
+	for k, read := range tx.reads {
+		current, ok := l.entries(k.namespace, k.collection)[k.key]
+		if !sameVersion(read, current, ok) {
+			return fmt.Errorf("%w: txid [%s] read key [%s] in namespace [%s] at a stale version", ErrMVCCReadConflict, txID, k.key, k.qualifiedNamespace())
+		}
+	}
+
+	for _, rr := range tx.rangeReads {
+		if err := l.validateRangeRead(rr); err != nil {
+			return fmt.Errorf("%w: txid [%s] %v", ErrPhantomReadConflict, txID, err)
+		}
+	}
+
+	l.height++
+	v := version{blockNum: l.height}
+	timestamp := stub.Timestamp
+	for k, w := range tx.writes {
+		entries := l.entries(k.namespace, k.collection)
+		if w.isDelete {
+			delete(entries, k.key)
+		} else {
+			var validationParameter []byte
+			if current, ok := entries[k.key]; ok {
+				validationParameter = current.validationParameter
+			}
+			entries[k.key] = &versionedValue{value: w.value, validationParameter: validationParameter, version: v}
+		}
+
+		if k.collection == "" {
+			ns := l.namespace(k.namespace)
+			ns.history[k.key] = append(ns.history[k.key], &queryresult.KeyModification{
+				TxId:      txID,
+				Value:     copyBytes(w.value),
+				Timestamp: timestamp,
+				IsDelete:  w.isDelete,
+			})
+		}
+	}
+	for k, ep := range tx.validationParameters {
+		if vv, ok := l.entries(k.namespace, k.collection)[k.key]; ok {
+			vv.validationParameter = ep
+			vv.version = v
+		}
+	}
+
+	tx.committed = true
+	return nil
+}
+
+// validateRangeRead re-executes a range query against the committed state and
+// checks that the portion the transaction iterated over has not changed. The
+// caller must hold l.mu.
+func (l *Ledger) validateRangeRead(rr *rangeRead) error {
+	endKey := rr.endKey
+	var current []*rangeResult
+	for key, vv := range l.entries(rr.namespace, "") {
+		if key < rr.startKey || (endKey != "" && key >= endKey) {
+			continue
+		}
+		if !rr.exhausted && (len(rr.results) == 0 || key > rr.results[len(rr.results)-1].key) {
+			continue
+		}
+		current = append(current, &rangeResult{key: key, version: vv.version})
+	}
+	sort.Slice(current, func(i, j int) bool { return current[i].key < current[j].key })
+
+	if len(current) != len(rr.results) {
+		return fmt.Errorf("range [%s, %s) in namespace [%s] returned %d keys at simulation and %d at commit", rr.startKey, rr.endKey, rr.namespace, len(rr.results), len(current))
+	}
+	for i := range current {
+		if current[i].key != rr.results[i].key || current[i].version != rr.results[i].version {
+			return fmt.Errorf("range [%s, %s) in namespace [%s] changed at key [%s]", rr.startKey, rr.endKey, rr.namespace, current[i].key)
+		}
+	}
+	return nil
+}
+
+func sameVersion(read *version, current *versionedValue, exists bool) bool {
+	if read == nil {
+		return !exists
+	}
+	return exists && current.version == *read
+}
+
+func copyBytes(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	c := make([]byte, len(b))
+	copy(c, b)
+	return c
+}
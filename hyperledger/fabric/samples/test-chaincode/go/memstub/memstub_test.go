@@ -0,0 +1,441 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package memstub_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritesAreVisibleAfterCommit(t *testing.T) {
+	ledger := memstub.NewLedger()
+
+	stub := ledger.NewStub("tx1")
+	require.NoError(t, stub.PutState("asset1", []byte("blue")))
+	value, err := stub.GetState("asset1")
+	require.NoError(t, err)
+	require.Nil(t, value, "writes must not be visible within the same transaction")
+	require.NoError(t, stub.Commit())
+	require.EqualValues(t, 1, ledger.Height())
+
+	value, err = ledger.NewStub("tx2").GetState("asset1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("blue"), value)
+
+	err = stub.Commit()
+	require.True(t, errors.Is(err, memstub.ErrAlreadyCommitted))
+
+	err = stub.PutState("", []byte("blue"))
+	require.EqualError(t, err, "key must not be an empty string")
+}
+
+func TestEmptyValueDeletesKey(t *testing.T) {
+	ledger := memstub.NewLedger()
+	commitState(t, ledger, map[string]string{"asset1": "blue"})
+
+	stub := ledger.NewStub("tx2")
+	require.NoError(t, stub.PutState("asset1", []byte{}))
+	require.NoError(t, stub.Commit())
+
+	value, err := ledger.NewStub("tx3").GetState("asset1")
+	require.NoError(t, err)
+	require.Nil(t, value)
+}
+
+func TestGetStateByRange(t *testing.T) {
+	ledger := memstub.NewLedger()
+	stub := ledger.NewStub("tx1")
+	for _, key := range []string{"asset3", "asset1", "asset2", "other"} {
+		require.NoError(t, stub.PutState(key, []byte(key)))
+	}
+	compositeKey, err := stub.CreateCompositeKey("color~name", []string{"blue", "asset1"})
+	require.NoError(t, err)
+	require.NoError(t, stub.PutState(compositeKey, []byte{0x00}))
+	require.NoError(t, stub.Commit())
+
+	stub = ledger.NewStub("tx2")
+	iterator, err := stub.GetStateByRange("", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1", "asset2", "asset3", "other"}, keys(t, iterator))
+
+	iterator, err = stub.GetStateByRange("asset2", "other")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset2", "asset3"}, keys(t, iterator))
+
+	_, err = stub.GetStateByRange(compositeKey, "")
+	require.Error(t, err)
+}
+
+func TestCompositeKeys(t *testing.T) {
+	ledger := memstub.NewLedger()
+	stub := ledger.NewStub("tx1")
+	for _, attributes := range [][]string{{"blue", "asset1"}, {"blue", "asset2"}, {"red", "asset3"}} {
+		key, err := stub.CreateCompositeKey("color~name", attributes)
+		require.NoError(t, err)
+		require.NoError(t, stub.PutState(key, []byte{0x00}))
+	}
+	require.NoError(t, stub.PutState("asset1", []byte("asset1")))
+	require.NoError(t, stub.Commit())
+
+	stub = ledger.NewStub("tx2")
+	iterator, err := stub.GetStateByPartialCompositeKey("color~name", []string{"blue"})
+	require.NoError(t, err)
+	var names []string
+	for _, key := range keys(t, iterator) {
+		objectType, attributes, err := stub.SplitCompositeKey(key)
+		require.NoError(t, err)
+		require.Equal(t, "color~name", objectType)
+		names = append(names, attributes[1])
+	}
+	require.Equal(t, []string{"asset1", "asset2"}, names)
+
+	_, err = stub.CreateCompositeKey("color~name", []string{"bl\x00ue"})
+	require.Error(t, err)
+	_, _, err = stub.SplitCompositeKey("asset1")
+	require.Error(t, err)
+}
+
+func TestPagination(t *testing.T) {
+	ledger := memstub.NewLedger()
+	commitState(t, ledger, map[string]string{"a": "1", "b": "2", "c": "3", "d": "4", "e": "5"})
+
+	stub := ledger.NewStub("tx2")
+	var pages [][]string
+	bookmark := ""
+	for {
+		iterator, metadata, err := stub.GetStateByRangeWithPagination("", "", 2, bookmark)
+		require.NoError(t, err)
+		page := keys(t, iterator)
+		require.EqualValues(t, len(page), metadata.FetchedRecordsCount)
+		pages = append(pages, page)
+		bookmark = metadata.Bookmark
+		if bookmark == "" {
+			break
+		}
+	}
+	require.Equal(t, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}, pages)
+
+	err := stub.PutState("f", []byte("6"))
+	require.EqualError(t, err, "txid [tx2]: transaction has already performed a paginated query. Writes are not allowed")
+
+	stub = ledger.NewStub("tx3")
+	require.NoError(t, stub.PutState("f", []byte("6")))
+	_, _, err = stub.GetStateByPartialCompositeKeyWithPagination("color~name", nil, 2, "")
+	require.EqualError(t, err, "txid [tx3]: paginated queries are supported only in a read-only transaction")
+}
+
+func TestPrivateData(t *testing.T) {
+	ledger := memstub.NewLedger()
+	stub := ledger.NewStub("tx1")
+	require.NoError(t, stub.PutPrivateData("Org1MSPPrivateCollection", "asset1", []byte("100")))
+	require.NoError(t, stub.PutPrivateData("Org1MSPPrivateCollection", "asset2", []byte("200")))
+	require.NoError(t, stub.Commit())
+
+	stub = ledger.NewStub("tx2")
+	value, err := stub.GetPrivateData("Org1MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("100"), value)
+
+	value, err = stub.GetPrivateData("Org2MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	hash, err := stub.GetPrivateDataHash("Org1MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	expectedHash := sha256.Sum256([]byte("100"))
+	require.Equal(t, expectedHash[:], hash)
+
+	value, err = stub.GetState("asset1")
+	require.NoError(t, err)
+	require.Nil(t, value, "private data must not leak into the public state")
+
+	iterator, err := stub.GetPrivateDataByRange("Org1MSPPrivateCollection", "", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1", "asset2"}, keys(t, iterator))
+
+	err = stub.PutState("asset1", []byte("blue"))
+	require.EqualError(t, err, "txid [tx2]: transaction has already performed queries on pvt data. Writes are not allowed")
+
+	_, err = stub.GetPrivateData("", "asset1")
+	require.EqualError(t, err, "collection must not be an empty string")
+}
+
+func TestPurgePrivateData(t *testing.T) {
+	ledger := memstub.NewLedger()
+	stub := ledger.NewStub("tx1")
+	require.NoError(t, stub.PutPrivateData("Org1MSPPrivateCollection", "asset1", []byte("100")))
+	require.NoError(t, stub.PutPrivateData("Org1MSPPrivateCollection", "asset2", []byte("200")))
+	require.NoError(t, stub.Commit())
+
+	stub = ledger.NewStub("tx2")
+	require.NoError(t, stub.PurgePrivateData("Org1MSPPrivateCollection", "asset1"))
+	require.NoError(t, stub.Commit())
+
+	stub = ledger.NewStub("tx3")
+	value, err := stub.GetPrivateData("Org1MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	require.Nil(t, value)
+	hash, err := stub.GetPrivateDataHash("Org1MSPPrivateCollection", "asset1")
+	require.NoError(t, err)
+	require.Nil(t, hash)
+
+	// like any other write, a purge is not allowed after a query on private data
+	iterator, err := stub.GetPrivateDataByPartialCompositeKey("Org1MSPPrivateCollection", "asset", []string{})
+	require.NoError(t, err)
+	require.Empty(t, keys(t, iterator))
+	err = stub.PurgePrivateData("Org1MSPPrivateCollection", "asset2")
+	require.EqualError(t, err, "txid [tx3]: transaction has already performed queries on pvt data. Writes are not allowed")
+}
+
+func TestTransientDataAndEvents(t *testing.T) {
+	ledger := memstub.NewLedger()
+	stub := ledger.NewStub("tx1")
+	stub.Transient["asset_properties"] = []byte(`{"color":"blue"}`)
+
+	transient, err := stub.GetTransient()
+	require.NoError(t, err)
+	require.Equal(t, []byte(`{"color":"blue"}`), transient["asset_properties"])
+
+	require.Nil(t, stub.Event())
+	require.NoError(t, stub.SetEvent("CreateAsset", []byte("asset1")))
+	require.Equal(t, &peer.ChaincodeEvent{
+		ChaincodeId: memstub.DefaultChaincodeName,
+		TxId:        "tx1",
+		EventName:   "CreateAsset",
+		Payload:     []byte("asset1"),
+	}, stub.Event())
+
+	err = stub.SetEvent("", nil)
+	require.EqualError(t, err, "event name can not be empty string")
+}
+
+func TestHistoryForKey(t *testing.T) {
+	ledger := memstub.NewLedger()
+	for _, txID := range []string{"tx1", "tx2"} {
+		stub := ledger.NewStub(txID)
+		require.NoError(t, stub.PutState("asset1", []byte(txID)))
+		require.NoError(t, stub.Commit())
+	}
+	stub := ledger.NewStub("tx3")
+	require.NoError(t, stub.DelState("asset1"))
+	require.NoError(t, stub.Commit())
+
+	iterator, err := ledger.NewStub("tx4").GetHistoryForKey("asset1")
+	require.NoError(t, err)
+	defer iterator.Close()
+
+	var txIDs []string
+	var deletes []bool
+	var lastSeconds int64
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		require.NoError(t, err)
+		txIDs = append(txIDs, modification.TxId)
+		deletes = append(deletes, modification.IsDelete)
+		if lastSeconds != 0 {
+			require.Less(t, modification.Timestamp.Seconds, lastSeconds)
+		}
+		lastSeconds = modification.Timestamp.Seconds
+	}
+	require.Equal(t, []string{"tx3", "tx2", "tx1"}, txIDs)
+	require.Equal(t, []bool{true, false, false}, deletes)
+}
+
+func TestMVCCReadConflict(t *testing.T) {
+	ledger := memstub.NewLedger()
+	commitState(t, ledger, map[string]string{"asset1": "Tomoko"})
+
+	first := ledger.NewStub("tx2")
+	second := ledger.NewStub("tx3")
+	for _, stub := range []*memstub.Stub{first, second} {
+		_, err := stub.GetState("asset1")
+		require.NoError(t, err)
+		require.NoError(t, stub.PutState("asset1", []byte(stub.GetTxID())))
+	}
+
+	require.NoError(t, first.Commit())
+	err := second.Commit()
+	require.True(t, errors.Is(err, memstub.ErrMVCCReadConflict), "unexpected error: %v", err)
+
+	value, err := ledger.NewStub("tx4").GetState("asset1")
+	require.NoError(t, err)
+	require.Equal(t, []byte("tx2"), value)
+
+	blind := ledger.NewStub("tx5")
+	require.NoError(t, blind.PutState("asset1", []byte("tx5")))
+	require.NoError(t, blind.Commit(), "blind writes never conflict")
+}
+
+func TestPhantomReadConflict(t *testing.T) {
+	ledger := memstub.NewLedger()
+	commitState(t, ledger, map[string]string{"asset1": "1", "asset3": "3"})
+
+	reader := ledger.NewStub("tx2")
+	iterator, err := reader.GetStateByRange("asset1", "asset9")
+	require.NoError(t, err)
+	require.Equal(t, []string{"asset1", "asset3"}, keys(t, iterator))
+	require.NoError(t, reader.PutState("count", []byte("2")))
+
+	commitState(t, ledger, map[string]string{"asset2": "2"})
+
+	err = reader.Commit()
+	require.True(t, errors.Is(err, memstub.ErrPhantomReadConflict), "unexpected error: %v", err)
+}
+
+type counterChaincode struct{}
+
+func (counterChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return shim.Success(nil)
+}
+
+func (counterChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	function, args := stub.GetFunctionAndParameters()
+	if function != "Set" {
+		return shim.Error("unknown function " + function)
+	}
+	if err := stub.PutState("counter", []byte(args[0])); err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(args[0]))
+}
+
+func TestInvokeChaincode(t *testing.T) {
+	ledger := memstub.NewLedger()
+	ledger.RegisterChaincode("counter", counterChaincode{})
+
+	stub := ledger.NewStub("tx1")
+	response := stub.InvokeChaincode("counter", [][]byte{[]byte("Set"), []byte("42")}, "")
+	require.EqualValues(t, shim.OK, response.Status)
+	require.Equal(t, []byte("42"), response.Payload)
+
+	response = stub.InvokeChaincode("missing", nil, "")
+	require.EqualValues(t, shim.ERROR, response.Status)
+	require.NoError(t, stub.Commit())
+
+	value, err := ledger.NewChaincodeStub("counter", "tx2").GetState("counter")
+	require.NoError(t, err)
+	require.Equal(t, []byte("42"), value)
+
+	value, err = ledger.NewStub("tx3").GetState("counter")
+	require.NoError(t, err)
+	require.Nil(t, value, "namespaces must be isolated")
+}
+
+func commitState(t *testing.T, ledger *memstub.Ledger, state map[string]string) {
+	stub := ledger.NewStub("setup")
+	for key, value := range state {
+		require.NoError(t, stub.PutState(key, []byte(value)))
+	}
+	require.NoError(t, stub.Commit())
+}
+
+func keys(t *testing.T, iterator shim.StateQueryIteratorInterface) []string {
+	defer iterator.Close()
+
+	var keys []string
+	for iterator.HasNext() {
+		kv, err := iterator.Next()
+		require.NoError(t, err)
+		keys = append(keys, kv.Key)
+	}
+	return keys
+}
+
+func TestClientIdentity(t *testing.T) {
+	identity := memstub.NewClientIdentity("user1", "Org1MSP")
+	identity.Attributes["abac.creator"] = "true"
+
+	id, err := identity.GetID()
+	require.NoError(t, err)
+	require.Equal(t, "user1", id)
+	mspID, err := identity.GetMSPID()
+	require.NoError(t, err)
+	require.Equal(t, "Org1MSP", mspID)
+
+	require.NoError(t, identity.AssertAttributeValue("abac.creator", "true"))
+	require.EqualError(t, identity.AssertAttributeValue("abac.creator", "false"), "attribute 'abac.creator' equals 'true', not 'false'")
+	_, found, err := identity.GetAttributeValue("hf.Type")
+	require.NoError(t, err)
+	require.False(t, found)
+}
+
+// proposalChaincode returns the name of the chaincode and the function targeted by
+// the transaction proposal
+type proposalChaincode struct{}
+
+func (proposalChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return shim.Success(nil)
+}
+
+func (proposalChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	signedProposal, err := stub.GetSignedProposal()
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	spec, err := invocationSpec(signedProposal)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+	return shim.Success([]byte(spec.ChaincodeSpec.ChaincodeId.Name + " " + string(spec.ChaincodeSpec.Input.Args[0])))
+}
+
+func invocationSpec(signedProposal *peer.SignedProposal) (*peer.ChaincodeInvocationSpec, error) {
+	proposal := &peer.Proposal{}
+	if err := proto.Unmarshal(signedProposal.ProposalBytes, proposal); err != nil {
+		return nil, err
+	}
+	payload := &peer.ChaincodeProposalPayload{}
+	if err := proto.Unmarshal(proposal.Payload, payload); err != nil {
+		return nil, err
+	}
+	spec := &peer.ChaincodeInvocationSpec{}
+	if err := proto.Unmarshal(payload.Input, spec); err != nil {
+		return nil, err
+	}
+	return spec, nil
+}
+
+func TestSignedProposal(t *testing.T) {
+	ledger := memstub.NewLedger()
+	ledger.RegisterChaincode("proposal", proposalChaincode{})
+
+	stub := ledger.NewChaincodeStub("caller", "tx1")
+	stub.Args = [][]byte{[]byte("Call"), []byte("arg")}
+	signedProposal, err := stub.GetSignedProposal()
+	require.NoError(t, err)
+	spec, err := invocationSpec(signedProposal)
+	require.NoError(t, err)
+	require.Equal(t, "caller", spec.ChaincodeSpec.ChaincodeId.Name)
+	require.Equal(t, stub.Args, spec.ChaincodeSpec.Input.Args)
+
+	response := stub.InvokeChaincode("proposal", [][]byte{[]byte("Other")}, "")
+	require.EqualValues(t, shim.OK, response.Status, response.Message)
+	require.Equal(t, "caller Call", string(response.Payload), "the callee must see the proposal of the transaction")
+}
+
+func TestNewCreator(t *testing.T) {
+	creator, err := memstub.NewCreator("Org1MSP", "user1")
+	require.NoError(t, err)
+
+	stub := memstub.NewLedger().NewStub("tx1")
+	stub.Creator = creator
+	identity, err := cid.New(stub)
+	require.NoError(t, err)
+
+	mspID, err := identity.GetMSPID()
+	require.NoError(t, err)
+	require.Equal(t, "Org1MSP", mspID)
+	certificate, err := identity.GetX509Certificate()
+	require.NoError(t, err)
+	require.Equal(t, "user1", certificate.Subject.CommonName)
+}
@@ -0,0 +1,539 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package memstub
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+const (
+	compositeKeyNamespace = "\x00"
+	emptyKeySubstitute    = "\x01"
+)
+
+// rwKey addresses a key in the public state (empty collection) or a private
+// data collection of a namespace
+type rwKey struct {
+	namespace  string
+	collection string
+	key        string
+}
+
+func (k rwKey) qualifiedNamespace() string {
+	if k.collection == "" {
+		return k.namespace
+	}
+	return k.namespace + "$" + k.collection
+}
+
+type write struct {
+	value    []byte
+	isDelete bool
+}
+
+// transaction is the read/write set of a simulated transaction. It is shared by
+// the stubs of chaincodes called through InvokeChaincode.
+type transaction struct {
+	reads                map[rwKey]*version
+	rangeReads           []*rangeRead
+	writes               map[rwKey]*write
+	validationParameters map[rwKey][]byte
+	paginatedQueries     bool
+	privateQueries       bool
+	committed            bool
+}
+
+func newTransaction() *transaction {
+	return &transaction{
+		reads:                make(map[rwKey]*version),
+		writes:               make(map[rwKey]*write),
+		validationParameters: make(map[rwKey][]byte),
+	}
+}
+
+func (tx *transaction) hasWrites() bool {
+	return len(tx.writes) > 0 || len(tx.validationParameters) > 0
+}
+
+// Stub is an in-memory shim.ChaincodeStubInterface simulating one transaction
+// against a Ledger. The exported fields describe the transaction proposal and
+// may be set by tests before the stub is handed to a contract.
+type Stub struct {
+	TxID           string
+	ChannelID      string
+	Args           [][]byte
+	Transient      map[string][]byte
+	Creator        []byte
+	Decorations    map[string][]byte
+	SignedProposal *peer.SignedProposal
+	Timestamp      *timestamp.Timestamp
+
+	ledger    *Ledger
+	namespace string
+	tx        *transaction
+	event     *peer.ChaincodeEvent
+}
+
+var _ shim.ChaincodeStubInterface = (*Stub)(nil)
+
+// Commit validates the transaction against the current committed state and, if
+// it is valid, writes it to the ledger as a new block. The returned error wraps
+// ErrMVCCReadConflict or ErrPhantomReadConflict when the transaction is invalidated.
+func (s *Stub) Commit() error {
+	return s.ledger.commit(s.TxID, s)
+}
+
+// Event returns the chaincode event set by the transaction, if any
+func (s *Stub) Event() *peer.ChaincodeEvent {
+	return s.event
+}
+
+// GetArgs returns the arguments of the transaction proposal
+func (s *Stub) GetArgs() [][]byte {
+	return s.Args
+}
+
+// GetStringArgs returns the arguments of the transaction proposal as strings
+func (s *Stub) GetStringArgs() []string {
+	args := make([]string, 0, len(s.Args))
+	for _, arg := range s.Args {
+		args = append(args, string(arg))
+	}
+	return args
+}
+
+// GetFunctionAndParameters returns the first argument as the function name and
+// the rest as its parameters
+func (s *Stub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+// GetArgsSlice returns the arguments of the transaction proposal concatenated
+func (s *Stub) GetArgsSlice() ([]byte, error) {
+	var res []byte
+	for _, arg := range s.Args {
+		res = append(res, arg...)
+	}
+	return res, nil
+}
+
+// GetTxID returns the transaction ID
+func (s *Stub) GetTxID() string {
+	return s.TxID
+}
+
+// GetChannelID returns the channel the transaction is simulated on
+func (s *Stub) GetChannelID() string {
+	return s.ChannelID
+}
+
+// InvokeChaincode calls a chaincode registered with the ledger in the context
+// of the current transaction. Its reads and writes join this transaction's
+// read/write set and are committed or invalidated together.
+func (s *Stub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) peer.Response {
+	if channel != "" && channel != s.ChannelID {
+		return shim.Error(fmt.Sprintf("channel %s is not available to the in-memory ledger", channel))
+	}
+	cc, ok := s.ledger.chaincode(chaincodeName)
+	if !ok {
+		return shim.Error(fmt.Sprintf("chaincode %s is not registered", chaincodeName))
+	}
+
+	signedProposal, err := s.GetSignedProposal()
+	if err != nil {
+		return shim.Error(fmt.Sprintf("failed to create signed proposal: %v", err))
+	}
+
+	callee := &Stub{
+		TxID:           s.TxID,
+		ChannelID:      s.ChannelID,
+		Args:           args,
+		Transient:      s.Transient,
+		Creator:        s.Creator,
+		Decorations:    s.Decorations,
+		SignedProposal: signedProposal,
+		Timestamp:      s.Timestamp,
+		ledger:         s.ledger,
+		namespace:      chaincodeName,
+		tx:             s.tx,
+	}
+	return cc.Invoke(callee)
+}
+
+// GetState returns the committed value of a key. As on a peer, writes made
+// earlier in the same transaction are not visible.
+func (s *Stub) GetState(key string) ([]byte, error) {
+	return s.read(rwKey{namespace: s.namespace, key: key})
+}
+
+// PutState adds a key to the write set. An empty value deletes the key.
+func (s *Stub) PutState(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+	return s.write(rwKey{namespace: s.namespace, key: key}, value)
+}
+
+// DelState adds the deletion of a key to the write set
+func (s *Stub) DelState(key string) error {
+	return s.write(rwKey{namespace: s.namespace, key: key}, nil)
+}
+
+// SetStateValidationParameter sets the key-level endorsement policy of a key
+func (s *Stub) SetStateValidationParameter(key string, ep []byte) error {
+	return s.setValidationParameter(rwKey{namespace: s.namespace, key: key}, ep)
+}
+
+// GetStateValidationParameter returns the committed key-level endorsement policy of a key
+func (s *Stub) GetStateValidationParameter(key string) ([]byte, error) {
+	return s.getValidationParameter(rwKey{namespace: s.namespace, key: key})
+}
+
+// GetStateByRange returns an iterator over the keys in [startKey, endKey).
+// Empty bounds leave the range open. Composite keys are never returned.
+func (s *Stub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	return s.rangeQuery(startKey, endKey), nil
+}
+
+// GetStateByRangeWithPagination returns one page of a range query. The
+// returned bookmark is the key the next page starts from, or empty on the last page.
+func (s *Stub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, nil, err
+	}
+	return s.paginatedQuery(startKey, endKey, pageSize, bookmark)
+}
+
+// GetStateByPartialCompositeKey returns an iterator over the composite keys
+// starting with the given object type and attributes
+func (s *Stub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	startKey, endKey, err := partialCompositeKeyRange(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return s.rangeQuery(startKey, endKey), nil
+}
+
+// GetStateByPartialCompositeKeyWithPagination returns one page of a partial
+// composite key query
+func (s *Stub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	startKey, endKey, err := partialCompositeKeyRange(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.paginatedQuery(startKey, endKey, pageSize, bookmark)
+}
+
+// CreateCompositeKey combines an object type and attributes into a composite key
+func (s *Stub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+// SplitCompositeKey splits a composite key into its object type and attributes
+func (s *Stub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, compositeKeyNamespace) || !strings.HasSuffix(compositeKey, compositeKeyNamespace) || len(compositeKey) < 2 {
+		return "", nil, fmt.Errorf("invalid composite key [%x]", compositeKey)
+	}
+	components := strings.Split(compositeKey[1:len(compositeKey)-1], compositeKeyNamespace)
+	return components[0], components[1:], nil
+}
+
+// GetQueryResult is not supported: like a peer using LevelDB, the in-memory
+// ledger cannot evaluate CouchDB rich queries
+func (s *Stub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, fmt.Errorf("ExecuteQuery not supported for leveldb")
+}
+
+// GetQueryResultWithPagination is not supported, see GetQueryResult
+func (s *Stub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	return nil, nil, fmt.Errorf("ExecuteQuery not supported for leveldb")
+}
+
+// GetHistoryForKey returns the committed modifications of a key, newest first
+func (s *Stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	return &historyIterator{results: s.ledger.history(s.namespace, key)}, nil
+}
+
+// GetPrivateData returns the committed value of a key in a private data collection
+func (s *Stub) GetPrivateData(collection, key string) ([]byte, error) {
+	if collection == "" {
+		return nil, fmt.Errorf("collection must not be an empty string")
+	}
+	return s.read(rwKey{namespace: s.namespace, collection: collection, key: key})
+}
+
+// GetPrivateDataHash returns the SHA-256 hash of a committed private data value.
+// Unlike GetPrivateData it is available to organizations outside the collection.
+func (s *Stub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	if collection == "" {
+		return nil, fmt.Errorf("collection must not be an empty string")
+	}
+	value, err := s.read(rwKey{namespace: s.namespace, collection: collection, key: key})
+	if err != nil || value == nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
+}
+
+// PutPrivateData adds a private data key to the write set. An empty value deletes the key.
+func (s *Stub) PutPrivateData(collection string, key string, value []byte) error {
+	if collection == "" {
+		return fmt.Errorf("collection must not be an empty string")
+	}
+	if key == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+	return s.write(rwKey{namespace: s.namespace, collection: collection, key: key}, value)
+}
+
+// DelPrivateData adds the deletion of a private data key to the write set
+func (s *Stub) DelPrivateData(collection, key string) error {
+	if collection == "" {
+		return fmt.Errorf("collection must not be an empty string")
+	}
+	return s.write(rwKey{namespace: s.namespace, collection: collection, key: key}, nil)
+}
+
+// PurgePrivateData adds the purge of a private data key to the write set. The
+// in-memory ledger keeps no private data history, so a purge removes the key like
+// a deletion, together with its hash.
+func (s *Stub) PurgePrivateData(collection, key string) error {
+	if collection == "" {
+		return fmt.Errorf("collection must not be an empty string")
+	}
+	return s.write(rwKey{namespace: s.namespace, collection: collection, key: key}, nil)
+}
+
+// SetPrivateDataValidationParameter sets the key-level endorsement policy of a private data key
+func (s *Stub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	return s.setValidationParameter(rwKey{namespace: s.namespace, collection: collection, key: key}, ep)
+}
+
+// GetPrivateDataValidationParameter returns the committed key-level endorsement
+// policy of a private data key
+func (s *Stub) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	return s.getValidationParameter(rwKey{namespace: s.namespace, collection: collection, key: key})
+}
+
+// GetPrivateDataByRange returns an iterator over the keys of a collection in
+// [startKey, endKey). Queries on private data make the transaction read-only.
+func (s *Stub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if collection == "" {
+		return nil, fmt.Errorf("collection must not be an empty string")
+	}
+	if startKey == "" {
+		startKey = emptyKeySubstitute
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	return s.privateQuery(collection, startKey, endKey)
+}
+
+// GetPrivateDataByPartialCompositeKey returns an iterator over the composite
+// keys of a collection starting with the given object type and attributes
+func (s *Stub) GetPrivateDataByPartialCompositeKey(collection, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	if collection == "" {
+		return nil, fmt.Errorf("collection must not be an empty string")
+	}
+	startKey, endKey, err := partialCompositeKeyRange(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return s.privateQuery(collection, startKey, endKey)
+}
+
+// GetPrivateDataQueryResult is not supported, see GetQueryResult
+func (s *Stub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, fmt.Errorf("ExecuteQuery not supported for leveldb")
+}
+
+// GetCreator returns the serialized identity of the transaction submitter
+func (s *Stub) GetCreator() ([]byte, error) {
+	return s.Creator, nil
+}
+
+// GetTransient returns the transient data of the transaction proposal
+func (s *Stub) GetTransient() (map[string][]byte, error) {
+	return s.Transient, nil
+}
+
+// GetBinding returns nil as the in-memory proposal is not signed
+func (s *Stub) GetBinding() ([]byte, error) {
+	return nil, nil
+}
+
+// GetDecorations returns the decorations added to the proposal by peer endorsement plugins
+func (s *Stub) GetDecorations() map[string][]byte {
+	return s.Decorations
+}
+
+// GetSignedProposal returns the signed transaction proposal. Unless set by the
+// test, it is built from the stub: a proposal by the Creator to invoke the chaincode
+// of the stub with Args. A chaincode called through InvokeChaincode sees the
+// proposal of the transaction, like on a peer.
+func (s *Stub) GetSignedProposal() (*peer.SignedProposal, error) {
+	if s.SignedProposal != nil {
+		return s.SignedProposal, nil
+	}
+	return newSignedProposal(s)
+}
+
+// GetTxTimestamp returns the timestamp the transaction was created with
+func (s *Stub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return s.Timestamp, nil
+}
+
+// SetEvent sets the chaincode event of the transaction, replacing any earlier event
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return fmt.Errorf("event name can not be empty string")
+	}
+	s.event = &peer.ChaincodeEvent{
+		ChaincodeId: s.namespace,
+		TxId:        s.TxID,
+		EventName:   name,
+		Payload:     copyBytes(payload),
+	}
+	return nil
+}
+
+// read returns the committed value of a key and records its version in the
+// read set the first time the key is read
+func (s *Stub) read(k rwKey) ([]byte, error) {
+	vv, ok := s.ledger.get(k.namespace, k.collection, k.key)
+	if _, seen := s.tx.reads[k]; !seen {
+		if ok {
+			v := vv.version
+			s.tx.reads[k] = &v
+		} else {
+			s.tx.reads[k] = nil
+		}
+	}
+	if !ok {
+		return nil, nil
+	}
+	return vv.value, nil
+}
+
+func (s *Stub) checkWritable() error {
+	if s.tx.paginatedQueries {
+		return fmt.Errorf("txid [%s]: transaction has already performed a paginated query. Writes are not allowed", s.TxID)
+	}
+	if s.tx.privateQueries {
+		return fmt.Errorf("txid [%s]: transaction has already performed queries on pvt data. Writes are not allowed", s.TxID)
+	}
+	return nil
+}
+
+func (s *Stub) write(k rwKey, value []byte) error {
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	s.tx.writes[k] = &write{value: copyBytes(value), isDelete: len(value) == 0}
+	return nil
+}
+
+func (s *Stub) setValidationParameter(k rwKey, ep []byte) error {
+	if err := s.checkWritable(); err != nil {
+		return err
+	}
+	s.tx.validationParameters[k] = copyBytes(ep)
+	return nil
+}
+
+func (s *Stub) getValidationParameter(k rwKey) ([]byte, error) {
+	if _, err := s.read(k); err != nil {
+		return nil, err
+	}
+	vv, ok := s.ledger.get(k.namespace, k.collection, k.key)
+	if !ok {
+		return nil, nil
+	}
+	return vv.validationParameter, nil
+}
+
+func (s *Stub) rangeQuery(startKey, endKey string) *queryIterator {
+	rr := &rangeRead{namespace: s.namespace, startKey: startKey, endKey: endKey}
+	s.tx.rangeReads = append(s.tx.rangeReads, rr)
+	return &queryIterator{
+		namespace: s.namespace,
+		results:   s.ledger.scan(s.namespace, "", startKey, endKey),
+		rangeRead: rr,
+	}
+}
+
+func (s *Stub) paginatedQuery(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if s.tx.hasWrites() {
+		return nil, nil, fmt.Errorf("txid [%s]: paginated queries are supported only in a read-only transaction", s.TxID)
+	}
+	s.tx.paginatedQueries = true
+
+	if bookmark != "" && bookmark > startKey {
+		startKey = bookmark
+	}
+	results := s.ledger.scan(s.namespace, "", startKey, endKey)
+	next := ""
+	if pageSize > 0 && len(results) > int(pageSize) {
+		next = results[pageSize].key
+		results = results[:pageSize]
+	}
+
+	metadata := &peer.QueryResponseMetadata{
+		FetchedRecordsCount: int32(len(results)),
+		Bookmark:            next,
+	}
+	return &queryIterator{namespace: s.namespace, results: results}, metadata, nil
+}
+
+func (s *Stub) privateQuery(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if s.tx.hasWrites() {
+		return nil, fmt.Errorf("txid [%s]: queries on pvt data is supported only in a read-only transaction", s.TxID)
+	}
+	s.tx.privateQueries = true
+
+	return &queryIterator{
+		namespace: s.namespace,
+		results:   s.ledger.scan(s.namespace, collection, startKey, endKey),
+	}, nil
+}
+
+func partialCompositeKeyRange(objectType string, attributes []string) (string, string, error) {
+	partialCompositeKey, err := shim.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return "", "", err
+	}
+	return partialCompositeKey, partialCompositeKey + string(utf8.MaxRune), nil
+}
+
+func validateSimpleKeys(simpleKeys ...string) error {
+	for _, key := range simpleKeys {
+		if len(key) > 0 && key[0] == compositeKeyNamespace[0] {
+			return fmt.Errorf(`first character of the key [%s] contains a null character which is not allowed`, key)
+		}
+	}
+	return nil
+}
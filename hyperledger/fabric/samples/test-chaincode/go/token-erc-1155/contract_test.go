@@ -0,0 +1,233 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokenerc1155
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/hyperledger/fabric-samples/token-erc-1155/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+const myOrg1Msp = "Org1MSP"
+const myOrg1Clientid = "myOrg1Userid"
+const myOrg2Msp = "Org2MSP"
+const myOrg2Clientid = "myOrg2Userid"
+
+func TestMintBatch(t *testing.T) {
+	n := newNetwork(t, myOrg2Msp, myOrg2Clientid)
+	token := chaincode.SmartContract{}
+
+	err := n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.Mint(ctx, myOrg2Clientid, 1, 100)
+	})
+	require.EqualError(t, err, "client is not authorized to mint new tokens")
+
+	n.setClient(myOrg1Msp, myOrg1Clientid)
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.MintBatch(ctx, myOrg2Clientid, []uint64{1, 2}, []uint64{100})
+	})
+	require.EqualError(t, err, "ids and amounts must have the same length")
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.MintBatch(ctx, "0x0", []uint64{1}, []uint64{100})
+	})
+	require.EqualError(t, err, "mint to the zero address")
+
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.MintBatch(ctx, myOrg2Clientid, []uint64{1, 2, 1}, []uint64{100, 1, 50})
+	})
+	require.NoError(t, err)
+	n.requireEvent("TransferBatch", map[string]interface{}{
+		"operator": myOrg1Clientid, "from": "0x0", "to": myOrg2Clientid,
+		"ids": []interface{}{1.0, 2.0, 1.0}, "values": []interface{}{100.0, 1.0, 50.0},
+	})
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.Mint(ctx, myOrg1Clientid, 3, 10)
+	})
+	require.NoError(t, err)
+	n.requireEvent("TransferSingle", map[string]interface{}{
+		"operator": myOrg1Clientid, "from": "0x0", "to": myOrg1Clientid, "id": 3.0, "value": 10.0,
+	})
+
+	balances, err := token.BalanceOfBatch(n.query(), []string{myOrg2Clientid, myOrg2Clientid, myOrg2Clientid, myOrg1Clientid}, []uint64{1, 2, 3, 3})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{150, 1, 0, 10}, balances)
+
+	_, err = token.BalanceOfBatch(n.query(), []string{myOrg2Clientid}, []uint64{1, 2})
+	require.EqualError(t, err, "accounts and ids must have the same length")
+}
+
+func TestSafeBatchTransferFrom(t *testing.T) {
+	n := newNetwork(t, myOrg1Msp, myOrg1Clientid)
+	token := chaincode.SmartContract{}
+	const myOrg2Operatorid = "myOrg2Operatorid"
+
+	err := n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.MintBatch(ctx, myOrg2Clientid, []uint64{1, 2}, []uint64{100, 1})
+	})
+	require.NoError(t, err)
+
+	// the operator needs the approval of the owner
+	n.setClient(myOrg2Msp, myOrg2Operatorid)
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SafeBatchTransferFrom(ctx, myOrg2Clientid, myOrg1Clientid, []uint64{1, 2}, []uint64{40, 1})
+	})
+	require.EqualError(t, err, "caller is not owner nor is approved")
+
+	n.setClient(myOrg2Msp, myOrg2Clientid)
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SetApprovalForAll(ctx, myOrg2Operatorid, true)
+	})
+	require.NoError(t, err)
+	n.requireEvent("ApprovalForAll", map[string]interface{}{"owner": myOrg2Clientid, "operator": myOrg2Operatorid, "approved": true})
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SetApprovalForAll(ctx, myOrg2Clientid, true)
+	})
+	require.EqualError(t, err, "setting approval status for self")
+
+	n.setClient(myOrg2Msp, myOrg2Operatorid)
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SafeBatchTransferFrom(ctx, myOrg2Clientid, myOrg1Clientid, []uint64{1, 2, 1}, []uint64{40, 1, 70})
+	})
+	require.EqualError(t, err, "failed to transfer: client account myOrg2Userid has insufficient funds of token 1")
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SafeBatchTransferFrom(ctx, myOrg2Clientid, "0x0", []uint64{1}, []uint64{40})
+	})
+	require.EqualError(t, err, "failed to transfer: transfer to the zero address")
+
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SafeBatchTransferFrom(ctx, myOrg2Clientid, myOrg1Clientid, []uint64{1, 2, 1}, []uint64{40, 1, 20})
+	})
+	require.NoError(t, err)
+	n.requireEvent("TransferBatch", map[string]interface{}{
+		"operator": myOrg2Operatorid, "from": myOrg2Clientid, "to": myOrg1Clientid,
+		"ids": []interface{}{1.0, 2.0, 1.0}, "values": []interface{}{40.0, 1.0, 20.0},
+	})
+
+	balances, err := token.BalanceOfBatch(n.query(), []string{myOrg2Clientid, myOrg2Clientid, myOrg1Clientid, myOrg1Clientid}, []uint64{1, 2, 1, 2})
+	require.NoError(t, err)
+	require.Equal(t, []uint64{40, 0, 60, 1}, balances)
+
+	// the owner revokes the approval
+	n.setClient(myOrg2Msp, myOrg2Clientid)
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SetApprovalForAll(ctx, myOrg2Operatorid, false)
+	})
+	require.NoError(t, err)
+	approved, err := token.IsApprovedForAll(n.query(), myOrg2Clientid, myOrg2Operatorid)
+	require.NoError(t, err)
+	require.False(t, approved)
+
+	n.setClient(myOrg2Msp, myOrg2Operatorid)
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SafeTransferFrom(ctx, myOrg2Clientid, myOrg2Operatorid, 1, 10)
+	})
+	require.EqualError(t, err, "caller is not owner nor is approved")
+
+	n.setClient(myOrg2Msp, myOrg2Clientid)
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SafeTransferFrom(ctx, myOrg2Clientid, myOrg2Operatorid, 1, 10)
+	})
+	require.NoError(t, err)
+	n.requireEvent("TransferSingle", map[string]interface{}{
+		"operator": myOrg2Clientid, "from": myOrg2Clientid, "to": myOrg2Operatorid, "id": 1.0, "value": 10.0,
+	})
+	balance, err := token.ClientAccountBalance(n.query(), 1)
+	require.NoError(t, err)
+	require.EqualValues(t, 30, balance)
+}
+
+func TestURI(t *testing.T) {
+	n := newNetwork(t, myOrg2Msp, myOrg2Clientid)
+	token := chaincode.SmartContract{}
+
+	err := n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SetURI(ctx, 1, "https://example.com/tokens/1.json")
+	})
+	require.EqualError(t, err, "client is not authorized to mint new tokens")
+
+	n.setClient(myOrg1Msp, myOrg1Clientid)
+	err = n.submit(func(ctx contractapi.TransactionContextInterface) error {
+		return token.SetURI(ctx, 1, "https://example.com/tokens/1.json")
+	})
+	require.NoError(t, err)
+	n.requireEvent("URI", map[string]interface{}{"value": "https://example.com/tokens/1.json", "id": 1.0})
+
+	uri, err := token.URI(n.query(), 1)
+	require.NoError(t, err)
+	require.Equal(t, "https://example.com/tokens/1.json", uri)
+	_, err = token.URI(n.query(), 2)
+	require.EqualError(t, err, "no URI is set for token 2")
+}
+
+func TestContractMetadata(t *testing.T) {
+	_, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	require.NoError(t, err)
+}
+
+// network runs the token contract against an in-memory ledger, one committed
+// transaction per call, as the current client
+type network struct {
+	t        *testing.T
+	ledger   *memstub.Ledger
+	identity *memstub.ClientIdentity
+	tx       int
+	event    *peer.ChaincodeEvent // event of the last committed transaction
+}
+
+func newNetwork(t *testing.T, orgMSP, clientID string) *network {
+	return &network{t: t, ledger: memstub.NewLedger(), identity: memstub.NewClientIdentity(clientID, orgMSP)}
+}
+
+// setClient changes the client identity that submits the next transactions
+func (n *network) setClient(orgMSP, clientID string) {
+	n.identity = memstub.NewClientIdentity(clientID, orgMSP)
+}
+
+func (n *network) newTransactionContext() (*contractapi.TransactionContext, *memstub.Stub) {
+	n.tx++
+	stub := n.ledger.NewStub(fmt.Sprintf("tx%d", n.tx))
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(n.identity)
+	return ctx, stub
+}
+
+// submit simulates a transaction of the current client and commits it if it succeeds
+func (n *network) submit(fn func(ctx contractapi.TransactionContextInterface) error) error {
+	ctx, stub := n.newTransactionContext()
+	err := fn(ctx)
+	if err != nil {
+		return err
+	}
+	err = stub.Commit()
+	if err != nil {
+		return err
+	}
+	n.event = stub.Event()
+	return nil
+}
+
+// query returns a transaction context of the current client for reading the ledger
+func (n *network) query() contractapi.TransactionContextInterface {
+	ctx, _ := n.newTransactionContext()
+	return ctx
+}
+
+// requireEvent checks the event of the last committed transaction
+func (n *network) requireEvent(name string, expected map[string]interface{}) {
+	require.NotNil(n.t, n.event)
+	require.Equal(n.t, name, n.event.EventName)
+
+	var actual map[string]interface{}
+	err := json.Unmarshal(n.event.Payload, &actual)
+	require.NoError(n.t, err)
+	require.Equal(n.t, expected, actual)
+}
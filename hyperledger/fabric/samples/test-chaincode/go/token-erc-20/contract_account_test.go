@@ -0,0 +1,158 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokenerc20
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+const tokenChaincodeName = "token_erc20"
+
+// forwarder is a chaincode that passes the arguments it is invoked with on to the token chaincode
+type forwarder struct{}
+
+func (forwarder) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	return shim.Success(nil)
+}
+
+func (forwarder) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	return stub.InvokeChaincode(tokenChaincodeName, stub.GetArgs(), "")
+}
+
+// network is an in-memory channel with the token chaincode and a forwarding chaincode
+type network struct {
+	t          *testing.T
+	ledger     *memstub.Ledger
+	chaincodes map[string]shim.Chaincode
+	tx         int
+	event      *peer.ChaincodeEvent // event of the last committed transaction
+}
+
+func newNetwork(t *testing.T) *network {
+	token, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	require.NoError(t, err)
+
+	n := &network{t: t, ledger: memstub.NewLedger(), chaincodes: map[string]shim.Chaincode{
+		tokenChaincodeName: token,
+		"forwarder":        forwarder{},
+	}}
+	for name, cc := range n.chaincodes {
+		n.ledger.RegisterChaincode(name, cc)
+	}
+	return n
+}
+
+// user returns the creator of a client of Org1
+func (n *network) user(name string) []byte {
+	return n.client("Org1MSP", name)
+}
+
+// client returns the creator of a client of an organization
+func (n *network) client(mspID string, name string) []byte {
+	creator, err := memstub.NewCreator(mspID, name)
+	require.NoError(n.t, err)
+	return creator
+}
+
+// id returns the client ID of a creator, which is also its token account
+func (n *network) id(creator []byte) string {
+	stub := n.ledger.NewStub("id")
+	stub.Creator = creator
+	identity, err := cid.New(stub)
+	require.NoError(n.t, err)
+	id, err := identity.GetID()
+	require.NoError(n.t, err)
+	return id
+}
+
+// invoke simulates a transaction sent by a client to a chaincode, and commits it if it succeeds
+func (n *network) invoke(chaincodeName string, creator []byte, args ...string) peer.Response {
+	n.tx++
+	stub := n.ledger.NewChaincodeStub(chaincodeName, fmt.Sprintf("tx%d", n.tx))
+	stub.Creator = creator
+	for _, arg := range args {
+		stub.Args = append(stub.Args, []byte(arg))
+	}
+
+	response := n.chaincodes[chaincodeName].Invoke(stub)
+	if response.Status == shim.OK {
+		require.NoError(n.t, stub.Commit())
+		n.event = stub.Event()
+	}
+	return response
+}
+
+// invokeToken invokes the token chaincode directly and requires the transaction to succeed
+func (n *network) invokeToken(creator []byte, args ...string) string {
+	response := n.invoke(tokenChaincodeName, creator, args...)
+	require.EqualValues(n.t, shim.OK, response.Status, response.Message)
+	return string(response.Payload)
+}
+
+// submitToken invokes the token chaincode directly and returns the error of the transaction, if any
+func (n *network) submitToken(creator []byte, args ...string) error {
+	response := n.invoke(tokenChaincodeName, creator, args...)
+	if response.Status != shim.OK {
+		return errors.New(response.Message)
+	}
+	return nil
+}
+
+// requireEvent checks the event of the last committed transaction
+func (n *network) requireEvent(name string, expected map[string]interface{}) {
+	require.NotNil(n.t, n.event)
+	require.Equal(n.t, name, n.event.EventName)
+
+	var actual map[string]interface{}
+	err := json.Unmarshal(n.event.Payload, &actual)
+	require.NoError(n.t, err)
+	require.Equal(n.t, expected, actual)
+}
+
+func TestForwardingChaincodeCannotSpendClientTokens(t *testing.T) {
+	n := newNetwork(t)
+	bank, user, attacker := n.user("bank"), n.user("user"), n.user("attacker")
+
+	n.invokeToken(bank, "Initialize", "Token", "TOK", "0")
+	n.invokeToken(bank, "Mint", "1000")
+	n.invokeToken(bank, "Transfer", n.id(user), "500")
+
+	// the forwarding chaincode passes on the user's identity and the arguments of a direct
+	// transfer, but acts on its own account
+	response := n.invoke("forwarder", user, "ClientAccountID")
+	require.EqualValues(t, shim.OK, response.Status, response.Message)
+	require.Equal(t, "chaincode:forwarder", string(response.Payload))
+
+	response = n.invoke("forwarder", user, "Transfer", n.id(attacker), "100")
+	require.EqualValues(t, shim.ERROR, response.Status)
+	require.Equal(t, "failed to transfer: client account chaincode:forwarder has no balance", response.Message)
+
+	require.Equal(t, "500", n.invokeToken(user, "BalanceOf", n.id(user)))
+	response = n.invoke(tokenChaincodeName, user, "BalanceOf", n.id(attacker))
+	require.Equal(t, fmt.Sprintf("the account %s does not exist", n.id(attacker)), response.Message)
+
+	// the user can still spend the tokens by invoking the token chaincode directly
+	n.invokeToken(user, "Transfer", n.id(attacker), "100")
+	require.Equal(t, "400", n.invokeToken(user, "BalanceOf", n.id(user)))
+}
+
+func TestUninitializedTokenRejectsCalls(t *testing.T) {
+	n := newNetwork(t)
+
+	response := n.invoke(tokenChaincodeName, n.user("user"), "ClientAccountID")
+	require.EqualValues(t, shim.ERROR, response.Status)
+	require.Equal(t, "the token chaincode is not initialized", response.Message)
+}
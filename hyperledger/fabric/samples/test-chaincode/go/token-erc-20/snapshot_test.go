@@ -0,0 +1,82 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokenerc20
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshot(t *testing.T) {
+	n, org1 := newInitializedNetwork(t)
+	org2 := n.client("Org2MSP", "org2user")
+	org1ID, org2ID := n.id(org1), n.id(org2)
+
+	n.invokeToken(org1, "Mint", "1000")
+
+	err := n.submitToken(org2, "Snapshot")
+	require.EqualError(t, err, "client is not authorized: the admin role is required")
+
+	// snapshot 1: Org1 has 1000 tokens
+	n.ledger.SetTime(time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC))
+	snapshot1 := n.invokeToken(org1, "Snapshot")
+	require.Equal(t, "1", snapshot1)
+	n.requireEvent("Snapshot", map[string]interface{}{"id": 1.0, "timestamp": "2021-03-01T09:00:00Z"})
+
+	n.invokeToken(org1, "Transfer", org2ID, "300")
+	n.invokeToken(org1, "Transfer", org2ID, "100")
+
+	// snapshot 2 and 3: Org1 has 600 tokens, Org2 has 400 tokens
+	snapshot2 := n.invokeToken(org1, "Snapshot")
+	snapshot3 := n.invokeToken(org1, "Snapshot")
+
+	n.invokeToken(org1, "Mint", "500")
+	n.invokeToken(org1, "Burn", "100")
+
+	for _, tc := range []struct {
+		snapshotID string
+		org1, org2 int
+		total      int
+		name       string
+	}{
+		{snapshot1, 1000, 0, 1000, "snapshot 1"},
+		{snapshot2, 600, 400, 1000, "snapshot 2"},
+		{snapshot3, 600, 400, 1000, "snapshot 3"},
+	} {
+		require.Equal(t, strconv.Itoa(tc.org1), n.invokeToken(org1, "BalanceOfAt", org1ID, tc.snapshotID), tc.name)
+		require.Equal(t, strconv.Itoa(tc.org2), n.invokeToken(org1, "BalanceOfAt", org2ID, tc.snapshotID), tc.name)
+		require.Equal(t, strconv.Itoa(tc.total), n.invokeToken(org1, "TotalSupplyAt", tc.snapshotID), tc.name)
+	}
+
+	// balances that did not change since the last snapshot are read from the current state
+	snapshot4 := n.invokeToken(org1, "Snapshot")
+	require.Equal(t, "1000", n.invokeToken(org1, "BalanceOfAt", org1ID, snapshot4))
+	require.Equal(t, "1400", n.invokeToken(org1, "TotalSupplyAt", snapshot4))
+
+	err = n.submitToken(org1, "BalanceOfAt", org1ID, "0")
+	require.EqualError(t, err, "snapshot id must be a positive integer")
+	err = n.submitToken(org1, "TotalSupplyAt", "5")
+	require.EqualError(t, err, "snapshot 5 does not exist")
+}
+
+func TestGetSnapshot(t *testing.T) {
+	n, org1 := newInitializedNetwork(t)
+
+	n.ledger.SetTime(time.Date(2021, time.March, 1, 9, 0, 0, 0, time.UTC))
+	n.invokeToken(org1, "Snapshot")
+	n.ledger.SetTime(time.Date(2021, time.March, 31, 17, 30, 0, 0, time.UTC))
+	n.invokeToken(org1, "Snapshot")
+
+	require.JSONEq(t, `{"id":1,"timestamp":"2021-03-01T09:00:00Z"}`, n.invokeToken(org1, "GetSnapshot", "1"))
+	require.JSONEq(t, `{"id":2,"timestamp":"2021-03-31T17:30:00Z"}`, n.invokeToken(org1, "GetSnapshot", "2"))
+
+	err := n.submitToken(org1, "GetSnapshot", "0")
+	require.EqualError(t, err, "snapshot id must be a positive integer")
+	err = n.submitToken(org1, "GetSnapshot", "3")
+	require.EqualError(t, err, "snapshot 3 does not exist")
+}
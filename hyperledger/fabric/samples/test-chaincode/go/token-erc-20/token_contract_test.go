@@ -0,0 +1,159 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokenerc20
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/token-erc-20/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+// newInitializedNetwork returns a network with a token initialized by the Org1 client
+func newInitializedNetwork(t *testing.T) (*network, []byte) {
+	n := newNetwork(t)
+	org1 := n.user("org1user")
+	n.invokeToken(org1, "Initialize", "some name", "SYM", "2")
+	return n, org1
+}
+
+func TestInitialize(t *testing.T) {
+	n := newNetwork(t)
+	org1, org2 := n.user("org1user"), n.client("Org2MSP", "org2user")
+
+	err := n.submitToken(org2, "Initialize", "some name", "SYM", "2")
+	require.EqualError(t, err, "client is not authorized to initialize the contract")
+
+	err = n.submitToken(org2, "Name")
+	require.EqualError(t, err, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+
+	n.invokeToken(org1, "Initialize", "some name", "SYM", "2")
+	require.Equal(t, "some name", n.invokeToken(org2, "Name"))
+	require.Equal(t, "SYM", n.invokeToken(org2, "Symbol"))
+	require.Equal(t, "2", n.invokeToken(org2, "Decimals"))
+
+	for _, role := range []string{"admin", "minter", "burner", "pauser"} {
+		require.Equal(t, "true", n.invokeToken(org2, "HasRole", role, n.id(org1)), role)
+	}
+
+	err = n.submitToken(org1, "Initialize", "other name", "OTH", "0")
+	require.EqualError(t, err, "contract options are already set, client is not authorized to change them")
+}
+
+func TestRoles(t *testing.T) {
+	n, org1 := newInitializedNetwork(t)
+	org2 := n.client("Org2MSP", "org2user")
+	org1ID, org2ID := n.id(org1), n.id(org2)
+
+	// Org2 cannot mint nor grant itself the minter role
+	err := n.submitToken(org2, "Mint", "100")
+	require.EqualError(t, err, "client is not authorized: the minter role is required")
+	err = n.submitToken(org2, "GrantRole", "minter", org2ID)
+	require.EqualError(t, err, "client is not authorized: the admin role is required")
+
+	err = n.submitToken(org1, "GrantRole", "owner", org2ID)
+	require.EqualError(t, err, "unknown role owner, expected one of admin, minter, burner or pauser")
+	n.invokeToken(org1, "GrantRole", "minter", org2ID)
+	n.requireEvent("RoleGranted", map[string]interface{}{"role": "minter", "account": org2ID, "sender": org1ID})
+
+	n.invokeToken(org2, "Mint", "100")
+	n.requireEvent("Transfer", map[string]interface{}{"from": "0x0", "to": org2ID, "value": 100.0})
+	err = n.submitToken(org2, "Burn", "50")
+	require.EqualError(t, err, "client is not authorized: the burner role is required")
+
+	n.invokeToken(org1, "RevokeRole", "minter", org2ID)
+	n.requireEvent("RoleRevoked", map[string]interface{}{"role": "minter", "account": org2ID, "sender": org1ID})
+	err = n.submitToken(org1, "RevokeRole", "minter", org2ID)
+	require.EqualError(t, err, fmt.Sprintf("account %s does not have the minter role", org2ID))
+	err = n.submitToken(org1, "RevokeRole", "admin", org1ID)
+	require.EqualError(t, err, "an admin cannot revoke its own admin role")
+
+	err = n.submitToken(org2, "Mint", "100")
+	require.EqualError(t, err, "client is not authorized: the minter role is required")
+
+	require.Equal(t, "100", n.invokeToken(org2, "BalanceOf", org2ID))
+}
+
+func TestPause(t *testing.T) {
+	n, org1 := newInitializedNetwork(t)
+	org2 := n.client("Org2MSP", "org2user")
+	org1ID, org2ID := n.id(org1), n.id(org2)
+
+	n.invokeToken(org1, "Mint", "1000")
+	n.invokeToken(org1, "Approve", org2ID, "500")
+
+	err := n.submitToken(org2, "Pause")
+	require.EqualError(t, err, "client is not authorized: the pauser role is required")
+
+	n.invokeToken(org1, "Pause")
+	n.requireEvent("Paused", map[string]interface{}{"account": org1ID})
+	require.Equal(t, "true", n.invokeToken(org1, "Paused"))
+	err = n.submitToken(org1, "Pause")
+	require.EqualError(t, err, "token is already paused")
+
+	// all the functions that change balances are stopped
+	err = n.submitToken(org1, "Mint", "100")
+	require.EqualError(t, err, "token is paused")
+	err = n.submitToken(org1, "Burn", "100")
+	require.EqualError(t, err, "token is paused")
+	err = n.submitToken(org1, "Transfer", org2ID, "100")
+	require.EqualError(t, err, "failed to transfer: token is paused")
+	err = n.submitToken(org2, "TransferFrom", org1ID, org2ID, "100")
+	require.EqualError(t, err, "token is paused")
+
+	n.invokeToken(org1, "Unpause")
+	n.requireEvent("Unpaused", map[string]interface{}{"account": org1ID})
+	err = n.submitToken(org1, "Unpause")
+	require.EqualError(t, err, "token is not paused")
+
+	n.invokeToken(org1, "Transfer", org2ID, "100")
+	require.Equal(t, "100", n.invokeToken(org2, "BalanceOf", org2ID))
+}
+
+func TestFreezeAccount(t *testing.T) {
+	n, org1 := newInitializedNetwork(t)
+	org2, spender := n.client("Org2MSP", "org2user"), n.client("Org2MSP", "org2spender")
+	org1ID, org2ID, spenderID := n.id(org1), n.id(org2), n.id(spender)
+
+	n.invokeToken(org1, "Mint", "1000")
+	n.invokeToken(org1, "Transfer", org2ID, "300")
+
+	n.invokeToken(org2, "Approve", spenderID, "200")
+	err := n.submitToken(org2, "FreezeAccount", org1ID)
+	require.EqualError(t, err, "client is not authorized: the admin role is required")
+
+	err = n.submitToken(org1, "FreezeAccount", org1ID)
+	require.EqualError(t, err, "an admin cannot freeze its own account")
+	n.invokeToken(org1, "FreezeAccount", org2ID)
+	n.requireEvent("AccountFrozen", map[string]interface{}{"account": org2ID, "sender": org1ID})
+	require.Equal(t, "true", n.invokeToken(org1, "IsFrozen", org2ID))
+
+	// the frozen account can neither send nor receive tokens
+	frozen := fmt.Sprintf("failed to transfer: account %s is frozen", org2ID)
+	err = n.submitToken(org1, "Transfer", org2ID, "100")
+	require.EqualError(t, err, frozen)
+	err = n.submitToken(org2, "Transfer", org1ID, "100")
+	require.EqualError(t, err, frozen)
+	err = n.submitToken(spender, "TransferFrom", org2ID, spenderID, "100")
+	require.EqualError(t, err, frozen)
+
+	// other accounts are not affected
+	n.invokeToken(org1, "Transfer", spenderID, "100")
+
+	n.invokeToken(org1, "UnfreezeAccount", org2ID)
+	n.requireEvent("AccountUnfrozen", map[string]interface{}{"account": org2ID, "sender": org1ID})
+	err = n.submitToken(org1, "UnfreezeAccount", org2ID)
+	require.EqualError(t, err, fmt.Sprintf("account %s is not frozen", org2ID))
+
+	n.invokeToken(spender, "TransferFrom", org2ID, spenderID, "100")
+	require.Equal(t, "200", n.invokeToken(spender, "BalanceOf", spenderID))
+}
+
+func TestContractMetadata(t *testing.T) {
+	_, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	require.NoError(t, err)
+}
@@ -0,0 +1,148 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokenutxo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/token-utxo/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPay(t *testing.T) {
+	for _, tc := range []struct {
+		strategy string
+		inputs   []string
+		change   int
+	}{
+		{"largest-first", []string{"tx3.0"}, 450},
+		{"smallest-first", []string{"tx1.0", "tx2.0"}, 0},
+		{"minimize-inputs", []string{"tx4.0"}, 50},
+	} {
+		t.Run(tc.strategy, func(t *testing.T) {
+			n := newNetwork(myOrg1Msp, myOrg1Clientid)
+			token := chaincode.SmartContract{}
+			mintUTXOs(t, n, 50, 200, 700, 300)
+
+			var outputs []chaincode.UTXO
+			err := n.submit("tx5", time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC), func(ctx contractapi.TransactionContextInterface) (err error) {
+				outputs, err = token.Pay(ctx, myOrg2Clientid, 250, tc.strategy)
+				return err
+			})
+			require.NoError(t, err)
+
+			expected := []chaincode.UTXO{{Key: "tx5.0", Owner: myOrg2Clientid, Amount: 250}}
+			if tc.change > 0 {
+				expected = append(expected, chaincode.UTXO{Key: "tx5.1", Owner: myOrg1Clientid, Amount: tc.change})
+			}
+			require.Equal(t, expected, outputs)
+
+			utxos, err := token.ClientUTXOs(n.query())
+			require.NoError(t, err)
+			for _, utxo := range utxos {
+				require.NotContains(t, tc.inputs, utxo.Key, "the selected inputs are spent")
+			}
+			require.Len(t, utxos, 4-len(tc.inputs)+len(expected)-1)
+		})
+	}
+}
+
+func TestPayMinimizeInputs(t *testing.T) {
+	n := newNetwork(myOrg1Msp, myOrg1Clientid)
+	token := chaincode.SmartContract{}
+	mintUTXOs(t, n, 100, 600, 400, 350, 700)
+
+	// the two largest UTXOs are needed, and 400 is the smallest UTXO that covers the rest after 700
+	var outputs []chaincode.UTXO
+	err := n.submit("tx6", time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC), func(ctx contractapi.TransactionContextInterface) (err error) {
+		outputs, err = token.Pay(ctx, myOrg2Clientid, 1100, "minimize-inputs")
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, []chaincode.UTXO{{Key: "tx6.0", Owner: myOrg2Clientid, Amount: 1100}}, outputs)
+
+	utxos, err := token.ClientUTXOs(n.query())
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.UTXO{
+		{Key: "tx1.0", Owner: myOrg1Clientid, Amount: 100},
+		{Key: "tx2.0", Owner: myOrg1Clientid, Amount: 600},
+		{Key: "tx4.0", Owner: myOrg1Clientid, Amount: 350},
+	}, utxos)
+}
+
+func TestPayErrors(t *testing.T) {
+	n := newNetwork(myOrg1Msp, myOrg1Clientid)
+	token := chaincode.SmartContract{}
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	mintUTXOs(t, n, 100, 200)
+
+	// lock the UTXO of 200 tokens for a day
+	err := n.submit("tx3", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.Transfer(ctx, []string{"tx2.0"}, []chaincode.UTXO{{Owner: myOrg1Clientid, Amount: 200, LockedUntil: "2021-01-02T00:00:00Z"}})
+		return err
+	})
+	require.NoError(t, err)
+
+	pay := func(txTime time.Time, amount int, strategy string) error {
+		return n.submit("tx4", txTime, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := token.Pay(ctx, myOrg2Clientid, amount, strategy)
+			return err
+		})
+	}
+	require.EqualError(t, pay(start, 150, "random"), "unknown coin selection strategy random, expected one of largest-first, smallest-first or minimize-inputs")
+	require.EqualError(t, pay(start, 0, "largest-first"), "payment amount must be a positive integer")
+	require.EqualError(t, pay(start, 150, "largest-first"), "client myOrg1Userid has insufficient spendable funds for a payment of 150")
+	require.NoError(t, pay(start.Add(24*time.Hour), 150, "largest-first"))
+}
+
+func TestConsolidate(t *testing.T) {
+	n := newNetwork(myOrg1Msp, myOrg1Clientid)
+	token := chaincode.SmartContract{}
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	mintUTXOs(t, n, 50, 200, 700, 300)
+
+	err := n.submit("tx5", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.Consolidate(ctx, 1)
+		return err
+	})
+	require.EqualError(t, err, "at least two utxos are needed to consolidate")
+
+	var consolidated *chaincode.UTXO
+	err = n.submit("tx5", start, func(ctx contractapi.TransactionContextInterface) (err error) {
+		consolidated, err = token.Consolidate(ctx, 3)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.UTXO{Key: "tx5.0", Owner: myOrg1Clientid, Amount: 550}, consolidated)
+
+	utxos, err := token.ClientUTXOs(n.query())
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.UTXO{
+		{Key: "tx3.0", Owner: myOrg1Clientid, Amount: 700},
+		{Key: "tx5.0", Owner: myOrg1Clientid, Amount: 550},
+	}, utxos)
+
+	n.setClient(myOrg2Msp, myOrg2Clientid)
+	err = n.submit("tx6", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.Consolidate(ctx, 3)
+		return err
+	})
+	require.EqualError(t, err, "client myOrg2Userid has fewer than two spendable utxos")
+}
+
+// mintUTXOs mints a UTXO for each amount, in transactions tx1, tx2 and so on
+func mintUTXOs(t *testing.T, n *network, amounts ...int) {
+	token := chaincode.SmartContract{}
+	for i, amount := range amounts {
+		err := n.submit(fmt.Sprintf("tx%d", i+1), time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC), func(ctx contractapi.TransactionContextInterface) error {
+			_, err := token.Mint(ctx, amount)
+			return err
+		})
+		require.NoError(t, err)
+	}
+}
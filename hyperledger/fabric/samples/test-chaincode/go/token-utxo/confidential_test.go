@@ -0,0 +1,185 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokenutxo
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/token-utxo/chaincode-go/chaincode"
+	"github.com/hyperledger/fabric-samples/token-utxo/chaincode-go/pedersen"
+	"github.com/stretchr/testify/require"
+)
+
+// confidentialOutput is an output that a client creates, with the amount and blinding factor it keeps off the ledger
+type confidentialOutput struct {
+	owner    string
+	amount   uint64
+	blinding *big.Int
+}
+
+func TestConfidentialTransfer(t *testing.T) {
+	n := newNetwork(myOrg1Msp, myOrg1Clientid)
+	token := chaincode.SmartContract{}
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	mintUTXOs(t, n, 100)
+
+	// Shield the plain utxo into two confidential utxos
+	shielded := newOutputs(t, confidentialOutput{owner: myOrg1Clientid, amount: 60}, confidentialOutput{owner: myOrg1Clientid, amount: 40})
+	utxoOutputs := prove(t, shielded)
+	signature := signBalance(t, "Shield", []string{"tx1.0"}, 0, nil, shielded, utxoOutputs)
+	var created []chaincode.ConfidentialUTXO
+	err := n.submit("tx2", start, func(ctx contractapi.TransactionContextInterface) (err error) {
+		created, err = token.Shield(ctx, []string{"tx1.0"}, utxoOutputs, *signature)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, "tx2.0", created[0].Key)
+	require.Equal(t, "tx2.1", created[1].Key)
+	require.Nil(t, created[0].RangeProof)
+
+	utxos, err := token.ClientUTXOs(n.query())
+	require.NoError(t, err)
+	require.Empty(t, utxos)
+
+	confidentialUTXOs, err := token.ClientConfidentialUTXOs(n.query())
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.ConfidentialUTXO{
+		{Key: "tx2.0", Owner: myOrg1Clientid, Commitment: pedersen.Commit(60, shielded[0].blinding)},
+		{Key: "tx2.1", Owner: myOrg1Clientid, Commitment: pedersen.Commit(40, shielded[1].blinding)},
+	}, confidentialUTXOs)
+
+	// Transfer 25 of the 60 to Org2, with 35 change
+	transferred := newOutputs(t, confidentialOutput{owner: myOrg2Clientid, amount: 25}, confidentialOutput{owner: myOrg1Clientid, amount: 35})
+	utxoOutputs = prove(t, transferred)
+	signature = signBalance(t, "ConfidentialTransfer", []string{"tx2.0"}, 0, shielded[:1], transferred, utxoOutputs)
+	err = n.submit("tx3", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.ConfidentialTransfer(ctx, []string{"tx2.0"}, utxoOutputs, *signature)
+		return err
+	})
+	require.NoError(t, err)
+
+	confidentialUTXOs, err = token.ClientConfidentialUTXOs(n.query())
+	require.NoError(t, err)
+	require.Len(t, confidentialUTXOs, 2)
+	require.Equal(t, "tx2.1", confidentialUTXOs[0].Key)
+	require.Equal(t, "tx3.1", confidentialUTXOs[1].Key)
+
+	// Org2 unshields 10 of its 25 and keeps 15 confidential
+	n.setClient(myOrg2Msp, myOrg2Clientid)
+	change := newOutputs(t, confidentialOutput{owner: myOrg2Clientid, amount: 15})
+	utxoOutputs = prove(t, change)
+	signature = signBalance(t, "Unshield", []string{"tx3.0"}, 10, transferred[:1], change, utxoOutputs)
+	var utxo *chaincode.UTXO
+	err = n.submit("tx4", start, func(ctx contractapi.TransactionContextInterface) (err error) {
+		utxo, err = token.Unshield(ctx, []string{"tx3.0"}, 10, utxoOutputs, *signature)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, &chaincode.UTXO{Key: "tx4.0", Owner: myOrg2Clientid, Amount: 10}, utxo)
+
+	utxos, err = token.ClientUTXOs(n.query())
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.UTXO{utxo}, utxos)
+
+	confidentialUTXOs, err = token.ClientConfidentialUTXOs(n.query())
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.ConfidentialUTXO{
+		{Key: "tx4.1", Owner: myOrg2Clientid, Commitment: pedersen.Commit(15, change[0].blinding)},
+	}, confidentialUTXOs)
+}
+
+func TestConfidentialTransferUnbalanced(t *testing.T) {
+	n := newNetwork(myOrg1Msp, myOrg1Clientid)
+	token := chaincode.SmartContract{}
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	mintUTXOs(t, n, 100)
+	shield := func(utxoOutputs []chaincode.ConfidentialUTXO, signature *pedersen.ExcessSignature) error {
+		return n.submit("tx2", start, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := token.Shield(ctx, []string{"tx1.0"}, utxoOutputs, *signature)
+			return err
+		})
+	}
+	transfer := func(utxoOutputs []chaincode.ConfidentialUTXO, signature *pedersen.ExcessSignature) error {
+		return n.submit("tx3", start, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := token.ConfidentialTransfer(ctx, []string{"tx2.0"}, utxoOutputs, *signature)
+			return err
+		})
+	}
+
+	// Shielding more than the inputs cannot be signed
+	shielded := newOutputs(t, confidentialOutput{owner: myOrg1Clientid, amount: 101})
+	utxoOutputs := prove(t, shielded)
+	signature := signBalance(t, "Shield", []string{"tx1.0"}, 0, nil, shielded, utxoOutputs)
+	require.EqualError(t, shield(utxoOutputs, signature), "excess signature is invalid, the input and output amounts do not balance")
+
+	shielded = newOutputs(t, confidentialOutput{owner: myOrg1Clientid, amount: 100})
+	utxoOutputs = prove(t, shielded)
+	signature = signBalance(t, "Shield", []string{"tx1.0"}, 0, nil, shielded, utxoOutputs)
+	require.NoError(t, shield(utxoOutputs, signature))
+
+	// The signature does not cover a different recipient
+	transferred := newOutputs(t, confidentialOutput{owner: myOrg2Clientid, amount: 100})
+	utxoOutputs = prove(t, transferred)
+	signature = signBalance(t, "ConfidentialTransfer", []string{"tx2.0"}, 0, shielded, transferred, utxoOutputs)
+	utxoOutputs[0].Owner = myOrg2Treasurerid
+	require.EqualError(t, transfer(utxoOutputs, signature), "excess signature is invalid, the input and output amounts do not balance")
+
+	// Each output requires its own range proof
+	utxoOutputs = prove(t, transferred)
+	utxoOutputs[0].RangeProof = nil
+	require.EqualError(t, transfer(utxoOutputs, signature), "invalid range proof of utxo output 0: range proof must have 32 bits")
+
+	other := prove(t, newOutputs(t, confidentialOutput{owner: myOrg2Clientid, amount: 100}))
+	utxoOutputs[0].RangeProof = other[0].RangeProof
+	require.EqualError(t, transfer(utxoOutputs, signature), "invalid range proof of utxo output 0: invalid proof of bit 0: challenge does not match")
+
+	// Only the owner can spend a confidential utxo
+	n.setClient(myOrg2Msp, myOrg2Clientid)
+	require.EqualError(t, transfer(prove(t, transferred), signature), "confidential utxoInput tx2.0 not found for client myOrg2Userid")
+}
+
+// newOutputs assigns random blinding factors to outputs
+func newOutputs(t *testing.T, outputs ...confidentialOutput) []confidentialOutput {
+	for i := range outputs {
+		blinding, err := pedersen.NewBlinding()
+		require.NoError(t, err)
+		outputs[i].blinding = blinding
+	}
+	return outputs
+}
+
+// prove returns the utxo outputs with the commitments and range proofs of the outputs
+func prove(t *testing.T, outputs []confidentialOutput) []chaincode.ConfidentialUTXO {
+	utxoOutputs := []chaincode.ConfidentialUTXO{}
+	for _, output := range outputs {
+		rangeProof, err := pedersen.ProveRange(output.amount, output.blinding)
+		require.NoError(t, err)
+		utxoOutputs = append(utxoOutputs, chaincode.ConfidentialUTXO{
+			Owner:      output.owner,
+			Commitment: pedersen.Commit(output.amount, output.blinding),
+			RangeProof: rangeProof,
+		})
+	}
+	return utxoOutputs
+}
+
+// signBalance signs the balance message of a transaction with the excess blinding factor of its inputs and outputs
+func signBalance(t *testing.T, function string, utxoInputKeys []string, amount int, inputs []confidentialOutput, outputs []confidentialOutput, utxoOutputs []chaincode.ConfidentialUTXO) *pedersen.ExcessSignature {
+	var inputBlindings, outputBlindings []*big.Int
+	for _, input := range inputs {
+		inputBlindings = append(inputBlindings, input.blinding)
+	}
+	for _, output := range outputs {
+		outputBlindings = append(outputBlindings, output.blinding)
+	}
+
+	message := chaincode.BalanceMessage(function, utxoInputKeys, amount, utxoOutputs)
+	signature, err := pedersen.SignExcess(pedersen.ExcessBlinding(inputBlindings, outputBlindings), message)
+	require.NoError(t, err)
+	return signature
+}
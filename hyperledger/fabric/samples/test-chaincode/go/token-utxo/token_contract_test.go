@@ -0,0 +1,269 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tokenutxo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-samples/test-chaincode/go/memstub"
+	"github.com/hyperledger/fabric-samples/token-utxo/chaincode-go/chaincode"
+	"github.com/stretchr/testify/require"
+)
+
+const myOrg1Msp = "Org1MSP"
+const myOrg1Clientid = "myOrg1Userid"
+const myOrg2Msp = "Org2MSP"
+const myOrg2Clientid = "myOrg2Userid"
+const myOrg2Treasurerid = "myOrg2Treasurerid"
+
+func TestTransferTimeLock(t *testing.T) {
+	n := newNetwork(myOrg1Msp, myOrg1Clientid)
+	token := chaincode.SmartContract{}
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	var minted *chaincode.UTXO
+	err := n.submit("tx1", start, func(ctx contractapi.TransactionContextInterface) (err error) {
+		minted, err = token.Mint(ctx, 1000)
+		return err
+	})
+	require.NoError(t, err)
+
+	err = n.submit("tx2", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.Transfer(ctx, []string{minted.Key}, []chaincode.UTXO{{Owner: myOrg2Clientid, Amount: 1000, LockedUntil: "tomorrow"}})
+		return err
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid utxo output lock "tomorrow"`)
+
+	var outputs []chaincode.UTXO
+	err = n.submit("tx2", start, func(ctx contractapi.TransactionContextInterface) (err error) {
+		outputs, err = token.Transfer(ctx, []string{minted.Key}, []chaincode.UTXO{
+			{Owner: myOrg2Clientid, Amount: 100, LockedUntil: "2021-01-02T01:00:00+01:00"},
+			{Owner: myOrg1Clientid, Amount: 900},
+		})
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, "tx2.0", outputs[0].Key)
+	require.Equal(t, "2021-01-02T00:00:00Z", outputs[0].LockedUntil)
+
+	n.setClient(myOrg2Msp, myOrg2Clientid)
+	utxos, err := token.ClientUTXOs(n.query())
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.UTXO{{Key: "tx2.0", Owner: myOrg2Clientid, Amount: 100, LockedUntil: "2021-01-02T00:00:00Z"}}, utxos)
+
+	// the locked output cannot be spent before the lock expires
+	err = n.submit("tx3", start.Add(23*time.Hour), func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.Transfer(ctx, []string{"tx2.0"}, []chaincode.UTXO{{Owner: myOrg1Clientid, Amount: 100}})
+		return err
+	})
+	require.EqualError(t, err, "utxoInput tx2.0 is locked until 2021-01-02T00:00:00Z")
+
+	err = n.submit("tx4", start.Add(24*time.Hour), func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.Transfer(ctx, []string{"tx2.0"}, []chaincode.UTXO{{Owner: myOrg1Clientid, Amount: 100}})
+		return err
+	})
+	require.NoError(t, err)
+
+	n.setClient(myOrg1Msp, myOrg1Clientid)
+	utxos, err = token.ClientUTXOs(n.query())
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.UTXO{{Key: "tx2.1", Owner: myOrg1Clientid, Amount: 900}, {Key: "tx4.0", Owner: myOrg1Clientid, Amount: 100}}, utxos)
+}
+
+func TestMultisigSpend(t *testing.T) {
+	n := newNetwork(myOrg1Msp, myOrg1Clientid)
+	token := chaincode.SmartContract{}
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	owners := []string{myOrg1Clientid, myOrg2Clientid, myOrg2Treasurerid}
+
+	var minted *chaincode.UTXO
+	err := n.submit("tx1", start, func(ctx contractapi.TransactionContextInterface) (err error) {
+		minted, err = token.Mint(ctx, 1000)
+		return err
+	})
+	require.NoError(t, err)
+
+	// 2 of 3 owners must approve a spend of the multisig output
+	err = n.submit("tx2", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.Transfer(ctx, []string{minted.Key}, []chaincode.UTXO{{Owners: owners, Threshold: 4, Amount: 1000}})
+		return err
+	})
+	require.EqualError(t, err, "threshold must be between 1 and the number of owners 3")
+	var outputs []chaincode.UTXO
+	err = n.submit("tx2", start, func(ctx contractapi.TransactionContextInterface) (err error) {
+		outputs, err = token.Transfer(ctx, []string{minted.Key}, []chaincode.UTXO{{Owners: owners, Threshold: 2, Amount: 1000}})
+		return err
+	})
+	require.NoError(t, err)
+
+	address, err := token.MultisigAddress(n.query(), []string{myOrg2Treasurerid, myOrg1Clientid, myOrg2Clientid}, 2)
+	require.NoError(t, err)
+	require.Equal(t, address, outputs[0].Owner, "the address does not depend on the order of the owners")
+
+	utxos, err := token.MultisigUTXOs(n.query(), address)
+	require.NoError(t, err)
+	require.Len(t, utxos, 1)
+	require.Equal(t, 1000, utxos[0].Amount)
+
+	// a single owner can neither transfer the output nor approve a spend alone
+	err = n.submit("tx3", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.Transfer(ctx, []string{"tx2.0"}, []chaincode.UTXO{{Owner: myOrg1Clientid, Amount: 1000}})
+		return err
+	})
+	require.EqualError(t, err, "utxoInput tx2.0 not found for client myOrg1Userid")
+
+	n.setClient(myOrg1Msp, "myOrg1Stranger")
+	err = n.submit("tx3", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.ProposeSpend(ctx, address, []string{"tx2.0"}, []chaincode.UTXO{{Owner: "myOrg1Stranger", Amount: 1000}})
+		return err
+	})
+	require.EqualError(t, err, "client myOrg1Stranger is not an owner of "+address)
+
+	n.setClient(myOrg1Msp, myOrg1Clientid)
+	var pendingSpend *chaincode.PendingSpend
+	err = n.submit("tx3", start, func(ctx contractapi.TransactionContextInterface) (err error) {
+		pendingSpend, err = token.ProposeSpend(ctx, address, []string{"tx2.0"}, []chaincode.UTXO{{Owner: myOrg2Clientid, Amount: 400}, {Owners: owners, Threshold: 2, Amount: 600}})
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, "pending", pendingSpend.Status)
+	require.Equal(t, []string{myOrg1Clientid}, pendingSpend.Approvals)
+
+	err = n.submit("tx4", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.ApproveSpend(ctx, "tx3")
+		return err
+	})
+	require.EqualError(t, err, "client myOrg1Userid has already approved spend tx3")
+
+	n.setClient(myOrg2Msp, myOrg2Treasurerid)
+	err = n.submit("tx4", start, func(ctx contractapi.TransactionContextInterface) (err error) {
+		pendingSpend, err = token.ApproveSpend(ctx, "tx3")
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, "executed", pendingSpend.Status)
+
+	err = n.submit("tx5", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.ApproveSpend(ctx, "tx3")
+		return err
+	})
+	require.EqualError(t, err, "spend tx3 is executed, only a pending spend can be approved")
+
+	n.setClient(myOrg2Msp, myOrg2Clientid)
+	utxos, err = token.ClientUTXOs(n.query())
+	require.NoError(t, err)
+	require.Equal(t, []*chaincode.UTXO{{Key: "tx3.0", Owner: myOrg2Clientid, Amount: 400}}, utxos)
+	utxos, err = token.MultisigUTXOs(n.query(), address)
+	require.NoError(t, err)
+	require.Len(t, utxos, 1)
+	require.Equal(t, "tx3.1", utxos[0].Key)
+
+	// a pending spend can be cancelled by its proposer, after which it can no longer be approved
+	err = n.submit("tx5", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.ProposeSpend(ctx, address, []string{"tx3.1"}, []chaincode.UTXO{{Owner: myOrg2Clientid, Amount: 600}})
+		return err
+	})
+	require.NoError(t, err)
+	n.setClient(myOrg1Msp, myOrg1Clientid)
+	err = n.submit("tx6", start, func(ctx contractapi.TransactionContextInterface) error {
+		return token.CancelSpend(ctx, "tx5")
+	})
+	require.EqualError(t, err, "spend tx5 can only be cancelled by its proposer")
+	n.setClient(myOrg2Msp, myOrg2Clientid)
+	err = n.submit("tx6", start, func(ctx contractapi.TransactionContextInterface) error {
+		return token.CancelSpend(ctx, "tx5")
+	})
+	require.NoError(t, err)
+	n.setClient(myOrg1Msp, myOrg1Clientid)
+	err = n.submit("tx7", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.ApproveSpend(ctx, "tx5")
+		return err
+	})
+	require.EqualError(t, err, "spend tx5 is cancelled, only a pending spend can be approved")
+}
+
+func TestMultisigSpendOfSpentInputs(t *testing.T) {
+	n := newNetwork(myOrg1Msp, myOrg1Clientid)
+	token := chaincode.SmartContract{}
+	start := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+	owners := []string{myOrg1Clientid, myOrg2Clientid}
+
+	mintUTXOs(t, n, 1000)
+	var outputs []chaincode.UTXO
+	err := n.submit("tx2", start, func(ctx contractapi.TransactionContextInterface) (err error) {
+		outputs, err = token.Transfer(ctx, []string{"tx1.0"}, []chaincode.UTXO{{Owners: owners, Threshold: 2, Amount: 1000}})
+		return err
+	})
+	require.NoError(t, err)
+	address := outputs[0].Owner
+
+	// two competing proposals spend the same input, only the first one to be approved is executed
+	for _, txID := range []string{"tx3", "tx4"} {
+		err = n.submit(txID, start, func(ctx contractapi.TransactionContextInterface) error {
+			_, err := token.ProposeSpend(ctx, address, []string{"tx2.0"}, []chaincode.UTXO{{Owner: myOrg1Clientid, Amount: 1000}})
+			return err
+		})
+		require.NoError(t, err)
+	}
+
+	n.setClient(myOrg2Msp, myOrg2Clientid)
+	err = n.submit("tx5", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.ApproveSpend(ctx, "tx4")
+		return err
+	})
+	require.NoError(t, err)
+	err = n.submit("tx6", start, func(ctx contractapi.TransactionContextInterface) error {
+		_, err := token.ApproveSpend(ctx, "tx3")
+		return err
+	})
+	require.EqualError(t, err, "utxoInput tx2.0 not found for client "+address)
+}
+
+func TestContractMetadata(t *testing.T) {
+	_, err := contractapi.NewChaincode(&chaincode.SmartContract{})
+	require.NoError(t, err)
+}
+
+// network runs the token contract against an in-memory ledger as the current client
+type network struct {
+	ledger   *memstub.Ledger
+	identity *memstub.ClientIdentity
+}
+
+func newNetwork(orgMSP, clientID string) *network {
+	return &network{ledger: memstub.NewLedger(), identity: memstub.NewClientIdentity(clientID, orgMSP)}
+}
+
+// setClient changes the client identity that submits the next transactions
+func (n *network) setClient(orgMSP, clientID string) {
+	n.identity = memstub.NewClientIdentity(clientID, orgMSP)
+}
+
+func (n *network) newTransactionContext(stub *memstub.Stub) *contractapi.TransactionContext {
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(n.identity)
+	return ctx
+}
+
+// submit simulates a transaction with the given ID and timestamp, and commits
+// it if it succeeds. The UTXO keys created by the transaction start with its ID.
+func (n *network) submit(txID string, txTime time.Time, fn func(ctx contractapi.TransactionContextInterface) error) error {
+	n.ledger.SetTime(txTime)
+	stub := n.ledger.NewStub(txID)
+	err := fn(n.newTransactionContext(stub))
+	if err != nil {
+		return err
+	}
+	return stub.Commit()
+}
+
+// query returns a transaction context of the current client for reading the ledger
+func (n *network) query() contractapi.TransactionContextInterface {
+	return n.newTransactionContext(n.ledger.NewStub("query"))
+}
@@ -0,0 +1,494 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Define objectType names for prefix
+const balancePrefix = "balance"
+const approvalPrefix = "approval"
+const uriPrefix = "uri"
+
+// The zero address is used as the sender of minted tokens
+const zeroAddress = "0x0"
+
+// SmartContract provides functions for minting and transferring multiple token types between accounts
+type SmartContract struct {
+	contractapi.Contract
+}
+
+// transferSingle is emitted when tokens of a single type are minted or transferred
+type transferSingle struct {
+	Operator string `json:"operator"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	ID       uint64 `json:"id"`
+	Value    uint64 `json:"value"`
+}
+
+// transferBatch is emitted when tokens of several types are minted or transferred
+type transferBatch struct {
+	Operator string   `json:"operator"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	IDs      []uint64 `json:"ids"`
+	Values   []uint64 `json:"values"`
+}
+
+// approvalForAll is emitted when an owner enables or disables an operator to manage all of its tokens
+type approvalForAll struct {
+	Owner    string `json:"owner"`
+	Operator string `json:"operator"`
+	Approved bool   `json:"approved"`
+}
+
+// uriEvent is emitted when the URI of a token type is set
+type uriEvent struct {
+	Value string `json:"value"`
+	ID    uint64 `json:"id"`
+}
+
+// Mint creates amount tokens of token type id and assigns them to account
+// This function triggers a TransferSingle event
+func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, account string, id uint64, amount uint64) error {
+
+	operator, err := authorizationHelper(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = mintHelper(ctx, account, []uint64{id}, []uint64{amount})
+	if err != nil {
+		return err
+	}
+
+	transferSingleEvent := transferSingle{operator, zeroAddress, account, id, amount}
+	return emitEvent(ctx, "TransferSingle", transferSingleEvent)
+}
+
+// MintBatch creates amounts[i] tokens of each token type ids[i] and assigns them to account
+// This function triggers a TransferBatch event
+func (s *SmartContract) MintBatch(ctx contractapi.TransactionContextInterface, account string, ids []uint64, amounts []uint64) error {
+
+	operator, err := authorizationHelper(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = mintHelper(ctx, account, ids, amounts)
+	if err != nil {
+		return err
+	}
+
+	transferBatchEvent := transferBatch{operator, zeroAddress, account, ids, amounts}
+	return emitEvent(ctx, "TransferBatch", transferBatchEvent)
+}
+
+// SafeTransferFrom transfers amount tokens of token type id from the "from" address to the "to" address
+// The client must be the owner of the "from" account, or an operator approved by the owner with SetApprovalForAll
+// This function triggers a TransferSingle event
+func (s *SmartContract) SafeTransferFrom(ctx contractapi.TransactionContextInterface, from string, to string, id uint64, amount uint64) error {
+
+	operator, err := operatorHelper(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	err = transferHelper(ctx, from, to, []uint64{id}, []uint64{amount})
+	if err != nil {
+		return fmt.Errorf("failed to transfer: %v", err)
+	}
+
+	transferSingleEvent := transferSingle{operator, from, to, id, amount}
+	return emitEvent(ctx, "TransferSingle", transferSingleEvent)
+}
+
+// SafeBatchTransferFrom transfers amounts[i] tokens of each token type ids[i] from the "from" address to the "to" address
+// The client must be the owner of the "from" account, or an operator approved by the owner with SetApprovalForAll
+// This function triggers a TransferBatch event
+func (s *SmartContract) SafeBatchTransferFrom(ctx contractapi.TransactionContextInterface, from string, to string, ids []uint64, amounts []uint64) error {
+
+	operator, err := operatorHelper(ctx, from)
+	if err != nil {
+		return err
+	}
+
+	err = transferHelper(ctx, from, to, ids, amounts)
+	if err != nil {
+		return fmt.Errorf("failed to transfer: %v", err)
+	}
+
+	transferBatchEvent := transferBatch{operator, from, to, ids, amounts}
+	return emitEvent(ctx, "TransferBatch", transferBatchEvent)
+}
+
+// BalanceOf returns the balance of the given account for token type id
+func (s *SmartContract) BalanceOf(ctx contractapi.TransactionContextInterface, account string, id uint64) (uint64, error) {
+	return balanceHelper(ctx, account, id)
+}
+
+// BalanceOfBatch returns the balance of accounts[i] for token type ids[i]
+func (s *SmartContract) BalanceOfBatch(ctx contractapi.TransactionContextInterface, accounts []string, ids []uint64) ([]uint64, error) {
+
+	if len(accounts) != len(ids) {
+		return nil, fmt.Errorf("accounts and ids must have the same length")
+	}
+
+	balances := make([]uint64, len(accounts))
+	for i := range accounts {
+		balance, err := balanceHelper(ctx, accounts[i], ids[i])
+		if err != nil {
+			return nil, err
+		}
+		balances[i] = balance
+	}
+
+	return balances, nil
+}
+
+// ClientAccountBalance returns the balance of the requesting client's account for token type id
+func (s *SmartContract) ClientAccountBalance(ctx contractapi.TransactionContextInterface, id uint64) (uint64, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	return balanceHelper(ctx, clientID, id)
+}
+
+// ClientAccountID returns the id of the requesting client's account
+// In this implementation, the client account ID is the clientId itself
+// Users can use this function to get their own account id, which they can then give to others as the payment address
+func (s *SmartContract) ClientAccountID(ctx contractapi.TransactionContextInterface) (string, error) {
+
+	// Get ID of submitting client identity
+	clientAccountID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	return clientAccountID, nil
+}
+
+// SetApprovalForAll enables or disables an operator to transfer all of the calling client's tokens
+// This function triggers an ApprovalForAll event
+func (s *SmartContract) SetApprovalForAll(ctx contractapi.TransactionContextInterface, operator string, approved bool) error {
+
+	// Get ID of submitting client identity
+	owner, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if owner == operator {
+		return fmt.Errorf("setting approval status for self")
+	}
+
+	approvalKey, err := ctx.GetStub().CreateCompositeKey(approvalPrefix, []string{owner, operator})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", approvalPrefix, err)
+	}
+
+	if approved {
+		err = ctx.GetStub().PutState(approvalKey, []byte(strconv.FormatBool(approved)))
+	} else {
+		err = ctx.GetStub().DelState(approvalKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update state of smart contract for key %s: %v", approvalKey, err)
+	}
+
+	log.Printf("client %s set approval of operator %s to %t", owner, operator, approved)
+
+	approvalForAllEvent := approvalForAll{owner, operator, approved}
+	return emitEvent(ctx, "ApprovalForAll", approvalForAllEvent)
+}
+
+// IsApprovedForAll returns true if the operator is approved to transfer all of the owner's tokens
+func (s *SmartContract) IsApprovedForAll(ctx contractapi.TransactionContextInterface, owner string, operator string) (bool, error) {
+	return isApprovedForAllHelper(ctx, owner, operator)
+}
+
+// SetURI sets the URI of the metadata of token type id
+// This function triggers a URI event
+func (s *SmartContract) SetURI(ctx contractapi.TransactionContextInterface, id uint64, uri string) error {
+
+	_, err := authorizationHelper(ctx)
+	if err != nil {
+		return err
+	}
+
+	if uri == "" {
+		return fmt.Errorf("uri must not be empty")
+	}
+
+	uriKey, err := ctx.GetStub().CreateCompositeKey(uriPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", uriPrefix, err)
+	}
+
+	err = ctx.GetStub().PutState(uriKey, []byte(uri))
+	if err != nil {
+		return fmt.Errorf("failed to update state of smart contract for key %s: %v", uriKey, err)
+	}
+
+	event := uriEvent{uri, id}
+	return emitEvent(ctx, "URI", event)
+}
+
+// URI returns the URI of the metadata of token type id
+func (s *SmartContract) URI(ctx contractapi.TransactionContextInterface, id uint64) (string, error) {
+
+	uriKey, err := ctx.GetStub().CreateCompositeKey(uriPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return "", fmt.Errorf("failed to create the composite key for prefix %s: %v", uriPrefix, err)
+	}
+
+	uriBytes, err := ctx.GetStub().GetState(uriKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read URI of token %d from world state: %v", id, err)
+	}
+	if uriBytes == nil {
+		return "", fmt.Errorf("no URI is set for token %d", id)
+	}
+
+	return string(uriBytes), nil
+}
+
+// Helper Functions
+
+// authorizationHelper checks that the client is allowed to mint tokens and set URIs, and returns the client ID
+// This sample assumes Org1 is the central banker with privilege to mint new tokens
+func authorizationHelper(ctx contractapi.TransactionContextInterface) (string, error) {
+
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if clientMSPID != "Org1MSP" {
+		return "", fmt.Errorf("client is not authorized to mint new tokens")
+	}
+
+	minter, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	return minter, nil
+}
+
+// operatorHelper checks that the client may transfer the tokens of the "from" account, and returns the client ID
+// Dependant functions include SafeTransferFrom and SafeBatchTransferFrom
+func operatorHelper(ctx contractapi.TransactionContextInterface, from string) (string, error) {
+
+	// Get ID of submitting client identity
+	operator, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if operator != from {
+		approved, err := isApprovedForAllHelper(ctx, from, operator)
+		if err != nil {
+			return "", err
+		}
+		if !approved {
+			return "", fmt.Errorf("caller is not owner nor is approved")
+		}
+	}
+
+	return operator, nil
+}
+
+// mintHelper adds the amounts to the balances of the account
+// Dependant functions include Mint and MintBatch
+func mintHelper(ctx contractapi.TransactionContextInterface, account string, ids []uint64, amounts []uint64) error {
+
+	if account == "" || account == zeroAddress {
+		return fmt.Errorf("mint to the zero address")
+	}
+
+	totals, err := totalAmounts(ids, amounts)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range sortedIDs(totals) {
+		err = addBalance(ctx, account, id, totals[id])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// transferHelper moves the amounts from the "from" address to the "to" address
+// Dependant functions include SafeTransferFrom and SafeBatchTransferFrom
+func transferHelper(ctx contractapi.TransactionContextInterface, from string, to string, ids []uint64, amounts []uint64) error {
+
+	if to == "" || to == zeroAddress {
+		return fmt.Errorf("transfer to the zero address")
+	}
+
+	// The amounts of a token type that appears several times are added up, because the
+	// balances that are written in this transaction cannot be read back before it commits
+	totals, err := totalAmounts(ids, amounts)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range sortedIDs(totals) {
+		fromBalance, err := balanceHelper(ctx, from, id)
+		if err != nil {
+			return err
+		}
+		if fromBalance < totals[id] {
+			return fmt.Errorf("client account %s has insufficient funds of token %d", from, id)
+		}
+
+		// A transfer to the same account leaves the balance unchanged
+		if from == to {
+			continue
+		}
+
+		err = setBalance(ctx, from, id, fromBalance-totals[id])
+		if err != nil {
+			return err
+		}
+		err = addBalance(ctx, to, id, totals[id])
+		if err != nil {
+			return err
+		}
+
+		log.Printf("%d tokens of token %d transferred from %s to %s", totals[id], id, from, to)
+	}
+
+	return nil
+}
+
+// totalAmounts checks the ids and amounts of a batch and adds up the amounts per token type
+func totalAmounts(ids []uint64, amounts []uint64) (map[uint64]uint64, error) {
+
+	if len(ids) != len(amounts) {
+		return nil, fmt.Errorf("ids and amounts must have the same length")
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("ids must not be empty")
+	}
+
+	totals := make(map[uint64]uint64)
+	for i, id := range ids {
+		if totals[id]+amounts[i] < totals[id] {
+			return nil, fmt.Errorf("amount of token %d overflows", id)
+		}
+		totals[id] += amounts[i]
+	}
+
+	return totals, nil
+}
+
+// sortedIDs returns the token types of a batch in ascending order, so that the writes are deterministic
+func sortedIDs(totals map[uint64]uint64) []uint64 {
+	ids := make([]uint64, 0, len(totals))
+	for id := range totals {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// balanceHelper returns the balance of an account for token type id, which is 0 if the account has no balance
+func balanceHelper(ctx contractapi.TransactionContextInterface, account string, id uint64) (uint64, error) {
+
+	balanceKey, err := ctx.GetStub().CreateCompositeKey(balancePrefix, []string{account, strconv.FormatUint(id, 10)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the composite key for prefix %s: %v", balancePrefix, err)
+	}
+
+	balanceBytes, err := ctx.GetStub().GetState(balanceKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read balance of account %s from world state: %v", account, err)
+	}
+	if balanceBytes == nil {
+		return 0, nil
+	}
+
+	balance, _ := strconv.ParseUint(string(balanceBytes), 10, 64) // Error handling not needed since FormatUint() was used when setting the balance, guaranteeing it was an integer.
+
+	return balance, nil
+}
+
+// addBalance adds an amount to the balance of an account for token type id
+func addBalance(ctx contractapi.TransactionContextInterface, account string, id uint64, amount uint64) error {
+
+	balance, err := balanceHelper(ctx, account, id)
+	if err != nil {
+		return err
+	}
+	if balance+amount < balance {
+		return fmt.Errorf("balance of account %s for token %d overflows", account, id)
+	}
+
+	return setBalance(ctx, account, id, balance+amount)
+}
+
+// setBalance updates the balance of an account for token type id
+func setBalance(ctx contractapi.TransactionContextInterface, account string, id uint64, balance uint64) error {
+
+	balanceKey, err := ctx.GetStub().CreateCompositeKey(balancePrefix, []string{account, strconv.FormatUint(id, 10)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", balancePrefix, err)
+	}
+
+	if balance == 0 {
+		err = ctx.GetStub().DelState(balanceKey)
+	} else {
+		err = ctx.GetStub().PutState(balanceKey, []byte(strconv.FormatUint(balance, 10)))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update balance of account %s: %v", account, err)
+	}
+
+	return nil
+}
+
+// isApprovedForAllHelper returns true if the operator is approved to transfer all of the owner's tokens
+func isApprovedForAllHelper(ctx contractapi.TransactionContextInterface, owner string, operator string) (bool, error) {
+
+	approvalKey, err := ctx.GetStub().CreateCompositeKey(approvalPrefix, []string{owner, operator})
+	if err != nil {
+		return false, fmt.Errorf("failed to create the composite key for prefix %s: %v", approvalPrefix, err)
+	}
+
+	approvalBytes, err := ctx.GetStub().GetState(approvalKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read approval for %s from world state: %v", approvalKey, err)
+	}
+
+	return approvalBytes != nil, nil
+}
+
+// emitEvent sets the event of the transaction
+func emitEvent(ctx contractapi.TransactionContextInterface, name string, event interface{}) error {
+
+	eventJSON, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+
+	err = ctx.GetStub().SetEvent(name, eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+
+	return nil
+}
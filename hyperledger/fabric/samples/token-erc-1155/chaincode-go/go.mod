@@ -0,0 +1,5 @@
+module github.com/hyperledger/fabric-samples/token-erc-1155/chaincode-go
+
+go 1.14
+
+require github.com/hyperledger/fabric-contract-api-go v1.1.0
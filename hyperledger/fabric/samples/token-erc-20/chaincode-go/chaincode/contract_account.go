@@ -0,0 +1,98 @@
+package chaincode
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// contractAccountPrefix prefixes the account ID of a chaincode that calls the token chaincode
+const contractAccountPrefix = "chaincode:"
+
+// chaincodeNameKey stores the name the token chaincode is deployed under, which Initialize
+// reads from the proposal of the client that invokes it
+const chaincodeNameKey = "chaincodeName"
+
+// getClientAccountID returns the account that the transaction acts on behalf of.
+// When a client invokes the token chaincode directly this is the client ID. When another
+// chaincode calls the token chaincode, Fabric passes on the identity of the client that
+// submitted the transaction, so the calling chaincode gets an account of its own instead,
+// e.g. chaincode:auction. Only that chaincode can spend from its account, which lets it
+// hold tokens in escrow.
+func getClientAccountID(ctx contractapi.TransactionContextInterface) (string, error) {
+	callingChaincode, err := getCallingChaincode(ctx)
+	if err != nil {
+		return "", err
+	}
+	if callingChaincode != "" {
+		return contractAccountPrefix + callingChaincode, nil
+	}
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	return clientID, nil
+}
+
+// getCallingChaincode returns the name of the chaincode that called the token chaincode,
+// or an empty string if the client invoked the token chaincode directly. The signed
+// proposal names the chaincode the client invoked, which is the token chaincode itself
+// only if it was invoked directly. The arguments of the proposal are not compared, since
+// a calling chaincode can forward the client's arguments unchanged.
+func getCallingChaincode(ctx contractapi.TransactionContextInterface) (string, error) {
+	proposalChaincode, err := getProposalChaincode(ctx)
+	if err != nil {
+		return "", err
+	}
+	if proposalChaincode == "" {
+		return "", nil
+	}
+
+	tokenChaincode, err := ctx.GetStub().GetState(chaincodeNameKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get chaincode name: %v", err)
+	}
+	if tokenChaincode == nil {
+		return "", fmt.Errorf("the token chaincode is not initialized")
+	}
+	if proposalChaincode == string(tokenChaincode) {
+		return "", nil
+	}
+
+	return proposalChaincode, nil
+}
+
+// getProposalChaincode returns the name of the chaincode the client invoked, or an
+// empty string if the transaction has no signed proposal
+func getProposalChaincode(ctx contractapi.TransactionContextInterface) (string, error) {
+	signedProposal, err := ctx.GetStub().GetSignedProposal()
+	if err != nil {
+		return "", fmt.Errorf("failed to get signed proposal: %v", err)
+	}
+	if signedProposal == nil {
+		return "", nil
+	}
+
+	proposal := &peer.Proposal{}
+	err = proto.Unmarshal(signedProposal.ProposalBytes, proposal)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal proposal: %v", err)
+	}
+	proposalPayload := &peer.ChaincodeProposalPayload{}
+	err = proto.Unmarshal(proposal.Payload, proposalPayload)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal proposal payload: %v", err)
+	}
+	invocationSpec := &peer.ChaincodeInvocationSpec{}
+	err = proto.Unmarshal(proposalPayload.Input, invocationSpec)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal chaincode invocation spec: %v", err)
+	}
+
+	return invocationSpec.GetChaincodeSpec().GetChaincodeId().GetName(), nil
+}
@@ -0,0 +1,208 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Define key names for options
+const pausedKey = "paused"
+
+// Define objectType names for prefix
+const frozenPrefix = "frozen"
+
+// pauseEvent provides an organized struct for emitting Paused and Unpaused events
+type pauseEvent struct {
+	Account string `json:"account"`
+}
+
+// freezeEvent provides an organized struct for emitting AccountFrozen and AccountUnfrozen events
+type freezeEvent struct {
+	Account string `json:"account"`
+	Sender  string `json:"sender"`
+}
+
+// Pause stops all transactions that change balances, until the token is unpaused
+// The client must have been granted the pauser role
+// This function triggers a Paused event
+func (s *SmartContract) Pause(ctx contractapi.TransactionContextInterface) error {
+	return setPaused(ctx, true, "Paused")
+}
+
+// Unpause resumes the transactions that change balances
+// The client must have been granted the pauser role
+// This function triggers an Unpaused event
+func (s *SmartContract) Unpause(ctx contractapi.TransactionContextInterface) error {
+	return setPaused(ctx, false, "Unpaused")
+}
+
+// Paused returns true if the token is paused
+func (s *SmartContract) Paused(ctx contractapi.TransactionContextInterface) (bool, error) {
+	return isPaused(ctx)
+}
+
+// FreezeAccount stops an account from sending and receiving tokens, until it is unfrozen
+// The client must have been granted the admin role
+// This function triggers an AccountFrozen event
+func (s *SmartContract) FreezeAccount(ctx contractapi.TransactionContextInterface, account string) error {
+	return setFrozen(ctx, account, true, "AccountFrozen")
+}
+
+// UnfreezeAccount allows a frozen account to send and receive tokens again
+// The client must have been granted the admin role
+// This function triggers an AccountUnfrozen event
+func (s *SmartContract) UnfreezeAccount(ctx contractapi.TransactionContextInterface, account string) error {
+	return setFrozen(ctx, account, false, "AccountUnfrozen")
+}
+
+// IsFrozen returns true if the account is frozen
+func (s *SmartContract) IsFrozen(ctx contractapi.TransactionContextInterface, account string) (bool, error) {
+	return isFrozen(ctx, account)
+}
+
+// setPaused updates the paused state of the token and emits the event
+func setPaused(ctx contractapi.TransactionContextInterface, paused bool, eventName string) error {
+
+	// Check pauser authorization - only accounts with the pauser role can pause the token
+	pauser, err := checkRole(ctx, pauserRole)
+	if err != nil {
+		return err
+	}
+
+	currentlyPaused, err := isPaused(ctx)
+	if err != nil {
+		return err
+	}
+	if currentlyPaused == paused {
+		if paused {
+			return fmt.Errorf("token is already paused")
+		}
+		return fmt.Errorf("token is not paused")
+	}
+
+	if paused {
+		// The value of the paused key is not used, but an empty value would delete the key
+		err = ctx.GetStub().PutState(pausedKey, []byte{0x00})
+	} else {
+		err = ctx.GetStub().DelState(pausedKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update paused state: %v", err)
+	}
+
+	pauseEventJSON, err := json.Marshal(pauseEvent{pauser})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(eventName, pauseEventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+
+	log.Printf("token paused state set to %t by %s", paused, pauser)
+
+	return nil
+}
+
+// setFrozen updates the frozen state of an account and emits the event
+func setFrozen(ctx contractapi.TransactionContextInterface, account string, frozen bool, eventName string) error {
+
+	// Check admin authorization - only accounts with the admin role can freeze accounts
+	admin, err := checkRole(ctx, adminRole)
+	if err != nil {
+		return err
+	}
+
+	if frozen && account == admin {
+		return fmt.Errorf("an admin cannot freeze its own account")
+	}
+
+	currentlyFrozen, err := isFrozen(ctx, account)
+	if err != nil {
+		return err
+	}
+	if currentlyFrozen == frozen {
+		if frozen {
+			return fmt.Errorf("account %s is already frozen", account)
+		}
+		return fmt.Errorf("account %s is not frozen", account)
+	}
+
+	frozenKey, err := ctx.GetStub().CreateCompositeKey(frozenPrefix, []string{account})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", frozenPrefix, err)
+	}
+
+	if frozen {
+		// The value of the frozen key is not used, but an empty value would delete the key
+		err = ctx.GetStub().PutState(frozenKey, []byte{0x00})
+	} else {
+		err = ctx.GetStub().DelState(frozenKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update frozen state of account %s: %v", account, err)
+	}
+
+	freezeEventJSON, err := json.Marshal(freezeEvent{account, admin})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(eventName, freezeEventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+
+	log.Printf("account %s frozen state set to %t by %s", account, frozen, admin)
+
+	return nil
+}
+
+// isPaused reads the paused state of the token
+func isPaused(ctx contractapi.TransactionContextInterface) (bool, error) {
+	pausedBytes, err := ctx.GetStub().GetState(pausedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read paused state from world state: %v", err)
+	}
+	return pausedBytes != nil, nil
+}
+
+// isFrozen reads the frozen state of an account
+func isFrozen(ctx contractapi.TransactionContextInterface, account string) (bool, error) {
+	frozenKey, err := ctx.GetStub().CreateCompositeKey(frozenPrefix, []string{account})
+	if err != nil {
+		return false, fmt.Errorf("failed to create the composite key for prefix %s: %v", frozenPrefix, err)
+	}
+	frozenBytes, err := ctx.GetStub().GetState(frozenKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read frozen state of account %s from world state: %v", account, err)
+	}
+	return frozenBytes != nil, nil
+}
+
+// checkNotPaused returns an error if the token is paused, or if one of the accounts is frozen
+// Dependant functions include Mint, Burn, Transfer and TransferFrom
+func checkNotPaused(ctx contractapi.TransactionContextInterface, accounts ...string) error {
+
+	paused, err := isPaused(ctx)
+	if err != nil {
+		return err
+	}
+	if paused {
+		return fmt.Errorf("token is paused")
+	}
+
+	for _, account := range accounts {
+		frozen, err := isFrozen(ctx, account)
+		if err != nil {
+			return err
+		}
+		if frozen {
+			return fmt.Errorf("account %s is frozen", account)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Define the roles of the role registry
+const (
+	adminRole  = "admin"
+	minterRole = "minter"
+	burnerRole = "burner"
+	pauserRole = "pauser"
+)
+
+// Define objectType names for prefix
+const rolePrefix = "role"
+
+// roleEvent provides an organized struct for emitting role events
+type roleEvent struct {
+	Role    string `json:"role"`
+	Account string `json:"account"`
+	Sender  string `json:"sender"`
+}
+
+// GrantRole grants a role to an account. The role is one of admin, minter, burner or pauser.
+// Only an account with the admin role can grant roles.
+// This function triggers a RoleGranted event
+func (s *SmartContract) GrantRole(ctx contractapi.TransactionContextInterface, role string, account string) error {
+
+	sender, err := checkRole(ctx, adminRole)
+	if err != nil {
+		return err
+	}
+
+	err = grantRole(ctx, role, account)
+	if err != nil {
+		return err
+	}
+
+	return emitRoleEvent(ctx, "RoleGranted", roleEvent{role, account, sender})
+}
+
+// RevokeRole revokes a role from an account
+// Only an account with the admin role can revoke roles, and an admin cannot revoke its own admin role.
+// This function triggers a RoleRevoked event
+func (s *SmartContract) RevokeRole(ctx contractapi.TransactionContextInterface, role string, account string) error {
+
+	sender, err := checkRole(ctx, adminRole)
+	if err != nil {
+		return err
+	}
+
+	if role == adminRole && account == sender {
+		return fmt.Errorf("an admin cannot revoke its own admin role")
+	}
+
+	granted, err := hasRole(ctx, role, account)
+	if err != nil {
+		return err
+	}
+	if !granted {
+		return fmt.Errorf("account %s does not have the %s role", account, role)
+	}
+
+	roleKey, err := ctx.GetStub().CreateCompositeKey(rolePrefix, []string{role, account})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", rolePrefix, err)
+	}
+
+	err = ctx.GetStub().DelState(roleKey)
+	if err != nil {
+		return fmt.Errorf("failed to delete role %s of account %s: %v", role, account, err)
+	}
+
+	log.Printf("role %s revoked from account %s", role, account)
+
+	return emitRoleEvent(ctx, "RoleRevoked", roleEvent{role, account, sender})
+}
+
+// HasRole returns true if the account has been granted the role
+func (s *SmartContract) HasRole(ctx contractapi.TransactionContextInterface, role string, account string) (bool, error) {
+	return hasRole(ctx, role, account)
+}
+
+// hasRole looks up the role of an account in the role registry
+func hasRole(ctx contractapi.TransactionContextInterface, role string, account string) (bool, error) {
+
+	err := validateRole(role)
+	if err != nil {
+		return false, err
+	}
+
+	roleKey, err := ctx.GetStub().CreateCompositeKey(rolePrefix, []string{role, account})
+	if err != nil {
+		return false, fmt.Errorf("failed to create the composite key for prefix %s: %v", rolePrefix, err)
+	}
+
+	roleBytes, err := ctx.GetStub().GetState(roleKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read role %s of account %s from world state: %v", role, account, err)
+	}
+
+	return roleBytes != nil, nil
+}
+
+// checkRole checks that the submitting client, or the calling chaincode, has been granted
+// the role. It returns the account of the client.
+func checkRole(ctx contractapi.TransactionContextInterface, role string) (string, error) {
+
+	// Get the account of the submitting client, or of the calling chaincode
+	clientID, err := getClientAccountID(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	granted, err := hasRole(ctx, role, clientID)
+	if err != nil {
+		return "", err
+	}
+	if !granted {
+		return "", fmt.Errorf("client is not authorized: the %s role is required", role)
+	}
+
+	return clientID, nil
+}
+
+// grantRole records the role of an account in the role registry
+func grantRole(ctx contractapi.TransactionContextInterface, role string, account string) error {
+
+	err := validateRole(role)
+	if err != nil {
+		return err
+	}
+	if account == "" {
+		return fmt.Errorf("account must not be empty")
+	}
+
+	roleKey, err := ctx.GetStub().CreateCompositeKey(rolePrefix, []string{role, account})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", rolePrefix, err)
+	}
+
+	// The value of the role key is not used, but an empty value would delete the key
+	err = ctx.GetStub().PutState(roleKey, []byte{0x00})
+	if err != nil {
+		return fmt.Errorf("failed to put role %s of account %s to world state: %v", role, account, err)
+	}
+
+	log.Printf("role %s granted to account %s", role, account)
+
+	return nil
+}
+
+// validateRole checks that the role is known to the role registry
+func validateRole(role string) error {
+	switch role {
+	case adminRole, minterRole, burnerRole, pauserRole:
+		return nil
+	}
+	return fmt.Errorf("unknown role %s, expected one of %s, %s, %s or %s", role, adminRole, minterRole, burnerRole, pauserRole)
+}
+
+// emitRoleEvent emits a RoleGranted or RoleRevoked event
+func emitRoleEvent(ctx contractapi.TransactionContextInterface, name string, e roleEvent) error {
+	eventJSON, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.GetStub().SetEvent(name, eventJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
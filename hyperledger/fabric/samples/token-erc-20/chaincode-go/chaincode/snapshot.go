@@ -0,0 +1,261 @@
+package chaincode
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Define key names for options
+const currentSnapshotIDKey = "currentSnapshotId"
+
+// Define objectType names for prefix
+const snapshotPrefix = "snapshot"
+const balanceSnapshotPrefix = "balanceSnapshot"
+const supplySnapshotPrefix = "supplySnapshot"
+
+// SnapshotInfo records when a snapshot was taken. It is also emitted in the Snapshot event.
+type SnapshotInfo struct {
+	ID        int    `json:"id"`
+	Timestamp string `json:"timestamp"`
+}
+
+// Snapshot records the balances of all accounts and the total supply at this point, and returns the ID of the snapshot
+// The balances are not copied when the snapshot is taken. Instead, the first time a balance changes after a snapshot,
+// its value before the change is recorded for that snapshot, so that BalanceOfAt and TotalSupplyAt can read it later.
+// The timestamp of the transaction is recorded with the snapshot, and can be read with GetSnapshot
+// The client must have been granted the admin role
+// This function triggers a Snapshot event
+func (s *SmartContract) Snapshot(ctx contractapi.TransactionContextInterface) (int, error) {
+
+	// Check admin authorization - only accounts with the admin role can take snapshots
+	_, err := checkRole(ctx, adminRole)
+	if err != nil {
+		return 0, err
+	}
+
+	currentSnapshotID, err := getCurrentSnapshotID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	snapshotID := currentSnapshotID + 1
+	err = ctx.GetStub().PutState(currentSnapshotIDKey, []byte(strconv.Itoa(snapshotID)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to update the current snapshot id: %v", err)
+	}
+
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get the transaction timestamp: %v", err)
+	}
+	snapshot := SnapshotInfo{ID: snapshotID, Timestamp: time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC().Format(time.RFC3339)}
+	snapshotJSON, err := json.Marshal(snapshot)
+	if err != nil {
+		return 0, fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+
+	snapshotKey, err := ctx.GetStub().CreateCompositeKey(snapshotPrefix, []string{formatSnapshotID(snapshotID)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the composite key for prefix %s: %v", snapshotPrefix, err)
+	}
+	err = ctx.GetStub().PutState(snapshotKey, snapshotJSON)
+	if err != nil {
+		return 0, fmt.Errorf("failed to put snapshot %d to world state: %v", snapshotID, err)
+	}
+
+	err = ctx.GetStub().SetEvent("Snapshot", snapshotJSON)
+	if err != nil {
+		return 0, fmt.Errorf("failed to set event: %v", err)
+	}
+
+	log.Printf("snapshot %d taken at %s", snapshotID, snapshot.Timestamp)
+
+	return snapshotID, nil
+}
+
+// GetSnapshot returns the ID of a snapshot and the time at which it was taken
+func (s *SmartContract) GetSnapshot(ctx contractapi.TransactionContextInterface, snapshotID int) (*SnapshotInfo, error) {
+
+	if snapshotID <= 0 {
+		return nil, fmt.Errorf("snapshot id must be a positive integer")
+	}
+
+	snapshotKey, err := ctx.GetStub().CreateCompositeKey(snapshotPrefix, []string{formatSnapshotID(snapshotID)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", snapshotPrefix, err)
+	}
+	snapshotBytes, err := ctx.GetStub().GetState(snapshotKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot %d from world state: %v", snapshotID, err)
+	}
+	if snapshotBytes == nil {
+		return nil, fmt.Errorf("snapshot %d does not exist", snapshotID)
+	}
+
+	var snapshot SnapshotInfo
+	err = json.Unmarshal(snapshotBytes, &snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain JSON decoding: %v", err)
+	}
+
+	return &snapshot, nil
+}
+
+// BalanceOfAt returns the balance of the given account at the time the snapshot was taken
+func (s *SmartContract) BalanceOfAt(ctx contractapi.TransactionContextInterface, account string, snapshotID int) (int, error) {
+
+	value, found, err := snapshotValue(ctx, balanceSnapshotPrefix, []string{account}, snapshotID)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return value, nil
+	}
+
+	// The balance has not changed since the snapshot
+	balanceBytes, err := ctx.GetStub().GetState(account)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read from world state: %v", err)
+	}
+
+	balance, _ := strconv.Atoi(string(balanceBytes)) // Error handling not needed since Itoa() was used when setting the account balance, guaranteeing it was an integer. A missing balance is 0.
+
+	return balance, nil
+}
+
+// TotalSupplyAt returns the total token supply at the time the snapshot was taken
+func (s *SmartContract) TotalSupplyAt(ctx contractapi.TransactionContextInterface, snapshotID int) (int, error) {
+
+	value, found, err := snapshotValue(ctx, supplySnapshotPrefix, []string{}, snapshotID)
+	if err != nil {
+		return 0, err
+	}
+	if found {
+		return value, nil
+	}
+
+	// The total supply has not changed since the snapshot
+	return s.TotalSupply(ctx)
+}
+
+// updateBalanceSnapshot records the balance of an account before it changes, if it has not been
+// recorded for the current snapshot yet
+// Dependant functions include Mint, Burn and transferHelper
+func updateBalanceSnapshot(ctx contractapi.TransactionContextInterface, account string, currentBalance int) error {
+	return updateSnapshot(ctx, balanceSnapshotPrefix, []string{account}, currentBalance)
+}
+
+// updateSupplySnapshot records the total supply before it changes, if it has not been recorded
+// for the current snapshot yet
+// Dependant functions include Mint and Burn
+func updateSupplySnapshot(ctx contractapi.TransactionContextInterface, currentSupply int) error {
+	return updateSnapshot(ctx, supplySnapshotPrefix, []string{}, currentSupply)
+}
+
+// updateSnapshot records a value under the current snapshot ID, unless the value is already recorded
+func updateSnapshot(ctx contractapi.TransactionContextInterface, prefix string, attributes []string, currentValue int) error {
+
+	currentSnapshotID, err := getCurrentSnapshotID(ctx)
+	if err != nil {
+		return err
+	}
+
+	// No snapshot has been taken yet
+	if currentSnapshotID == 0 {
+		return nil
+	}
+
+	snapshotKey, err := ctx.GetStub().CreateCompositeKey(prefix, append(attributes, formatSnapshotID(currentSnapshotID)))
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", prefix, err)
+	}
+
+	snapshotBytes, err := ctx.GetStub().GetState(snapshotKey)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot %s from world state: %v", snapshotKey, err)
+	}
+	if snapshotBytes != nil {
+		return nil
+	}
+
+	err = ctx.GetStub().PutState(snapshotKey, []byte(strconv.Itoa(currentValue)))
+	if err != nil {
+		return fmt.Errorf("failed to put snapshot %s to world state: %v", snapshotKey, err)
+	}
+
+	return nil
+}
+
+// snapshotValue returns the value that was recorded for the first snapshot at or after the given
+// snapshot, which is the value at the time the given snapshot was taken. If no value was recorded,
+// the value has not changed since the snapshot.
+func snapshotValue(ctx contractapi.TransactionContextInterface, prefix string, attributes []string, snapshotID int) (int, bool, error) {
+
+	if snapshotID <= 0 {
+		return 0, false, fmt.Errorf("snapshot id must be a positive integer")
+	}
+
+	currentSnapshotID, err := getCurrentSnapshotID(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	if snapshotID > currentSnapshotID {
+		return 0, false, fmt.Errorf("snapshot %d does not exist", snapshotID)
+	}
+
+	snapshotIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(prefix, attributes)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get snapshots for prefix %s: %v", prefix, err)
+	}
+	defer snapshotIterator.Close()
+
+	// The snapshot IDs are zero padded, so that the keys are returned in the order of the snapshots
+	for snapshotIterator.HasNext() {
+		queryResponse, err := snapshotIterator.Next()
+		if err != nil {
+			return 0, false, err
+		}
+
+		_, keyParts, err := ctx.GetStub().SplitCompositeKey(queryResponse.Key)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to split the composite key %s: %v", queryResponse.Key, err)
+		}
+		if len(keyParts) != len(attributes)+1 {
+			continue
+		}
+
+		recordedSnapshotID, err := strconv.Atoi(keyParts[len(attributes)])
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid snapshot id in key %s: %v", queryResponse.Key, err)
+		}
+		if recordedSnapshotID >= snapshotID {
+			value, _ := strconv.Atoi(string(queryResponse.Value)) // Error handling not needed since Itoa() was used when setting the snapshot, guaranteeing it was an integer.
+			return value, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+// getCurrentSnapshotID returns the ID of the last snapshot, or 0 if no snapshot has been taken
+func getCurrentSnapshotID(ctx contractapi.TransactionContextInterface) (int, error) {
+
+	currentSnapshotIDBytes, err := ctx.GetStub().GetState(currentSnapshotIDKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read the current snapshot id: %v", err)
+	}
+
+	currentSnapshotID, _ := strconv.Atoi(string(currentSnapshotIDBytes)) // Error handling not needed since Itoa() was used when setting the snapshot id, guaranteeing it was an integer. A missing id is 0.
+
+	return currentSnapshotID, nil
+}
+
+// formatSnapshotID pads a snapshot ID with zeros, so that the keys of a snapshot sort in numeric order
+func formatSnapshotID(snapshotID int) string {
+	return fmt.Sprintf("%020d", snapshotID)
+}
@@ -11,6 +11,9 @@ import (
 )
 
 // Define key names for options
+const nameKey = "name"
+const symbolKey = "symbol"
+const decimalsKey = "decimals"
 const totalSupplyKey = "totalSupply"
 
 // Define objectType names for prefix
@@ -23,30 +26,149 @@ type SmartContract struct {
 
 // event provides an organized struct for emitting events
 type event struct {
-	from  string
-	to    string
-	value int
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Value int    `json:"value"`
 }
 
-// Mint creates new tokens and adds them to minter's account balance
-// This function triggers a Transfer event
-func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount int) error {
+// Initialize sets the name, symbol and decimals of the token. It can only be called once.
+// This sample assumes Org1 is the central banker with privilege to initialize the contract.
+// The client that initializes the contract is granted the admin, minter, burner and pauser roles,
+// and can grant them to other accounts with GrantRole. Initialize must be invoked by the client
+// directly, not through another chaincode, as it records the name of the token chaincode.
+func (s *SmartContract) Initialize(ctx contractapi.TransactionContextInterface, name string, symbol string, decimals int) error {
 
-	// Check minter authorization - this sample assumes Org1 is the central banker with privilege to mint new tokens
+	// Check authorization - this sample assumes Org1 is the central banker with privilege to initialize the contract
 	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
 	if err != nil {
 		return fmt.Errorf("failed to get MSPID: %v", err)
 	}
 	if clientMSPID != "Org1MSP" {
-		return fmt.Errorf("client is not authorized to mint new tokens")
+		return fmt.Errorf("client is not authorized to initialize the contract")
+	}
+
+	// Check that the contract options are not already set
+	bytes, err := ctx.GetStub().GetState(nameKey)
+	if err != nil {
+		return fmt.Errorf("failed to get name: %v", err)
+	}
+	if bytes != nil {
+		return fmt.Errorf("contract options are already set, client is not authorized to change them")
+	}
+
+	if name == "" || symbol == "" {
+		return fmt.Errorf("name and symbol must not be empty")
+	}
+	if decimals < 0 {
+		return fmt.Errorf("decimals must not be negative")
+	}
+
+	err = ctx.GetStub().PutState(nameKey, []byte(name))
+	if err != nil {
+		return fmt.Errorf("failed to set token name: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(symbolKey, []byte(symbol))
+	if err != nil {
+		return fmt.Errorf("failed to set symbol: %v", err)
+	}
+
+	err = ctx.GetStub().PutState(decimalsKey, []byte(strconv.Itoa(decimals)))
+	if err != nil {
+		return fmt.Errorf("failed to set decimals: %v", err)
+	}
+
+	// Record the name of the token chaincode, so that calls from other chaincodes can
+	// be told apart from direct calls. Initialize must be invoked directly by the client.
+	chaincodeName, err := getProposalChaincode(ctx)
+	if err != nil {
+		return err
+	}
+	if chaincodeName != "" {
+		err = ctx.GetStub().PutState(chaincodeNameKey, []byte(chaincodeName))
+		if err != nil {
+			return fmt.Errorf("failed to set chaincode name: %v", err)
+		}
 	}
 
 	// Get ID of submitting client identity
-	minter, err := ctx.GetClientIdentity().GetID()
+	admin, err := ctx.GetClientIdentity().GetID()
 	if err != nil {
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
 
+	for _, role := range []string{adminRole, minterRole, burnerRole, pauserRole} {
+		err = grantRole(ctx, role, admin)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Name returns a descriptive name for fungible tokens in this contract
+func (s *SmartContract) Name(ctx contractapi.TransactionContextInterface) (string, error) {
+
+	bytes, err := ctx.GetStub().GetState(nameKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get name: %v", err)
+	}
+	if bytes == nil {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return string(bytes), nil
+}
+
+// Symbol returns an abbreviated name for fungible tokens in this contract
+func (s *SmartContract) Symbol(ctx contractapi.TransactionContextInterface) (string, error) {
+
+	bytes, err := ctx.GetStub().GetState(symbolKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get symbol: %v", err)
+	}
+	if bytes == nil {
+		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return string(bytes), nil
+}
+
+// Decimals returns the number of decimals the token uses
+// e.g. 8, means to divide the token amount by 100000000 to get its user representation
+func (s *SmartContract) Decimals(ctx contractapi.TransactionContextInterface) (int, error) {
+
+	bytes, err := ctx.GetStub().GetState(decimalsKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get decimals: %v", err)
+	}
+	if bytes == nil {
+		return 0, fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	decimals, _ := strconv.Atoi(string(bytes)) // Error handling not needed since Itoa() was used when setting the decimals, guaranteeing it was an integer.
+
+	return decimals, nil
+}
+
+// Mint creates new tokens and adds them to minter's account balance
+// The client must have been granted the minter role
+// This function triggers a Transfer event
+func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount int) error {
+
+	// Check minter authorization - only accounts with the minter role can mint new tokens
+	minter, err := checkRole(ctx, minterRole)
+	if err != nil {
+		return err
+	}
+
+	// Check that the token is not paused and the minter account is not frozen
+	err = checkNotPaused(ctx, minter)
+	if err != nil {
+		return err
+	}
+
 	if amount <= 0 {
 		return fmt.Errorf("mint amount must be a positive integer")
 	}
@@ -65,6 +187,12 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 		currentBalance, _ = strconv.Atoi(string(currentBalanceBytes)) // Error handling not needed since Itoa() was used when setting the account balance, guaranteeing it was an integer.
 	}
 
+	// Record the balance for the current snapshot before it changes
+	err = updateBalanceSnapshot(ctx, minter, currentBalance)
+	if err != nil {
+		return err
+	}
+
 	updatedBalance := currentBalance + amount
 
 	err = ctx.GetStub().PutState(minter, []byte(strconv.Itoa(updatedBalance)))
@@ -87,6 +215,12 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 		totalSupply, _ = strconv.Atoi(string(totalSupplyBytes)) // Error handling not needed since Itoa() was used when setting the totalSupply, guaranteeing it was an integer.
 	}
 
+	// Record the total supply for the current snapshot before it changes
+	err = updateSupplySnapshot(ctx, totalSupply)
+	if err != nil {
+		return err
+	}
+
 	// Add the mint amount to the total supply and update the state
 	totalSupply += amount
 	err = ctx.GetStub().PutState(totalSupplyKey, []byte(strconv.Itoa(totalSupply)))
@@ -111,22 +245,20 @@ func (s *SmartContract) Mint(ctx contractapi.TransactionContextInterface, amount
 }
 
 // Burn redeems tokens the minter's account balance
+// The client must have been granted the burner role
 // This function triggers a Transfer event
 func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, amount int) error {
 
-	// Check minter authorization - this sample assumes Org1 is the central banker with privilege to burn new tokens
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	// Check burner authorization - only accounts with the burner role can burn tokens
+	minter, err := checkRole(ctx, burnerRole)
 	if err != nil {
-		return fmt.Errorf("failed to get MSPID: %v", err)
-	}
-	if clientMSPID != "Org1MSP" {
-		return fmt.Errorf("client is not authorized to mint new tokens")
+		return err
 	}
 
-	// Get ID of submitting client identity
-	minter, err := ctx.GetClientIdentity().GetID()
+	// Check that the token is not paused and the minter account is not frozen
+	err = checkNotPaused(ctx, minter)
 	if err != nil {
-		return fmt.Errorf("failed to get client id: %v", err)
+		return err
 	}
 
 	if amount <= 0 {
@@ -147,6 +279,12 @@ func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, amount
 
 	currentBalance, _ = strconv.Atoi(string(currentBalanceBytes)) // Error handling not needed since Itoa() was used when setting the account balance, guaranteeing it was an integer.
 
+	// Record the balance for the current snapshot before it changes
+	err = updateBalanceSnapshot(ctx, minter, currentBalance)
+	if err != nil {
+		return err
+	}
+
 	updatedBalance := currentBalance - amount
 
 	err = ctx.GetStub().PutState(minter, []byte(strconv.Itoa(updatedBalance)))
@@ -167,6 +305,12 @@ func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, amount
 
 	totalSupply, _ := strconv.Atoi(string(totalSupplyBytes)) // Error handling not needed since Itoa() was used when setting the totalSupply, guaranteeing it was an integer.
 
+	// Record the total supply for the current snapshot before it changes
+	err = updateSupplySnapshot(ctx, totalSupply)
+	if err != nil {
+		return err
+	}
+
 	// Subtract the burn amount to the total supply and update the state
 	totalSupply -= amount
 	err = ctx.GetStub().PutState(totalSupplyKey, []byte(strconv.Itoa(totalSupply)))
@@ -195,10 +339,10 @@ func (s *SmartContract) Burn(ctx contractapi.TransactionContextInterface, amount
 // This function triggers a Transfer event
 func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, recipient string, amount int) error {
 
-	// Get ID of submitting client identity
-	clientID, err := ctx.GetClientIdentity().GetID()
+	// Get the account of the submitting client, or of the calling chaincode
+	clientID, err := getClientAccountID(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get client id: %v", err)
+		return err
 	}
 
 	err = transferHelper(ctx, clientID, recipient, amount)
@@ -238,10 +382,10 @@ func (s *SmartContract) BalanceOf(ctx contractapi.TransactionContextInterface, a
 // ClientAccountBalance returns the balance of the requesting client's account
 func (s *SmartContract) ClientAccountBalance(ctx contractapi.TransactionContextInterface) (int, error) {
 
-	// Get ID of submitting client identity
-	clientID, err := ctx.GetClientIdentity().GetID()
+	// Get the account of the submitting client, or of the calling chaincode
+	clientID, err := getClientAccountID(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get client id: %v", err)
+		return 0, err
 	}
 
 	balanceBytes, err := ctx.GetStub().GetState(clientID)
@@ -260,12 +404,13 @@ func (s *SmartContract) ClientAccountBalance(ctx contractapi.TransactionContextI
 // ClientAccountID returns the id of the requesting client's account
 // In this implementation, the client account ID is the clientId itself
 // Users can use this function to get their own account id, which they can then give to others as the payment address
+// When called by another chaincode it returns the account of that chaincode
 func (s *SmartContract) ClientAccountID(ctx contractapi.TransactionContextInterface) (string, error) {
 
-	// Get ID of submitting client identity
-	clientAccountID, err := ctx.GetClientIdentity().GetID()
+	// Get the account of the submitting client, or of the calling chaincode
+	clientAccountID, err := getClientAccountID(ctx)
 	if err != nil {
-		return "", fmt.Errorf("failed to get client id: %v", err)
+		return "", err
 	}
 
 	return clientAccountID, nil
@@ -299,10 +444,10 @@ func (s *SmartContract) TotalSupply(ctx contractapi.TransactionContextInterface)
 // This function triggers an Approval event
 func (s *SmartContract) Approve(ctx contractapi.TransactionContextInterface, spender string, value int) error {
 
-	// Get ID of submitting client identity
-	owner, err := ctx.GetClientIdentity().GetID()
+	// Get the account of the submitting client, or of the calling chaincode
+	owner, err := getClientAccountID(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get client id: %v", err)
+		return err
 	}
 
 	// Create allowanceKey
@@ -366,10 +511,10 @@ func (s *SmartContract) Allowance(ctx contractapi.TransactionContextInterface, o
 // This function triggers a Transfer event
 func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface, from string, to string, value int) error {
 
-	// Get ID of submitting client identity
-	spender, err := ctx.GetClientIdentity().GetID()
+	// Get the account of the submitting client, or of the calling chaincode
+	spender, err := getClientAccountID(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get client id: %v", err)
+		return err
 	}
 
 	// Create allowanceKey
@@ -378,6 +523,12 @@ func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface
 		return fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
 	}
 
+	// Check that the spender account is not frozen
+	err = checkNotPaused(ctx, spender)
+	if err != nil {
+		return err
+	}
+
 	// Retrieve the allowance of the spender
 	currentAllowanceBytes, err := ctx.GetStub().GetState(allowanceKey)
 	if err != nil {
@@ -425,12 +576,19 @@ func (s *SmartContract) TransferFrom(ctx contractapi.TransactionContextInterface
 
 // transferHelper is a helper function that transfers tokens from the "from" address to the "to" address
 // Dependant functions include Transfer and TransferFrom
+// The transfer fails if the token is paused, or if either account is frozen
 func transferHelper(ctx contractapi.TransactionContextInterface, from string, to string, value int) error {
 
 	if value < 0 { // transfer of 0 is allowed in ERC-20, so just validate against negative amounts
 		return fmt.Errorf("transfer amount cannot be negative")
 	}
 
+	// Check that the token is not paused and neither account is frozen
+	err := checkNotPaused(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
 	fromCurrentBalanceBytes, err := ctx.GetStub().GetState(from)
 	if err != nil {
 		return fmt.Errorf("failed to read client account %s from world state: %v", from, err)
@@ -459,6 +617,16 @@ func transferHelper(ctx contractapi.TransactionContextInterface, from string, to
 		toCurrentBalance, _ = strconv.Atoi(string(toCurrentBalanceBytes)) // Error handling not needed since Itoa() was used when setting the account balance, guaranteeing it was an integer.
 	}
 
+	// Record the balances for the current snapshot before they change
+	err = updateBalanceSnapshot(ctx, from, fromCurrentBalance)
+	if err != nil {
+		return err
+	}
+	err = updateBalanceSnapshot(ctx, to, toCurrentBalance)
+	if err != nil {
+		return err
+	}
+
 	fromUpdatedBalance := fromCurrentBalance - value
 	toUpdatedBalance := toCurrentBalance + value
 
@@ -0,0 +1,215 @@
+package chaincode
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Coin selection strategies of Pay
+const (
+	largestFirst   = "largest-first"
+	smallestFirst  = "smallest-first"
+	minimizeInputs = "minimize-inputs"
+)
+
+// Pay transfers an amount of tokens from the client to a recipient, without the client having to choose
+// the UTXOs to spend. The contract selects UTXOs of the client with one of the following strategies:
+// largest-first spends the largest UTXOs first, smallest-first spends the smallest UTXOs first to clean up
+// small UTXOs, and minimize-inputs spends as few UTXOs as possible with as little change as possible.
+// Any amount left over is returned to the client as a change UTXO.
+// Time-locked UTXOs are only selected once their lock has expired.
+func (s *SmartContract) Pay(ctx contractapi.TransactionContextInterface, recipient string, amount int, strategy string) ([]UTXO, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if amount <= 0 {
+		return nil, fmt.Errorf("payment amount must be a positive integer")
+	}
+
+	spendable, err := spendableUTXOs(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected []*UTXO
+	switch strategy {
+	case largestFirst:
+		selected = selectInOrder(spendable, amount, func(a, b *UTXO) bool { return a.Amount > b.Amount })
+	case smallestFirst:
+		selected = selectInOrder(spendable, amount, func(a, b *UTXO) bool { return a.Amount < b.Amount })
+	case minimizeInputs:
+		selected = selectMinimizingInputs(spendable, amount)
+	default:
+		return nil, fmt.Errorf("unknown coin selection strategy %s, expected one of %s, %s or %s", strategy, largestFirst, smallestFirst, minimizeInputs)
+	}
+	if selected == nil {
+		return nil, fmt.Errorf("client %s has insufficient spendable funds for a payment of %d", clientID, amount)
+	}
+
+	utxoInputs := make(map[string]*UTXO)
+	var totalInputAmount int
+	for _, utxo := range selected {
+		utxoInputs[utxo.Key] = utxo
+		totalInputAmount += utxo.Amount
+	}
+
+	utxoOutputs := []UTXO{{Owner: recipient, Amount: amount}}
+	if change := totalInputAmount - amount; change > 0 {
+		utxoOutputs = append(utxoOutputs, UTXO{Owner: clientID, Amount: change})
+	}
+
+	_, err = prepareUTXOOutputs(ctx, utxoOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = spendUTXOs(ctx, utxoInputs, utxoOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("client %s paid %d to %s with %d utxos selected %s", clientID, amount, recipient, len(selected), strategy)
+
+	return utxoOutputs, nil
+}
+
+// Consolidate merges up to maxInputs of the client's spendable UTXOs into a single UTXO, starting with the
+// smallest ones, so that later payments need fewer inputs
+func (s *SmartContract) Consolidate(ctx contractapi.TransactionContextInterface, maxInputs int) (*UTXO, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if maxInputs < 2 {
+		return nil, fmt.Errorf("at least two utxos are needed to consolidate")
+	}
+
+	spendable, err := spendableUTXOs(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(spendable) < 2 {
+		return nil, fmt.Errorf("client %s has fewer than two spendable utxos", clientID)
+	}
+
+	sortUTXOs(spendable, func(a, b *UTXO) bool { return a.Amount < b.Amount })
+	if len(spendable) > maxInputs {
+		spendable = spendable[:maxInputs]
+	}
+
+	utxoInputs := make(map[string]*UTXO)
+	var totalInputAmount int
+	for _, utxo := range spendable {
+		utxoInputs[utxo.Key] = utxo
+		totalInputAmount += utxo.Amount
+	}
+
+	utxoOutputs := []UTXO{{Owner: clientID, Amount: totalInputAmount}}
+	_, err = prepareUTXOOutputs(ctx, utxoOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = spendUTXOs(ctx, utxoInputs, utxoOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("client %s consolidated %d utxos into %+v", clientID, len(spendable), utxoOutputs[0])
+
+	return &utxoOutputs[0], nil
+}
+
+// spendableUTXOs returns the UTXOs of an owner that are not time-locked at the time of the transaction
+func spendableUTXOs(ctx contractapi.TransactionContextInterface, owner string) ([]*UTXO, error) {
+
+	utxos, err := queryUTXOs(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	spendable := []*UTXO{}
+	for _, utxo := range utxos {
+		if utxo.LockedUntil != "" {
+			lockedUntil, _ := time.Parse(time.RFC3339, utxo.LockedUntil) // Error handling not needed since the lock was validated when the utxo was created.
+			if now.Before(lockedUntil) {
+				continue
+			}
+		}
+		spendable = append(spendable, utxo)
+	}
+
+	return spendable, nil
+}
+
+// selectInOrder selects UTXOs in the given order until they cover the amount, or returns nil if they cannot
+func selectInOrder(utxos []*UTXO, amount int, less func(a, b *UTXO) bool) []*UTXO {
+
+	sortUTXOs(utxos, less)
+
+	var selected []*UTXO
+	var total int
+	for _, utxo := range utxos {
+		selected = append(selected, utxo)
+		total += utxo.Amount
+		if total >= amount {
+			return selected
+		}
+	}
+
+	return nil
+}
+
+// selectMinimizingInputs selects the fewest UTXOs that cover the amount. The fewest UTXOs are found by
+// taking the largest ones first. The last of them is then replaced by the smallest UTXO that still covers
+// the rest of the amount, to keep the change small. A single UTXO that covers the amount exactly is
+// therefore spent without change.
+func selectMinimizingInputs(utxos []*UTXO, amount int) []*UTXO {
+
+	selected := selectInOrder(utxos, amount, func(a, b *UTXO) bool { return a.Amount > b.Amount })
+	if selected == nil {
+		return nil
+	}
+
+	// utxos is now sorted largest first. The UTXOs from the last selected one onwards are candidates for the
+	// last input, and the last candidate that covers the rest of the amount is the smallest one that does.
+	last := len(selected) - 1
+	rest := amount
+	for _, utxo := range selected[:last] {
+		rest -= utxo.Amount
+	}
+	for _, utxo := range utxos[last:] {
+		if utxo.Amount >= rest {
+			selected[last] = utxo
+		}
+	}
+
+	return selected
+}
+
+// sortUTXOs sorts UTXOs in the given order, with UTXOs of the same amount in the order of their keys,
+// so that all endorsing peers select the same UTXOs
+func sortUTXOs(utxos []*UTXO, less func(a, b *UTXO) bool) {
+	sort.SliceStable(utxos, func(i, j int) bool {
+		if utxos[i].Amount == utxos[j].Amount {
+			return utxos[i].Key < utxos[j].Key
+		}
+		return less(utxos[i], utxos[j])
+	})
+}
@@ -0,0 +1,335 @@
+package chaincode
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"github.com/hyperledger/fabric-samples/token-utxo/chaincode-go/pedersen"
+)
+
+// confidentialUTXOObjectType is the objectType of the composite key of a confidential utxo
+const confidentialUTXOObjectType = "confidentialUtxo"
+
+// ConfidentialUTXO represents an unspent transaction output whose amount is hidden in a Pedersen commitment
+// The owner keeps the amount and the blinding factor of the commitment off the ledger. A new output comes with
+// a range proof that its amount is not negative, which is verified when the output is created but not stored.
+type ConfidentialUTXO struct {
+	Key        string               `json:"utxo_key"`
+	Owner      string               `json:"owner"`
+	Commitment string               `json:"commitment"`
+	RangeProof *pedersen.RangeProof `json:"range_proof,omitempty" metadata:",optional"`
+}
+
+// Shield spends plain UTXOs of the client and creates confidential UTXOs with the same total amount
+// The amount of the inputs is public, so the excess signature proves that the outputs commit to it.
+func (s *SmartContract) Shield(ctx contractapi.TransactionContextInterface, utxoInputKeys []string, utxoOutputs []ConfidentialUTXO, excessSignature pedersen.ExcessSignature) ([]ConfidentialUTXO, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	// Validate and summarize utxo inputs
+	utxoInputs, totalInputAmount, err := readUTXOInputs(ctx, clientID, utxoInputKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the confidential utxo outputs and their balance with the public input amount
+	publicInput := []string{pedersen.Commit(uint64(totalInputAmount), new(big.Int))}
+	err = prepareConfidentialOutputs(ctx, "Shield", utxoInputKeys, publicInput, 0, utxoOutputs, &excessSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = spendUTXOs(ctx, utxoInputs, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putConfidentialUTXOs(ctx, utxoOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("client %s shielded %d in %d utxos", clientID, totalInputAmount, len(utxoInputKeys))
+
+	return utxoOutputs, nil
+}
+
+// ConfidentialTransfer transfers confidential UTXOs from the client to recipient(s) without revealing the amounts
+// Each output must have a range proof, and the excess signature must prove that the outputs commit to the same total
+// amount as the inputs. The client signs the BalanceMessage of the transfer with the excess blinding factor, which is
+// the sum of the blinding factors of the inputs minus the sum of the blinding factors of the outputs.
+func (s *SmartContract) ConfidentialTransfer(ctx contractapi.TransactionContextInterface, utxoInputKeys []string, utxoOutputs []ConfidentialUTXO, excessSignature pedersen.ExcessSignature) ([]ConfidentialUTXO, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	// Validate utxo inputs
+	utxoInputs, err := readConfidentialInputs(ctx, clientID, utxoInputKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate the utxo outputs and the balance of the inputs and outputs
+	err = prepareConfidentialOutputs(ctx, "ConfidentialTransfer", utxoInputKeys, commitments(utxoInputs), 0, utxoOutputs, &excessSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = delConfidentialUTXOs(ctx, utxoInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putConfidentialUTXOs(ctx, utxoOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("client %s transferred %d confidential utxos into %d", clientID, len(utxoInputs), len(utxoOutputs))
+
+	return utxoOutputs, nil
+}
+
+// Unshield spends confidential UTXOs of the client and reveals an amount of them as a plain UTXO owned by the client
+// The rest of the inputs can be returned to the client or others as confidential change outputs. The excess signature
+// must prove that the inputs commit to the amount plus the amounts of the change outputs.
+func (s *SmartContract) Unshield(ctx contractapi.TransactionContextInterface, utxoInputKeys []string, amount int, changeOutputs []ConfidentialUTXO, excessSignature pedersen.ExcessSignature) (*UTXO, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	if amount <= 0 || amount >= 1<<pedersen.RangeBits {
+		return nil, fmt.Errorf("unshield amount must be a positive integer less than 2^%d", pedersen.RangeBits)
+	}
+
+	// Validate utxo inputs
+	utxoInputs, err := readConfidentialInputs(ctx, clientID, utxoInputKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// The plain output is the first output of the transaction, followed by the change outputs
+	err = prepareConfidentialOutputs(ctx, "Unshield", utxoInputKeys, commitments(utxoInputs), amount, changeOutputs, &excessSignature)
+	if err != nil {
+		return nil, err
+	}
+
+	err = delConfidentialUTXOs(ctx, utxoInputs)
+	if err != nil {
+		return nil, err
+	}
+
+	err = putConfidentialUTXOs(ctx, changeOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	utxoOutputs := []UTXO{{Key: ctx.GetStub().GetTxID() + ".0", Owner: clientID, Amount: amount}}
+	err = spendUTXOs(ctx, nil, utxoOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("client %s unshielded %d from %d confidential utxos", clientID, amount, len(utxoInputs))
+
+	return &utxoOutputs[0], nil
+}
+
+// ClientConfidentialUTXOs returns all confidential UTXOs owned by the calling client
+func (s *SmartContract) ClientConfidentialUTXOs(ctx contractapi.TransactionContextInterface) ([]*ConfidentialUTXO, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	// since confidential utxos have a composite key of owner:utxoKey, we can query for all utxos matching owner:*
+	utxoResultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey(confidentialUTXOObjectType, []string{clientID})
+	if err != nil {
+		return nil, err
+	}
+	defer utxoResultsIterator.Close()
+
+	utxos := []*ConfidentialUTXO{}
+	for utxoResultsIterator.HasNext() {
+		utxoRecord, err := utxoResultsIterator.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		// composite key is expected to be owner:utxoKey
+		_, compositeKeyParts, err := ctx.GetStub().SplitCompositeKey(utxoRecord.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(compositeKeyParts) != 2 {
+			return nil, fmt.Errorf("expected composite key with two parts (owner:utxoKey)")
+		}
+
+		utxos = append(utxos, &ConfidentialUTXO{
+			Key:        compositeKeyParts[1],
+			Owner:      compositeKeyParts[0],
+			Commitment: string(utxoRecord.Value),
+		})
+	}
+
+	return utxos, nil
+}
+
+// BalanceMessage returns the message that the excess signature of a Shield, ConfidentialTransfer or Unshield
+// transaction signs. It binds the signature to the function, the inputs, the public amount and the outputs,
+// so that the signature cannot be reused to create different outputs.
+func BalanceMessage(function string, utxoInputKeys []string, amount int, utxoOutputs []ConfidentialUTXO) []byte {
+	parts := []string{function, strings.Join(utxoInputKeys, ","), fmt.Sprint(amount)}
+	for _, utxoOutput := range utxoOutputs {
+		parts = append(parts, utxoOutput.Owner, utxoOutput.Commitment)
+	}
+	return []byte(strings.Join(parts, "\x00"))
+}
+
+// readConfidentialInputs reads the confidential utxos of an owner that are spent by a transaction
+// Dependant functions include ConfidentialTransfer and Unshield
+func readConfidentialInputs(ctx contractapi.TransactionContextInterface, owner string, utxoInputKeys []string) ([]ConfidentialUTXO, error) {
+
+	if len(utxoInputKeys) == 0 {
+		return nil, fmt.Errorf("at least one utxo input is required")
+	}
+
+	var utxoInputs []ConfidentialUTXO
+	spent := make(map[string]bool)
+	for _, utxoInputKey := range utxoInputKeys {
+		if spent[utxoInputKey] {
+			return nil, fmt.Errorf("the same utxo input can not be spend twice")
+		}
+		spent[utxoInputKey] = true
+
+		utxoInputCompositeKey, err := ctx.GetStub().CreateCompositeKey(confidentialUTXOObjectType, []string{owner, utxoInputKey})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create composite key: %v", err)
+		}
+
+		// validate that owner has a confidential utxo matching the input key
+		commitment, err := ctx.GetStub().GetState(utxoInputCompositeKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read utxoInputCompositeKey %s from world state: %v", utxoInputCompositeKey, err)
+		}
+		if commitment == nil {
+			return nil, fmt.Errorf("confidential utxoInput %s not found for client %s", utxoInputKey, owner)
+		}
+
+		utxoInputs = append(utxoInputs, ConfidentialUTXO{Key: utxoInputKey, Owner: owner, Commitment: string(commitment)})
+	}
+
+	return utxoInputs, nil
+}
+
+// prepareConfidentialOutputs validates the confidential utxos created by a transaction and their range proofs,
+// verifies that they balance the inputs minus the public output amount, and assigns their keys
+// Dependant functions include Shield, ConfidentialTransfer and Unshield
+func prepareConfidentialOutputs(ctx contractapi.TransactionContextInterface, function string, utxoInputKeys []string, inputs []string, publicOutputAmount int, utxoOutputs []ConfidentialUTXO, excessSignature *pedersen.ExcessSignature) error {
+
+	var outputs []string
+	if publicOutputAmount > 0 {
+		outputs = append(outputs, pedersen.Commit(uint64(publicOutputAmount), new(big.Int)))
+	}
+
+	for i, utxoOutput := range utxoOutputs {
+		if utxoOutput.Owner == "" {
+			return fmt.Errorf("utxo output owner must not be empty")
+		}
+		if strings.HasPrefix(utxoOutput.Owner, multisigPrefix) {
+			return fmt.Errorf("confidential utxo output can not be owned by a multisig address")
+		}
+		if utxoOutput.Commitment == "" {
+			return fmt.Errorf("utxo output commitment must not be empty")
+		}
+
+		err := pedersen.VerifyRange(utxoOutput.Commitment, utxoOutput.RangeProof)
+		if err != nil {
+			return fmt.Errorf("invalid range proof of utxo output %d: %v", i, err)
+		}
+
+		outputs = append(outputs, utxoOutput.Commitment)
+	}
+
+	message := BalanceMessage(function, utxoInputKeys, publicOutputAmount, utxoOutputs)
+	err := pedersen.VerifyBalance(inputs, outputs, excessSignature, message)
+	if err != nil {
+		return err
+	}
+
+	// Outputs of Unshield follow its plain output
+	txID := ctx.GetStub().GetTxID()
+	first := 0
+	if publicOutputAmount > 0 {
+		first = 1
+	}
+	for i := range utxoOutputs {
+		utxoOutputs[i].Key = fmt.Sprintf("%s.%d", txID, first+i)
+		utxoOutputs[i].RangeProof = nil
+	}
+
+	return nil
+}
+
+// putConfidentialUTXOs stores the commitments of confidential utxos under their composite keys
+func putConfidentialUTXOs(ctx contractapi.TransactionContextInterface, utxoOutputs []ConfidentialUTXO) error {
+
+	for _, utxoOutput := range utxoOutputs {
+		utxoOutputCompositeKey, err := ctx.GetStub().CreateCompositeKey(confidentialUTXOObjectType, []string{utxoOutput.Owner, utxoOutput.Key})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+
+		err = ctx.GetStub().PutState(utxoOutputCompositeKey, []byte(utxoOutput.Commitment))
+		if err != nil {
+			return err
+		}
+		log.Printf("confidential utxoOutput created: %+v", utxoOutput)
+	}
+
+	return nil
+}
+
+// delConfidentialUTXOs deletes spent confidential utxos
+func delConfidentialUTXOs(ctx contractapi.TransactionContextInterface, utxoInputs []ConfidentialUTXO) error {
+
+	for _, utxoInput := range utxoInputs {
+		utxoInputCompositeKey, err := ctx.GetStub().CreateCompositeKey(confidentialUTXOObjectType, []string{utxoInput.Owner, utxoInput.Key})
+		if err != nil {
+			return fmt.Errorf("failed to create composite key: %v", err)
+		}
+
+		err = ctx.GetStub().DelState(utxoInputCompositeKey)
+		if err != nil {
+			return err
+		}
+		log.Printf("confidential utxoInput deleted: %+v", utxoInput)
+	}
+
+	return nil
+}
+
+// commitments returns the commitments of confidential utxos
+func commitments(utxos []ConfidentialUTXO) []string {
+	var result []string
+	for _, utxo := range utxos {
+		result = append(result, utxo.Commitment)
+	}
+	return result
+}
@@ -0,0 +1,273 @@
+package chaincode
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// multisigPrefix prefixes the address that owns the utxos of a group of owners
+const multisigPrefix = "multisig:"
+
+// pendingSpendObjectType is the objectType of the composite key of a pending spend
+const pendingSpendObjectType = "pendingSpend"
+
+// PendingSpend is a proposal to spend utxos owned by a multisig address, which is executed
+// once the threshold number of owners have approved it
+type PendingSpend struct {
+	ID            string   `json:"id"`
+	Address       string   `json:"address"`
+	Proposer      string   `json:"proposer"`
+	UTXOInputKeys []string `json:"utxo_input_keys"`
+	UTXOOutputs   []UTXO   `json:"utxo_outputs"`
+	Owners        []string `json:"owners"`
+	Threshold     int      `json:"threshold"`
+	Approvals     []string `json:"approvals"`
+	Status        string   `json:"status"`
+}
+
+// MultisigAddress returns the address of the utxos that are owned by the owners and
+// require threshold of them to approve a spend. A Transfer output with owners and a
+// threshold is assigned to this address.
+func (s *SmartContract) MultisigAddress(ctx contractapi.TransactionContextInterface, owners []string, threshold int) (string, error) {
+	return multisigAddress(owners, threshold)
+}
+
+// MultisigUTXOs returns all UTXOs owned by a multisig address
+func (s *SmartContract) MultisigUTXOs(ctx contractapi.TransactionContextInterface, address string) ([]*UTXO, error) {
+
+	if !strings.HasPrefix(address, multisigPrefix) {
+		return nil, fmt.Errorf("%s is not a multisig address", address)
+	}
+
+	return queryUTXOs(ctx, address)
+}
+
+// ProposeSpend proposes to spend utxos owned by a multisig address. The client must be one of
+// the owners, and its proposal counts as its approval. The spend is executed once threshold
+// owners have approved it with ApproveSpend, or right away if the threshold is 1.
+func (s *SmartContract) ProposeSpend(ctx contractapi.TransactionContextInterface, address string, utxoInputKeys []string, utxoOutputs []UTXO) (*PendingSpend, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	// Validate and summarize utxo inputs
+	utxoInputs, totalInputAmount, err := readUTXOInputs(ctx, address, utxoInputKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// All inputs of an address have the same owners and threshold
+	utxoInput := utxoInputs[utxoInputKeys[0]]
+	if len(utxoInput.Owners) == 0 {
+		return nil, fmt.Errorf("%s is not a multisig address", address)
+	}
+	if !contains(utxoInput.Owners, clientID) {
+		return nil, fmt.Errorf("client %s is not an owner of %s", clientID, address)
+	}
+
+	// Validate and summarize utxo outputs
+	totalOutputAmount, err := prepareUTXOOutputs(ctx, utxoOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate total inputs equals total outputs
+	if totalInputAmount != totalOutputAmount {
+		return nil, fmt.Errorf("total utxoInput amount %d does not equal total utxoOutput amount %d", totalInputAmount, totalOutputAmount)
+	}
+
+	pendingSpend := &PendingSpend{
+		ID:            ctx.GetStub().GetTxID(),
+		Address:       address,
+		Proposer:      clientID,
+		UTXOInputKeys: utxoInputKeys,
+		UTXOOutputs:   utxoOutputs,
+		Owners:        utxoInput.Owners,
+		Threshold:     utxoInput.Threshold,
+		Approvals:     []string{clientID},
+		Status:        "pending",
+	}
+
+	if len(pendingSpend.Approvals) >= pendingSpend.Threshold {
+		err = spendUTXOs(ctx, utxoInputs, utxoOutputs)
+		if err != nil {
+			return nil, err
+		}
+		pendingSpend.Status = "executed"
+	}
+
+	err = putPendingSpend(ctx, pendingSpend)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("spend %s of %s proposed by %s", pendingSpend.ID, address, clientID)
+
+	return pendingSpend, nil
+}
+
+// ApproveSpend adds the approval of the client, which must be one of the owners, to a pending spend.
+// When the threshold is reached, the inputs are spent and the outputs are created.
+func (s *SmartContract) ApproveSpend(ctx contractapi.TransactionContextInterface, spendID string) (*PendingSpend, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	pendingSpend, err := s.QueryPendingSpend(ctx, spendID)
+	if err != nil {
+		return nil, err
+	}
+
+	if pendingSpend.Status != "pending" {
+		return nil, fmt.Errorf("spend %s is %s, only a pending spend can be approved", spendID, pendingSpend.Status)
+	}
+	if !contains(pendingSpend.Owners, clientID) {
+		return nil, fmt.Errorf("client %s is not an owner of %s", clientID, pendingSpend.Address)
+	}
+	if contains(pendingSpend.Approvals, clientID) {
+		return nil, fmt.Errorf("client %s has already approved spend %s", clientID, spendID)
+	}
+
+	pendingSpend.Approvals = append(pendingSpend.Approvals, clientID)
+
+	if len(pendingSpend.Approvals) >= pendingSpend.Threshold {
+		// The inputs may have been spent by another spend since the proposal, or may still be locked
+		utxoInputs, _, err := readUTXOInputs(ctx, pendingSpend.Address, pendingSpend.UTXOInputKeys)
+		if err != nil {
+			return nil, err
+		}
+
+		err = spendUTXOs(ctx, utxoInputs, pendingSpend.UTXOOutputs)
+		if err != nil {
+			return nil, err
+		}
+		pendingSpend.Status = "executed"
+	}
+
+	err = putPendingSpend(ctx, pendingSpend)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("spend %s approved by %s, status %s", spendID, clientID, pendingSpend.Status)
+
+	return pendingSpend, nil
+}
+
+// CancelSpend cancels a pending spend. Only the proposer can cancel it.
+func (s *SmartContract) CancelSpend(ctx contractapi.TransactionContextInterface, spendID string) error {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	pendingSpend, err := s.QueryPendingSpend(ctx, spendID)
+	if err != nil {
+		return err
+	}
+
+	if pendingSpend.Proposer != clientID {
+		return fmt.Errorf("spend %s can only be cancelled by its proposer", spendID)
+	}
+	if pendingSpend.Status != "pending" {
+		return fmt.Errorf("spend %s is %s, only a pending spend can be cancelled", spendID, pendingSpend.Status)
+	}
+
+	pendingSpend.Status = "cancelled"
+
+	return putPendingSpend(ctx, pendingSpend)
+}
+
+// QueryPendingSpend returns a proposed spend of a multisig address
+func (s *SmartContract) QueryPendingSpend(ctx contractapi.TransactionContextInterface, spendID string) (*PendingSpend, error) {
+
+	pendingSpendKey, err := ctx.GetStub().CreateCompositeKey(pendingSpendObjectType, []string{spendID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	pendingSpendBytes, err := ctx.GetStub().GetState(pendingSpendKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spend %s from world state: %v", spendID, err)
+	}
+	if pendingSpendBytes == nil {
+		return nil, fmt.Errorf("spend %s does not exist", spendID)
+	}
+
+	var pendingSpend PendingSpend
+	err = json.Unmarshal(pendingSpendBytes, &pendingSpend)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal spend %s: %v", spendID, err)
+	}
+
+	return &pendingSpend, nil
+}
+
+// putPendingSpend stores a pending spend under its composite key
+func putPendingSpend(ctx contractapi.TransactionContextInterface, pendingSpend *PendingSpend) error {
+
+	pendingSpendKey, err := ctx.GetStub().CreateCompositeKey(pendingSpendObjectType, []string{pendingSpend.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create composite key: %v", err)
+	}
+
+	pendingSpendBytes, err := json.Marshal(pendingSpend)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+
+	return ctx.GetStub().PutState(pendingSpendKey, pendingSpendBytes)
+}
+
+// multisigAddress derives the address of a group of owners and a threshold from their hash,
+// so that the same owners and threshold always have the same address
+func multisigAddress(owners []string, threshold int) (string, error) {
+
+	if len(owners) == 0 {
+		return "", fmt.Errorf("a multisig address requires at least one owner")
+	}
+	if threshold < 1 || threshold > len(owners) {
+		return "", fmt.Errorf("threshold must be between 1 and the number of owners %d", len(owners))
+	}
+
+	sortedOwners := append([]string{}, owners...)
+	sort.Strings(sortedOwners)
+	for i, owner := range sortedOwners {
+		if owner == "" {
+			return "", fmt.Errorf("owners must not be empty")
+		}
+		if i > 0 && owner == sortedOwners[i-1] {
+			return "", fmt.Errorf("owner %s is listed twice", owner)
+		}
+	}
+
+	hash := sha256.Sum256([]byte(strconv.Itoa(threshold) + "\x00" + strings.Join(sortedOwners, "\x00")))
+
+	return multisigPrefix + hex.EncodeToString(hash[:]), nil
+}
+
+// contains returns true if the list contains the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -1,9 +1,12 @@
 package chaincode
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hyperledger/fabric-contract-api-go/contractapi"
 )
@@ -14,10 +17,15 @@ type SmartContract struct {
 }
 
 // UTXO represents an unspent transaction output
+// An output can optionally be owned by several clients, M (threshold) of which must approve a spend,
+// and can optionally be locked until a time in RFC3339 format.
 type UTXO struct {
-	Key    string `json:"utxo_key"`
-	Owner  string `json:"owner"`
-	Amount int    `json:"amount"`
+	Key         string   `json:"utxo_key"`
+	Owner       string   `json:"owner"`
+	Amount      int      `json:"amount"`
+	Owners      []string `json:"owners,omitempty" metadata:",optional"`
+	Threshold   int      `json:"threshold,omitempty" metadata:",optional"`
+	LockedUntil string   `json:"locked_until,omitempty" metadata:",optional"`
 }
 
 // Mint creates a new unspent transaction output (UTXO) owned by the minter
@@ -70,47 +78,136 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, ut
 	}
 
 	// Validate and summarize utxo inputs
+	utxoInputs, totalInputAmount, err := readUTXOInputs(ctx, clientID, utxoInputKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate and summarize utxo outputs
+	totalOutputAmount, err := prepareUTXOOutputs(ctx, utxoOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate total inputs equals total outputs
+	if totalInputAmount != totalOutputAmount {
+		return nil, fmt.Errorf("total utxoInput amount %d does not equal total utxoOutput amount %d", totalInputAmount, totalOutputAmount)
+	}
+
+	// Since the transaction is valid, now delete utxo inputs from owner's state and create the utxo outputs
+	err = spendUTXOs(ctx, utxoInputs, utxoOutputs)
+	if err != nil {
+		return nil, err
+	}
+
+	return utxoOutputs, nil
+}
+
+// ClientUTXOs returns all UTXOs owned by the calling client
+func (s *SmartContract) ClientUTXOs(ctx contractapi.TransactionContextInterface) ([]*UTXO, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	return queryUTXOs(ctx, clientID)
+}
+
+// ClientID returns the client id of the calling client
+// Users can use this function to get their own client id, which they can then give to others as the payment address
+func (s *SmartContract) ClientID(ctx contractapi.TransactionContextInterface) (string, error) {
+
+	// Get ID of submitting client identity
+	clientID, err := ctx.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	return clientID, nil
+}
+
+// Helper Functions
+
+// readUTXOInputs reads the utxos of an owner that are spent by a transaction, and returns them with their total amount
+// Inputs that are time-locked can only be spent once the lock has expired
+// Dependant functions include Transfer and ApproveSpend
+func readUTXOInputs(ctx contractapi.TransactionContextInterface, owner string, utxoInputKeys []string) (map[string]*UTXO, int, error) {
+
+	if len(utxoInputKeys) == 0 {
+		return nil, 0, fmt.Errorf("at least one utxo input is required")
+	}
+
+	now, err := getTxTime(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	utxoInputs := make(map[string]*UTXO)
 	var totalInputAmount int
 	for _, utxoInputKey := range utxoInputKeys {
 		if utxoInputs[utxoInputKey] != nil {
-			return nil, fmt.Errorf("the same utxo input can not be spend twice")
+			return nil, 0, fmt.Errorf("the same utxo input can not be spend twice")
 		}
 
-		utxoInputCompositeKey, err := ctx.GetStub().CreateCompositeKey("utxo", []string{clientID, utxoInputKey})
+		// validate that owner has a utxo matching the input key
+		utxoInput, err := readUTXO(ctx, owner, utxoInputKey)
 		if err != nil {
-			return nil, fmt.Errorf("failed to create composite key: %v", err)
+			return nil, 0, err
 		}
-
-		// validate that client has a utxo matching the input key
-		valueBytes, err := ctx.GetStub().GetState(utxoInputCompositeKey)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read utxoInputCompositeKey %s from world state: %v", utxoInputCompositeKey, err)
+		if utxoInput == nil {
+			return nil, 0, fmt.Errorf("utxoInput %s not found for client %s", utxoInputKey, owner)
 		}
 
-		if valueBytes == nil {
-			return nil, fmt.Errorf("utxoInput %s not found for client %s", utxoInputKey, clientID)
+		if utxoInput.LockedUntil != "" {
+			lockedUntil, _ := time.Parse(time.RFC3339, utxoInput.LockedUntil) // Error handling not needed since the lock was validated when the utxo was created.
+			if now.Before(lockedUntil) {
+				return nil, 0, fmt.Errorf("utxoInput %s is locked until %s", utxoInputKey, utxoInput.LockedUntil)
+			}
 		}
 
-		amount, _ := strconv.Atoi(string(valueBytes)) // Error handling not needed since Itoa() was used when setting the utxo amount, guaranteeing it was an integer.
-
-		utxoInput := &UTXO{
-			Key:    utxoInputKey,
-			Owner:  clientID,
-			Amount: amount,
-		}
-
-		totalInputAmount += amount
+		totalInputAmount += utxoInput.Amount
 		utxoInputs[utxoInputKey] = utxoInput
 	}
 
-	// Validate and summarize utxo outputs
+	return utxoInputs, totalInputAmount, nil
+}
+
+// prepareUTXOOutputs validates the utxos created by a transaction, assigns their keys, and returns their total amount
+// Outputs with several owners are assigned to the multisig address of the owners and threshold
+// Dependant functions include Transfer and ProposeSpend
+func prepareUTXOOutputs(ctx contractapi.TransactionContextInterface, utxoOutputs []UTXO) (int, error) {
+
 	var totalOutputAmount int
 	txID := ctx.GetStub().GetTxID()
 	for i, utxoOutput := range utxoOutputs {
 
 		if utxoOutput.Amount <= 0 {
-			return nil, fmt.Errorf("utxo output amount must be a positive integer")
+			return 0, fmt.Errorf("utxo output amount must be a positive integer")
+		}
+
+		if len(utxoOutput.Owners) > 0 {
+			address, err := multisigAddress(utxoOutput.Owners, utxoOutput.Threshold)
+			if err != nil {
+				return 0, err
+			}
+			utxoOutputs[i].Owner = address
+		} else if utxoOutput.Threshold != 0 {
+			return 0, fmt.Errorf("utxo output threshold requires owners")
+		} else if strings.HasPrefix(utxoOutput.Owner, multisigPrefix) {
+			return 0, fmt.Errorf("utxo output to a multisig address requires its owners and threshold")
+		}
+		if utxoOutputs[i].Owner == "" {
+			return 0, fmt.Errorf("utxo output owner must not be empty")
+		}
+
+		if utxoOutput.LockedUntil != "" {
+			lockedUntil, err := time.Parse(time.RFC3339, utxoOutput.LockedUntil)
+			if err != nil {
+				return 0, fmt.Errorf("invalid utxo output lock %q: %v", utxoOutput.LockedUntil, err)
+			}
+			utxoOutputs[i].LockedUntil = lockedUntil.UTC().Format(time.RFC3339)
 		}
 
 		utxoOutputs[i].Key = fmt.Sprintf("%s.%d", txID, i)
@@ -118,22 +215,23 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, ut
 		totalOutputAmount += utxoOutput.Amount
 	}
 
-	// Validate total inputs equals total outputs
-	if totalInputAmount != totalOutputAmount {
-		return nil, fmt.Errorf("total utxoInput amount %d does not equal total utxoOutput amount %d", totalInputAmount, totalOutputAmount)
-	}
+	return totalOutputAmount, nil
+}
+
+// spendUTXOs deletes the utxo inputs from the owner's state and creates the utxo outputs
+// Dependant functions include Transfer and ApproveSpend
+func spendUTXOs(ctx contractapi.TransactionContextInterface, utxoInputs map[string]*UTXO, utxoOutputs []UTXO) error {
 
-	// Since the transaction is valid, now delete utxo inputs from owner's state
 	for _, utxoInput := range utxoInputs {
 
 		utxoInputCompositeKey, err := ctx.GetStub().CreateCompositeKey("utxo", []string{utxoInput.Owner, utxoInput.Key})
 		if err != nil {
-			return nil, fmt.Errorf("failed to create composite key: %v", err)
+			return fmt.Errorf("failed to create composite key: %v", err)
 		}
 
 		err = ctx.GetStub().DelState(utxoInputCompositeKey)
 		if err != nil {
-			return nil, err
+			return err
 		}
 		log.Printf("utxoInput deleted: %+v", utxoInput)
 	}
@@ -142,30 +240,92 @@ func (s *SmartContract) Transfer(ctx contractapi.TransactionContextInterface, ut
 	for _, utxoOutput := range utxoOutputs {
 		utxoOutputCompositeKey, err := ctx.GetStub().CreateCompositeKey("utxo", []string{utxoOutput.Owner, utxoOutput.Key})
 		if err != nil {
-			return nil, fmt.Errorf("failed to create composite key: %v", err)
+			return fmt.Errorf("failed to create composite key: %v", err)
 		}
 
-		err = ctx.GetStub().PutState(utxoOutputCompositeKey, []byte(strconv.Itoa(utxoOutput.Amount)))
+		value, err := marshalUTXO(&utxoOutput)
 		if err != nil {
-			return nil, err
+			return err
+		}
+
+		err = ctx.GetStub().PutState(utxoOutputCompositeKey, value)
+		if err != nil {
+			return err
 		}
 		log.Printf("utxoOutput created: %+v", utxoOutput)
 	}
 
-	return utxoOutputs, nil
+	return nil
 }
 
-// ClientUTXOs returns all UTXOs owned by the calling client
-func (s *SmartContract) ClientUTXOs(ctx contractapi.TransactionContextInterface) ([]*UTXO, error) {
+// readUTXO returns the utxo of an owner, or nil if the owner has no utxo with the key
+func readUTXO(ctx contractapi.TransactionContextInterface, owner string, utxoKey string) (*UTXO, error) {
 
-	// Get ID of submitting client identity
-	clientID, err := ctx.GetClientIdentity().GetID()
+	utxoCompositeKey, err := ctx.GetStub().CreateCompositeKey("utxo", []string{owner, utxoKey})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get client id: %v", err)
+		return nil, fmt.Errorf("failed to create composite key: %v", err)
 	}
 
+	valueBytes, err := ctx.GetStub().GetState(utxoCompositeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read utxoCompositeKey %s from world state: %v", utxoCompositeKey, err)
+	}
+	if valueBytes == nil {
+		return nil, nil
+	}
+
+	return unmarshalUTXO(owner, utxoKey, valueBytes)
+}
+
+// marshalUTXO returns the state of a utxo. The state of a plain utxo is its amount, while a
+// utxo with several owners or a time lock is stored as JSON.
+func marshalUTXO(utxo *UTXO) ([]byte, error) {
+	if len(utxo.Owners) == 0 && utxo.LockedUntil == "" {
+		return []byte(strconv.Itoa(utxo.Amount)), nil
+	}
+
+	utxoJSON, err := json.Marshal(utxo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return utxoJSON, nil
+}
+
+// unmarshalUTXO parses the state of a utxo that was stored by marshalUTXO
+func unmarshalUTXO(owner string, utxoKey string, value []byte) (*UTXO, error) {
+	if len(value) > 0 && value[0] == '{' {
+		var utxo UTXO
+		err := json.Unmarshal(value, &utxo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal utxo %s: %v", utxoKey, err)
+		}
+		return &utxo, nil
+	}
+
+	amount, _ := strconv.Atoi(string(value)) // Error handling not needed since Itoa() was used when setting the utxo amount, guaranteeing it was an integer.
+
+	return &UTXO{
+		Key:    utxoKey,
+		Owner:  owner,
+		Amount: amount,
+	}, nil
+}
+
+// getTxTime returns the timestamp of the transaction, which is the same on all endorsing peers
+func getTxTime(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	timestamp, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return time.Unix(timestamp.Seconds, int64(timestamp.Nanos)).UTC(), nil
+}
+
+// queryUTXOs returns all UTXOs owned by the owner
+// Dependant functions include ClientUTXOs and MultisigUTXOs
+func queryUTXOs(ctx contractapi.TransactionContextInterface, owner string) ([]*UTXO, error) {
+
 	// since utxos have a composite key of owner:utxoKey, we can query for all utxos matching owner:*
-	utxoResultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("utxo", []string{clientID})
+	utxoResultsIterator, err := ctx.GetStub().GetStateByPartialCompositeKey("utxo", []string{owner})
 	if err != nil {
 		return nil, err
 	}
@@ -194,28 +354,12 @@ func (s *SmartContract) ClientUTXOs(ctx contractapi.TransactionContextInterface)
 			return nil, fmt.Errorf("utxo %s has no value", utxoKey)
 		}
 
-		amount, _ := strconv.Atoi(string(utxoRecord.Value)) // Error handling not needed since Itoa() was used when setting the utxo amount, guaranteeing it was an integer.
-
-		utxo := &UTXO{
-			Key:    utxoKey,
-			Owner:  clientID,
-			Amount: amount,
+		utxo, err := unmarshalUTXO(owner, utxoKey, utxoRecord.Value)
+		if err != nil {
+			return nil, err
 		}
 
 		utxos = append(utxos, utxo)
 	}
 	return utxos, nil
 }
-
-// ClientID returns the client id of the calling client
-// Users can use this function to get their own client id, which they can then give to others as the payment address
-func (s *SmartContract) ClientID(ctx contractapi.TransactionContextInterface) (string, error) {
-
-	// Get ID of submitting client identity
-	clientID, err := ctx.GetClientIdentity().GetID()
-	if err != nil {
-		return "", fmt.Errorf("failed to get client id: %v", err)
-	}
-
-	return clientID, nil
-}
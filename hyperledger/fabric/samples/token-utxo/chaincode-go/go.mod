@@ -2,4 +2,7 @@ module github.com/hyperledger/fabric-samples/token-utxo/chaincode-go
 
 go 1.14
 
-require github.com/hyperledger/fabric-contract-api-go v1.1.0
+require (
+	github.com/hyperledger/fabric-contract-api-go v1.1.0
+	github.com/stretchr/testify v1.5.1
+)
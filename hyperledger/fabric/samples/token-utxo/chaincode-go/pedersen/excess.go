@@ -0,0 +1,85 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pedersen
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// ExcessSignature proves that the amounts of a transfer balance. If the input amounts equal
+// the output amounts, the sum of the input commitments minus the sum of the output commitments,
+// the excess, is a multiple x*H of H, where x is the excess blinding factor. A Schnorr signature
+// with the excess as public key proves that the signer knows x, which it cannot if the excess has
+// a G component. Signing a message that describes the transfer prevents the signature from being
+// reused for a different transfer.
+type ExcessSignature struct {
+	R string `json:"r"`
+	S string `json:"s"`
+}
+
+// SignExcess signs the message with the excess blinding factor
+func SignExcess(excessBlinding *big.Int, message []byte) (*ExcessSignature, error) {
+
+	n := curve.Params().N
+	excess := mulH(excessBlinding)
+
+	k, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	r := mulH(k)
+
+	e := excessChallenge(r, excess, message)
+	s := new(big.Int).Mul(e, excessBlinding)
+	s.Add(s, k)
+	s.Mod(s, n)
+
+	return &ExcessSignature{
+		R: encodePoint(r),
+		S: encodeScalar(s),
+	}, nil
+}
+
+// VerifyBalance checks that the inputs commit to the same total amount as the outputs, by
+// verifying the excess signature of the message
+func VerifyBalance(inputs []string, outputs []string, signature *ExcessSignature, message []byte) error {
+
+	if signature == nil {
+		return fmt.Errorf("excess signature is required")
+	}
+
+	excess, err := sumCommitments(inputs, outputs)
+	if err != nil {
+		return err
+	}
+
+	r, err := decodePoint(signature.R)
+	if err != nil {
+		return err
+	}
+	s, err := decodeScalar(signature.S)
+	if err != nil {
+		return err
+	}
+
+	// s*H = R + e*excess
+	e := excessChallenge(r, excess, message)
+	if !equal(mulH(s), add(r, mul(excess, e))) {
+		return fmt.Errorf("excess signature is invalid, the input and output amounts do not balance")
+	}
+
+	return nil
+}
+
+// excessChallenge returns the challenge of an excess signature
+func excessChallenge(r point, excess point, message []byte) *big.Int {
+	return challenge(
+		[]byte("excess"),
+		[]byte(encodePoint(r)),
+		[]byte(encodePoint(excess)),
+		message,
+	)
+}
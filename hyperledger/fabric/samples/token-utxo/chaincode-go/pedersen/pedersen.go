@@ -0,0 +1,212 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package pedersen implements the cryptography of the confidential UTXOs of the token contract:
+// Pedersen commitments to amounts, range proofs that a committed amount is not negative, and
+// signatures that prove that the amounts of a transfer balance. It uses the NIST P-256 curve of
+// the Go standard library.
+//
+// A commitment to an amount v with the blinding factor r is the point v*G + r*H, where G is the
+// base point of the curve and H is a second generator whose discrete logarithm with respect to G
+// is unknown. The commitment hides the amount, since r is random, and binds the owner to it, since
+// it cannot be opened to a different amount. Commitments can be added, which adds the amounts and
+// the blinding factors they commit to.
+package pedersen
+
+import (
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+var curve = elliptic.P256()
+
+// h is the second generator H of the commitments. It is derived by hashing a fixed string to a
+// point on the curve, so that nobody knows its discrete logarithm with respect to G.
+var hx, hy = hashToPoint([]byte("fabric-samples token-utxo pedersen generator H"))
+
+// point is a point on the curve. The point at infinity is (0, 0).
+type point struct {
+	x, y *big.Int
+}
+
+// Commit returns the hex encoded commitment value*G + blinding*H
+func Commit(value uint64, blinding *big.Int) string {
+	return encodePoint(commit(new(big.Int).SetUint64(value), blinding))
+}
+
+// NewBlinding returns a random blinding factor
+func NewBlinding() (*big.Int, error) {
+	for {
+		blinding, err := rand.Int(rand.Reader, curve.Params().N)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate blinding factor: %v", err)
+		}
+		if blinding.Sign() != 0 {
+			return blinding, nil
+		}
+	}
+}
+
+// ExcessBlinding returns the sum of the input blinding factors minus the sum of the output blinding
+// factors of a transfer, which is the private key of the excess signature of the transfer
+func ExcessBlinding(inputBlindings []*big.Int, outputBlindings []*big.Int) *big.Int {
+	excess := new(big.Int)
+	for _, blinding := range inputBlindings {
+		excess.Add(excess, blinding)
+	}
+	for _, blinding := range outputBlindings {
+		excess.Sub(excess, blinding)
+	}
+	return excess.Mod(excess, curve.Params().N)
+}
+
+// VerifyOpening checks that a commitment commits to the value with the blinding factor
+func VerifyOpening(commitment string, value uint64, blinding *big.Int) error {
+	c, err := decodePoint(commitment)
+	if err != nil {
+		return err
+	}
+	expected := commit(new(big.Int).SetUint64(value), blinding)
+	if !equal(c, expected) {
+		return fmt.Errorf("commitment does not match the value and blinding factor")
+	}
+	return nil
+}
+
+// commit returns value*G + blinding*H
+func commit(value *big.Int, blinding *big.Int) point {
+	vx, vy := curve.ScalarBaseMult(scalarBytes(value))
+	rx, ry := curve.ScalarMult(hx, hy, scalarBytes(blinding))
+	return add(point{vx, vy}, point{rx, ry})
+}
+
+// sumCommitments adds the commitments and subtracts the other commitments
+func sumCommitments(commitments []string, minus []string) (point, error) {
+	sum := point{new(big.Int), new(big.Int)}
+	for _, commitment := range commitments {
+		c, err := decodePoint(commitment)
+		if err != nil {
+			return point{}, err
+		}
+		sum = add(sum, c)
+	}
+	for _, commitment := range minus {
+		c, err := decodePoint(commitment)
+		if err != nil {
+			return point{}, err
+		}
+		sum = add(sum, neg(c))
+	}
+	return sum, nil
+}
+
+// add returns p + q
+func add(p point, q point) point {
+	x, y := curve.Add(p.x, p.y, q.x, q.y)
+	return point{x, y}
+}
+
+// neg returns -p
+func neg(p point) point {
+	if p.y.Sign() == 0 {
+		return point{new(big.Int).Set(p.x), new(big.Int)}
+	}
+	return point{new(big.Int).Set(p.x), new(big.Int).Sub(curve.Params().P, p.y)}
+}
+
+// mulH returns scalar*H
+func mulH(scalar *big.Int) point {
+	x, y := curve.ScalarMult(hx, hy, scalarBytes(scalar))
+	return point{x, y}
+}
+
+// mul returns scalar*p
+func mul(p point, scalar *big.Int) point {
+	x, y := curve.ScalarMult(p.x, p.y, scalarBytes(scalar))
+	return point{x, y}
+}
+
+// equal returns true if p and q are the same point
+func equal(p point, q point) bool {
+	return p.x.Cmp(q.x) == 0 && p.y.Cmp(q.y) == 0
+}
+
+// scalarBytes returns a scalar reduced modulo the order of the curve as 32 bytes
+func scalarBytes(scalar *big.Int) []byte {
+	reduced := new(big.Int).Mod(scalar, curve.Params().N).Bytes()
+	return append(make([]byte, 32-len(reduced)), reduced...)
+}
+
+// encodePoint returns the hex encoded uncompressed point, or an empty string for the point at infinity
+func encodePoint(p point) string {
+	if p.x.Sign() == 0 && p.y.Sign() == 0 {
+		return ""
+	}
+	return hex.EncodeToString(elliptic.Marshal(curve, p.x, p.y))
+}
+
+// decodePoint parses a point that was encoded by encodePoint, and checks that it is on the curve
+func decodePoint(encoded string) (point, error) {
+	if encoded == "" {
+		return point{new(big.Int), new(big.Int)}, nil
+	}
+	data, err := hex.DecodeString(encoded)
+	if err != nil {
+		return point{}, fmt.Errorf("invalid point %q: %v", encoded, err)
+	}
+	x, y := elliptic.Unmarshal(curve, data)
+	if x == nil {
+		return point{}, fmt.Errorf("invalid point %q: not on the curve", encoded)
+	}
+	return point{x, y}, nil
+}
+
+// hashToPoint maps data to a point on the curve by hashing it with a counter until the hash is
+// the x coordinate of a point
+func hashToPoint(data []byte) (*big.Int, *big.Int) {
+	params := curve.Params()
+	three := big.NewInt(3)
+	for counter := uint32(0); ; counter++ {
+		counterBytes := make([]byte, 4)
+		binary.BigEndian.PutUint32(counterBytes, counter)
+		digest := sha256.Sum256(append(append([]byte{}, data...), counterBytes...))
+
+		x := new(big.Int).SetBytes(digest[:])
+		x.Mod(x, params.P)
+
+		// y² = x³ - 3x + b
+		y2 := new(big.Int).Exp(x, three, params.P)
+		y2.Sub(y2, new(big.Int).Mul(three, x))
+		y2.Add(y2, params.B)
+		y2.Mod(y2, params.P)
+
+		y := new(big.Int).ModSqrt(y2, params.P)
+		if y != nil && curve.IsOnCurve(x, y) {
+			return x, y
+		}
+	}
+}
+
+// challenge hashes the parts of a proof to a scalar
+func challenge(parts ...[]byte) *big.Int {
+	digest := sha256.New()
+	for _, part := range parts {
+		length := make([]byte, 4)
+		binary.BigEndian.PutUint32(length, uint32(len(part)))
+		digest.Write(length)
+		digest.Write(part)
+	}
+	e := new(big.Int).SetBytes(digest.Sum(nil))
+	return e.Mod(e, curve.Params().N)
+}
+
+// randomScalar returns a random non-zero scalar
+func randomScalar() (*big.Int, error) {
+	return NewBlinding()
+}
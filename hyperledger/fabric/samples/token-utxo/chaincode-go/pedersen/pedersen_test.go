@@ -0,0 +1,81 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pedersen_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/hyperledger/fabric-samples/token-utxo/chaincode-go/pedersen"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommit(t *testing.T) {
+	blinding := newBlinding(t)
+
+	commitment := pedersen.Commit(10, blinding)
+	require.NoError(t, pedersen.VerifyOpening(commitment, 10, blinding))
+	require.Error(t, pedersen.VerifyOpening(commitment, 11, blinding))
+
+	// The same amount with a different blinding factor gives a different commitment
+	require.NotEqual(t, commitment, pedersen.Commit(10, newBlinding(t)))
+}
+
+func TestRangeProof(t *testing.T) {
+	for _, value := range []uint64{0, 1, 1000, 1<<pedersen.RangeBits - 1} {
+		blinding := newBlinding(t)
+		commitment := pedersen.Commit(value, blinding)
+
+		proof, err := pedersen.ProveRange(value, blinding)
+		require.NoError(t, err)
+		require.NoError(t, pedersen.VerifyRange(commitment, proof))
+
+		// The proof is bound to its commitment
+		require.Error(t, pedersen.VerifyRange(pedersen.Commit(value, newBlinding(t)), proof))
+	}
+
+	_, err := pedersen.ProveRange(1<<pedersen.RangeBits, newBlinding(t))
+	require.EqualError(t, err, "value 4294967296 is out of range, must be less than 2^32")
+}
+
+func TestRangeProofTampered(t *testing.T) {
+	blinding := newBlinding(t)
+	commitment := pedersen.Commit(5, blinding)
+
+	proof, err := pedersen.ProveRange(5, blinding)
+	require.NoError(t, err)
+
+	// Swapping the challenges of a bit breaks its proof
+	proof.Bits[0].E0, proof.Bits[0].E1 = proof.Bits[0].E1, proof.Bits[0].E0
+	require.EqualError(t, pedersen.VerifyRange(commitment, proof), "invalid proof of bit 0: challenge does not match")
+
+	proof.Bits = proof.Bits[1:]
+	require.EqualError(t, pedersen.VerifyRange(commitment, proof), "range proof must have 32 bits")
+}
+
+func TestVerifyBalance(t *testing.T) {
+	in1, in2, out1, out2 := newBlinding(t), newBlinding(t), newBlinding(t), newBlinding(t)
+	inputs := []string{pedersen.Commit(30, in1), pedersen.Commit(20, in2)}
+	outputs := []string{pedersen.Commit(45, out1), pedersen.Commit(5, out2)}
+	message := []byte("transfer")
+
+	excess := pedersen.ExcessBlinding([]*big.Int{in1, in2}, []*big.Int{out1, out2})
+	signature, err := pedersen.SignExcess(excess, message)
+	require.NoError(t, err)
+	require.NoError(t, pedersen.VerifyBalance(inputs, outputs, signature, message))
+
+	// The signature is bound to the message
+	require.Error(t, pedersen.VerifyBalance(inputs, outputs, signature, []byte("other transfer")))
+
+	// Outputs that do not add up to the inputs cannot be signed
+	outputs = []string{pedersen.Commit(46, out1), pedersen.Commit(5, out2)}
+	require.EqualError(t, pedersen.VerifyBalance(inputs, outputs, signature, message), "excess signature is invalid, the input and output amounts do not balance")
+}
+
+func newBlinding(t *testing.T) *big.Int {
+	blinding, err := pedersen.NewBlinding()
+	require.NoError(t, err)
+	return blinding
+}
@@ -0,0 +1,224 @@
+/*
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pedersen
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+)
+
+// RangeBits is the number of bits of the amounts that a range proof covers, so amounts
+// must be less than 2^RangeBits
+const RangeBits = 32
+
+// RangeProof proves that a commitment commits to an amount between 0 and 2^RangeBits - 1
+// without revealing the amount. The amount is split into bits, and each bit is committed to
+// separately, so that the bit commitments add up to the commitment. Each bit commitment
+// comes with a proof that it commits to either 0 or its power of two.
+type RangeProof struct {
+	Bits []BitProof `json:"bits"`
+}
+
+// BitProof proves that the commitment C to bit i commits to 0 or 2^i, that is, that the
+// prover knows the blinding factor of either C or C - 2^i*G with respect to H. It is a
+// ring signature over the two public keys, in which the challenges E0 and E1 must add up
+// to the hash of the proof. The prover chooses the challenge of the false statement and
+// simulates its response S, and can only answer the challenge that remains for the true
+// statement.
+type BitProof struct {
+	Commitment string `json:"commitment"`
+	E0         string `json:"e0"`
+	E1         string `json:"e1"`
+	S0         string `json:"s0"`
+	S1         string `json:"s1"`
+}
+
+// ProveRange returns a range proof for the commitment Commit(value, blinding)
+func ProveRange(value uint64, blinding *big.Int) (*RangeProof, error) {
+
+	if value >= 1<<RangeBits {
+		return nil, fmt.Errorf("value %d is out of range, must be less than 2^%d", value, RangeBits)
+	}
+
+	n := curve.Params().N
+	commitment := commit(new(big.Int).SetUint64(value), blinding)
+
+	proof := &RangeProof{}
+	blindingSum := new(big.Int)
+	for i := 0; i < RangeBits; i++ {
+		// The blinding factors of the bits add up to the blinding factor of the commitment
+		var bitBlinding *big.Int
+		if i < RangeBits-1 {
+			var err error
+			bitBlinding, err = randomScalar()
+			if err != nil {
+				return nil, err
+			}
+			blindingSum.Add(blindingSum, bitBlinding)
+		} else {
+			bitBlinding = new(big.Int).Sub(blinding, blindingSum)
+			bitBlinding.Mod(bitBlinding, n)
+		}
+
+		bit := int(value>>uint(i)) & 1
+		bitProof, err := proveBit(commitment, i, bit, bitBlinding)
+		if err != nil {
+			return nil, err
+		}
+		proof.Bits = append(proof.Bits, *bitProof)
+	}
+
+	return proof, nil
+}
+
+// VerifyRange checks that a range proof is valid for the commitment
+func VerifyRange(commitment string, proof *RangeProof) error {
+
+	c, err := decodePoint(commitment)
+	if err != nil {
+		return err
+	}
+	if proof == nil || len(proof.Bits) != RangeBits {
+		return fmt.Errorf("range proof must have %d bits", RangeBits)
+	}
+
+	sum := point{new(big.Int), new(big.Int)}
+	for i, bitProof := range proof.Bits {
+		bitCommitment, err := verifyBit(c, i, &bitProof)
+		if err != nil {
+			return fmt.Errorf("invalid proof of bit %d: %v", i, err)
+		}
+		sum = add(sum, bitCommitment)
+	}
+
+	if !equal(sum, c) {
+		return fmt.Errorf("bit commitments do not add up to the commitment")
+	}
+
+	return nil
+}
+
+// proveBit returns the proof that the commitment to bit i commits to 0 or 2^i
+func proveBit(commitment point, i int, bit int, bitBlinding *big.Int) (*BitProof, error) {
+
+	n := curve.Params().N
+
+	// The two statements are that P0 = C or P1 = C - 2^i*G is a multiple of H
+	bitCommitment := commit(new(big.Int).Lsh(big.NewInt(int64(bit)), uint(i)), bitBlinding)
+	keys := bitKeys(bitCommitment, i)
+
+	k, err := randomScalar()
+	if err != nil {
+		return nil, err
+	}
+
+	// Simulate the proof of the false statement with a random challenge and response
+	other := 1 - bit
+	e := make([]*big.Int, 2)
+	s := make([]*big.Int, 2)
+	r := make([]point, 2)
+	e[other], err = randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	s[other], err = randomScalar()
+	if err != nil {
+		return nil, err
+	}
+	r[other] = add(mulH(s[other]), neg(mul(keys[other], e[other])))
+
+	// Prove the true statement with the rest of the challenge
+	r[bit] = mulH(k)
+	total := bitChallenge(commitment, i, bitCommitment, r[0], r[1])
+	e[bit] = new(big.Int).Sub(total, e[other])
+	e[bit].Mod(e[bit], n)
+	s[bit] = new(big.Int).Mul(e[bit], bitBlinding)
+	s[bit].Add(s[bit], k)
+	s[bit].Mod(s[bit], n)
+
+	return &BitProof{
+		Commitment: encodePoint(bitCommitment),
+		E0:         encodeScalar(e[0]),
+		E1:         encodeScalar(e[1]),
+		S0:         encodeScalar(s[0]),
+		S1:         encodeScalar(s[1]),
+	}, nil
+}
+
+// verifyBit checks the proof of bit i of the commitment, and returns the commitment to the bit
+func verifyBit(commitment point, i int, proof *BitProof) (point, error) {
+
+	bitCommitment, err := decodePoint(proof.Commitment)
+	if err != nil {
+		return point{}, err
+	}
+	keys := bitKeys(bitCommitment, i)
+
+	var e, s [2]*big.Int
+	for j, encoded := range []string{proof.E0, proof.E1, proof.S0, proof.S1} {
+		scalar, err := decodeScalar(encoded)
+		if err != nil {
+			return point{}, err
+		}
+		if j < 2 {
+			e[j] = scalar
+		} else {
+			s[j-2] = scalar
+		}
+	}
+
+	// R = s*H - e*P for both statements
+	r0 := add(mulH(s[0]), neg(mul(keys[0], e[0])))
+	r1 := add(mulH(s[1]), neg(mul(keys[1], e[1])))
+
+	total := new(big.Int).Add(e[0], e[1])
+	total.Mod(total, curve.Params().N)
+	if total.Cmp(bitChallenge(commitment, i, bitCommitment, r0, r1)) != 0 {
+		return point{}, fmt.Errorf("challenge does not match")
+	}
+
+	return bitCommitment, nil
+}
+
+// bitKeys returns the two public keys of the proof of bit i, C and C - 2^i*G
+func bitKeys(bitCommitment point, i int) []point {
+	px, py := curve.ScalarBaseMult(scalarBytes(new(big.Int).Lsh(big.NewInt(1), uint(i))))
+	return []point{bitCommitment, add(bitCommitment, neg(point{px, py}))}
+}
+
+// bitChallenge returns the challenge of the proof of bit i, which binds the proof to the
+// commitment and the position of the bit
+func bitChallenge(commitment point, i int, bitCommitment point, r0 point, r1 point) *big.Int {
+	index := make([]byte, 4)
+	binary.BigEndian.PutUint32(index, uint32(i))
+	return challenge(
+		[]byte("range"),
+		[]byte(encodePoint(commitment)),
+		index,
+		[]byte(encodePoint(bitCommitment)),
+		[]byte(encodePoint(r0)),
+		[]byte(encodePoint(r1)),
+	)
+}
+
+// encodeScalar returns the hex encoded scalar
+func encodeScalar(scalar *big.Int) string {
+	return hex.EncodeToString(scalarBytes(scalar))
+}
+
+// decodeScalar parses a scalar that was encoded by encodeScalar
+func decodeScalar(encoded string) (*big.Int, error) {
+	data, err := hex.DecodeString(encoded)
+	if err != nil || len(data) != 32 {
+		return nil, fmt.Errorf("invalid scalar %q", encoded)
+	}
+	scalar := new(big.Int).SetBytes(data)
+	if scalar.Cmp(curve.Params().N) >= 0 {
+		return nil, fmt.Errorf("invalid scalar %q: not less than the order of the curve", encoded)
+	}
+	return scalar, nil
+}